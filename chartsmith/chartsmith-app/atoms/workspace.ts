@@ -104,26 +104,31 @@ export const chartsAtom = atom(get => {
   return workspace.charts
 })
 
+// hasPendingChange is true if a plan changed the content of the file, or deleted or renamed it, and the
+// change hasn't been accepted or rejected yet
+export const hasPendingChange = (f?: WorkspaceFile) =>
+  !!f && ((!!f.contentPending && f.contentPending.length > 0) || !!f.pendingDelete || !!f.filePathPending)
+
 export const chartsBeforeApplyingContentPendingAtom = atom<Chart[]>([])
 export const looseFilesBeforeApplyingContentPendingAtom = atom<WorkspaceFile[]>([])
 
 export const allFilesBeforeApplyingContentPendingAtom = atom(get => {
   const charts = get(chartsBeforeApplyingContentPendingAtom)
-  const chartFilesBeforeApplyingContentPending = charts.flatMap(c => c.files.filter(f => f.contentPending && f.contentPending.length > 0))
+  const chartFilesBeforeApplyingContentPending = charts.flatMap(c => c.files.filter(hasPendingChange))
   const looseFilesBeforeApplyingContentPending = get(looseFilesBeforeApplyingContentPendingAtom)
   return [...chartFilesBeforeApplyingContentPending, ...looseFilesBeforeApplyingContentPending]
 })
 
 export const allFilesWithContentPendingAtom = atom(get => {
   const files = get(looseFilesAtom)
-  const filesWithContentPending = files.filter(f => f.contentPending && f.contentPending.length > 0)
+  const filesWithContentPending = files.filter(hasPendingChange)
 
   // find files in charts with pending patches too
   const charts = get(chartsAtom)
-  const chartsWithContentPending = charts.filter(c => c.files.some(f => f.contentPending && f.contentPending.length > 0))
+  const chartsWithContentPending = charts.filter(c => c.files.some(hasPendingChange))
   // get the files with pending patches from each of the charts with pending patches
   const filesWithContentPendingFromCharts = chartsWithContentPending.flatMap(c =>
-    c.files.filter(f => f.contentPending && f.contentPending.length > 0))
+    c.files.filter(hasPendingChange))
 
   return [...filesWithContentPending, ...filesWithContentPendingFromCharts]
 })
@@ -232,8 +237,10 @@ export const renderedFilesByRevisionAtom = atom(get => {
     // Filter renders by the specified revision
     const revisionRenders = renders.filter(render => render.revisionNumber === revisionNumber);
 
+    // the rendered files of a revision are the charts rendered with their own values, values profile renders
+    // keep their files on their rendered chart
     return revisionRenders.flatMap(render =>
-      render.charts.flatMap(chart =>
+      render.charts.filter(chart => !chart.valuesProfile).flatMap(chart =>
         chart.renderedFiles || []
       )
     );
@@ -26,6 +26,7 @@ import { conversionByIdAtom, messageByIdAtom, messagesAtom, renderByIdAtom, work
 
 // actions
 import { cancelMessageAction } from "@/lib/workspace/actions/cancel-message";
+import { cancelRenderAction } from "@/lib/workspace/actions/cancel-work";
 import { performFollowupAction } from "@/lib/workspace/actions/perform-followup-action";
 import { createChatMessageAction } from "@/lib/workspace/actions/create-chat-message";
 import { getWorkspaceMessagesAction } from "@/lib/workspace/actions/get-workspace-messages";
@@ -179,6 +180,17 @@ export function ChatMessage({
 
         {message?.responseRenderId && !render?.isAutorender && (
           <div className="space-y-4 mt-4">
+            {render && !render.completedAt && (
+              <div className="flex justify-end">
+                <button
+                  onClick={() => cancelRenderAction(session, render.id)}
+                  data-testid="render-cancel-button"
+                  className={`text-xs px-2 py-0.5 rounded ${theme === "dark" ? "text-gray-400 hover:text-gray-200 hover:bg-dark-border/40" : "text-gray-500 hover:text-gray-700 hover:bg-gray-100"}`}
+                >
+                  Cancel render
+                </button>
+              </div>
+            )}
             {render?.charts ? (
               render.charts.map((chart, index) => (
                 <Terminal
@@ -188,6 +200,9 @@ export function ChatMessage({
                   depUpdateCommandStreamed={chart.depUpdateCommand}
                   depUpdateStderrStreamed={chart.depUpdateStderr}
                   depUpdateStdoutStreamed={chart.depUpdateStdout}
+                  helmLintCommandStreamed={chart.helmLintCommand}
+                  helmLintStderrStreamed={chart.helmLintStderr}
+                  helmLintStdoutStreamed={chart.helmLintStdout}
                   helmTemplateCommandStreamed={chart.helmTemplateCommand}
                   helmTemplateStderrStreamed={chart.helmTemplateStderr}
                 />
@@ -6,7 +6,7 @@ import { Check, X, ChevronUp, ChevronDown } from "lucide-react";
 
 // atoms
 import { selectedFileAtom, currentDiffIndexAtom, updateCurrentDiffIndexAtom, updateFileContentAtom } from "@/atoms/workspace";
-import { allFilesBeforeApplyingContentPendingAtom, allFilesWithContentPendingAtom, workspaceAtom, addFileToWorkspaceAtom, plansAtom } from "@/atoms/workspace";
+import { allFilesBeforeApplyingContentPendingAtom, allFilesWithContentPendingAtom, workspaceAtom, addFileToWorkspaceAtom, plansAtom, hasPendingChange } from "@/atoms/workspace";
 
 // types
 import type { editor } from "monaco-editor";
@@ -168,8 +168,34 @@ export const CodeEditor = React.memo(function CodeEditor({
   // Always show the diff header when there are pending changes, but the contents will change based on plan status
   const showDiffHeader = allFilesWithContentPending.length > 0;
 
+  // a delete or rename without a change to the content has nothing to show in the editor, so the workspace
+  // is fetched again once it has been accepted or rejected
+  const handlePendingPathChange = async (accept: boolean) => {
+    if (!selectedFile) return;
+
+    setAcceptDropdownOpen(false);
+    setRejectDropdownOpen(false);
+
+    try {
+      const updatedFile = accept
+        ? await acceptPatchAction(session, workspace.id, selectedFile.id, workspace.currentRevisionNumber)
+        : await rejectPatchAction(session, selectedFile.id, workspace.currentRevisionNumber);
+
+      const freshWorkspace = await getWorkspaceAction(session, workspace.id);
+      if (freshWorkspace) {
+        setWorkspace(freshWorkspace);
+      }
+
+      setSelectedFile(accept && updatedFile.pendingDelete ? undefined : updatedFile);
+    } catch (error) {
+      console.error("Error applying pending file change:", error);
+    }
+  };
+
   const handleAcceptThisFile = async () => {
-    if (selectedFile?.contentPending && selectedFile.contentPending.length > 0) {
+    if (hasPendingChange(selectedFile) && !selectedFile?.contentPending) {
+      await handlePendingPathChange(true);
+    } else if (selectedFile?.contentPending && selectedFile.contentPending.length > 0) {
       try {
         // Get the modified content from our pre-computed value and store it locally
         // This ensures we have the value even if the editor is disposed during state updates
@@ -383,7 +409,9 @@ export const CodeEditor = React.memo(function CodeEditor({
   };
 
   const handleRejectThisFile = async () => {
-    if (selectedFile?.contentPending && selectedFile.contentPending.length > 0) {
+    if (hasPendingChange(selectedFile) && !selectedFile?.contentPending) {
+      await handlePendingPathChange(false);
+    } else if (selectedFile?.contentPending && selectedFile.contentPending.length > 0) {
       try {
         // First, immediately close the dropdown to prevent double-clicks
         setRejectDropdownOpen(false);
@@ -622,8 +650,13 @@ export const CodeEditor = React.memo(function CodeEditor({
           </div>
         )}
 
-        {allFilesWithContentPending.length > 0 && selectedFile?.contentPending && selectedFile.contentPending.length > 0 && (
+        {allFilesWithContentPending.length > 0 && hasPendingChange(selectedFile) && (
           <div className="flex items-center gap-2">
+            {(selectedFile?.pendingDelete || selectedFile?.filePathPending) && (
+              <span className={`text-xs font-mono ${theme === "dark" ? "text-gray-400" : "text-gray-500"}`}>
+                {selectedFile.pendingDelete ? "Deleted" : `Renamed to ${selectedFile.filePathPending}`}
+              </span>
+            )}
             {mostRecentPlan?.status === "applied" && (
               <>
                 <div ref={acceptButtonRef} className="relative">
@@ -8,6 +8,7 @@ import { conversionByIdAtom, conversionsAtom, handleConversionUpdatedAtom } from
 import { FileList } from './FileList';
 import type { ConversionStep, FileConversion } from "./conversion-types";
 import { getWorkspaceConversionAction } from "@/lib/workspace/actions/get-workspace-conversion";
+import { cancelConversionAction } from "@/lib/workspace/actions/cancel-work";
 import { useSession } from "@/app/hooks/useSession";
 import { Conversion, ConversionStatus } from "@/lib/types/workspace";
 
@@ -251,9 +252,34 @@ export function ConversionProgress({ conversionId }: { conversionId: string }) {
     console.log('Continue clicked - implement next action');
   };
 
+  const isRunning = conversion.status !== ConversionStatus.Complete &&
+    conversion.status !== ConversionStatus.Cancelled &&
+    conversion.status !== ConversionStatus.QuotaExceeded;
+
   return (
     <div className="p-4">
       <Header />
+      {conversion.status === ConversionStatus.Cancelled && (
+        <div className="text-sm text-gray-400 py-2">
+          Conversion cancelled
+        </div>
+      )}
+      {conversion.status === ConversionStatus.QuotaExceeded && (
+        <div className="text-sm text-red-400 py-2">
+          Conversion stopped, the LLM usage limit has been reached
+        </div>
+      )}
+      {isRunning && session && (
+        <div className="flex justify-end">
+          <button
+            onClick={() => cancelConversionAction(session, conversion.id)}
+            data-testid="conversion-cancel-button"
+            className="text-xs px-2 py-0.5 rounded text-gray-400 hover:text-gray-200 hover:bg-blue-500/10"
+          >
+            Cancel conversion
+          </button>
+        </div>
+      )}
       <div className="space-y-1">
         {steps.map((step, index) => {
           const statusOrder = [
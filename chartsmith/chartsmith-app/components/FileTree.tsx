@@ -15,6 +15,8 @@ interface TreeNode {
   children: TreeNode[];
   name: string;
   contentPending?: string;
+  pendingDelete?: boolean;
+  filePathPending?: string;
 }
 
 interface FileTreeProps {
@@ -398,6 +400,8 @@ export function FileTree({ files = [], charts = [] }: FileTreeProps) {
               filePath: rest.filePath,
               content: rest.content || '',
               contentPending: rest.contentPending,
+              pendingDelete: rest.pendingDelete,
+              filePathPending: rest.filePathPending,
               revisionNumber: 0, // Default revision number
             });
           }
@@ -416,7 +420,12 @@ export function FileTree({ files = [], charts = [] }: FileTreeProps) {
           <FileText className={`w-4 h-4 mr-2 ${selectedFile?.filePath === node.filePath ? "text-primary" : theme === "dark" ? "text-gray-400" : "text-gray-500"}`} />
         )}
         <div className="flex-1 flex items-center min-w-0">
-          <span className="text-xs truncate">{node.name}</span>
+          <span className={`text-xs truncate ${node.pendingDelete ? "line-through opacity-60" : ""}`}>{node.name}</span>
+          {node.filePathPending && (
+            <span className="ml-2 text-[10px] font-mono truncate text-yellow-500">
+              → {node.filePathPending.split('/').pop()}
+            </span>
+          )}
           {/* For new files (empty content with pending changes) */}
           {patchStats && (patchStats.additions > 0 || patchStats.deletions > 0) ? (
             <span className="ml-2 text-[10px] font-mono whitespace-nowrap">
@@ -13,7 +13,8 @@ import { FeedbackModal } from "@/components/FeedbackModal";
 
 // actions
 import { ignorePlanAction } from "@/lib/workspace/actions/ignore-plan";
-import { ThumbsUp, ThumbsDown, Send, ChevronDown, ChevronUp, Plus, Pencil, Trash2 } from "lucide-react";
+import { cancelPlanAction } from "@/lib/workspace/actions/cancel-work";
+import { ThumbsUp, ThumbsDown, Send, ChevronDown, ChevronUp, Plus, Pencil, Trash2, ArrowRight } from "lucide-react";
 import { createRevisionAction } from "@/lib/workspace/actions/create-revision";
 import { messagesAtom, workspaceAtom, handlePlanUpdatedAtom, planByIdAtom } from "@/atoms/workspace";
 import { createChatMessageAction } from "@/lib/workspace/actions/create-chat-message";
@@ -21,6 +22,7 @@ import { createChatMessageAction } from "@/lib/workspace/actions/create-chat-mes
 // types
 import { Message } from "@/components/types";
 import { Session } from "@/lib/types/session";
+import { PlanStatus } from "@/lib/types/workspace";
 
 interface PlanChatMessageProps {
   showActions?: boolean;
@@ -70,6 +72,7 @@ export function PlanChatMessage({
 
   const [chatInput, setChatInput] = useState("");
   const [isSubmitting, setIsSubmitting] = useState(false);
+  const [isCancelling, setIsCancelling] = useState(false);
   const actionsRef = useRef<HTMLDivElement>(null);
   const proceedButtonRef = useRef<HTMLButtonElement>(null);
 
@@ -142,6 +145,18 @@ export function PlanChatMessage({
     }
   };
 
+  // the plan is marked cancelled by the worker once it has stopped, which arrives as a plan update
+  const handleCancel = async () => {
+    if (!session || !plan) return;
+
+    setIsCancelling(true);
+    try {
+      await cancelPlanAction(session, plan.id);
+    } finally {
+      setIsCancelling(false);
+    }
+  };
+
   const handleProceed = async () => {
     if (!session || !plan) return;
 
@@ -189,7 +204,9 @@ export function PlanChatMessage({
               {plan.status === 'ignored' ? 'Superseded Plan' : 'Proposed Plan'}
             </span>
             <span className={`text-xs ${
-              plan.status === 'planning'
+              plan.status === PlanStatus.Cancelled || plan.status === PlanStatus.QuotaExceeded
+                ? `${theme === "dark" ? "text-red-500/70" : "text-red-600/70"}`
+                : plan.status === 'planning'
                 ? `${theme === "dark" ? "text-yellow-500/70" : "text-yellow-600/70"}`
                 : plan.status === 'pending'
                   ? `${theme === "dark" ? "text-blue-500/70" : "text-blue-600/70"}`
@@ -199,6 +216,18 @@ export function PlanChatMessage({
             }`}>
               ({plan.status})
             </span>
+            {session && (plan.status === PlanStatus.Planning || plan.status === PlanStatus.Applying) && (
+              <Button
+                variant="ghost"
+                size="sm"
+                onClick={handleCancel}
+                disabled={isCancelling}
+                data-testid="plan-message-cancel-button"
+                className={`ml-auto px-2 py-0.5 text-xs ${theme === "dark" ? "hover:bg-dark-border/40 text-gray-400 hover:text-gray-200" : "hover:bg-gray-100 text-gray-500 hover:text-gray-700"}`}
+              >
+                {isCancelling ? "Cancelling..." : "Cancel"}
+              </Button>
+            )}
           </div>
           <div className={`${
             plan.status === 'ignored'
@@ -279,11 +308,12 @@ export function PlanChatMessage({
                                 {action.action === 'create' && <Plus className="h-3 w-3 text-primary/70" />}
                                 {action.action === 'update' && <Pencil className="h-3 w-3 text-primary/70" />}
                                 {action.action === 'delete' && <Trash2 className="h-3 w-3 text-primary/70" />}
+                                {action.action === 'rename' && <ArrowRight className="h-3 w-3 text-primary/70" />}
                               </>
                             )}
                           </div>
                           <span className={`${theme === "dark" ? "text-gray-300" : "text-gray-600"} truncate font-mono text-[10px]`}>
-                            {action.path}
+                            {action.action === 'rename' && action.newPath ? `${action.path} → ${action.newPath}` : action.path}
                           </span>
                         </div>
                       </div>
@@ -6,6 +6,9 @@ interface TerminalProps {
   depUpdateCommandStreamed?: string;
   depUpdateStderrStreamed?: string;
   depUpdateStdoutStreamed?: string;
+  helmLintCommandStreamed?: string;
+  helmLintStderrStreamed?: string;
+  helmLintStdoutStreamed?: string;
   helmTemplateCommandStreamed?: string;
   helmTemplateStderrStreamed?: string;
   'data-testid'?: string;
@@ -19,6 +22,9 @@ export function Terminal({
   depUpdateCommandStreamed,
   depUpdateStderrStreamed,
   depUpdateStdoutStreamed,
+  helmLintCommandStreamed,
+  helmLintStderrStreamed,
+  helmLintStdoutStreamed,
   helmTemplateCommandStreamed,
   helmTemplateStderrStreamed,
   isCollapsed,
@@ -31,6 +37,9 @@ export function Terminal({
   const depUpdateCommandToShow = depUpdateCommandStreamed || chart.depUpdateCommand;
   const depUpdateStderrToShow = depUpdateStderrStreamed || chart.depUpdateStderr;
   const depUpdateStdoutToShow = depUpdateStdoutStreamed || chart.depUpdateStdout;
+  const helmLintCommandToShow = helmLintCommandStreamed || chart.helmLintCommand;
+  const helmLintStderrToShow = helmLintStderrStreamed || chart.helmLintStderr;
+  const helmLintStdoutToShow = helmLintStdoutStreamed || chart.helmLintStdout;
   const helmTemplateCommandToShow = helmTemplateCommandStreamed || chart.helmTemplateCommand;
   const helmTemplateStderrToShow = helmTemplateStderrStreamed || chart.helmTemplateStderr;
 
@@ -53,6 +62,7 @@ export function Terminal({
         </div>
         <div className={`flex-1 text-center text-xs ${theme === "dark" ? "text-gray-400" : "text-gray-300"}`}>
           {chart.chartName}
+          {chart.valuesProfile && <span className="opacity-70"> ({chart.valuesProfile} values)</span>}
         </div>
       </div>
       {isCollapsed ? (
@@ -61,7 +71,7 @@ export function Terminal({
         </div>
       ) : (
         <div className={`p-3 text-[11px] ${theme === "dark" ? "text-gray-300" : "text-gray-100"}`}>
-          {!depUpdateCommandToShow && !helmTemplateCommandToShow ? (
+          {!depUpdateCommandToShow && !helmLintCommandToShow && !helmTemplateCommandToShow ? (
             <div className="mt-1 flex items-center">
               <span className="w-2 h-4 bg-gray-300 animate-pulse"></span>
             </div>
@@ -89,6 +99,33 @@ export function Terminal({
                   <span className="w-2 h-4 bg-gray-300 animate-pulse"></span>
                 </div>
               )}
+              {helmLintCommandToShow && (
+                <div className="flex gap-2 mt-4">
+                  <span className="flex-shrink-0 text-primary/70">% </span>
+                  <span className="text-primary/70 whitespace-pre-wrap">{helmLintCommandToShow}</span>
+                </div>
+              )}
+              {helmLintStderrToShow ? (
+                <div className="mt-2 text-red-400 whitespace-pre-wrap">
+                  {helmLintStderrToShow}
+                </div>
+              ) : null}
+              {chart.helmLintFindings && chart.helmLintFindings.length > 0 ? (
+                <div className="mt-2 space-y-1">
+                  {chart.helmLintFindings.map((finding, index) => (
+                    <div
+                      key={`${chart.id}-lint-${index}`}
+                      className={`whitespace-pre-wrap ${finding.severity === "error" ? "text-red-400" : finding.severity === "warning" ? "text-yellow-400" : "opacity-70"}`}
+                    >
+                      [{finding.severity.toUpperCase()}] {finding.filePath ? `${finding.filePath}${finding.line ? `:${finding.line}` : ""}: ` : ""}{finding.message}
+                    </div>
+                  ))}
+                </div>
+              ) : helmLintStdoutToShow ? (
+                <div className="mt-2 whitespace-pre-wrap">
+                  {helmLintStdoutToShow}
+                </div>
+              ) : null}
               {helmTemplateCommandToShow && (
                 <div className="flex gap-2 mt-4">
                   <span className="flex-shrink-0 text-primary/70">% </span>
@@ -103,12 +140,23 @@ export function Terminal({
               {chart.renderedFiles?.length > 0 && (
                 <div className="mt-4 space-y-1">
                   {chart.renderedFiles.map((file, index) => (
-                    <div
-                      key={`${chart.id}-${file.id}-${file.filePath}-${index}`}
-                      className="flex items-center gap-2 pl-2"
-                    >
-                      <span className="text-green-500">✓</span>
-                      <span>{file.filePath}</span>
+                    <div key={`${chart.id}-${file.id}-${file.filePath}-${index}`} className="pl-2">
+                      <div className="flex items-center gap-2">
+                        {file.findings?.some(finding => finding.severity === "error") ? (
+                          <span className="text-red-400">✗</span>
+                        ) : (
+                          <span className="text-green-500">✓</span>
+                        )}
+                        <span>{file.filePath}</span>
+                      </div>
+                      {file.findings?.filter(finding => finding.severity !== "info").map((finding, findingIndex) => (
+                        <div
+                          key={`${file.filePath}-finding-${findingIndex}`}
+                          className={`pl-6 whitespace-pre-wrap ${finding.severity === "error" ? "text-red-400" : "text-yellow-400"}`}
+                        >
+                          {finding.kind && finding.name ? `${finding.kind}/${finding.name}: ` : ""}{finding.message}
+                        </div>
+                      ))}
                     </div>
                   ))}
                 </div>
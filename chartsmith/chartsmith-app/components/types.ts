@@ -1,4 +1,4 @@
-import { Plan, Workspace, WorkspaceFile, RenderedFile, Conversion, ConversionFile } from "@/lib/types/workspace";
+import { Plan, Workspace, WorkspaceFile, RenderedFile, Conversion, ConversionFile, LintFinding } from "@/lib/types/workspace";
 
 export interface FileNode {
   name: string;
@@ -64,6 +64,8 @@ export interface RawFile {
   chart_id?: string;
   content: string;
   content_pending?: string;
+  pending_delete?: boolean;
+  file_path_pending?: string;
   revision_number: number;
 }
 
@@ -97,6 +99,10 @@ export interface CentrifugoMessageData {
   depUpdateCommand?: string;
   depUpdateStdout?: string;
   depUpdateStderr?: string;
+  helmLintCommand?: string;
+  helmLintStdout?: string;
+  helmLintStderr?: string;
+  helmLintFindings?: LintFinding[];
   helmTemplateCommand?: string;
   helmTemplateStdout?: string;
   helmTemplateStderr?: string;
@@ -107,6 +113,10 @@ export interface CentrifugoMessageData {
   status?: string;
   completedAt?: string;
   isAutorender?: boolean;
+  // the kind and id of the plan, render or conversion of a work-cancelled or quota-exceeded event
+  kind?: string;
+  id?: string;
+  quotaMessage?: string;
 }
 
 export interface RawRevision {
@@ -172,6 +182,10 @@ export interface RenderStreamEvent {
   depUpdateCommand?: string;
   depUpdateStdout?: string;
   depUpdateStderr?: string;
+  helmLintCommand?: string;
+  helmLintStdout?: string;
+  helmLintStderr?: string;
+  helmLintFindings?: LintFinding[];
   helmTemplateCommand?: string;
   helmTemplateStdout?: string;
   helmTemplateStderr?: string;
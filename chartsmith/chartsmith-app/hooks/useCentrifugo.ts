@@ -13,6 +13,8 @@ import { getCentrifugoTokenAction } from "@/lib/centrifugo/actions/get-centrifug
 import { getWorkspaceAction } from "@/lib/workspace/actions/get-workspace";
 import { getWorkspaceMessagesAction } from "@/lib/workspace/actions/get-workspace-messages";
 import { getWorkspaceRenderAction } from "@/lib/workspace/actions/get-workspace-render";
+import { getPlanAction } from "@/lib/workspace/actions/get-plan";
+import { getWorkspaceConversionAction } from "@/lib/workspace/actions/get-workspace-conversion";
 
 
 // atoms
@@ -30,7 +32,7 @@ import { selectedFileAtom } from "@/atoms/workspace";
 
 
 // types
-import { RenderedChart } from "@/lib/types/workspace";
+import { RenderedChart, WorkspaceFile } from "@/lib/types/workspace";
 import { replayEventsAction } from "@/lib/centrifugo/actions/reply-events-action";
 
 const RECONNECT_DELAY_MS = 1000;
@@ -189,7 +191,9 @@ export function useCentrifugo({
               revisionNumber: artifactFile.revision_number,
               filePath: artifactFile.filePath,
               content: artifactFile.content || "",
-              contentPending: artifactFile.content_pending
+              contentPending: artifactFile.content_pending,
+              pendingDelete: artifactFile.pending_delete,
+              filePathPending: artifactFile.file_path_pending
             };
 
             if (fileIndex >= 0) {
@@ -221,7 +225,9 @@ export function useCentrifugo({
           revisionNumber: artifactFile.revision_number,
           filePath: artifactFile.filePath,
           content: artifactFile.content || "",
-          contentPending: artifactFile.content_pending
+          contentPending: artifactFile.content_pending,
+          pendingDelete: artifactFile.pending_delete,
+          filePathPending: artifactFile.file_path_pending
         };
 
         if (fileIndex >= 0) {
@@ -251,7 +257,9 @@ export function useCentrifugo({
               revisionNumber: artifactFile.revision_number,
               filePath: artifactFile.filePath,
               content: artifactFile.content || "",
-              contentPending: artifactFile.content_pending
+              contentPending: artifactFile.content_pending,
+              pendingDelete: artifactFile.pending_delete,
+              filePathPending: artifactFile.file_path_pending
             });
           }, 50);
         }, 50);
@@ -263,6 +271,31 @@ export function useCentrifugo({
     });
   }, [setWorkspace, setSelectedFile]);
 
+  // artifact-deleted removes a file that an action created and then undid before it was saved
+  const handleArtifactDeleted = useCallback((data: CentrifugoMessageData) => {
+    if (!data.file || !data.workspaceId) return;
+
+    const deletedFile = data.file;
+    const workspaceId = data.workspaceId;
+    const isDeletedFile = (f: WorkspaceFile) => f.id === deletedFile.id;
+
+    setWorkspace(prevWorkspace => {
+      if (!prevWorkspace || prevWorkspace.id !== workspaceId) return prevWorkspace;
+
+      return {
+        ...prevWorkspace,
+        files: prevWorkspace.files.filter(f => !isDeletedFile(f)),
+        charts: prevWorkspace.charts.map(chart => ({
+          ...chart,
+          files: chart.files.filter(f => !isDeletedFile(f)),
+        })),
+      };
+    });
+
+    // Don't leave the deleted file open in the editor
+    setSelectedFile(prevSelectedFile => prevSelectedFile && isDeletedFile(prevSelectedFile) ? undefined : prevSelectedFile);
+  }, [setWorkspace, setSelectedFile]);
+
   const handleRenderStreamEvent = useCallback(async (data: CentrifugoMessageData) => {
     if (!session) return;
     if (data.eventType !== 'render-stream' || !data.renderChartId || !data.renderId) {
@@ -368,6 +401,10 @@ export function useCentrifugo({
               depUpdateCommand: data.depUpdateCommand,
               depUpdateStderr: data.depUpdateStderr,
               depUpdateStdout: data.depUpdateStdout,
+              helmLintCommand: data.helmLintCommand,
+              helmLintStderr: data.helmLintStderr,
+              helmLintStdout: data.helmLintStdout,
+              helmLintFindings: data.helmLintFindings ?? chart.helmLintFindings,
               completedAt: chartCompletedAt,
             };
           })
@@ -440,6 +477,37 @@ export function useCentrifugo({
     handleConversionUpdated(data.conversion);
   }, []);
 
+  // work-cancelled is sent once the worker has stopped a plan, render or conversion, after it updated the status
+  // of the plan or conversion. Renders are failed without an event of their own, so they are fetched again here
+  const handleWorkCancelled = useCallback(async (data: CentrifugoMessageData) => {
+    if (!session || !data.id) return;
+
+    if (data.kind === 'render') {
+      setActiveRenderIds(prev => prev.filter(id => id !== data.id));
+
+      const render = await getWorkspaceRenderAction(session, data.id);
+      setRenders(prev => prev.map(r => r.id !== render.id ? r : {
+        ...render,
+        createdAt: new Date(render.createdAt),
+        completedAt: render.completedAt ? new Date(render.completedAt) : undefined,
+        charts: render.charts.map(chart => ({
+          ...chart,
+          createdAt: new Date(chart.createdAt),
+          completedAt: chart.completedAt ? new Date(chart.completedAt) : undefined,
+        })),
+      }));
+    } else if (data.kind === 'plan') {
+      const plan = await getPlanAction(session, data.id);
+      handlePlanUpdated({
+        ...plan,
+        createdAt: new Date(plan.createdAt)
+      });
+    } else if (data.kind === 'conversion') {
+      const conversion = await getWorkspaceConversionAction(session, data.id);
+      handleConversionUpdated(conversion);
+    }
+  }, [session, setActiveRenderIds, setRenders, handlePlanUpdated, handleConversionUpdated]);
+
   const handleCentrifugoMessage = useCallback((message: { data: CentrifugoMessageData }) => {
     const eventType = message.data.eventType;
 
@@ -463,6 +531,13 @@ export function useCentrifugo({
       handleConversationUpdatedMessage(message.data);
     } else if (eventType === 'artifact-updated') {
       handleArtifactUpdated(message.data);
+    } else if (eventType === 'artifact-deleted') {
+      handleArtifactDeleted(message.data);
+    } else if (eventType === 'work-cancelled') {
+      handleWorkCancelled(message.data);
+    } else if (eventType === 'quota-exceeded') {
+      // the limit is explained in the chat message response, the plan or conversion only needs its new status
+      handleWorkCancelled(message.data);
     }
 
     const isWorkspaceUpdatedEvent = message.data.workspace;
@@ -476,9 +551,11 @@ export function useCentrifugo({
     handleRenderStreamEvent,
     handleWorkspaceUpdated,
     handleArtifactUpdated,
+    handleArtifactDeleted,
     handleRenderFileEvent,
     handleConversionFileUpdatedMessage,
-    handleConversationUpdatedMessage
+    handleConversationUpdatedMessage,
+    handleWorkCancelled
   ]);
 
   // Clear active renders when component unmounts
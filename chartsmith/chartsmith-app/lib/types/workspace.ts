@@ -14,6 +14,8 @@ export interface Workspace {
   createdByUserName?: string;
   createdByUserEmail?: string;
   createdByUserId?: string;
+  kubernetesVersion?: string;
+  disabledPolicyRules?: string[];
 }
 
 export interface WorkspaceFile {
@@ -22,6 +24,9 @@ export interface WorkspaceFile {
   filePath: string;
   content: string;
   contentPending?: string;
+  // a delete or rename made by a plan, applied when the change is accepted
+  pendingDelete?: boolean;
+  filePathPending?: string;
 }
 
 export interface Chart {
@@ -44,10 +49,16 @@ export interface RenderedChart {
   id: string;
   chartId: string;
   chartName: string;
+  // the values profile the chart was rendered with, unset when it was rendered with its own values
+  valuesProfile?: string;
   isSuccess: boolean;
   depUpdateCommand?: string;
   depUpdateStdout?: string;
   depUpdateStderr?: string;
+  helmLintCommand?: string;
+  helmLintStdout?: string;
+  helmLintStderr?: string;
+  helmLintFindings?: LintFinding[];
   helmTemplateCommand?: string;
   helmTemplateStdout?: string;
   helmTemplateStderr?: string;
@@ -56,10 +67,37 @@ export interface RenderedChart {
   renderedFiles: RenderedFile[];
 }
 
+// a message of helm lint, filePath is relative to the root of the chart and unset for the chart as a whole
+export interface LintFinding {
+  severity: "error" | "warning" | "info";
+  filePath?: string;
+  line?: number;
+  column?: number;
+  message: string;
+}
+
+export interface ValuesProfile {
+  name: string;
+  valuesYaml: string;
+  createdAt: Date;
+  updatedAt: Date;
+}
+
 export interface RenderedFile {
   id: string;
   filePath: string;
   renderedContent: string;
+  findings?: RenderFinding[];
+}
+
+export interface RenderFinding {
+  source: string;
+  severity: "error" | "warning" | "info";
+  rule: string;
+  kind?: string;
+  name?: string;
+  field?: string;
+  message: string;
 }
 
 export enum ConversionStatus {
@@ -71,6 +109,8 @@ export enum ConversionStatus {
   Simplifying = 'simplifying',
   Finalizing = 'finalizing',
   Complete = 'complete',
+  Cancelled = 'cancelled',
+  QuotaExceeded = 'quota_exceeded',
 }
 
 export interface Conversion {
@@ -98,6 +138,18 @@ export interface ConversionFile {
   status: ConversionFileStatus;
 }
 
+// PlanStatus matches the statuses of a plan in pkg/workspace/types. The app also shows plans with its own
+// statuses while they're being created, so Plan.status isn't limited to these
+export enum PlanStatus {
+  Pending = 'pending',
+  Planning = 'planning',
+  Review = 'review',
+  Applying = 'applying',
+  Applied = 'applied',
+  Cancelled = 'cancelled',
+  QuotaExceeded = 'quota_exceeded',
+}
+
 export interface Plan {
   id: string;
   description: string;
@@ -113,6 +165,8 @@ export interface ActionFile {
   action: string;
   path: string;
   status: string;
+  description?: string;
+  newPath?: string;
 }
 
 export interface ChatMessage {
@@ -136,6 +190,7 @@ export interface ChatMessage {
   isIgnored?: boolean;
   planId?: string;
   messageFromPersona?: ChatMessageFromPersona;
+  responseModel?: string;
 }
 
 export interface FollowupAction {
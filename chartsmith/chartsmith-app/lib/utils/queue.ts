@@ -18,3 +18,23 @@ export async function enqueueWork(channel: string, payload: QueuePayload): Promi
 
   await client.query(`SELECT pg_notify('${channel}', $1)`, [id]);
 }
+
+// cancelWorkForPayload requests cancellation of the unfinished jobs on channels that have value for key in their
+// payload, the same way persistence.CancelWorkForPayload does in the worker. It returns the ids of the jobs
+export async function cancelWorkForPayload(channels: string[], key: string, value: string): Promise<string[]> {
+  const client = getDB(await getParam("DB_URI"));
+
+  const result = await client.query(
+    `UPDATE work_queue SET cancel_requested_at = NOW() ` +
+    `WHERE channel = ANY($1) AND payload->>$2 = $3 AND completed_at IS NULL AND cancel_requested_at IS NULL ` +
+    `RETURNING id`,
+    [channels, key, value]
+  );
+
+  const ids: string[] = result.rows.map((row: { id: string }) => row.id);
+  for (const id of ids) {
+    await client.query(`SELECT pg_notify('work_queue_cancel', $1)`, [id]);
+  }
+
+  return ids;
+}
@@ -0,0 +1,37 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { cancelConversion, cancelPlan, cancelRender } from "../cancel";
+
+// cancelPlanAction stops the plan from being created or applied. The plan is marked cancelled once the worker
+// running it stops
+export async function cancelPlanAction(session: Session, planId: string): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  const jobIds = await cancelPlan(planId);
+  logger.info("cancelPlanAction", { planId, jobIds });
+}
+
+// cancelRenderAction stops a render. The render is failed once the worker running it stops
+export async function cancelRenderAction(session: Session, renderId: string): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  const jobIds = await cancelRender(renderId);
+  logger.info("cancelRenderAction", { renderId, jobIds });
+}
+
+// cancelConversionAction stops a conversion. The conversion is marked cancelled once the worker running it stops
+export async function cancelConversionAction(session: Session, conversionId: string): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  const jobIds = await cancelConversion(conversionId);
+  logger.info("cancelConversionAction", { conversionId, jobIds });
+}
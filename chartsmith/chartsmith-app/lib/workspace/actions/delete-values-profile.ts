@@ -0,0 +1,15 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { deleteValuesProfile } from "../values-profile";
+
+export async function deleteValuesProfileAction(session: Session, workspaceId: string, name: string): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  logger.info("deleteValuesProfileAction", { workspaceId, name });
+  await deleteValuesProfile(workspaceId, name);
+}
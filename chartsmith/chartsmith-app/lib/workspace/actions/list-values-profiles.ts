@@ -0,0 +1,14 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { ValuesProfile } from "@/lib/types/workspace";
+import { AppError } from "@/lib/utils/error";
+import { listValuesProfiles } from "../values-profile";
+
+export async function listValuesProfilesAction(session: Session, workspaceId: string): Promise<ValuesProfile[]> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  return listValuesProfiles(workspaceId);
+}
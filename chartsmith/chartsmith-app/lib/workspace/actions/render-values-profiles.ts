@@ -0,0 +1,31 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { enqueueWork } from "@/lib/utils/queue";
+import { getWorkspace } from "../workspace";
+
+// renderValuesProfilesAction renders the current revision once for each values profile. The render job is
+// created by the worker, and shows up in the workspace with its first render-stream event
+export async function renderValuesProfilesAction(session: Session, workspaceId: string, valuesProfiles: string[]): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  if (valuesProfiles.length === 0) {
+    throw new Error("At least one values profile is required");
+  }
+
+  const workspace = await getWorkspace(workspaceId);
+  if (!workspace) {
+    throw new Error("Workspace not found");
+  }
+
+  logger.info("renderValuesProfilesAction", { workspaceId, valuesProfiles });
+  await enqueueWork("render_workspace", {
+    workspaceId,
+    revisionNumber: workspace.currentRevisionNumber,
+    valuesProfiles,
+  });
+}
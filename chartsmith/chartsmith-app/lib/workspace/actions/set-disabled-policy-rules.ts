@@ -0,0 +1,28 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { enqueueWork } from "@/lib/utils/queue";
+import { setDisabledPolicyRules } from "../policy-rules";
+import { getWorkspace } from "../workspace";
+
+// setDisabledPolicyRulesAction chooses the policy rules that aren't run over the rendered manifests of the
+// workspace, and renders the current revision again so that its findings match
+export async function setDisabledPolicyRulesAction(session: Session, workspaceId: string, disabledPolicyRules: string[]): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  const workspace = await getWorkspace(workspaceId);
+  if (!workspace) {
+    throw new Error("Workspace not found");
+  }
+
+  logger.info("setDisabledPolicyRulesAction", { workspaceId, disabledPolicyRules });
+  await setDisabledPolicyRules(workspaceId, disabledPolicyRules);
+  await enqueueWork("render_workspace", {
+    workspaceId,
+    revisionNumber: workspace.currentRevisionNumber,
+  });
+}
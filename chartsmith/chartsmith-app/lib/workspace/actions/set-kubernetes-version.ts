@@ -0,0 +1,33 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { enqueueWork } from "@/lib/utils/queue";
+import { getWorkspace, setWorkspaceKubernetesVersion } from "../workspace";
+
+const kubernetesVersionPattern = /^v?1\.\d+(\.\d+)?$/;
+
+// setKubernetesVersionAction sets the version of Kubernetes rendered manifests are validated against, and
+// renders the current revision again. An empty version validates against the default version
+export async function setKubernetesVersionAction(session: Session, workspaceId: string, kubernetesVersion: string): Promise<void> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  if (kubernetesVersion !== "" && !kubernetesVersionPattern.test(kubernetesVersion)) {
+    throw new Error("Kubernetes version must be in the form 1.<minor>");
+  }
+
+  const workspace = await getWorkspace(workspaceId);
+  if (!workspace) {
+    throw new Error("Workspace not found");
+  }
+
+  logger.info("setKubernetesVersionAction", { workspaceId, kubernetesVersion });
+  await setWorkspaceKubernetesVersion(workspaceId, kubernetesVersion || null);
+  await enqueueWork("render_workspace", {
+    workspaceId,
+    revisionNumber: workspace.currentRevisionNumber,
+  });
+}
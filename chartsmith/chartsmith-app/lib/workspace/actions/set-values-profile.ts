@@ -0,0 +1,16 @@
+"use server";
+
+import { Session } from "@/lib/types/session";
+import { ValuesProfile } from "@/lib/types/workspace";
+import { AppError } from "@/lib/utils/error";
+import { logger } from "@/lib/utils/logger";
+import { setValuesProfile } from "../values-profile";
+
+export async function setValuesProfileAction(session: Session, workspaceId: string, name: string, valuesYaml: string): Promise<ValuesProfile> {
+  if (!session?.user?.id) {
+    throw new AppError("Unauthorized", "UNAUTHORIZED");
+  }
+
+  logger.info("setValuesProfileAction", { workspaceId, name });
+  return setValuesProfile(workspaceId, name, valuesYaml);
+}
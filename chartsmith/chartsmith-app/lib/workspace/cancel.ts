@@ -0,0 +1,21 @@
+import { cancelWorkForPayload } from "../utils/queue";
+
+// the work queue channels of the jobs of a plan and of each step of a conversion, these match pkg/workspace/cancel.go
+const planChannels = ["new_plan", "execute_plan", "apply_plan"];
+const conversionChannels = ["new_conversion", "conversion_next_file", "conversion_normalize_values", "conversion_simplify"];
+
+// cancelPlan requests cancellation of the unfinished jobs of a plan. The worker running them marks the plan as cancelled
+export async function cancelPlan(planId: string): Promise<string[]> {
+  return cancelWorkForPayload(planChannels, "planId", planId);
+}
+
+// cancelRender requests cancellation of the job of a render. The worker running it fails the render
+export async function cancelRender(renderId: string): Promise<string[]> {
+  return cancelWorkForPayload(["render_workspace"], "id", renderId);
+}
+
+// cancelConversion requests cancellation of the unfinished jobs of a conversion. The worker running them marks the
+// conversion as cancelled
+export async function cancelConversion(conversionId: string): Promise<string[]> {
+  return cancelWorkForPayload(conversionChannels, "conversionId", conversionId);
+}
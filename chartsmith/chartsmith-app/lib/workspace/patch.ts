@@ -3,6 +3,7 @@ import { WorkspaceFile } from "../types/workspace";
 import { logger } from "../utils/logger";
 import { getDB } from "../data/db";
 import { getParam } from "../data/param";
+import { pendingChangeCondition } from "./workspace";
 
 
 export async function getFile(fileID: string, revisionNumber: number): Promise<WorkspaceFile> {
@@ -18,7 +19,9 @@ export async function getFile(fileID: string, revisionNumber: number): Promise<W
           workspace_id,
           file_path,
           content,
-          content_pending
+          content_pending,
+          pending_delete,
+          file_path_pending
         FROM
           workspace_file
         WHERE
@@ -36,6 +39,8 @@ export async function getFile(fileID: string, revisionNumber: number): Promise<W
       filePath: rows.rows[0].file_path,
       content: rows.rows[0].content,
       contentPending: rows.rows[0].content_pending,
+      pendingDelete: rows.rows[0].pending_delete || undefined,
+      filePathPending: rows.rows[0].file_path_pending ?? undefined,
     }
 
     return file;
@@ -50,7 +55,7 @@ export async function rejectAllPatches(workspaceId: string, revisionNumber: numb
 
   try {
     const db = getDB(await getParam("DB_URI"))
-    const rows = await db.query(`SELECT id, content_pending FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND content_pending IS NOT NULL`, [workspaceId, revisionNumber]);
+    const rows = await db.query(`SELECT id, content_pending FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND ${pendingChangeCondition}`, [workspaceId, revisionNumber]);
     const files = rows.rows.map((row) => ({
       id: row.id,
       contentPending: row.content_pending,
@@ -85,8 +90,8 @@ export async function rejectPatch(fileID: string, revisionNumber: number): Promi
       throw new Error(`File ${fileID} not found at revision ${revisionNumber}`);
     }
 
-    // update the file content to the pending content
-    await db.query(`UPDATE workspace_file SET content_pending = NULL WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
+    // drop the pending content, delete and rename
+    await db.query(`UPDATE workspace_file SET content_pending = NULL, pending_delete = false, file_path_pending = NULL WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
 
     return getFile(fileID, revisionNumber);
   } catch (error) {
@@ -100,7 +105,7 @@ export async function acceptAllPatches(workspaceId: string, revisionNumber: numb
 
   try {
     const db = getDB(await getParam("DB_URI"))
-    const rows = await db.query(`SELECT id, content_pending FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND content_pending IS NOT NULL`, [workspaceId, revisionNumber]);
+    const rows = await db.query(`SELECT id, content_pending FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND ${pendingChangeCondition}`, [workspaceId, revisionNumber]);
     const files = rows.rows.map((row) => ({
       id: row.id,
       contentPending: row.content_pending,
@@ -128,19 +133,26 @@ export async function acceptPatch(fileID: string, revisionNumber: number): Promi
 
   try {
     const db = getDB(await getParam("DB_URI"))
-    const rows = await db.query(`SELECT content_pending FROM workspace_file WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
+    const rows = await db.query(`SELECT content_pending, pending_delete, file_path_pending FROM workspace_file WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
     const row = rows.rows[0];
 
     if (!row) {
       throw new Error(`File ${fileID} not found at revision ${revisionNumber}`);
     }
 
-    if (!row.content_pending) {
-      throw new Error(`File ${fileID} has no pending content at revision ${revisionNumber}`);
+    if (!row.content_pending && !row.pending_delete && !row.file_path_pending) {
+      throw new Error(`File ${fileID} has no pending changes at revision ${revisionNumber}`);
+    }
+
+    // a deleted file is returned as it was, with pendingDelete set, so the caller can remove it
+    if (row.pending_delete) {
+      const file = await getFile(fileID, revisionNumber);
+      await db.query(`DELETE FROM workspace_file WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
+      return file;
     }
 
-    // update the file content to the pending content
-    await db.query(`UPDATE workspace_file SET content = $1, content_pending = NULL WHERE id = $2 AND revision_number = $3`, [row.content_pending, fileID, revisionNumber]);
+    // update the file content and path to the pending content and path
+    await db.query(`UPDATE workspace_file SET content = COALESCE(content_pending, content), file_path = COALESCE(file_path_pending, file_path), content_pending = NULL, file_path_pending = NULL WHERE id = $1 AND revision_number = $2`, [fileID, revisionNumber]);
 
     return getFile(fileID, revisionNumber);
   } catch (error) {
@@ -0,0 +1,35 @@
+import { getDB } from "../data/db";
+import { getParam } from "../data/param";
+import { logger } from "../utils/logger";
+import policyRulesJSON from "./policy-rules.json";
+
+export interface PolicyRule {
+  name: string;
+  description: string;
+}
+
+// the policy rules the worker runs over rendered manifests. policy-rules.json is generated from
+// pkg/validation/policy.go with `make policy-rules`
+export const policyRules: PolicyRule[] = policyRulesJSON;
+
+export function isPolicyRule(name: string): boolean {
+  return policyRules.some(rule => rule.name === name);
+}
+
+export async function setDisabledPolicyRules(workspaceId: string, disabledPolicyRules: string[]): Promise<void> {
+  const unknownRules = disabledPolicyRules.filter(rule => !isPolicyRule(rule));
+  if (unknownRules.length > 0) {
+    throw new Error(`Unknown policy rules: ${unknownRules.join(", ")}`);
+  }
+
+  try {
+    const db = getDB(await getParam("DB_URI"));
+    await db.query(
+      `UPDATE workspace SET disabled_policy_rules = $1, last_updated_at = now() WHERE id = $2`,
+      [Array.from(new Set(disabledPolicyRules)).sort(), workspaceId]
+    );
+  } catch (err) {
+    logger.error("Failed to set disabled policy rules", { err });
+    throw err;
+  }
+}
@@ -93,10 +93,15 @@ export async function listRenderedChartsForWorkspaceRender(renderId: string, wor
         workspace_rendered_chart.id,
         workspace_rendered_chart.chart_id,
         workspace_chart.name,
+        workspace_rendered_chart.values_profile,
         workspace_rendered_chart.is_success,
         workspace_rendered_chart.dep_update_command,
         workspace_rendered_chart.dep_update_stdout,
         workspace_rendered_chart.dep_update_stderr,
+        workspace_rendered_chart.helm_lint_command,
+        workspace_rendered_chart.helm_lint_stdout,
+        workspace_rendered_chart.helm_lint_stderr,
+        workspace_rendered_chart.helm_lint_findings,
         workspace_rendered_chart.helm_template_command,
         workspace_rendered_chart.helm_template_stdout,
         workspace_rendered_chart.helm_template_stderr,
@@ -117,10 +122,15 @@ export async function listRenderedChartsForWorkspaceRender(renderId: string, wor
         id: row.id,
         chartId: row.chart_id,
         chartName: row.name,
+        valuesProfile: row.values_profile || undefined,
         isSuccess: row.is_success,
         depUpdateCommand: row.dep_update_command,
         depUpdateStdout: row.dep_update_stdout,
         depUpdateStderr: row.dep_update_stderr,
+        helmLintCommand: row.helm_lint_command,
+        helmLintStdout: row.helm_lint_stdout,
+        helmLintStderr: row.helm_lint_stderr,
+        helmLintFindings: row.helm_lint_findings || undefined,
         helmTemplateCommand: row.helm_template_command,
         helmTemplateStdout: row.helm_template_stdout,
         helmTemplateStderr: row.helm_template_stderr,
@@ -129,7 +139,7 @@ export async function listRenderedChartsForWorkspaceRender(renderId: string, wor
         renderedFiles: [],
       };
 
-      const renderedFiles = await listRenderedFilesForChartRender(renderedChart.chartId, workspaceId, revisionNumber);
+      const renderedFiles = await listRenderedFilesForChartRender(renderedChart.chartId, workspaceId, revisionNumber, row.values_profile || "");
       renderedChart.renderedFiles = renderedFiles;
       logger.debug(`Found ${renderedFiles.length} rendered files for chart ${renderedChart.id}`);
 
@@ -143,9 +153,9 @@ export async function listRenderedChartsForWorkspaceRender(renderId: string, wor
   }
 }
 
-export async function listRenderedFilesForChartRender(chartId: string, workspaceId: string, revisionNumber: number): Promise<RenderedFile[]> {
+export async function listRenderedFilesForChartRender(chartId: string, workspaceId: string, revisionNumber: number, valuesProfile: string = ""): Promise<RenderedFile[]> {
   try {
-    logger.debug("Listing rendered files for chart render", { chartId, workspaceId, revisionNumber });
+    logger.debug("Listing rendered files for chart render", { chartId, workspaceId, revisionNumber, valuesProfile });
     const db = getDB(await getParam("DB_URI"));
 
     // Modified query to prevent duplicate rows by adding DISTINCT
@@ -156,22 +166,25 @@ export async function listRenderedFilesForChartRender(chartId: string, workspace
         workspace_rendered_file.workspace_id,
         workspace_rendered_file.revision_number,
         workspace_file.file_path,
-        workspace_rendered_file.content
+        workspace_rendered_file.content,
+        workspace_rendered_file.findings
       FROM workspace_rendered_file
       INNER JOIN workspace_file ON workspace_rendered_file.file_id = workspace_file.id
       INNER JOIN workspace_chart ON workspace_file.chart_id = workspace_chart.id
       WHERE workspace_chart.id = $1
         AND workspace_rendered_file.workspace_id = $2
         AND workspace_rendered_file.revision_number = $3
+        AND workspace_rendered_file.values_profile = $4
     `;
 
-    const result = await db.query(query, [chartId, workspaceId, revisionNumber]);
+    const result = await db.query(query, [chartId, workspaceId, revisionNumber, valuesProfile]);
     const renderedFiles: RenderedFile[] = [];
     for (const row of result.rows) {
       const renderedFile: RenderedFile = {
         id: row.file_id, // Use file_id as the id
         filePath: row.file_path,
         renderedContent: row.content,
+        findings: row.findings || undefined,
       };
 
       renderedFiles.push(renderedFile);
@@ -212,10 +225,12 @@ export async function listRenderedFilesForWorkspace(
           workspace_id,
           revision_number,
           file_path,
-          content
+          content,
+          findings
         FROM workspace_rendered_file
         WHERE workspace_id = $1
           AND revision_number = $2
+          AND values_profile = ''
       `,
       [workspaceId, revisionNumber]
     );
@@ -226,6 +241,7 @@ export async function listRenderedFilesForWorkspace(
         id: row.file_id,
         filePath: row.file_path,
         renderedContent: row.content,
+        findings: row.findings || undefined,
       };
 
       renderedFiles.push(renderedFile);
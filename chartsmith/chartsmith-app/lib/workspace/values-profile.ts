@@ -0,0 +1,69 @@
+import { getDB } from "../data/db";
+import { getParam } from "../data/param";
+import { ValuesProfile } from "../types/workspace";
+import { logger } from "../utils/logger";
+
+// profile names are used in the UI and in render requests, so they are kept short and simple
+const valuesProfileNameRegex = /^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$/;
+
+export function isValidValuesProfileName(name: string): boolean {
+  return valuesProfileNameRegex.test(name);
+}
+
+export async function listValuesProfiles(workspaceId: string): Promise<ValuesProfile[]> {
+  try {
+    const db = getDB(await getParam("DB_URI"));
+    const result = await db.query(
+      `SELECT name, values_yaml, created_at, updated_at FROM workspace_values_profile WHERE workspace_id = $1 ORDER BY name`,
+      [workspaceId]
+    );
+
+    return result.rows.map((row: { name: string; values_yaml: string; created_at: Date; updated_at: Date }) => ({
+      name: row.name,
+      valuesYaml: row.values_yaml,
+      createdAt: row.created_at,
+      updatedAt: row.updated_at,
+    }));
+  } catch (err) {
+    logger.error("Failed to list values profiles", { err });
+    throw err;
+  }
+}
+
+export async function setValuesProfile(workspaceId: string, name: string, valuesYaml: string): Promise<ValuesProfile> {
+  if (!isValidValuesProfileName(name)) {
+    throw new Error(`Invalid values profile name: ${name}`);
+  }
+
+  try {
+    const db = getDB(await getParam("DB_URI"));
+    const result = await db.query(
+      `INSERT INTO workspace_values_profile (workspace_id, name, values_yaml, created_at, updated_at)
+        VALUES ($1, $2, $3, now(), now())
+        ON CONFLICT (workspace_id, name) DO UPDATE SET values_yaml = EXCLUDED.values_yaml, updated_at = now()
+        RETURNING name, values_yaml, created_at, updated_at`,
+      [workspaceId, name, valuesYaml]
+    );
+
+    const row = result.rows[0];
+    return {
+      name: row.name,
+      valuesYaml: row.values_yaml,
+      createdAt: row.created_at,
+      updatedAt: row.updated_at,
+    };
+  } catch (err) {
+    logger.error("Failed to set values profile", { err });
+    throw err;
+  }
+}
+
+export async function deleteValuesProfile(workspaceId: string, name: string): Promise<void> {
+  try {
+    const db = getDB(await getParam("DB_URI"));
+    await db.query(`DELETE FROM workspace_values_profile WHERE workspace_id = $1 AND name = $2`, [workspaceId, name]);
+  } catch (err) {
+    logger.error("Failed to delete values profile", { err });
+    throw err;
+  }
+}
@@ -543,7 +543,7 @@ export async function getPlan(planId: string): Promise<Plan> {
 
 async function listActionFiles(planId: string): Promise<ActionFile[]> {
   const db = getDB(await getParam("DB_URI"));
-  const result = await db.query(`SELECT action, path, status FROM workspace_plan_action_file WHERE plan_id = $1`, [planId]);
+  const result = await db.query(`SELECT action, path, status, description, new_path FROM workspace_plan_action_file WHERE plan_id = $1`, [planId]);
   const actionFiles: ActionFile[] = [];
 
   for (const row of result.rows) {
@@ -551,6 +551,8 @@ async function listActionFiles(planId: string): Promise<ActionFile[]> {
       action: row.action,
       path: row.path,
       status: row.status,
+      description: row.description ?? undefined,
+      newPath: row.new_path ?? undefined,
     });
   }
 
@@ -569,7 +571,9 @@ async function listFilesForWorkspace(workspaceID: string, revisionNumber: number
           workspace_id,
           file_path,
           content,
-          content_pending
+          content_pending,
+          pending_delete,
+          file_path_pending
         FROM
           workspace_file
         WHERE
@@ -583,13 +587,15 @@ async function listFilesForWorkspace(workspaceID: string, revisionNumber: number
       return [];
     }
 
-    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string }) => {
+    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string, pending_delete: boolean, file_path_pending?: string }) => {
       return {
         id: row.id,
         revisionNumber: row.revision_number,
         filePath: row.file_path,
         content: row.content,
         contentPending: row.content_pending,
+        pendingDelete: row.pending_delete || undefined,
+        filePathPending: row.file_path_pending ?? undefined,
       };
     });
 
@@ -736,10 +742,23 @@ export async function renderWorkspace(workspaceId: string, chatMessageId: string
   }
 }
 
+export async function setWorkspaceKubernetesVersion(workspaceId: string, kubernetesVersion: string | null): Promise<void> {
+  try {
+    const db = getDB(await getParam("DB_URI"));
+    await db.query(`UPDATE workspace SET kubernetes_version = $1, last_updated_at = now() WHERE id = $2`, [kubernetesVersion, workspaceId]);
+  } catch (err) {
+    logger.error("Failed to set workspace kubernetes version", { err });
+    throw err;
+  }
+}
+
+// pendingChangeCondition matches the files with a pending change to their content, a pending delete or a pending rename
+export const pendingChangeCondition = `(content_pending IS NOT NULL OR pending_delete OR file_path_pending IS NOT NULL)`;
+
 export async function countFilesWithPendingContent(workspaceId: string, revisionNumber: number): Promise<number> {
   try {
     const db = getDB(await getParam("DB_URI"));
-    const result = await db.query(`SELECT count(1) FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND content_pending IS NOT NULL`, [workspaceId, revisionNumber]);
+    const result = await db.query(`SELECT count(1) FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND ${pendingChangeCondition}`, [workspaceId, revisionNumber]);
     return result.rows[0].count;
   } catch (err) {
     logger.error("Failed to count files with pending content", { err });
@@ -759,7 +778,9 @@ export async function getWorkspace(id: string): Promise<Workspace | undefined> {
                 workspace.name,
                 workspace.created_by_user_id,
                 workspace.created_type,
-                workspace.current_revision_number
+                workspace.current_revision_number,
+                workspace.kubernetes_version,
+                workspace.disabled_policy_rules
             FROM
                 workspace
             WHERE
@@ -780,6 +801,8 @@ export async function getWorkspace(id: string): Promise<Workspace | undefined> {
       lastUpdatedAt: row.last_updated_at,
       name: row.name,
       currentRevisionNumber: row.current_revision_number,
+      kubernetesVersion: row.kubernetes_version || undefined,
+      disabledPolicyRules: row.disabled_policy_rules || undefined,
       files: [],
       charts: [],
       isCurrentVersionComplete: true,
@@ -1122,7 +1145,9 @@ async function listFilesForChart(workspaceID: string, chartID: string, revisionN
           workspace_id,
           file_path,
           content,
-          content_pending
+          content_pending,
+          pending_delete,
+          file_path_pending
         FROM
           workspace_file
         WHERE
@@ -1135,13 +1160,15 @@ async function listFilesForChart(workspaceID: string, chartID: string, revisionN
       return [];
     }
 
-    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string }) => {
+    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string, pending_delete: boolean, file_path_pending?: string }) => {
       return {
         id: row.id,
         revisionNumber: row.revision_number,
         filePath: row.file_path,
         content: row.content,
         contentPending: row.content_pending,
+        pendingDelete: row.pending_delete || undefined,
+        filePathPending: row.file_path_pending ?? undefined,
       };
     });
 
@@ -1164,7 +1191,9 @@ async function listFilesWithoutChartsForWorkspace(workspaceID: string, revisionN
           workspace_id,
           file_path,
           content,
-          content_pending
+          content_pending,
+          pending_delete,
+          file_path_pending
         FROM
           workspace_file
         WHERE
@@ -1179,13 +1208,15 @@ async function listFilesWithoutChartsForWorkspace(workspaceID: string, revisionN
       return [];
     }
 
-    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string }) => {
+    const files: WorkspaceFile[] = result.rows.map((row: { id: string; revision_number: number; file_path: string; content: string; summary: string, content_pending?: string, pending_delete: boolean, file_path_pending?: string }) => {
       return {
         id: row.id,
         revisionNumber: row.revision_number,
         filePath: row.file_path,
         content: row.content,
         contentPending: row.content_pending,
+        pendingDelete: row.pending_delete || undefined,
+        filePathPending: row.file_path_pending ?? undefined,
       };
     });
 
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/workspace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func CancelWorkCmd() *cobra.Command {
+	cancelWorkCmd := &cobra.Command{
+		Use:   "cancel-work [id...]",
+		Short: "Cancel work_queue jobs by id, or all unfinished jobs of a plan, render or conversion",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+
+			sess, err := session.NewSession(aws.NewConfig().WithCredentialsChainVerboseErrors(true))
+			if err != nil {
+				fmt.Printf("Failed to create aws session: %v\n", err)
+			}
+
+			if err := param.Init(sess); err != nil {
+				return fmt.Errorf("failed to init params: %w", err)
+			}
+
+			pgOpts := persistence.PostgresOpts{
+				URI: param.Get().PGURI,
+			}
+			if err := persistence.InitPostgres(pgOpts); err != nil {
+				return fmt.Errorf("failed to initialize postgres connection: %w", err)
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			ctx := cmd.Context()
+
+			ids := []string{}
+			var err error
+			switch {
+			case v.GetString("plan") != "":
+				ids, err = workspace.CancelPlan(ctx, v.GetString("plan"))
+			case v.GetString("render") != "":
+				ids, err = workspace.CancelRender(ctx, v.GetString("render"))
+			case v.GetString("conversion") != "":
+				ids, err = workspace.CancelConversion(ctx, v.GetString("conversion"))
+			case len(args) > 0:
+				for _, id := range args {
+					if err := persistence.CancelWork(ctx, id); err != nil {
+						return fmt.Errorf("failed to cancel %s: %w", id, err)
+					}
+					ids = append(ids, id)
+				}
+			default:
+				return fmt.Errorf("no work specified, pass one or more ids, --plan, --render or --conversion")
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				fmt.Println("No unfinished work found")
+				return nil
+			}
+			for _, id := range ids {
+				fmt.Printf("Requested cancellation of %s\n", id)
+			}
+
+			return nil
+		},
+	}
+
+	cancelWorkCmd.Flags().String("plan", "", "Cancel the unfinished jobs of this plan")
+	cancelWorkCmd.Flags().String("render", "", "Cancel the job of this render")
+	cancelWorkCmd.Flags().String("conversion", "", "Cancel the unfinished jobs of this conversion")
+
+	return cancelWorkCmd
+}
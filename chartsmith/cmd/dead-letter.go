@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func DeadLetterCmd() *cobra.Command {
+	deadLetterCmd := &cobra.Command{
+		Use:   "dead-letter",
+		Short: "Inspect and requeue work_queue jobs that exhausted their retries",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+
+			sess, err := session.NewSession(aws.NewConfig().WithCredentialsChainVerboseErrors(true))
+			if err != nil {
+				fmt.Printf("Failed to create aws session: %v\n", err)
+			}
+
+			if err := param.Init(sess); err != nil {
+				return fmt.Errorf("failed to init params: %w", err)
+			}
+
+			pgOpts := persistence.PostgresOpts{
+				URI: param.Get().PGURI,
+			}
+			if err := persistence.InitPostgres(pgOpts); err != nil {
+				return fmt.Errorf("failed to initialize postgres connection: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	deadLetterCmd.AddCommand(deadLetterListCmd())
+	deadLetterCmd.AddCommand(deadLetterRequeueCmd())
+
+	return deadLetterCmd
+}
+
+func deadLetterListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List dead letter jobs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			deadWork, err := persistence.ListDeadWork(cmd.Context(), v.GetString("channel"))
+			if err != nil {
+				return fmt.Errorf("failed to list dead work: %w", err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCHANNEL\tATTEMPTS\tFAILED AT\tLAST ERROR")
+			for _, dw := range deadWork {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", dw.ID, dw.Channel, dw.AttemptCount, dw.FailedAt.Format(time.RFC3339), dw.LastError)
+			}
+			return w.Flush()
+		},
+	}
+
+	listCmd.Flags().String("channel", "", "Only list jobs for this channel")
+
+	return listCmd
+}
+
+func deadLetterRequeueCmd() *cobra.Command {
+	requeueCmd := &cobra.Command{
+		Use:   "requeue [id...]",
+		Short: "Move dead letter jobs back to the work queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			ids := args
+			if v.GetBool("all") {
+				if len(args) > 0 {
+					return fmt.Errorf("cannot specify ids with --all")
+				}
+
+				deadWork, err := persistence.ListDeadWork(cmd.Context(), v.GetString("channel"))
+				if err != nil {
+					return fmt.Errorf("failed to list dead work: %w", err)
+				}
+				for _, dw := range deadWork {
+					ids = append(ids, dw.ID)
+				}
+			}
+
+			if len(ids) == 0 {
+				return fmt.Errorf("no ids specified, pass one or more ids or --all")
+			}
+
+			for _, id := range ids {
+				if err := persistence.RequeueDeadWork(cmd.Context(), id); err != nil {
+					return fmt.Errorf("failed to requeue %s: %w", id, err)
+				}
+				fmt.Printf("Requeued %s\n", id)
+			}
+
+			return nil
+		},
+	}
+
+	requeueCmd.Flags().Bool("all", false, "Requeue all dead letter jobs")
+	requeueCmd.Flags().String("channel", "", "With --all, only requeue jobs for this channel")
+
+	return requeueCmd
+}
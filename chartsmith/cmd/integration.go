@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/replicatedhq/chartsmith/pkg/integration"
+	"github.com/replicatedhq/chartsmith/pkg/llm"
 	"github.com/replicatedhq/chartsmith/pkg/param"
 	"github.com/replicatedhq/chartsmith/pkg/persistence"
 	"github.com/replicatedhq/chartsmith/pkg/realtime"
@@ -37,10 +38,12 @@ func IntegrationCmd() *cobra.Command {
 
 			missingParams := []string{}
 
-			if param.Get().AnthropicAPIKey == "" {
+			// replayed LLM and embedding fixtures don't need API keys
+			replay := llm.FixtureMode(viper.GetString("llm-fixtures")) == llm.FixtureModeReplay
+			if !replay && param.Get().AnthropicAPIKey == "" {
 				missingParams = append(missingParams, "ANTHROPIC_API_KEY")
 			}
-			if param.Get().VoyageAPIKey == "" {
+			if !replay && param.Get().VoyageAPIKey == "" {
 				missingParams = append(missingParams, "VOYAGE_API_KEY")
 			}
 
@@ -50,6 +53,10 @@ func IntegrationCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := llm.EnableFixtures(llm.FixtureMode(viper.GetString("llm-fixtures")), viper.GetString("llm-fixtures-dir")); err != nil {
+				return fmt.Errorf("failed to enable llm fixtures: %w", err)
+			}
+
 			realtime.Init(&realtimetypes.Config{
 				Address: param.Get().CentrifugoAddress,
 				APIKey:  param.Get().CentrifugoAPIKey,
@@ -66,6 +73,9 @@ func IntegrationCmd() *cobra.Command {
 		},
 	}
 
+	integrationCmd.Flags().String("llm-fixtures", "", "Record LLM requests to fixtures with \"record\", or serve them from fixtures without calling a model with \"replay\"")
+	integrationCmd.Flags().String("llm-fixtures-dir", "testdata/llm-fixtures", "Directory that LLM fixtures are recorded to and replayed from")
+
 	return integrationCmd
 }
 
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/replicatedhq/chartsmith/pkg/validation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func KubernetesSchemasCmd() *cobra.Command {
+	kubernetesSchemasCmd := &cobra.Command{
+		Use:   "kubernetes-schemas [client-go version...]",
+		Short: "Generate the schemas rendered manifests are validated against, for the Kubernetes version of each client-go version",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			for _, clientGoVersion := range args {
+				clientGoDir, err := downloadModule("k8s.io/client-go", clientGoVersion)
+				if err != nil {
+					return err
+				}
+				apiDir, err := downloadModule("k8s.io/api", clientGoVersion)
+				if err != nil {
+					return err
+				}
+
+				kubernetesVersion, schemas, err := validation.GenerateKubernetesSchemas(clientGoVersion, clientGoDir, apiDir)
+				if err != nil {
+					return fmt.Errorf("failed to generate schemas for client-go %s: %w", clientGoVersion, err)
+				}
+
+				out := filepath.Join(v.GetString("out"), kubernetesVersion+".json")
+				if err := os.WriteFile(out, schemas, 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", out, err)
+				}
+				fmt.Printf("Wrote schemas of Kubernetes %s to %s\n", kubernetesVersion, out)
+			}
+
+			return nil
+		},
+	}
+
+	kubernetesSchemasCmd.Flags().String("out", "pkg/validation/schemas", "Directory to write the schemas to")
+
+	return kubernetesSchemasCmd
+}
+
+// downloadModule downloads a version of a module to the module cache and returns its directory
+func downloadModule(module string, version string) (string, error) {
+	output, err := exec.Command("go", "mod", "download", "-json", module+"@"+version).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s@%s: %w", module, version, err)
+	}
+
+	downloaded := struct {
+		Dir   string
+		Error string
+	}{}
+	if err := json.Unmarshal(output, &downloaded); err != nil {
+		return "", fmt.Errorf("failed to parse go mod download output: %w", err)
+	}
+	if downloaded.Error != "" {
+		return "", fmt.Errorf("failed to download %s@%s: %s", module, version, downloaded.Error)
+	}
+
+	return downloaded.Dir, nil
+}
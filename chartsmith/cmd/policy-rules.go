@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/replicatedhq/chartsmith/pkg/validation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func PolicyRulesCmd() *cobra.Command {
+	policyRulesCmd := &cobra.Command{
+		Use:   "policy-rules",
+		Short: "Write the policy rules that rendered manifests are checked for to the file the app reads them from",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			rules, err := validation.PolicyRulesJSON()
+			if err != nil {
+				return err
+			}
+
+			out := v.GetString("out")
+			if err := os.WriteFile(out, rules, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", out, err)
+			}
+			fmt.Printf("Wrote %d policy rules to %s\n", len(validation.PolicyRules), out)
+
+			return nil
+		},
+	}
+
+	policyRulesCmd.Flags().String("out", "chartsmith-app/lib/workspace/policy-rules.json", "File to write the policy rules to")
+
+	return policyRulesCmd
+}
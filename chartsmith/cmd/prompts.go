@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/replicatedhq/chartsmith/pkg/llm"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func PromptsCmd() *cobra.Command {
+	promptsCmd := &cobra.Command{
+		Use:   "prompts",
+		Short: "List the prompt versions and compare their str_replace failure rates",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+
+			sess, err := session.NewSession(aws.NewConfig().WithCredentialsChainVerboseErrors(true))
+			if err != nil {
+				fmt.Printf("Failed to create aws session: %v\n", err)
+			}
+
+			if err := param.Init(sess); err != nil {
+				return fmt.Errorf("failed to init params: %w", err)
+			}
+
+			pgOpts := persistence.PostgresOpts{
+				URI: param.Get().PGURI,
+			}
+			if err := persistence.InitPostgres(pgOpts); err != nil {
+				return fmt.Errorf("failed to initialize postgres connection: %w", err)
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			versions, err := llm.PromptVersions()
+			if err != nil {
+				return fmt.Errorf("failed to load prompts: %w", err)
+			}
+			fmt.Printf("Prompt versions: %s\n\n", strings.Join(versions, ", "))
+
+			report, err := llm.GetStrReplaceReport(cmd.Context(), time.Now().Add(-v.GetDuration("since")))
+			if err != nil {
+				return fmt.Errorf("failed to get str_replace report: %w", err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "PROMPT VERSION\tSTR_REPLACE\tFAILED\tFAILURE RATE\n")
+			for _, row := range report {
+				version := row.PromptVersion
+				if version == "" {
+					version = "-"
+				}
+
+				rate := 0.0
+				if row.Replacements > 0 {
+					rate = float64(row.Failures) / float64(row.Replacements) * 100
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\n", version, row.Replacements, row.Failures, rate)
+			}
+			return w.Flush()
+		},
+	}
+
+	promptsCmd.Flags().Duration("since", 30*24*time.Hour, "Only include str_replace edits from this long ago until now")
+
+	return promptsCmd
+}
@@ -17,6 +17,12 @@ func RootCmd() *cobra.Command {
 	rootCmd.AddCommand(TestData())
 	rootCmd.AddCommand(ArtifactHubCmd())
 	rootCmd.AddCommand(DebugConsoleCmd())
+	rootCmd.AddCommand(DeadLetterCmd())
+	rootCmd.AddCommand(CancelWorkCmd())
+	rootCmd.AddCommand(UsageCmd())
+	rootCmd.AddCommand(PromptsCmd())
+	rootCmd.AddCommand(KubernetesSchemasCmd())
+	rootCmd.AddCommand(PolicyRulesCmd())
 
 	return rootCmd
 }
@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/replicatedhq/chartsmith/pkg/listener"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/metrics"
 	"github.com/replicatedhq/chartsmith/pkg/param"
 	"github.com/replicatedhq/chartsmith/pkg/persistence"
 	"github.com/replicatedhq/chartsmith/pkg/realtime"
 	realtimetypes "github.com/replicatedhq/chartsmith/pkg/realtime/types"
+	"github.com/replicatedhq/chartsmith/pkg/tracing"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -49,17 +53,64 @@ func RunCmd() *cobra.Command {
 			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 			defer stop()
 
-			if err := runWorker(ctx, param.Get().PGURI); err != nil {
+			if otlpEndpoint := viper.GetString("otlp-endpoint"); otlpEndpoint != "" {
+				shutdownTracing, err := tracing.Init(ctx, tracing.Opts{
+					ServiceName:  "chartsmith-worker",
+					OTLPEndpoint: otlpEndpoint,
+					Insecure:     viper.GetBool("otlp-insecure"),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to init tracing: %w", err)
+				}
+				defer func() {
+					shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					if err := shutdownTracing(shutdownCtx); err != nil {
+						logger.Error(fmt.Errorf("failed to flush traces: %w", err))
+					}
+				}()
+			}
+
+			if metricsAddr := viper.GetString("metrics-addr"); metricsAddr != "" {
+				go func() {
+					if err := metrics.Serve(ctx, metricsAddr); err != nil {
+						logger.Error(fmt.Errorf("metrics server error: %w", err))
+					}
+				}()
+			}
+
+			workerConfig, err := listener.LoadWorkerConfig(viper.GetString("worker-config"))
+			if err != nil {
+				return fmt.Errorf("failed to load worker config: %w", err)
+			}
+			if channels := viper.GetStringSlice("channels"); len(channels) > 0 {
+				workerConfig.Channels = channels
+			}
+
+			listenerOpts := listener.StartListenersOpts{
+				DrainTimeout: viper.GetDuration("drain-timeout"),
+				HealthAddr:   viper.GetString("health-addr"),
+				Config:       workerConfig,
+			}
+			if err := runWorker(ctx, param.Get().PGURI, listenerOpts); err != nil {
 				return fmt.Errorf("worker error: %w", err)
 			}
 			return nil
 		},
 	}
 
+	runCmd.Flags().Duration("drain-timeout", listener.DefaultDrainTimeout, "How long to wait for in-flight jobs to finish on shutdown before releasing them")
+	runCmd.Flags().String("worker-config", "", "Path to a YAML file with per-channel worker config. Defaults to $CHARTSMITH_WORKER_CONFIG")
+	runCmd.Flags().StringSlice("channels", []string{}, "Only handle these channels, e.g. --channels=render_workspace,publish_workspace. Overrides the worker config")
+	runCmd.Flags().String("health-addr", "", "Address to serve /healthz and /readyz on, e.g. :8080. Health endpoints are not served if empty")
+	runCmd.Flags().String("metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9090. Metrics are not served if empty")
+	runCmd.Flags().String("otlp-endpoint", "", "host:port of an OTLP/HTTP collector to send traces to, e.g. localhost:4318. Traces are not exported if empty")
+	runCmd.Flags().Bool("otlp-insecure", false, "Send traces to the OTLP collector without TLS")
+
 	return runCmd
 }
 
-func runWorker(ctx context.Context, pgURI string) error {
+func runWorker(ctx context.Context, pgURI string, listenerOpts listener.StartListenersOpts) error {
 	pgOpts := persistence.PostgresOpts{
 		URI: pgURI,
 	}
@@ -71,7 +122,7 @@ func runWorker(ctx context.Context, pgURI string) error {
 	// This ensures our connections stay alive even during idle periods
 	listener.StartHeartbeat(ctx)
 	
-	if err := listener.StartListeners(ctx); err != nil {
+	if err := listener.StartListeners(ctx, listenerOpts); err != nil {
 		return fmt.Errorf("failed to start listeners: %w", err)
 	}
 
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/replicatedhq/chartsmith/pkg/llm"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func UsageCmd() *cobra.Command {
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report LLM token usage and cost by workspace, user, plan or prompt version",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+			if err := v.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+
+			sess, err := session.NewSession(aws.NewConfig().WithCredentialsChainVerboseErrors(true))
+			if err != nil {
+				fmt.Printf("Failed to create aws session: %v\n", err)
+			}
+
+			if err := param.Init(sess); err != nil {
+				return fmt.Errorf("failed to init params: %w", err)
+			}
+
+			pgOpts := persistence.PostgresOpts{
+				URI: param.Get().PGURI,
+			}
+			if err := persistence.InitPostgres(pgOpts); err != nil {
+				return fmt.Errorf("failed to initialize postgres connection: %w", err)
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			groupBy := llm.UsageGroupBy(v.GetString("group-by"))
+			since := time.Now().Add(-v.GetDuration("since"))
+
+			report, err := llm.GetUsageReport(cmd.Context(), groupBy, since)
+			if err != nil {
+				return fmt.Errorf("failed to get usage report: %w", err)
+			}
+
+			var totalRequests, totalInput, totalOutput, totalUnpriced int64
+			var totalCost float64
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\tREQUESTS\tINPUT TOKENS\tOUTPUT TOKENS\tCOST (USD)\tUNPRICED\n", groupByHeader(groupBy))
+			for _, row := range report {
+				key := row.Key
+				if key == "" {
+					key = "-"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.4f\t%d\n", key, row.Requests, row.InputTokens, row.OutputTokens, row.CostUSD, row.UnpricedRequests)
+
+				totalRequests += row.Requests
+				totalInput += row.InputTokens
+				totalOutput += row.OutputTokens
+				totalCost += row.CostUSD
+				totalUnpriced += row.UnpricedRequests
+			}
+			fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t%.4f\t%d\n", totalRequests, totalInput, totalOutput, totalCost, totalUnpriced)
+			return w.Flush()
+		},
+	}
+
+	usageCmd.Flags().String("group-by", string(llm.UsageGroupByWorkspace), "Group usage by workspace, user, plan or prompt_version")
+	usageCmd.Flags().Duration("since", 30*24*time.Hour, "Only include usage from this long ago until now")
+
+	return usageCmd
+}
+
+func groupByHeader(groupBy llm.UsageGroupBy) string {
+	switch groupBy {
+	case llm.UsageGroupByUser:
+		return "USER"
+	case llm.UsageGroupByPlan:
+		return "PLAN"
+	case llm.UsageGroupByPromptVersion:
+		return "STAGE:PROMPT VERSION"
+	default:
+		return "WORKSPACE"
+	}
+}
@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/replicatedhq/chartsmith v0.0.0
 	helm.sh/helm/v3 v3.17.0
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -143,7 +144,6 @@ require (
 	sigs.k8s.io/kustomize/api v0.18.0 // indirect
 	sigs.k8s.io/kustomize/kyaml v0.18.1 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.2 // indirect
-	sigs.k8s.io/yaml v1.4.0 // indirect
 )
 
 replace github.com/replicatedhq/chartsmith => ../
@@ -0,0 +1,155 @@
+package helmutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+var (
+	// helm lint writes a line such as "[ERROR] templates/: <message>" for each message
+	lintMessageRegex = regexp.MustCompile(`^\[(INFO|WARNING|ERROR|UNKNOWN)\] (.*)$`)
+
+	// template errors have the location in the message, e.g. "template: mychart/templates/service.yaml:12:3: ..."
+	lintLocationRegex = regexp.MustCompile(`([\w./-]+\.(?:yaml|yml|tpl|txt|json)):(\d+)(?::(\d+))?`)
+
+	// YAML errors have the line in the file of the message, e.g. "yaml: line 4: did not find expected key"
+	lintYAMLLineRegex = regexp.MustCompile(`yaml: line (\d+)`)
+
+	// the summary is an error when a chart failed, e.g. "Error: 1 chart(s) linted, 1 chart(s) failed"
+	lintSummaryRegex = regexp.MustCompile(`^(Error: )?\d+ chart\(s\) linted`)
+)
+
+// LintChartNative lints a chart with the given files and values in process, the way helm lint does. The
+// result has the format of the output of helm lint, so it can be parsed with ParseLintOutput
+func LintChartNative(files []types.File, valuesYAML string) (string, error) {
+	chartDir, err := findChartDir(files)
+	if err != nil {
+		return "", err
+	}
+
+	values := map[string]interface{}{}
+	if valuesYAML != "" {
+		values, err = chartutil.ReadValues([]byte(valuesYAML))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse values")
+		}
+	}
+
+	// helm lints charts from disk
+	rootDir, err := os.MkdirTemp("", "chartsmith")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(rootDir)
+
+	if err := writeChartFiles(rootDir, files); err != nil {
+		return "", err
+	}
+
+	chartPath := filepath.Join(rootDir, chartDir)
+
+	lint := action.NewLint()
+	lint.Namespace = "default"
+	result := lint.Run([]string{chartPath}, values)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "==> Linting %s\n", chartDir)
+	for _, message := range result.Messages {
+		// rules about the whole chart have the directory of the chart as their path
+		path := message.Path
+		if rel, err := filepath.Rel(chartPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		fmt.Fprintf(&sb, "[%s] %s: %s\n", lintSeverityName(message.Severity), path, message.Err)
+	}
+
+	failed := 0
+	if len(result.Errors) > 0 {
+		failed = 1
+	}
+	fmt.Fprintf(&sb, "\n%d chart(s) linted, %d chart(s) failed\n", result.TotalChartsLinted, failed)
+
+	return sb.String(), nil
+}
+
+// ParseLintOutput parses the messages in the output of helm lint. The file of a message is the file that
+// the location in the message points to, relative to the root of the chart, or the path helm lint reported
+// it for. Messages about the chart as a whole don't have a file
+func ParseLintOutput(chartName string, output string) []types.LintFinding {
+	findings := []types.LintFinding{}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		matches := lintMessageRegex.FindStringSubmatch(line)
+		if matches == nil {
+			// messages can span lines
+			if len(findings) > 0 && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "==> ") && !lintSummaryRegex.MatchString(line) {
+				findings[len(findings)-1].Message += "\n" + line
+			}
+			continue
+		}
+
+		path, message, ok := strings.Cut(matches[2], ": ")
+		if !ok {
+			path, message = "", matches[2]
+		}
+
+		finding := types.LintFinding{
+			Severity: lintSeverity(matches[1]),
+			FilePath: strings.TrimPrefix(strings.TrimSpace(path), chartName+"/"),
+			Message:  message,
+		}
+		if finding.FilePath == "." {
+			finding.FilePath = ""
+		}
+
+		if location := lintLocationRegex.FindStringSubmatch(message); location != nil {
+			finding.FilePath = strings.TrimPrefix(location[1], chartName+"/")
+			finding.Line, _ = strconv.Atoi(location[2])
+			finding.Column, _ = strconv.Atoi(location[3])
+		} else if yamlLine := lintYAMLLineRegex.FindStringSubmatch(message); yamlLine != nil {
+			finding.Line, _ = strconv.Atoi(yamlLine[1])
+		}
+
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+func lintSeverity(name string) string {
+	switch name {
+	case "ERROR":
+		return types.FindingSeverityError
+	case "WARNING":
+		return types.FindingSeverityWarning
+	default:
+		return types.FindingSeverityInfo
+	}
+}
+
+// lintSeverityName returns the name helm lint prints for the severity of a message
+func lintSeverityName(severity int) string {
+	switch severity {
+	case support.InfoSev:
+		return "INFO"
+	case support.WarningSev:
+		return "WARNING"
+	case support.ErrorSev:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,182 @@
+package helmutils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+func TestParseLintOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		chartName string
+		output    string
+		want      []types.LintFinding
+	}{
+		{
+			name:      "no messages",
+			chartName: "mychart",
+			output:    "==> Linting .\n\n1 chart(s) linted, 0 chart(s) failed\n",
+			want:      []types.LintFinding{},
+		},
+		{
+			name:      "chart messages",
+			chartName: "mychart",
+			output: `==> Linting .
+[INFO] Chart.yaml: icon is recommended
+[WARNING] .: chart directory is missing these dependencies: redis
+
+1 chart(s) linted, 0 chart(s) failed
+`,
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityInfo, FilePath: "Chart.yaml", Message: "icon is recommended"},
+				{Severity: types.FindingSeverityWarning, FilePath: "", Message: "chart directory is missing these dependencies: redis"},
+			},
+		},
+		{
+			name:      "template error with location",
+			chartName: "mychart",
+			output: `==> Linting .
+[INFO] Chart.yaml: icon is recommended
+[ERROR] templates/: template: mychart/templates/deployment.yaml:12:20: executing "mychart/templates/deployment.yaml" at <.Values.image.repository>: nil pointer evaluating interface {}.repository
+
+Error: 1 chart(s) linted, 1 chart(s) failed
+`,
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityInfo, FilePath: "Chart.yaml", Message: "icon is recommended"},
+				{
+					Severity: types.FindingSeverityError,
+					FilePath: "templates/deployment.yaml",
+					Line:     12,
+					Column:   20,
+					Message:  `template: mychart/templates/deployment.yaml:12:20: executing "mychart/templates/deployment.yaml" at <.Values.image.repository>: nil pointer evaluating interface {}.repository`,
+				},
+			},
+		},
+		{
+			name:      "chart name from Chart.yaml",
+			chartName: "nginx-ingress",
+			output: `==> Linting charts/nginx
+[ERROR] templates/: parse error at (nginx-ingress/templates/_helpers.tpl:3): unexpected EOF
+`,
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityError, FilePath: "templates/_helpers.tpl", Line: 3, Message: "parse error at (nginx-ingress/templates/_helpers.tpl:3): unexpected EOF"},
+			},
+		},
+		{
+			name:      "yaml error",
+			chartName: "mychart",
+			output: `==> Linting .
+[ERROR] templates/configmap.yaml: unable to parse YAML: error converting YAML to JSON: yaml: line 4: did not find expected key
+
+Error: 1 chart(s) linted, 1 chart(s) failed
+`,
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityError, FilePath: "templates/configmap.yaml", Line: 4, Message: "unable to parse YAML: error converting YAML to JSON: yaml: line 4: did not find expected key"},
+			},
+		},
+		{
+			name:      "multi-line message",
+			chartName: "mychart",
+			output: `==> Linting .
+[ERROR] values.yaml: - (root): image is required
+- replicaCount: Invalid type. Expected: integer, given: string
+[WARNING] templates/ingress.yaml: networking.k8s.io/v1beta1 Ingress is deprecated in v1.19+, unavailable in v1.22+; use networking.k8s.io/v1 Ingress
+
+Error: 1 chart(s) linted, 1 chart(s) failed
+`,
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityError, FilePath: "values.yaml", Message: "- (root): image is required\n- replicaCount: Invalid type. Expected: integer, given: string"},
+				{Severity: types.FindingSeverityWarning, FilePath: "templates/ingress.yaml", Message: "networking.k8s.io/v1beta1 Ingress is deprecated in v1.19+, unavailable in v1.22+; use networking.k8s.io/v1 Ingress"},
+			},
+		},
+		{
+			name:      "windows line endings",
+			chartName: "mychart",
+			output:    "==> Linting .\r\n[INFO] Chart.yaml: icon is recommended\r\n\r\n1 chart(s) linted, 0 chart(s) failed\r\n",
+			want: []types.LintFinding{
+				{Severity: types.FindingSeverityInfo, FilePath: "Chart.yaml", Message: "icon is recommended"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLintOutput(tt.chartName, tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLintOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartName(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []types.File
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "root chart",
+			files: []types.File{
+				{FilePath: "mychart/charts/redis/Chart.yaml", Content: testSubchartYAML},
+				{FilePath: "mychart/Chart.yaml", Content: testChartYAML},
+			},
+			want: "mychart",
+		},
+		{
+			name: "name differs from directory",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: "apiVersion: v2\nname: nginx-ingress\nversion: 1.0.0\n"},
+			},
+			want: "nginx-ingress",
+		},
+		{
+			name: "no name",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: "apiVersion: v2\nversion: 1.0.0\n"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no Chart.yaml",
+			files:   []types.File{{FilePath: "values.yaml", Content: "replicas: 1\n"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChartName(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ChartName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintChartNative(t *testing.T) {
+	files := []types.File{
+		{FilePath: "mychart/Chart.yaml", Content: testChartYAML},
+		{FilePath: "mychart/values.yaml", Content: "replicas: 1\n"},
+		{FilePath: "mychart/templates/configmap.yaml", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name.first }}\n"},
+	}
+
+	output, err := LintChartNative(files, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := ParseLintOutput("mychart", output)
+	for _, finding := range findings {
+		if finding.Severity == types.FindingSeverityError && finding.FilePath == "templates/configmap.yaml" && finding.Line == 4 {
+			return
+		}
+	}
+	t.Errorf("no error for templates/configmap.yaml:4 in %+v, output:\n%s", findings, output)
+}
@@ -21,6 +21,9 @@ type RenderChannels struct {
 	DepUpdateCmd       chan string
 	DepUpdateStderr    chan string
 	DepUpdateStdout    chan string
+	HelmLintCmd        chan string
+	HelmLintStderr     chan string
+	HelmLintStdout     chan string
 	HelmTemplateCmd    chan string
 	HelmTemplateStderr chan string
 	HelmTemplateStdout chan string
@@ -30,13 +33,13 @@ type RenderChannels struct {
 
 // RenderChartExec executes helm commands to render a chart with the given files and values
 // For backward compatibility, this function wraps RenderChartExecWithVersion with an empty version
-func RenderChartExec(files []types.File, valuesYAML string, renderChannels RenderChannels) error {
-	return RenderChartExecWithVersion(files, valuesYAML, renderChannels, "")
+func RenderChartExec(ctx context.Context, files []types.File, valuesYAML string, renderChannels RenderChannels) error {
+	return RenderChartExecWithVersion(ctx, files, valuesYAML, renderChannels, "")
 }
 
 // RenderChartExecWithVersion executes helm commands with specific version to render a chart
-// with the given files and values
-func RenderChartExecWithVersion(files []types.File, valuesYAML string, renderChannels RenderChannels, helmVersion string) error {
+// with the given files and values. The helm processes are killed if ctx is cancelled
+func RenderChartExecWithVersion(ctx context.Context, files []types.File, valuesYAML string, renderChannels RenderChannels, helmVersion string) error {
 	start := time.Now()
 	defer func() {
 		fmt.Printf("RenderChartExec completed in %v\n", time.Since(start))
@@ -163,7 +166,7 @@ clusters:
 		}
 
 		// Create a context with timeout for the command
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		depUpdateCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
 
 		// Wait in a goroutine
@@ -180,9 +183,13 @@ clusters:
 				return
 			}
 			helmDepUpdateExitCh <- nil
-		case <-ctx.Done():
-			// Attempt to kill the process if it times out
+		case <-depUpdateCtx.Done():
+			// Attempt to kill the process if it times out or is cancelled
 			depUpdateCmd.Process.Kill()
+			if ctx.Err() != nil {
+				helmDepUpdateExitCh <- errors.Wrap(context.Cause(ctx), "helm dependency update cancelled")
+				return
+			}
 			helmDepUpdateExitCh <- errors.New("helm dependency update timed out after 5 minutes")
 		}
 	}()
@@ -199,27 +206,40 @@ clusters:
 		return errors.Wrap(err, "failed to update dependencies")
 	}
 
-	// helm template with values
-	templateCmd := exec.Command(helmCmd, "template", "chartsmith", ".", "--include-crds", "--values", "/dev/stdin")
-	templateCmd.Env = []string{"KUBECONFIG=" + fakeKubeconfigPath}
-	templateCmd.Dir = workingDir
-
+	// lint and template use the same values
+	valuesArgs := []string{}
 	if valuesYAML != "" {
 		valuesFile := filepath.Join(workingDir, "values.yaml")
 		if err := os.WriteFile(valuesFile, []byte(valuesYAML), 0644); err != nil {
 			renderChannels.Done <- fmt.Errorf("failed to write values file: %w", err)
 			return fmt.Errorf("failed to write values file: %w", err)
 		}
-		templateCmd.Args = append(templateCmd.Args, "-f", "values.yaml")
+		valuesArgs = append(valuesArgs, "-f", "values.yaml")
+	}
+
+	// helm lint
+	lintCmd := exec.Command(helmCmd, append([]string{"lint", "."}, valuesArgs...)...)
+	lintCmd.Env = []string{"KUBECONFIG=" + fakeKubeconfigPath}
+	lintCmd.Dir = workingDir
+
+	if err := runHelmLint(ctx, lintCmd, renderChannels); err != nil {
+		renderChannels.Done <- err
+		return err
 	}
 
+	// helm template with values
+	templateCmd := exec.Command(helmCmd, "template", "chartsmith", ".", "--include-crds", "--values", "/dev/stdin")
+	templateCmd.Env = []string{"KUBECONFIG=" + fakeKubeconfigPath}
+	templateCmd.Dir = workingDir
+	templateCmd.Args = append(templateCmd.Args, valuesArgs...)
+
 	fmt.Printf("Running helm template with args: %v\n", templateCmd.Args)
 
 	// Send command to the command channel
 	renderChannels.HelmTemplateCmd <- templateCmd.String()
 
 	// Create a context with timeout for the template command
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	templateCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
 	// Create a channel to receive the command result
@@ -245,9 +265,14 @@ clusters:
 	case result := <-cmdDone:
 		output = result.output
 		cmdErr = result.err
-	case <-ctx.Done():
-		// Attempt to kill the process if it times out
+	case <-templateCtx.Done():
+		// Attempt to kill the process if it times out or is cancelled
 		templateCmd.Process.Kill()
+		if ctx.Err() != nil {
+			renderChannels.HelmTemplateStderr <- "Helm template command cancelled\n"
+			renderChannels.Done <- errors.Wrap(context.Cause(ctx), "helm template command cancelled")
+			return errors.Wrap(context.Cause(ctx), "helm template command cancelled")
+		}
 		renderChannels.HelmTemplateStderr <- "Helm template command timed out after 5 minutes\n"
 		renderChannels.Done <- errors.New("helm template command timed out after 5 minutes")
 		return errors.New("helm template command timed out after 5 minutes")
@@ -294,6 +319,54 @@ clusters:
 	return nil
 }
 
+// runHelmLint runs helm lint and sends its output to the lint channels. The problems helm lint finds are
+// in its output and don't fail the render, it only fails if helm lint can't run
+func runHelmLint(ctx context.Context, lintCmd *exec.Cmd, renderChannels RenderChannels) error {
+	renderChannels.HelmLintCmd <- lintCmd.String()
+
+	var stdout, stderr bytes.Buffer
+	lintCmd.Stdout = &stdout
+	lintCmd.Stderr = &stderr
+
+	if err := lintCmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to start helm lint")
+	}
+
+	lintCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- lintCmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		// helm lint exits with 1 when the chart has errors
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return errors.Wrap(err, "helm lint failed")
+		}
+	case <-lintCtx.Done():
+		lintCmd.Process.Kill()
+		if ctx.Err() != nil {
+			renderChannels.HelmLintStderr <- "Helm lint command cancelled\n"
+			return errors.Wrap(context.Cause(ctx), "helm lint command cancelled")
+		}
+		renderChannels.HelmLintStderr <- "Helm lint command timed out after 5 minutes\n"
+		return errors.New("helm lint command timed out after 5 minutes")
+	}
+
+	if stdout.Len() > 0 {
+		renderChannels.HelmLintStdout <- stdout.String()
+	}
+	if stderr.Len() > 0 {
+		renderChannels.HelmLintStderr <- stderr.String()
+	}
+
+	return nil
+}
+
 // findExecutableForHelmVersion returns the path to the helm executable for the specified version
 func findExecutableForHelmVersion(helmVersion string) (string, error) {
 	if helmVersion == "" {
@@ -1,82 +1,294 @@
-//go:build never
-// +build never
-
 package helmutils
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/yaml"
 
 	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
 )
 
-// RenderChartNative renders a Helm chart with the given files and values
-func RenderChartNative(files []types.File, valuesYAML string) (string, error) {
-	// Create a new chart loader
-	settings := cli.New()
-	actionConfig := new(action.Configuration)
+// RendererEnvName selects how charts are rendered. Charts are rendered in process with the helm SDK
+// unless it's set to RendererExec, which runs the helm binary with RenderChartExec
+const (
+	RendererEnvName = "CHARTSMITH_HELM_RENDERER"
+	RendererExec    = "exec"
+)
 
-	// Initialize without cluster communication
-	if err := actionConfig.Init(settings.RESTClientGetter(), "", "", nil); err != nil {
-		return "", err
+// UseExecRenderer returns true if charts should be rendered by running the helm binary
+func UseExecRenderer() bool {
+	return os.Getenv(RendererEnvName) == RendererExec
+}
+
+// RenderChartNative renders a chart with the given files and values in process, the way helm template
+// does. Dependencies in Chart.yaml that aren't in charts/ are downloaded. The result maps the path of
+// each template that produced output, relative to the root of the chart (e.g. templates/service.yaml or
+// charts/redis/templates/service.yaml), to its rendered manifests. Progress is written to out
+func RenderChartNative(ctx context.Context, files []types.File, valuesYAML string, out io.Writer) (map[string]string, error) {
+	c, err := loadChart(files, out)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create install action with dry-run for templating
-	install := action.NewInstall(actionConfig)
-	install.DryRun = true
+	values := map[string]interface{}{}
+	if valuesYAML != "" {
+		values, err = chartutil.ReadValues([]byte(valuesYAML))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse values")
+		}
+	}
+
+	// with ClientOnly, install replaces the kube client and release storage with fakes, so the
+	// configuration doesn't need a cluster
+	cfg := &action.Configuration{
+		Log: func(format string, v ...interface{}) {},
+	}
+
+	install := action.NewInstall(cfg)
 	install.ReleaseName = "chartsmith"
-	install.ClientOnly = true // Skip cluster communication
+	install.Namespace = "default"
+	install.DryRun = true
+	install.DryRunOption = "client"
+	install.ClientOnly = true
+	install.Replace = true
+	install.IncludeCRDs = true
 
-	// Convert workspace files to chart files
-	chartFiles := make([]*chart.File, 0, len(files))
-	templates := make([]*chart.File, 0, len(files))
+	rel, err := install.RunWithContext(ctx, c, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render chart")
+	}
+
+	return renderedTemplates(c.Name(), rel), nil
+}
+
+// FormatRenderedTemplates joins rendered templates into a multi-document manifest with a # Source
+// comment before each template, like the output of helm template
+func FormatRenderedTemplates(templates map[string]string) string {
+	paths := make([]string, 0, len(templates))
+	for path := range templates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "---\n# Source: %s\n%s\n", path, templates[path])
+	}
+	return sb.String()
+}
+
+// loadChart loads the chart in files, with its subcharts and dependencies
+func loadChart(files []types.File, out io.Writer) (*chart.Chart, error) {
+	chartDir, err := findChartDir(files)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(out, "Using chart directory: %s\n", chartDir)
+
+	bufferedFiles := []*loader.BufferedFile{}
 	for _, file := range files {
-		if strings.HasPrefix(file.FilePath, "templates/") {
-			// Add to Templates if it's in the templates directory
-			templates = append(templates, &chart.File{
-				Name: file.FilePath,
-				Data: []byte(file.Content),
-			})
-		} else {
-			// All other files go to Files
-			chartFiles = append(chartFiles, &chart.File{
-				Name: file.FilePath,
-				Data: []byte(file.Content),
-			})
+		name, err := filepath.Rel(chartDir, file.FilePath)
+		if err != nil || strings.HasPrefix(name, "..") {
+			continue
 		}
+		bufferedFiles = append(bufferedFiles, &loader.BufferedFile{
+			Name: filepath.ToSlash(name),
+			Data: []byte(file.Content),
+		})
 	}
 
-	// Create in-memory chart
-	c := &chart.Chart{
-		Metadata: &chart.Metadata{
-			Name:       "chartsmith",
-			Version:    "0.1.0",
-			APIVersion: "v2",
-		},
-		Files:     chartFiles,
-		Templates: templates, // Add templates separately
+	c, err := loader.LoadFiles(bufferedFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load chart")
 	}
+	fmt.Fprintf(out, "Loaded chart %s %s\n", c.Name(), c.Metadata.Version)
 
-	// Parse values
-	values := map[string]interface{}{}
-	if valuesYAML != "" {
-		var err error
-		values, err = chartutil.ReadValues([]byte(valuesYAML))
-		if err != nil {
-			return "", fmt.Errorf("failed to parse values: %w", err)
+	if len(c.Metadata.Dependencies) == 0 {
+		return c, nil
+	}
+
+	if err := action.CheckDependencies(c, c.Metadata.Dependencies); err == nil {
+		fmt.Fprintf(out, "All %d dependencies are in charts/\n", len(c.Metadata.Dependencies))
+		return c, nil
+	}
+
+	return loadChartWithDependencies(chartDir, files, out)
+}
+
+// loadChartWithDependencies writes the chart to a temp dir and downloads its dependencies into charts/,
+// like helm dependency update, before loading it
+func loadChartWithDependencies(chartDir string, files []types.File, out io.Writer) (*chart.Chart, error) {
+	rootDir, err := os.MkdirTemp("", "chartsmith")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(rootDir)
+
+	if err := writeChartFiles(rootDir, files); err != nil {
+		return nil, err
+	}
+
+	settings := cli.New()
+	registryClient, err := registry.NewClient(registry.ClientOptWriter(out))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create registry client")
+	}
+
+	chartPath := filepath.Join(rootDir, chartDir)
+	manager := &downloader.Manager{
+		Out:              out,
+		ChartPath:        chartPath,
+		Getters:          getter.All(settings),
+		RegistryClient:   registryClient,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+
+	fmt.Fprintf(out, "Downloading dependencies\n")
+	if err := manager.Update(); err != nil {
+		return nil, errors.Wrap(err, "failed to update dependencies")
+	}
+
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load chart with dependencies")
+	}
+
+	return c, nil
+}
+
+// writeChartFiles writes files to their paths in rootDir
+func writeChartFiles(rootDir string, files []types.File) error {
+	for _, file := range files {
+		filePath := filepath.Join(rootDir, file.FilePath)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create dir %q", filepath.Dir(filePath))
+		}
+		if err := os.WriteFile(filePath, []byte(file.Content), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write file %q", filePath)
 		}
 	}
+	return nil
+}
 
-	// Render the templates
-	rendered, err := install.Run(c, values)
+// ChartName returns the name in the chart's Chart.yaml, which helm prefixes the names of its templates with
+func ChartName(files []types.File) (string, error) {
+	chartDir, err := findChartDir(files)
 	if err != nil {
 		return "", err
 	}
 
-	return rendered.Manifest, nil
+	for _, file := range files {
+		if file.FilePath != filepath.Join(chartDir, "Chart.yaml") {
+			continue
+		}
+
+		metadata := chart.Metadata{}
+		if err := yaml.Unmarshal([]byte(file.Content), &metadata); err != nil {
+			return "", errors.Wrap(err, "failed to parse Chart.yaml")
+		}
+		if metadata.Name == "" {
+			return "", errors.New("Chart.yaml has no name")
+		}
+		return metadata.Name, nil
+	}
+
+	return "", errors.New("no Chart.yaml file found")
+}
+
+// findChartDir returns the directory of the chart's Chart.yaml. Subcharts have their own Chart.yaml,
+// so it's the one closest to the root
+func findChartDir(files []types.File) (string, error) {
+	depth := func(dir string) int {
+		if dir == "." {
+			return 0
+		}
+		return strings.Count(dir, "/") + 1
+	}
+
+	chartDir := ""
+	for _, file := range files {
+		if filepath.Base(file.FilePath) != "Chart.yaml" {
+			continue
+		}
+
+		dir := filepath.Dir(file.FilePath)
+		if chartDir == "" || depth(dir) < depth(chartDir) {
+			chartDir = dir
+		}
+	}
+
+	if chartDir == "" {
+		return "", errors.New("no Chart.yaml file found")
+	}
+
+	return chartDir, nil
+}
+
+// renderedTemplates groups the manifests and hooks of a release by the template that rendered them
+func renderedTemplates(chartName string, rel *release.Release) map[string]string {
+	templates := SplitManifest(chartName, rel.Manifest)
+
+	for _, hook := range rel.Hooks {
+		if isTestHook(hook) {
+			continue
+		}
+		addRenderedTemplate(templates, chartName, hook.Path, hook.Manifest)
+	}
+
+	return templates
+}
+
+// SplitManifest groups the documents in the output of helm template by the template that rendered them.
+// Helm names templates after the chart, e.g. mychart/templates/service.yaml, so the chart name is removed.
+// A template that renders several documents has a manifest for each of them, which are joined again
+func SplitManifest(chartName string, manifest string) map[string]string {
+	templates := map[string]string{}
+
+	// helm writes "---\n# Source: <template>\n<manifest>\n" for each manifest
+	for _, doc := range strings.Split("\n"+strings.TrimSpace(manifest), "\n---\n# Source: ") {
+		name, content, ok := strings.Cut(doc, "\n")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		addRenderedTemplate(templates, chartName, name, content)
+	}
+
+	return templates
+}
+
+func addRenderedTemplate(templates map[string]string, chartName string, name string, manifest string) {
+	path := strings.TrimPrefix(strings.TrimSpace(name), chartName+"/")
+	manifest = strings.TrimSpace(manifest)
+	if existing, ok := templates[path]; ok {
+		templates[path] = existing + "\n---\n" + manifest
+		return
+	}
+	templates[path] = manifest
+}
+
+// isTestHook returns true for helm test hooks, which helm template leaves out too
+func isTestHook(hook *release.Hook) bool {
+	for _, event := range hook.Events {
+		if event == release.HookTest {
+			return true
+		}
+	}
+	return false
 }
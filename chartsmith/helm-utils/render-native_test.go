@@ -0,0 +1,204 @@
+package helmutils
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+const testChartYAML = `apiVersion: v2
+name: mychart
+version: 0.1.0
+`
+
+const testSubchartYAML = `apiVersion: v2
+name: redis
+version: 1.0.0
+`
+
+func TestRenderChartNative(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []types.File
+		valuesYAML string
+		want       map[string]string
+		wantErr    string
+	}{
+		{
+			name: "templates with values",
+			files: []types.File{
+				{FilePath: "mychart/Chart.yaml", Content: testChartYAML},
+				{FilePath: "mychart/values.yaml", Content: "replicas: 1\n"},
+				{FilePath: "mychart/templates/configmap.yaml", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  replicas: \"{{ .Values.replicas }}\"\n"},
+				{FilePath: "mychart/templates/_helpers.tpl", Content: "{{- define \"mychart.name\" -}}mychart{{- end -}}\n"},
+			},
+			valuesYAML: "replicas: 3\n",
+			want: map[string]string{
+				"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: chartsmith\ndata:\n  replicas: \"3\"",
+			},
+		},
+		{
+			name: "template with several documents",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: testChartYAML},
+				{FilePath: "templates/configmaps.yaml", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n"},
+			},
+			want: map[string]string{
+				"templates/configmaps.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b",
+			},
+		},
+		{
+			name: "subchart in charts",
+			files: []types.File{
+				{FilePath: "mychart/Chart.yaml", Content: testChartYAML},
+				{FilePath: "mychart/templates/configmap.yaml", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: parent\n"},
+				{FilePath: "mychart/charts/redis/Chart.yaml", Content: testSubchartYAML},
+				{FilePath: "mychart/charts/redis/templates/configmap.yaml", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: redis\n"},
+			},
+			want: map[string]string{
+				"templates/configmap.yaml":              "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: parent",
+				"charts/redis/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: redis",
+			},
+		},
+		{
+			name: "hooks without test hooks",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: testChartYAML},
+				{FilePath: "templates/job.yaml", Content: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  annotations:\n    helm.sh/hook: pre-install\n"},
+				{FilePath: "templates/tests/test.yaml", Content: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\n  annotations:\n    helm.sh/hook: test\n"},
+			},
+			want: map[string]string{
+				"templates/job.yaml": "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  annotations:\n    helm.sh/hook: pre-install",
+			},
+		},
+		{
+			name: "no Chart.yaml",
+			files: []types.File{
+				{FilePath: "templates/configmap.yaml", Content: "apiVersion: v1\nkind: ConfigMap\n"},
+			},
+			wantErr: "no Chart.yaml file found",
+		},
+		{
+			name: "invalid values",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: testChartYAML},
+			},
+			valuesYAML: "replicas: [\n",
+			wantErr:    "failed to parse values",
+		},
+		{
+			name: "template error",
+			files: []types.File{
+				{FilePath: "Chart.yaml", Content: testChartYAML},
+				{FilePath: "templates/configmap.yaml", Content: "{{ required \"name is required\" .Values.name }}\n"},
+			},
+			wantErr: "name is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderChartNative(context.Background(), tt.files, tt.valuesYAML, io.Discard)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("templates = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitManifest(t *testing.T) {
+	tests := []struct {
+		name      string
+		chartName string
+		manifest  string
+		want      map[string]string
+	}{
+		{
+			name:      "empty",
+			chartName: "mychart",
+			manifest:  "",
+			want:      map[string]string{},
+		},
+		{
+			name:      "templates",
+			chartName: "mychart",
+			manifest:  "---\n# Source: mychart/templates/service.yaml\nkind: Service\n---\n# Source: mychart/templates/deployment.yaml\nkind: Deployment\n",
+			want: map[string]string{
+				"templates/service.yaml":    "kind: Service",
+				"templates/deployment.yaml": "kind: Deployment",
+			},
+		},
+		{
+			name:      "template with several documents",
+			chartName: "mychart",
+			manifest:  "---\n# Source: mychart/templates/configmaps.yaml\nname: a\n---\n# Source: mychart/templates/configmaps.yaml\nname: b\n",
+			want: map[string]string{
+				"templates/configmaps.yaml": "name: a\n---\nname: b",
+			},
+		},
+		{
+			name:      "subchart",
+			chartName: "mychart",
+			manifest:  "---\n# Source: mychart/charts/redis/templates/service.yaml\nkind: Service\n",
+			want: map[string]string{
+				"charts/redis/templates/service.yaml": "kind: Service",
+			},
+		},
+		{
+			name:      "other chart name",
+			chartName: "mychart",
+			manifest:  "---\n# Source: other/templates/service.yaml\nkind: Service\n",
+			want: map[string]string{
+				"other/templates/service.yaml": "kind: Service",
+			},
+		},
+		{
+			name:      "output before the first source",
+			chartName: "mychart",
+			manifest:  "WARNING: something\n---\n# Source: mychart/templates/service.yaml\nkind: Service",
+			want: map[string]string{
+				"templates/service.yaml": "kind: Service",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitManifest(tt.chartName, tt.manifest)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitManifest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRenderedTemplates(t *testing.T) {
+	templates := map[string]string{
+		"templates/service.yaml":    "kind: Service",
+		"templates/deployment.yaml": "kind: Deployment",
+	}
+
+	formatted := FormatRenderedTemplates(templates)
+	want := "---\n# Source: templates/deployment.yaml\nkind: Deployment\n---\n# Source: templates/service.yaml\nkind: Service\n"
+	if formatted != want {
+		t.Fatalf("FormatRenderedTemplates() = %q, want %q", formatted, want)
+	}
+
+	// templates formatted without a chart name are split back to the same paths
+	if got := SplitManifest("mychart", formatted); !reflect.DeepEqual(got, templates) {
+		t.Errorf("SplitManifest(FormatRenderedTemplates()) = %q, want %q", got, templates)
+	}
+}
@@ -1351,15 +1351,12 @@ func (c *DebugConsole) executePlan(args []string) error {
 		return errors.Wrap(err, "failed to list files")
 	}
 
-	currentContent := ""
+	currentContent := map[string]string{}
 	for _, file := range files {
-		if file.FilePath == filePath {
-			currentContent = file.Content
-			break
-		}
+		currentContent[file.FilePath] = file.Content
 	}
 
-	interimContentCh := make(chan string)
+	interimContentCh := make(chan llmtypes.Artifact)
 	doneCh := make(chan error)
 
 	go func() {
@@ -1381,7 +1378,7 @@ func (c *DebugConsole) executePlan(args []string) error {
 			}
 			done = true
 		case stream := <-interimContentCh:
-			fmt.Printf(boldGreen("Interim content: %s\n"), stream)
+			fmt.Printf(boldGreen("Interim content for %s: %s\n"), stream.Path, stream.Content)
 		}
 	}
 
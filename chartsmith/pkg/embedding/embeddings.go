@@ -18,6 +18,8 @@ import (
 
 const VOYAGE_API_URL = "https://api.voyageai.com/v1/embeddings"
 
+const voyageModel = "voyage-01"
+
 type embeddingRequest struct {
 	Model string   `json:"model"`
 	Input []string `json:"input"`
@@ -52,23 +54,65 @@ func Embeddings(content string) (string, error) {
 		return cachedEmbeddings, nil
 	}
 
+	embedding, err := fetchEmbedding(content)
+	if err != nil {
+		return "", err
+	}
+
+	// Convert float64 slice to PostgreSQL vector format
+	strValues := make([]string, len(embedding))
+	for i, v := range embedding {
+		strValues[i] = fmt.Sprintf("%.6f", v)
+	}
+
+	newEmbeddings := "[" + strings.Join(strValues, ",") + "]"
+
+	query = `insert into content_cache (content_sha256, embeddings) values ($1, $2) on conflict (content_sha256) do update set embeddings = $2`
+	_, err = conn.Exec(context.Background(), query, fmt.Sprintf("%x", contentSHA256), newEmbeddings)
+	if err != nil {
+		return "", fmt.Errorf("error inserting embeddings: %v", err)
+	}
+
+	return newEmbeddings, nil
+}
+
+// fetchEmbedding returns the embedding of content from a fixture, or from the Voyage API and records it
+// when fixtures are being recorded
+func fetchEmbedding(content string) ([]float64, error) {
+	switch fixtureMode {
+	case fixtureModeReplay:
+		return replayEmbedding(voyageModel, content)
+	case fixtureModeRecord:
+		embedding, err := requestEmbedding(content)
+		if err != nil {
+			return nil, err
+		}
+		recordEmbedding(voyageModel, content, embedding)
+		return embedding, nil
+	default:
+		return requestEmbedding(content)
+	}
+}
+
+// requestEmbedding sends content to the Voyage API and returns its embedding
+func requestEmbedding(content string) ([]float64, error) {
 	if param.Get().VoyageAPIKey == "" {
-		return "", fmt.Errorf("VOYAGE_API_KEY environment variable not set")
+		return nil, fmt.Errorf("VOYAGE_API_KEY environment variable not set")
 	}
 
 	reqBody := embeddingRequest{
-		Model: "voyage-01",
+		Model: voyageModel,
 		Input: []string{content},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("marshal error: %v", err)
+		return nil, fmt.Errorf("marshal error: %v", err)
 	}
 
 	req, err := http.NewRequest("POST", VOYAGE_API_URL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("request creation error: %v", err)
+		return nil, fmt.Errorf("request creation error: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -76,41 +120,27 @@ func Embeddings(content string) (string, error) {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request error: %v", err)
+		return nil, fmt.Errorf("request error: %v", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("response read error: %v", err)
+		return nil, fmt.Errorf("response read error: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, body)
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, body)
 	}
 
 	var embeddings embeddingResponse
 	if err := json.Unmarshal(body, &embeddings); err != nil {
-		return "", fmt.Errorf("unmarshal error: %v", err)
+		return nil, fmt.Errorf("unmarshal error: %v", err)
 	}
 
 	if len(embeddings.Data) == 0 {
-		return "", fmt.Errorf("no embeddings generated")
-	}
-
-	// Convert float64 slice to PostgreSQL vector format
-	strValues := make([]string, len(embeddings.Data[0].Embedding))
-	for i, v := range embeddings.Data[0].Embedding {
-		strValues[i] = fmt.Sprintf("%.6f", v)
+		return nil, fmt.Errorf("no embeddings generated")
 	}
 
-	newEmbeddings := "[" + strings.Join(strValues, ",") + "]"
-
-	query = `insert into content_cache (content_sha256, embeddings) values ($1, $2) on conflict (content_sha256) do update set embeddings = $2`
-	_, err = conn.Exec(context.Background(), query, fmt.Sprintf("%x", contentSHA256), newEmbeddings)
-	if err != nil {
-		return "", fmt.Errorf("error inserting embeddings: %v", err)
-	}
-
-	return newEmbeddings, nil
+	return embeddings.Data[0].Embedding, nil
 }
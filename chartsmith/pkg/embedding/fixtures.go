@@ -0,0 +1,100 @@
+package embedding
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// the fixture modes, the same values as the LLM fixture modes
+const (
+	fixtureModeOff    = ""
+	fixtureModeRecord = "record"
+	fixtureModeReplay = "replay"
+)
+
+var (
+	fixtureMode string
+	fixtureDir  string
+)
+
+// embeddingFixture is a recorded embedding of some content
+type embeddingFixture struct {
+	Model     string    `json:"model"`
+	Content   string    `json:"content"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EnableFixtures records the embeddings from the Voyage API to, or replays them from, a JSON file per
+// content in dir. mode is "record", "replay" or empty to call the API as usual
+func EnableFixtures(mode string, dir string) error {
+	switch mode {
+	case fixtureModeOff:
+		return nil
+	case fixtureModeRecord:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create embedding fixtures dir: %w", err)
+		}
+	case fixtureModeReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("failed to read embedding fixtures dir: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown fixture mode %q", mode)
+	}
+
+	fixtureMode = mode
+	fixtureDir = dir
+	return nil
+}
+
+// fixturePath returns the fixture file for the embedding of content by model
+func fixturePath(model string, content string) string {
+	hash := sha256.Sum256([]byte(model + "\n" + content))
+	return filepath.Join(fixtureDir, fmt.Sprintf("%s-%x.json", model, hash[:12]))
+}
+
+func replayEmbedding(model string, content string) ([]float64, error) {
+	path := fixturePath(model, content)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no embedding fixture recorded at %s, record it with fixture mode %q", path, fixtureModeRecord)
+		}
+		return nil, fmt.Errorf("failed to read embedding fixture: %w", err)
+	}
+
+	f := embeddingFixture{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding fixture %s: %w", path, err)
+	}
+
+	return f.Embedding, nil
+}
+
+// recordEmbedding saves the fixture. Failing to save one doesn't fail the request
+func recordEmbedding(model string, content string, embedding []float64) {
+	path := fixturePath(model, content)
+	b, err := json.MarshalIndent(embeddingFixture{
+		Model:     model,
+		Content:   content,
+		Embedding: embedding,
+	}, "", "  ")
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to marshal embedding fixture: %w", err))
+		return
+	}
+
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		logger.Error(fmt.Errorf("failed to write embedding fixture: %w", err))
+		return
+	}
+
+	logger.Debug("Recorded embedding fixture", zap.String("path", path))
+}
@@ -0,0 +1,59 @@
+package embedding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddingFixtures(t *testing.T) {
+	dir := t.TempDir()
+	if err := EnableFixtures(fixtureModeRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		fixtureMode, fixtureDir = fixtureModeOff, ""
+	})
+
+	recordEmbedding(voyageModel, "apiVersion: v1", []float64{0.1, 0.2, 0.3})
+
+	if err := EnableFixtures(fixtureModeReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []float64
+		wantErr string
+	}{
+		{
+			name:    "recorded",
+			content: "apiVersion: v1",
+			want:    []float64{0.1, 0.2, 0.3},
+		},
+		{
+			name:    "not recorded",
+			content: "apiVersion: v2",
+			wantErr: "no embedding fixture recorded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchEmbedding(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("embedding = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	realtimetypes "github.com/replicatedhq/chartsmith/pkg/realtime/types"
 	"github.com/replicatedhq/chartsmith/pkg/workspace"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"github.com/tuvistavie/securerandom"
 	"go.uber.org/zap"
 )
 
@@ -48,7 +49,7 @@ func applyPlanLockKeyExtractor(payload []byte) (string, error) {
 
 // handleApplyPlanNotification handles a apply_plan notification by processing
 // all action files for a plan in sequence
-func handleApplyPlanNotification(ctx context.Context, payload string) error {
+func handleApplyPlanNotification(ctx context.Context, payload string) (err error) {
 	logger.Info("New apply plan notification received", zap.String("payload", payload))
 
 	// Parse the payload
@@ -57,6 +58,14 @@ func handleApplyPlanNotification(ctx context.Context, payload string) error {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
+	defer func() {
+		if err != nil && isWorkCancelled(ctx) {
+			if cancelErr := markPlanCancelled(context.WithoutCancel(ctx), p.PlanID); cancelErr != nil {
+				logger.Error(fmt.Errorf("failed to mark plan as cancelled: %w", cancelErr))
+			}
+		}
+	}()
+
 	// Get the plan
 	plan, err := workspace.GetPlan(ctx, nil, p.PlanID)
 	if err != nil {
@@ -101,6 +110,18 @@ func handleApplyPlanNotification(ctx context.Context, payload string) error {
 			continue
 		}
 
+		// stop between files when over quota and return the plan to review so that it can be resumed later
+		quotaErr, err := checkLLMQuota(ctx, w.ID, lastChatMessageID(plan.ChatMessageIDs))
+		if err != nil {
+			return err
+		}
+		if quotaErr != nil {
+			if err := returnPlanToReview(ctx, plan.ID, realtimeRecipient); err != nil {
+				return err
+			}
+			return sendQuotaExceededEvent(ctx, realtimeRecipient, w.ID, "plan", plan.ID, quotaErr)
+		}
+
 		logger.Info("Processing action file",
 			zap.String("path", actionFile.Path),
 			zap.String("action", actionFile.Action),
@@ -172,6 +193,29 @@ func handleApplyPlanNotification(ctx context.Context, payload string) error {
 	return nil
 }
 
+// returnPlanToReview sets the status of a plan that was stopped before all of its files were applied
+// back to review. Files that were applied keep their status and are skipped when the plan is applied again
+func returnPlanToReview(ctx context.Context, planID string, realtimeRecipient realtimetypes.Recipient) error {
+	if err := workspace.UpdatePlanStatus(ctx, planID, workspacetypes.PlanStatusReview); err != nil {
+		return fmt.Errorf("error updating plan status: %w", err)
+	}
+
+	plan, err := workspace.GetPlan(ctx, nil, planID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	e := realtimetypes.PlanUpdatedEvent{
+		WorkspaceID: plan.WorkspaceID,
+		Plan:        plan,
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send plan update: %w", err)
+	}
+
+	return nil
+}
+
 // updateActionFileStatus updates the status of an action file in a plan
 func updateActionFileStatus(ctx context.Context, planID, path, status string) error {
 	conn := persistence.MustGetPooledPostgresSession()
@@ -208,6 +252,14 @@ func updateActionFileStatus(ctx context.Context, planID, path, status string) er
 
 // processActionFile processes a single action file for a plan
 func processActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *workspacetypes.Plan, actionFile workspacetypes.ActionFile, realtimeRecipient realtimetypes.Recipient) error {
+	// deletes and renames don't need the model, they are applied to the new revision directly
+	switch actionFile.Action {
+	case workspacetypes.ActionFileActionDelete:
+		return deleteActionFile(ctx, w, plan, actionFile, realtimeRecipient)
+	case workspacetypes.ActionFileActionRename:
+		return renameActionFile(ctx, w, plan, actionFile, realtimeRecipient)
+	}
+
 	// Get user model preference
 	modelID, err := llm.GetUserModelPreferenceFromWorkspace(ctx, w.ID)
 	if err != nil {
@@ -215,36 +267,59 @@ func processActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *w
 		modelID = llm.DefaultOpenRouterModel
 	}
 
-	// Get chart and current content
-	currentContent := ""
-	var chartID string
+	if len(w.Charts) == 0 {
+		return fmt.Errorf("no charts found in workspace")
+	}
+	chartID := w.Charts[0].ID
 
-	if len(w.Charts) > 0 {
-		c := w.Charts[0]
-		chartID = c.ID
+	// Get the files from the workspace. Earlier actions in the plan may have left pending content,
+	// which the action builds on
+	files, err := workspace.ListFiles(ctx, w.ID, w.CurrentRevision, chartID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
 
-		for _, file := range c.Files {
-			if file.FilePath == actionFile.Path {
-				currentContent = file.Content
-				break
-			}
+	filesByPath := map[string]*workspacetypes.File{}
+	currentContent := map[string]string{}
+	// createdPaths are the files the action created, which aren't saved until it completes
+	createdPaths := map[string]bool{}
+	for i, f := range files {
+		// deletes and renames by earlier actions are pending too
+		if f.PendingDelete {
+			continue
 		}
-	} else {
-		return fmt.Errorf("no charts found in workspace")
+		path := f.FilePath
+		if f.FilePathPending != nil {
+			path = *f.FilePathPending
+		}
+
+		filesByPath[path] = &files[i]
+		if f.ContentPending != nil {
+			currentContent[path] = *f.ContentPending
+		} else {
+			currentContent[path] = f.Content
+		}
+	}
+
+	// an empty file left by an earlier attempt at a create action can still be created
+	if content, ok := currentContent[actionFile.Path]; ok && content == "" && actionFile.Action == workspacetypes.ActionFileActionCreate {
+		delete(currentContent, actionFile.Path)
 	}
 
 	// Set up channels for content updates
-	interimContentCh := make(chan string)
-	finalContentCh := make(chan string)
+	interimContentCh := make(chan llmtypes.Artifact)
+	finalContentCh := make(chan map[string]string)
 	errCh := make(chan error)
 
 	// Process the file in a goroutine
 	go func() {
 		apwp := llmtypes.ActionPlanWithPath{
 			ActionPlan: llmtypes.ActionPlan{
-				Action: actionFile.Action,
-				Type:   "file",
-				Status: llmtypes.ActionPlanStatusPending,
+				Action:      actionFile.Action,
+				Type:        "file",
+				Status:      llmtypes.ActionPlanStatusPending,
+				Description: actionFile.Description,
+				NewPath:     actionFile.NewPath,
 			},
 			Path: actionFile.Path,
 		}
@@ -264,23 +339,12 @@ func processActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *w
 	noActivityTimeout := time.After(3 * time.Minute)
 	lastActivity := time.Now()
 
-	// Get the file from the workspace, if it exists
-	files, err := workspace.ListFiles(ctx, w.ID, w.CurrentRevision, chartID)
-	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
-	}
-
-	var file *workspacetypes.File
-	for _, f := range files {
-		if f.FilePath == actionFile.Path {
-			file = &f
-			break
-		}
-	}
-
 	// Process updates until done
 	for {
 		select {
+		case <-ctx.Done():
+			return fmt.Errorf("action execution stopped: %w", context.Cause(ctx))
+
 		case <-timeout:
 			return fmt.Errorf("timeout waiting for action execution")
 
@@ -302,31 +366,45 @@ func processActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *w
 			lastActivity = time.Now()
 			noActivityTimeout = time.After(3 * time.Minute)
 
-			if file == nil {
-				// We need to create the file since we got content
-				err := workspace.AddFileToChart(ctx, chartID, w.ID, w.CurrentRevision, actionFile.Path, "")
-				if err != nil {
-					return fmt.Errorf("failed to add file to chart: %w", err)
-				}
+			file := filesByPath[interimContent.Path]
 
-				files, err := workspace.ListFiles(ctx, w.ID, w.CurrentRevision, chartID)
-				if err != nil {
-					return fmt.Errorf("failed to list files: %w", err)
+			// a file that the action created and then undid is removed from the workspace view
+			if interimContent.Deleted {
+				if file == nil || !createdPaths[interimContent.Path] {
+					continue
 				}
+				delete(filesByPath, interimContent.Path)
+				delete(createdPaths, interimContent.Path)
 
-				for _, f := range files {
-					if f.FilePath == actionFile.Path {
-						file = &f
-						break
-					}
+				e := realtimetypes.ArtifactDeletedEvent{
+					WorkspaceID:   w.ID,
+					WorkspaceFile: file,
 				}
+				if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+					return fmt.Errorf("failed to send artifact deleted: %w", err)
+				}
+				continue
 			}
 
 			if file == nil {
-				return fmt.Errorf("file not found in workspace")
+				// Interim content is only sent to the workspace view. The file is created in the
+				// workspace with the final content, so a failed or undone create doesn't leave a file behind
+				id, err := securerandom.Hex(16)
+				if err != nil {
+					return fmt.Errorf("failed to generate file id: %w", err)
+				}
+				file = &workspacetypes.File{
+					ID:             "file-" + id,
+					RevisionNumber: w.CurrentRevision,
+					ChartID:        chartID,
+					WorkspaceID:    w.ID,
+					FilePath:       interimContent.Path,
+				}
+				filesByPath[interimContent.Path] = file
+				createdPaths[interimContent.Path] = true
 			}
 
-			file.ContentPending = &interimContent
+			file.ContentPending = &interimContent.Content
 
 			e := realtimetypes.ArtifactUpdatedEvent{
 				WorkspaceID:   w.ID,
@@ -338,31 +416,108 @@ func processActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *w
 			}
 
 		case finalContent := <-finalContentCh:
-			// Save final content
-			if err := workspace.SetFileContentPending(ctx, actionFile.Path, w.CurrentRevision, chartID, w.ID, finalContent); err != nil {
+			// Save the final content of every file the action changed together
+			if err := workspace.SetFilesContentPending(ctx, w.CurrentRevision, chartID, w.ID, finalContent); err != nil {
 				return fmt.Errorf("failed to set file content pending: %w", err)
 			}
 
-			// Update action file status
-			if err := updateActionFileStatus(ctx, plan.ID, actionFile.Path, string(llmtypes.ActionPlanStatusCreated)); err != nil {
-				return fmt.Errorf("failed to update action file status: %w", err)
+			// files that were only in the workspace view until now get their saved IDs
+			if len(createdPaths) > 0 {
+				if err := sendCreatedFiles(ctx, w, chartID, createdPaths, realtimeRecipient); err != nil {
+					return err
+				}
 			}
 
-			// Send plan update
-			updatedPlan, err := workspace.GetPlan(ctx, nil, plan.ID)
-			if err != nil {
-				return fmt.Errorf("failed to get updated plan: %w", err)
-			}
+			return completeActionFile(ctx, w.ID, plan.ID, actionFile.Path, realtimeRecipient)
+		}
+	}
+}
 
-			e := realtimetypes.PlanUpdatedEvent{
-				WorkspaceID: w.ID,
-				Plan:        updatedPlan,
-			}
-			if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
-				return fmt.Errorf("failed to send plan update: %w", err)
-			}
+// sendCreatedFiles sends the saved version of the files at paths, replacing the unsaved versions that were
+// sent while the action was running
+func sendCreatedFiles(ctx context.Context, w *workspacetypes.Workspace, chartID string, paths map[string]bool, realtimeRecipient realtimetypes.Recipient) error {
+	files, err := workspace.ListFiles(ctx, w.ID, w.CurrentRevision, chartID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
 
-			return nil
+	for i, f := range files {
+		if !paths[f.FilePath] {
+			continue
+		}
+
+		e := realtimetypes.ArtifactUpdatedEvent{
+			WorkspaceID:   w.ID,
+			WorkspaceFile: &files[i],
+		}
+		if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+			return fmt.Errorf("failed to send artifact update: %w", err)
 		}
 	}
+
+	return nil
+}
+
+// deleteActionFile stages the delete of the file of a delete action in the revision the plan is applied to.
+// The file is deleted when the change is accepted
+func deleteActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *workspacetypes.Plan, actionFile workspacetypes.ActionFile, realtimeRecipient realtimetypes.Recipient) error {
+	file, err := workspace.StageFileDelete(ctx, w.ID, w.CurrentRevision, actionFile.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stage file delete: %w", err)
+	}
+
+	e := realtimetypes.ArtifactUpdatedEvent{
+		WorkspaceID:   w.ID,
+		WorkspaceFile: file,
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send artifact update: %w", err)
+	}
+
+	return completeActionFile(ctx, w.ID, plan.ID, actionFile.Path, realtimeRecipient)
+}
+
+// renameActionFile stages the move of the file of a rename action to its new path in the revision the plan
+// is applied to. The file is moved when the change is accepted
+func renameActionFile(ctx context.Context, w *workspacetypes.Workspace, plan *workspacetypes.Plan, actionFile workspacetypes.ActionFile, realtimeRecipient realtimetypes.Recipient) error {
+	if actionFile.NewPath == "" {
+		return fmt.Errorf("rename of %s has no new path", actionFile.Path)
+	}
+
+	file, err := workspace.StageFileRename(ctx, w.ID, w.CurrentRevision, actionFile.Path, actionFile.NewPath)
+	if err != nil {
+		return fmt.Errorf("failed to stage file rename: %w", err)
+	}
+
+	e := realtimetypes.ArtifactUpdatedEvent{
+		WorkspaceID:   w.ID,
+		WorkspaceFile: file,
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send artifact update: %w", err)
+	}
+
+	return completeActionFile(ctx, w.ID, plan.ID, actionFile.Path, realtimeRecipient)
+}
+
+// completeActionFile marks an action file as created and sends the updated plan
+func completeActionFile(ctx context.Context, workspaceID string, planID string, path string, realtimeRecipient realtimetypes.Recipient) error {
+	if err := updateActionFileStatus(ctx, planID, path, string(llmtypes.ActionPlanStatusCreated)); err != nil {
+		return fmt.Errorf("failed to update action file status: %w", err)
+	}
+
+	updatedPlan, err := workspace.GetPlan(ctx, nil, planID)
+	if err != nil {
+		return fmt.Errorf("failed to get updated plan: %w", err)
+	}
+
+	e := realtimetypes.PlanUpdatedEvent{
+		WorkspaceID: workspaceID,
+		Plan:        updatedPlan,
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send plan update: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,239 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/realtime"
+	realtimetypes "github.com/replicatedhq/chartsmith/pkg/realtime/types"
+	"github.com/replicatedhq/chartsmith/pkg/workspace"
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"go.uber.org/zap"
+)
+
+// errWorkCancelled is the cause of a handler context that was cancelled by persistence.CancelWork or CancelWorkForPayload
+var errWorkCancelled = errors.New("work cancelled")
+
+// errJobReleased is the cause of a handler context that was cancelled because Drain released the job so that
+// another worker can run it. The handler's result isn't recorded, the job belongs to whoever claims it next
+var errJobReleased = errors.New("job released")
+
+// errClaimLost is the cause of a handler context whose job was claimed by another worker or moved to the
+// dead letter table while it ran. That only happens when the other worker took this one for dead, a job
+// that runs past the maxDuration of its channel is never taken away from a live handler on this worker
+var errClaimLost = errors.New("job claimed by another worker")
+
+// isWorkCancelled returns true if ctx was cancelled because cancellation of the job was requested
+func isWorkCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errWorkCancelled)
+}
+
+// cancelMessage cancels the handler context for a message with cause if it's running on this worker
+func (l *Listener) cancelMessage(messageID string, cause error) {
+	l.mu.Lock()
+	cancel, ok := l.cancels[messageID]
+	l.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	logger.Info("Cancelling message", zap.String("id", messageID), zap.Error(cause))
+	cancel(cause)
+}
+
+// pollCancellations catches cancellation requests for running messages that were
+// missed because the notification arrived while the listener was reconnecting. It also
+// stops handlers whose message was claimed again by another worker
+func (l *Listener) pollCancellations(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		running := make(map[string]time.Time, len(l.inFlight))
+		for id, claimedAt := range l.inFlight {
+			running[id] = claimedAt
+		}
+		l.mu.Unlock()
+
+		if len(running) == 0 {
+			continue
+		}
+
+		cancelled, lost, err := l.listCancelledOrLost(ctx, running)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to check for cancelled messages: %w", err))
+			continue
+		}
+
+		for _, id := range lost {
+			l.cancelMessage(id, errClaimLost)
+		}
+		for _, id := range cancelled {
+			l.cancelMessage(id, errWorkCancelled)
+		}
+	}
+}
+
+// listCancelledOrLost returns the running messages that cancellation was requested for, and the ones
+// that are no longer claimed by the attempt running on this worker
+func (l *Listener) listCancelledOrLost(ctx context.Context, running map[string]time.Time) (cancelled []string, lost []string, err error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(queryCtx, l.pgURI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(queryCtx)
+
+	ids := make([]string, 0, len(running))
+	for id := range running {
+		ids = append(ids, id)
+	}
+
+	rows, err := conn.Query(queryCtx, fmt.Sprintf(`SELECT id, cancel_requested_at IS NOT NULL, processing_started_at FROM %s WHERE id = ANY($1)`, WorkQueueTable), ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query running messages: %w", err)
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var id string
+		var cancelRequested bool
+		var processingStartedAt *time.Time
+		if err := rows.Scan(&id, &cancelRequested, &processingStartedAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan running message: %w", err)
+		}
+		found[id] = true
+
+		if processingStartedAt == nil || !processingStartedAt.Equal(running[id]) {
+			lost = append(lost, id)
+		} else if cancelRequested {
+			cancelled = append(cancelled, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read running messages: %w", err)
+	}
+
+	// messages that another worker found timed out on their final attempt were moved to the dead letter table
+	for _, id := range ids {
+		if !found[id] {
+			lost = append(lost, id)
+		}
+	}
+
+	return cancelled, lost, nil
+}
+
+// markPlanCancelled sets the plan status to cancelled and notifies the workspace users.
+// ctx must not be the cancelled handler context
+func markPlanCancelled(ctx context.Context, planID string) error {
+	if err := workspace.UpdatePlanStatus(ctx, planID, workspacetypes.PlanStatusCancelled); err != nil {
+		return fmt.Errorf("failed to update plan status: %w", err)
+	}
+
+	plan, err := workspace.GetPlan(ctx, nil, planID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, plan.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	realtimeRecipient := realtimetypes.Recipient{
+		UserIDs: userIDs,
+	}
+
+	if err := realtime.SendEvent(ctx, realtimeRecipient, realtimetypes.PlanUpdatedEvent{
+		WorkspaceID: plan.WorkspaceID,
+		Plan:        plan,
+	}); err != nil {
+		return fmt.Errorf("failed to send plan update: %w", err)
+	}
+
+	return sendWorkCancelledEvent(ctx, realtimeRecipient, plan.WorkspaceID, "plan", plan.ID)
+}
+
+// markConversionCancelled sets the conversion status to cancelled and notifies the workspace users.
+// ctx must not be the cancelled handler context
+func markConversionCancelled(ctx context.Context, conversionID string) error {
+	if err := workspace.SetConversionStatus(ctx, conversionID, workspacetypes.ConversionStatusCancelled); err != nil {
+		return fmt.Errorf("failed to set conversion status: %w", err)
+	}
+
+	c, err := workspace.GetConversion(ctx, conversionID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversion: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, c.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	realtimeRecipient := realtimetypes.Recipient{
+		UserIDs: userIDs,
+	}
+
+	if err := realtime.SendEvent(ctx, realtimeRecipient, realtimetypes.ConversionStatusEvent{
+		WorkspaceID: c.WorkspaceID,
+		Conversion:  *c,
+	}); err != nil {
+		return fmt.Errorf("failed to send conversion status event: %w", err)
+	}
+
+	return sendWorkCancelledEvent(ctx, realtimeRecipient, c.WorkspaceID, "conversion", c.ID)
+}
+
+// markRenderCancelled fails the render with a cancelled message and notifies the workspace users.
+// ctx must not be the cancelled handler context
+func markRenderCancelled(ctx context.Context, renderID string) error {
+	if err := workspace.FailRendered(ctx, renderID, errWorkCancelled.Error()); err != nil {
+		return fmt.Errorf("failed to fail rendered: %w", err)
+	}
+
+	rendered, err := workspace.GetRendered(ctx, renderID)
+	if err != nil {
+		return fmt.Errorf("failed to get rendered: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, rendered.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	realtimeRecipient := realtimetypes.Recipient{
+		UserIDs: userIDs,
+	}
+
+	return sendWorkCancelledEvent(ctx, realtimeRecipient, rendered.WorkspaceID, "render", rendered.ID)
+}
+
+func sendWorkCancelledEvent(ctx context.Context, realtimeRecipient realtimetypes.Recipient, workspaceID string, kind string, id string) error {
+	e := realtimetypes.WorkCancelledEvent{
+		WorkspaceID: workspaceID,
+		Kind:        kind,
+		ID:          id,
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send work cancelled event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package listener
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// workerEnvPrefix is the prefix of the env vars that override a channel's config, e.g.
+	// CHARTSMITH_WORKER_APPLY_PLAN_MAX_WORKERS=4 or CHARTSMITH_WORKER_APPLY_PLAN_MAX_DURATION=15m
+	workerEnvPrefix        = "CHARTSMITH_WORKER_"
+	maxWorkersEnvSuffix    = "_MAX_WORKERS"
+	maxDurationEnvSuffix   = "_MAX_DURATION"
+	channelsEnvName        = "CHARTSMITH_WORKER_CHANNELS"
+	workerConfigFileEnvVar = "CHARTSMITH_WORKER_CONFIG"
+)
+
+// WorkerConfig overrides the defaults that StartListeners uses for each channel
+type WorkerConfig struct {
+	// Channels limits the worker to these channels. Every channel is handled if empty
+	Channels []string `yaml:"channels"`
+	// ChannelConfig overrides the defaults for individual channels
+	ChannelConfig map[string]ChannelConfig `yaml:"channelConfig"`
+}
+
+// ChannelConfig controls how many jobs on a channel run at once on a worker and how long
+// each job can run before other workers consider its worker gone and claim it again. Zero
+// values keep the channel's default
+type ChannelConfig struct {
+	MaxWorkers  int           `yaml:"maxWorkers"`
+	MaxDuration time.Duration `yaml:"maxDuration"`
+}
+
+// LoadWorkerConfig reads the YAML config at path, or at $CHARTSMITH_WORKER_CONFIG if path is
+// empty, and then applies any env var overrides. Env vars take precedence over the file
+func LoadWorkerConfig(path string) (*WorkerConfig, error) {
+	if path == "" {
+		path = os.Getenv(workerConfigFileEnvVar)
+	}
+
+	config := &WorkerConfig{}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read worker config: %w", err)
+		}
+		if err := yaml.Unmarshal(b, config); err != nil {
+			return nil, fmt.Errorf("failed to parse worker config %s: %w", path, err)
+		}
+	}
+	if config.ChannelConfig == nil {
+		config.ChannelConfig = map[string]ChannelConfig{}
+	}
+
+	if err := config.applyEnv(os.Environ()); err != nil {
+		return nil, fmt.Errorf("failed to apply worker config from env: %w", err)
+	}
+
+	return config, nil
+}
+
+func (c *WorkerConfig) applyEnv(environ []string) error {
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, workerEnvPrefix) {
+			continue
+		}
+
+		if name == channelsEnvName {
+			c.Channels = splitChannels(value)
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(name, maxWorkersEnvSuffix):
+			channel := envChannelName(name, maxWorkersEnvSuffix)
+			maxWorkers, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			channelConfig := c.ChannelConfig[channel]
+			channelConfig.MaxWorkers = maxWorkers
+			c.ChannelConfig[channel] = channelConfig
+
+		case strings.HasSuffix(name, maxDurationEnvSuffix):
+			channel := envChannelName(name, maxDurationEnvSuffix)
+			maxDuration, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			channelConfig := c.ChannelConfig[channel]
+			channelConfig.MaxDuration = maxDuration
+			c.ChannelConfig[channel] = channelConfig
+		}
+	}
+
+	return nil
+}
+
+// envChannelName converts CHARTSMITH_WORKER_APPLY_PLAN_MAX_WORKERS to apply_plan
+func envChannelName(name string, suffix string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, workerEnvPrefix), suffix))
+}
+
+func splitChannels(value string) []string {
+	channels := []string{}
+	for _, channel := range strings.Split(value, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// handles returns true if the worker should process jobs on channel
+func (c *WorkerConfig) handles(channel string) bool {
+	if c == nil || len(c.Channels) == 0 {
+		return true
+	}
+	for _, ch := range c.Channels {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the effective config for channel, starting from its defaults
+func (c *WorkerConfig) resolve(channel string, defaults ChannelConfig) ChannelConfig {
+	if c == nil {
+		return defaults
+	}
+
+	effective := defaults
+	if override, ok := c.ChannelConfig[channel]; ok {
+		if override.MaxWorkers > 0 {
+			effective.MaxWorkers = override.MaxWorkers
+		}
+		if override.MaxDuration > 0 {
+			effective.MaxDuration = override.MaxDuration
+		}
+	}
+	return effective
+}
@@ -0,0 +1,139 @@
+package listener
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWorkerConfigApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    WorkerConfig
+		wantErr bool
+	}{
+		{
+			name:    "unrelated env",
+			environ: []string{"HOME=/root", "CHARTSMITH_OTHER", "PATH=/bin"},
+			want:    WorkerConfig{ChannelConfig: map[string]ChannelConfig{}},
+		},
+		{
+			name:    "channels",
+			environ: []string{"CHARTSMITH_WORKER_CHANNELS= apply_plan, ,render_workspace,"},
+			want: WorkerConfig{
+				Channels:      []string{"apply_plan", "render_workspace"},
+				ChannelConfig: map[string]ChannelConfig{},
+			},
+		},
+		{
+			name: "max workers and duration",
+			environ: []string{
+				"CHARTSMITH_WORKER_APPLY_PLAN_MAX_WORKERS=4",
+				"CHARTSMITH_WORKER_APPLY_PLAN_MAX_DURATION=15m",
+				"CHARTSMITH_WORKER_RENDER_WORKSPACE_MAX_DURATION=90s",
+			},
+			want: WorkerConfig{
+				ChannelConfig: map[string]ChannelConfig{
+					"apply_plan":       {MaxWorkers: 4, MaxDuration: 15 * time.Minute},
+					"render_workspace": {MaxDuration: 90 * time.Second},
+				},
+			},
+		},
+		{
+			name:    "invalid max workers",
+			environ: []string{"CHARTSMITH_WORKER_APPLY_PLAN_MAX_WORKERS=four"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid max duration",
+			environ: []string{"CHARTSMITH_WORKER_APPLY_PLAN_MAX_DURATION=15"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := WorkerConfig{ChannelConfig: map[string]ChannelConfig{}}
+			err := config.applyEnv(tt.environ)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(config, tt.want) {
+				t.Errorf("config = %+v, want %+v", config, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.yaml")
+	err := os.WriteFile(path, []byte(`channels:
+  - apply_plan
+channelConfig:
+  apply_plan:
+    maxWorkers: 2
+    maxDuration: 10m
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CHARTSMITH_WORKER_APPLY_PLAN_MAX_WORKERS", "8")
+
+	config, err := LoadWorkerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &WorkerConfig{
+		Channels: []string{"apply_plan"},
+		ChannelConfig: map[string]ChannelConfig{
+			"apply_plan": {MaxWorkers: 8, MaxDuration: 10 * time.Minute},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadWorkerConfig() = %+v, want %+v", config, want)
+	}
+}
+
+func TestWorkerConfigResolve(t *testing.T) {
+	defaults := ChannelConfig{MaxWorkers: 1, MaxDuration: 5 * time.Minute}
+	config := &WorkerConfig{
+		Channels: []string{"apply_plan"},
+		ChannelConfig: map[string]ChannelConfig{
+			"apply_plan":       {MaxWorkers: 4},
+			"render_workspace": {MaxDuration: time.Minute},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		config      *WorkerConfig
+		channel     string
+		want        ChannelConfig
+		wantHandles bool
+	}{
+		{name: "nil config", config: nil, channel: "apply_plan", want: defaults, wantHandles: true},
+		{name: "max workers override", config: config, channel: "apply_plan", want: ChannelConfig{MaxWorkers: 4, MaxDuration: 5 * time.Minute}, wantHandles: true},
+		{name: "max duration override", config: config, channel: "render_workspace", want: ChannelConfig{MaxWorkers: 1, MaxDuration: time.Minute}, wantHandles: false},
+		{name: "no override", config: config, channel: "new_summarize", want: defaults, wantHandles: false},
+		{name: "all channels", config: &WorkerConfig{}, channel: "new_summarize", want: defaults, wantHandles: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.resolve(tt.channel, defaults); got != tt.want {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+			if got := tt.config.handles(tt.channel); got != tt.wantHandles {
+				t.Errorf("handles() = %v, want %v", got, tt.wantHandles)
+			}
+		})
+	}
+}
@@ -50,6 +50,18 @@ func handleConverationalNotification(ctx context.Context, payload string) error
 		UserIDs: userIDs,
 	}
 
+	quotaErr, err := checkLLMQuota(ctx, w.ID, chatMessage.ID)
+	if err != nil {
+		return err
+	}
+	if quotaErr != nil {
+		// the limit was explained in the response, complete the message so that the user can send another
+		if err := workspace.SetChatMessageIntent(ctx, chatMessage.ID, true, true, false, false, false); err != nil {
+			return fmt.Errorf("failed to set chat message intent: %w", err)
+		}
+		return nil
+	}
+
 	// Get user model preference
 	modelID, err := llm.GetUserModelPreferenceFromWorkspace(ctx, w.ID)
 	if err != nil {
@@ -39,6 +39,7 @@ func handleConversionNormalizeValuesNotification(ctx context.Context, payload st
 	if err != nil {
 		return fmt.Errorf("failed to get workspace: %w", err)
 	}
+	ctx = llm.WithUsageScope(ctx, llm.UsageScope{WorkspaceID: w.ID})
 
 	// Get user model preference
 	modelID, err := llm.GetUserModelPreferenceFromWorkspace(ctx, w.ID)
@@ -36,6 +36,7 @@ func handleExecutePlanNotification(ctx context.Context, payload string) error {
 	if err != nil {
 		return fmt.Errorf("error getting plan: %w", err)
 	}
+	ctx = llm.WithUsageScope(ctx, llm.PlanUsageScope(plan))
 
 	w, err := workspace.GetWorkspace(ctx, plan.WorkspaceID)
 	if err != nil {
@@ -137,9 +138,11 @@ func handleExecutePlanNotification(ctx context.Context, payload string) error {
 			}
 
 			actionFile := workspacetypes.ActionFile{
-				Action: actionPlanWithPath.Action,
-				Path:   actionPlanWithPath.Path,
-				Status: string(llmtypes.ActionPlanStatusPending),
+				Action:      actionPlanWithPath.Action,
+				Path:        actionPlanWithPath.Path,
+				Status:      string(llmtypes.ActionPlanStatusPending),
+				Description: actionPlanWithPath.Description,
+				NewPath:     actionPlanWithPath.NewPath,
 			}
 			currentPlan.ActionFiles = append(currentPlan.ActionFiles, actionFile)
 
@@ -0,0 +1,144 @@
+package listener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/realtime"
+	"go.uber.org/zap"
+)
+
+// processorStaleAfter is how long a processor can go without a pass over its queue or a
+// finished handler before it's considered dead. An idle processor passes over its queue every
+// queuePollInterval, and a busy one is allowed maxDuration on top for its handlers to finish
+const processorStaleAfter = time.Minute
+
+// healthCheckTimeout bounds the checks that call out to postgres and centrifugo
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is the result of a single check
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthReport is the result of all checks for a probe. OK is false if any check failed
+type HealthReport struct {
+	OK     bool          `json:"ok"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+func (r *HealthReport) add(name string, err error) {
+	check := HealthCheck{
+		Name: name,
+		OK:   err == nil,
+	}
+	if err != nil {
+		check.Error = err.Error()
+		r.OK = false
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// Liveness reports whether every processor loop is still running. It doesn't check
+// dependencies, a database outage should not cause the worker to be restarted
+func (l *Listener) Liveness() HealthReport {
+	report := HealthReport{OK: true}
+	l.addProcessorChecks(&report)
+	return report
+}
+
+// Readiness reports whether the worker can process work: the LISTEN connection is
+// subscribed, the pool and centrifugo are reachable, every processor loop is running
+// and the worker isn't draining
+func (l *Listener) Readiness(ctx context.Context) HealthReport {
+	report := HealthReport{OK: true}
+
+	var drainErr error
+	if l.draining.Load() {
+		drainErr = errors.New("worker is draining")
+	}
+	report.add("draining", drainErr)
+
+	var listenErr error
+	if !l.listening.Load() {
+		listenErr = errors.New("listen connection is not subscribed")
+	}
+	report.add("listen", listenErr)
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	report.add("postgres_pool", persistence.Ping(checkCtx))
+	report.add("centrifugo", realtime.Ping(checkCtx))
+
+	l.addProcessorChecks(&report)
+
+	return report
+}
+
+func (l *Listener) addProcessorChecks(report *HealthReport) {
+	for channel, processor := range l.processors {
+		var err error
+		if since := time.Since(time.Unix(0, processor.lastActive.Load())); since > processorStaleAfter+processor.maxDuration {
+			err = fmt.Errorf("processor has not processed its queue or finished a message for %s", since.Round(time.Second))
+		}
+		report.add("processor:"+channel, err)
+	}
+}
+
+// HealthHandler serves the liveness report on /healthz and the readiness report on /readyz
+func (l *Listener) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, l.Liveness())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, l.Readiness(r.Context()))
+	})
+	return mux
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.OK {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Error(fmt.Errorf("failed to write health report: %w", err))
+	}
+}
+
+// serveHealth serves the health endpoints at addr until ctx is done
+func (l *Listener) serveHealth(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           l.HealthHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(fmt.Errorf("failed to shutdown health server: %w", err))
+		}
+	}()
+
+	logger.Info("Starting health server", zap.String("addr", addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve health endpoints: %w", err)
+	}
+
+	return nil
+}
@@ -2,21 +2,31 @@ package listener
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/metrics"
 	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// NotificationHandler is a function type that handles notifications
-type NotificationHandler func(notification *pgconn.Notification) error
+// NotificationHandler is a function type that handles notifications. The context is
+// cancelled if cancellation of the job is requested while the handler is running
+type NotificationHandler func(ctx context.Context, notification *pgconn.Notification) error
 
 // LockKeyExtractor is a function type that extracts the lock key from the payload
 type LockKeyExtractor func(payload []byte) (string, error)
@@ -31,54 +41,94 @@ type Listener struct {
 	pgURI             string // Store the connection string for pooled connections
 	queueLocks        map[string]map[string]chan struct{}
 	mu                sync.Mutex
+	hostname          string // Recorded on each job attempt
+	draining          atomic.Bool
+	inFlight          map[string]time.Time // Claim time of messages with a running handler by ID, guarded by mu
+	inFlightWg        sync.WaitGroup
+	cancels           map[string]context.CancelCauseFunc // Cancel funcs for running messages, guarded by mu
+	listening         atomic.Bool                        // True while conn is subscribed to every channel
 }
 
 const (
 	WorkQueueTable = "work_queue"
+
+	// queuePollInterval is how often each queue is checked for work that didn't send a notification
+	queuePollInterval = 5 * time.Second
 )
 
 type queueProcessor struct {
 	channel          string
 	handler          NotificationHandler
 	workerPool       chan struct{}
-	processing       bool
+	processing       atomic.Bool // Set while processQueue is running for the channel
 	pollTicker       *time.Ticker
 	maxWorkers       int
-	maxDuration      time.Duration // Maximum time a task can be processing before considered failed
+	maxDuration      time.Duration // Time a task can be processing before other workers consider it failed and claim it again
 	lockKeyExtractor LockKeyExtractor
+	retryPolicy      RetryPolicy
+	handlerCtx       context.Context // Parent of the context passed to each handler
+	lastActive       atomic.Int64    // Unix nanos of the last queue pass or finished handler, for health checks
 }
 
 // NewListener creates a new Listener instance
 func NewListener() *Listener {
+	return newListener(param.Get().PGURI)
+}
+
+func newListener(pgURI string) *Listener {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Warn("failed to get hostname", zap.Error(err))
+		hostname = "unknown"
+	}
+
 	return &Listener{
 		handlers:          make(map[string]NotificationHandler),
 		reconnectInterval: 5 * time.Second, // Start with a shorter interval
 		maxReconnectRetry: 0,               // 0 means unlimited retries
 		processors:        make(map[string]*queueProcessor),
-		pgURI:             param.Get().PGURI,
+		pgURI:             pgURI,
 		queueLocks:        make(map[string]map[string]chan struct{}),
 		mu:                sync.Mutex{},
+		hostname:          hostname,
+		inFlight:          make(map[string]time.Time),
+		cancels:           make(map[string]context.CancelCauseFunc),
 	}
 }
 
-// AddHandler registers a handler for a specific type of work
-func (l *Listener) AddHandler(ctx context.Context, channel string, maxWorkers int, maxDuration time.Duration, handler NotificationHandler, lockKeyExtractor LockKeyExtractor) error {
+// AddHandler registers a handler for a specific type of work. Handlers are passed a context
+// derived from ctx. The retry policy controls how failed and timed out jobs are retried
+// before they are moved to the dead letter table
+func (l *Listener) AddHandler(ctx context.Context, channel string, maxWorkers int, maxDuration time.Duration, handler NotificationHandler, lockKeyExtractor LockKeyExtractor, retryPolicy RetryPolicy) error {
 	l.handlers[channel] = handler
 
 	// Initialize queue processor
-	l.processors[channel] = &queueProcessor{
+	processor := &queueProcessor{
 		channel:          channel,
 		handler:          handler,
 		workerPool:       make(chan struct{}, maxWorkers),
-		pollTicker:       time.NewTicker(5 * time.Second),
+		pollTicker:       time.NewTicker(queuePollInterval),
 		maxWorkers:       maxWorkers,
 		maxDuration:      maxDuration,
 		lockKeyExtractor: lockKeyExtractor,
+		retryPolicy:      retryPolicy,
+		handlerCtx:       ctx,
 	}
+	processor.lastActive.Store(time.Now().UnixNano())
+	l.processors[channel] = processor
 
 	return nil
 }
 
+// listenChannels returns every channel the listener connection subscribes to
+func (l *Listener) listenChannels() []string {
+	channels := make([]string, 0, len(l.handlers)+1)
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	return append(channels, persistence.WorkCancelChannel)
+}
+
 // Start begins listening for notifications
 func (l *Listener) Start(ctx context.Context) error {
 	logger.Info("Starting listener")
@@ -117,7 +167,7 @@ func (l *Listener) Start(ctx context.Context) error {
 		zap.Int("channelCount", len(l.handlers)))
 
 	channelCount := 0
-	for channel := range l.handlers {
+	for _, channel := range l.listenChannels() {
 		// Use a dedicated context for each LISTEN command with timeout
 		listenCtx, listenCancel := context.WithTimeout(ctx, 10*time.Second)
 
@@ -161,19 +211,26 @@ func (l *Listener) Start(ctx context.Context) error {
 		channelCount++
 
 		// Check for existing work in each queue and start processing
-		processor := l.processors[channel]
-		if !processor.processing {
-			processor.processing = true
+		processor, ok := l.processors[channel]
+		if ok && processor.processing.CompareAndSwap(false, true) {
 			go l.processQueue(ctx, processor)
 		}
 	}
 
 	logger.Info("Successfully subscribed to all channels",
 		zap.Int("channelCount", channelCount))
+	l.listening.Store(true)
 
 	// Start processing notifications in a separate goroutine
 	go l.processNotifications(ctx)
 
+	// Retries and scheduled work are in the future and won't send a notification when
+	// they become due, so poll each queue periodically as well
+	go l.pollQueues(ctx)
+
+	// Cancellation is normally delivered by notification, this catches any that were missed
+	go l.pollCancellations(ctx)
+
 	logger.Info("Listener started successfully")
 	return nil
 }
@@ -292,6 +349,7 @@ func (l *Listener) processNotifications(ctx context.Context) {
 				logger.Warn("Connection appears to be closed, forcing reconnection")
 
 				// Force connection to nil to ensure reconnection on next iteration
+				l.listening.Store(false)
 				if l.conn != nil {
 					// Don't try to close an already closed connection
 					l.conn = nil
@@ -350,6 +408,11 @@ func (l *Listener) processNotifications(ctx context.Context) {
 		consecutiveErrors = 0
 		lastSuccessTime = time.Now()
 
+		if notification.Channel == persistence.WorkCancelChannel {
+			l.cancelMessage(notification.Payload, errWorkCancelled)
+			continue
+		}
+
 		processor, exists := l.processors[notification.Channel]
 		if !exists {
 			logger.Warn("no processor registered for channel", zap.String("channel", notification.Channel))
@@ -357,18 +420,43 @@ func (l *Listener) processNotifications(ctx context.Context) {
 		}
 
 		// Trigger processing if not already processing
-		if !processor.processing {
-			processor.processing = true
+		if processor.processing.CompareAndSwap(false, true) {
 			go l.processQueue(ctx, processor)
 		}
 	}
 }
 
+// pollQueues starts processing any queue that isn't already being processed on each
+// tick of its poll ticker, so that delayed retries, scheduled work and timed out messages are picked up
+func (l *Listener) pollQueues(ctx context.Context) {
+	for _, processor := range l.processors {
+		go func(processor *queueProcessor) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-processor.pollTicker.C:
+					if processor.processing.CompareAndSwap(false, true) {
+						go l.processQueue(ctx, processor)
+					}
+				}
+			}
+		}(processor)
+	}
+}
+
 // processQueue handles message processing for a specific queue
 func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor) {
-	defer func() { processor.processing = false }()
+	defer processor.processing.Store(false)
 
 	for {
+		processor.lastActive.Store(time.Now().UnixNano())
+
+		if l.draining.Load() {
+			logger.Info("Listener is draining, not claiming new messages", zap.String("channel", processor.channel))
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			logger.Info("Received context done, existing process queue")
@@ -396,7 +484,7 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 			SELECT
 				COUNT(*) as total,
 				COUNT(CASE WHEN processing_started_at IS NOT NULL AND completed_at IS NULL THEN 1 END) as in_flight,
-				COUNT(CASE WHEN processing_started_at IS NULL AND completed_at IS NULL THEN 1 END) as available
+				COUNT(CASE WHEN processing_started_at IS NULL AND completed_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= NOW()) AND (run_after IS NULL OR run_after <= NOW()) THEN 1 END) as available
 			FROM %s
 			WHERE channel = $1
 			AND completed_at IS NULL`, WorkQueueTable), processor.channel).Scan(&total, &inFlight, &available)
@@ -409,6 +497,7 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 			dbCancel()
 			return
 		} else {
+			metrics.SetQueueStats(processor.channel, total, inFlight, available)
 			logger.Info("queue status",
 				zap.String("channel", processor.channel),
 				zap.Int("total", total),
@@ -425,7 +514,9 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 		}
 
 		// Query and lock unprocessed messages atomically
-		// This SQL's logic has been fixed to NOT increment attempt_count for new messages
+		// This SQL's logic has been fixed to NOT increment attempt_count for new messages.
+		// Messages with a handler running on this worker are never claimed again, however long
+		// they take. Only messages whose worker went away are reclaimed after maxDuration
 		rows, err := fetchConn.Query(dbCtx, fmt.Sprintf(`
 			WITH next_available_messages AS (
 				SELECT id, payload
@@ -436,22 +527,25 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 					processing_started_at IS NULL
 					OR processing_started_at < NOW() - $2::interval
 				)
+				AND NOT (id = ANY($3::text[]))
+				AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+				AND (run_after IS NULL OR run_after <= NOW())
 				ORDER BY created_at ASC
 				LIMIT %d
 				FOR UPDATE SKIP LOCKED
 			)
 			UPDATE %s AS wq
 			SET processing_started_at = NOW(),
-				-- Only increment for timed out messages, not for new ones
-				attempt_count = CASE 
+				-- Only increment for timed out messages, failed messages were already counted
+				attempt_count = CASE
 					WHEN wq.processing_started_at IS NOT NULL THEN COALESCE(wq.attempt_count, 0) + 1
-					ELSE 0
-				END 
+					ELSE COALESCE(wq.attempt_count, 0)
+				END
 			FROM next_available_messages
 			WHERE wq.id = next_available_messages.id
-			RETURNING wq.id, wq.payload, COALESCE(wq.attempt_count, 0)::int`,
+			RETURNING wq.id, wq.payload, COALESCE(wq.attempt_count, 0)::int, wq.cancel_requested_at IS NOT NULL, wq.processing_started_at`,
 			WorkQueueTable, processor.maxWorkers, WorkQueueTable),
-			processor.channel, processor.maxDuration.String())
+			processor.channel, processor.maxDuration.String(), l.inFlightIDs())
 
 		if err != nil {
 			logger.Error(fmt.Errorf("failed to query messages: %w", err))
@@ -462,25 +556,54 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 
 		// Count how many messages we're about to process
 		messages := make([]struct {
-			id           string
-			payload      []byte
-			attemptCount int
+			id              string
+			payload         []byte
+			attemptCount    int
+			cancelRequested bool
+			claimedAt       time.Time
 		}, 0)
 
 		for rows.Next() {
 			var msg struct {
-				id           string
-				payload      []byte
-				attemptCount int
+				id              string
+				payload         []byte
+				attemptCount    int
+				cancelRequested bool
+				claimedAt       time.Time
 			}
-			if err := rows.Scan(&msg.id, &msg.payload, &msg.attemptCount); err != nil {
+			if err := rows.Scan(&msg.id, &msg.payload, &msg.attemptCount, &msg.cancelRequested, &msg.claimedAt); err != nil {
 				logger.Error(fmt.Errorf("failed to scan message: %w", err))
 				continue
 			}
 			messages = append(messages, msg)
 		}
 		rows.Close()
-		
+
+		// Messages that timed out on their final attempt are not run again
+		runnable := messages[:0]
+		for _, msg := range messages {
+			if !processor.retryPolicy.isExhausted(msg.attemptCount) {
+				runnable = append(runnable, msg)
+				continue
+			}
+
+			// The timed out attempt ran on another worker. If that worker is still running it, it
+			// stops the handler once it sees the job was moved to the dead letter table
+			lastError := fmt.Sprintf("processing timed out after %s", processor.maxDuration)
+			if err := moveToDeadLetter(dbCtx, fetchConn, msg.id, msg.claimedAt, msg.attemptCount, lastError); err != nil {
+				logger.Error(fmt.Errorf("failed to move message %s to dead letter: %w", msg.id, err))
+				continue
+			}
+
+			logger.Warn("message moved to dead letter",
+				zap.String("id", msg.id),
+				zap.String("channel", processor.channel),
+				zap.Int("attempts", msg.attemptCount),
+				zap.String("lastError", lastError))
+		}
+		hasMessages := len(messages) > 0
+		messages = runnable
+
 		// Close the fetch connection as soon as we're done with it
 		fetchConn.Close(dbCtx)
 		dbCancel()
@@ -491,8 +614,25 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 				zap.String("channel", processor.channel))
 		}
 
+		// We claimed these but if we are shutting down, give back the ones that haven't
+		// started so that another worker can run them now
+		releaseUnstarted := func(from int) {
+			unstarted := make([]string, 0, len(messages)-from)
+			for _, m := range messages[from:] {
+				unstarted = append(unstarted, m.id)
+			}
+			if err := l.releaseMessages(unstarted); err != nil {
+				logger.Error(fmt.Errorf("failed to release unstarted messages: %w", err))
+			}
+		}
+
 		// Process the messages
-		for _, msg := range messages {
+		for i, msg := range messages {
+			if ctx.Err() != nil || l.draining.Load() {
+				releaseUnstarted(i)
+				return
+			}
+
 			if msg.attemptCount > 0 {
 				logger.Info("processing message retry",
 					zap.String("id", msg.id),
@@ -504,10 +644,42 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 			}
 
 			// Wait for worker slot
-			processor.workerPool <- struct{}{}
+			select {
+			case processor.workerPool <- struct{}{}:
+			case <-ctx.Done():
+				releaseUnstarted(i)
+				return
+			}
 
-			go func(messageID string, messagePayload []byte) {
-				defer func() { <-processor.workerPool }()
+			// Each message gets its own context so that it can be cancelled on its own
+			jobCtx, cancelJob := context.WithCancelCause(processor.handlerCtx)
+			if msg.cancelRequested {
+				// Cancellation was requested before the job started, let the handler
+				// run with a cancelled context so it can mark its work as cancelled
+				cancelJob(errWorkCancelled)
+			}
+
+			l.mu.Lock()
+			l.inFlight[msg.id] = msg.claimedAt
+			l.cancels[msg.id] = cancelJob
+			l.mu.Unlock()
+			l.inFlightWg.Add(1)
+
+			go func(messageID string, messagePayload []byte, attemptCount int, claimedAt time.Time) {
+				defer func() {
+					processor.lastActive.Store(time.Now().UnixNano())
+					<-processor.workerPool
+				}()
+				defer func() {
+					l.mu.Lock()
+					if l.inFlight[messageID].Equal(claimedAt) {
+						delete(l.inFlight, messageID)
+						delete(l.cancels, messageID)
+					}
+					l.mu.Unlock()
+					l.inFlightWg.Done()
+					cancelJob(nil)
+				}()
 
 				startTime := time.Now()
 
@@ -527,19 +699,73 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 					}
 				}
 
+				// The job can be cancelled, released or claimed again while it waits for the lock, in which
+				// case the handler isn't run and the job is finished the way the handler would have been
+				var waitErr error
 				if lockKey != "" {
 					lockChan := l.getQueueLock(processor.channel, lockKey)
-					<-lockChan
-					defer func() {
-						lockChan <- struct{}{}
-					}()
+					select {
+					case <-lockChan:
+						defer func() {
+							lockChan <- struct{}{}
+						}()
+					case <-jobCtx.Done():
+						waitErr = fmt.Errorf("failed to acquire lock %s: %w", lockKey, context.Cause(jobCtx))
+					}
 				}
 
+				// Continue the trace of the request that enqueued the message
+				traceCtx := tracing.ExtractPayload(jobCtx, messagePayload)
+				traceCtx, span := tracing.StartSpan(traceCtx, "process "+processor.channel,
+					trace.WithSpanKind(trace.SpanKindConsumer),
+					trace.WithAttributes(
+						attribute.String("work_queue.channel", processor.channel),
+						attribute.String("work_queue.id", messageID),
+						attribute.Int("work_queue.attempt", attemptCount+1),
+					))
+
 				// Process message
-				handlerErr := processor.handler(notification)
+				handlerStart := time.Now()
+				var panicStack string
+				handlerErr := waitErr
+				if waitErr == nil {
+					panicStack, handlerErr = runHandler(traceCtx, processor.handler, notification)
+				}
+				handlerEnd := time.Now()
+				tracing.EndSpan(span, handlerErr)
+				metrics.ObserveHandler(processor.channel, handlerEnd.Sub(handlerStart), handlerErr)
+
+				attempt := persistence.JobAttempt{
+					JobID:      messageID,
+					Channel:    processor.channel,
+					WorkerHost: l.hostname,
+					StartedAt:  handlerStart,
+					EndedAt:    handlerEnd,
+					Duration:   handlerEnd.Sub(handlerStart),
+					PanicStack: panicStack,
+				}
+				if handlerErr != nil {
+					attempt.Error = handlerErr.Error()
+				}
+				attemptCtx, attemptCancel := context.WithTimeout(context.Background(), 10*time.Second)
+				if err := persistence.RecordJobAttempt(attemptCtx, attempt); err != nil {
+					logger.Error(fmt.Errorf("failed to record attempt for message %s: %w", messageID, err))
+				}
+				attemptCancel()
+
+				// Drain gave the job back to the queue, or another worker claimed it again. Either way
+				// it belongs to another attempt now, so the result of this one must not touch it
+				if cause := context.Cause(jobCtx); errors.Is(cause, errJobReleased) || errors.Is(cause, errClaimLost) {
+					logger.Info("message was given up before it finished",
+						zap.String("id", messageID),
+						zap.String("channel", processor.channel),
+						zap.Error(cause))
+					return
+				}
 
-				// Create a new context with timeout for database operations
-				updateCtx, updateCancel := context.WithTimeout(ctx, 10*time.Second)
+				// Create a new context with timeout for database operations. This is not derived from
+				// ctx so that handlers that finish while the listener is draining are still recorded
+				updateCtx, updateCancel := context.WithTimeout(context.Background(), 10*time.Second)
 				
 				// Use a new pooled connection for updating the message status
 				updateConn, connErr := pgx.Connect(updateCtx, l.pgURI)
@@ -550,25 +776,57 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 				}
 				
 				var dbErr error
-				
-				if handlerErr != nil {
-					// If processing failed, mark it as available for retry
+
+				if handlerErr != nil && isWorkCancelled(jobCtx) {
+					// Cancelled jobs are finished, they are never retried
 					_, dbErr = updateConn.Exec(updateCtx, fmt.Sprintf(`
 						UPDATE %s
-						SET processing_started_at = NULL,
-							last_error = $2,
-							attempt_count = attempt_count + 1
-						WHERE id = $1`, WorkQueueTable),
-						messageID, handlerErr.Error())
+						SET completed_at = NOW(),
+							last_error = $2
+						WHERE id = $1
+						AND processing_started_at = $3`, WorkQueueTable), messageID, errWorkCancelled.Error(), claimedAt)
 					if dbErr != nil {
-						logger.Error(fmt.Errorf("failed to mark message %s as failed: %w", messageID, dbErr))
+						logger.Error(fmt.Errorf("failed to mark message %s as cancelled: %w", messageID, dbErr))
+					} else {
+						logger.Info("message cancelled",
+							zap.String("id", messageID),
+							zap.String("channel", processor.channel))
+					}
+				} else if handlerErr != nil {
+					failedAttempts := attemptCount + 1
+					if processor.retryPolicy.isExhausted(failedAttempts) {
+						// This was the final attempt, move it out of the queue
+						dbErr = moveToDeadLetter(updateCtx, updateConn, messageID, claimedAt, failedAttempts, handlerErr.Error())
+						if dbErr != nil {
+							logger.Error(fmt.Errorf("failed to move message %s to dead letter: %w", messageID, dbErr))
+						} else {
+							logger.Warn("message moved to dead letter",
+								zap.String("id", messageID),
+								zap.String("channel", processor.channel),
+								zap.Int("attempts", failedAttempts),
+								zap.String("lastError", handlerErr.Error()))
+						}
+					} else {
+						// If processing failed, mark it as available for retry after the backoff
+						delay := processor.retryPolicy.backoff(failedAttempts)
+						dbErr = scheduleRetry(updateCtx, updateConn, messageID, claimedAt, failedAttempts, delay, handlerErr.Error())
+						if dbErr != nil {
+							logger.Error(fmt.Errorf("failed to mark message %s as failed: %w", messageID, dbErr))
+						} else {
+							logger.Info("message scheduled for retry",
+								zap.String("id", messageID),
+								zap.String("channel", processor.channel),
+								zap.Int("attempts", failedAttempts),
+								zap.Duration("backoff", delay))
+						}
 					}
 				} else {
 					// Mark as completed
 					_, dbErr = updateConn.Exec(updateCtx, fmt.Sprintf(`
 						UPDATE %s
 						SET completed_at = NOW()
-						WHERE id = $1`, WorkQueueTable), messageID)
+						WHERE id = $1
+						AND processing_started_at = $2`, WorkQueueTable), messageID, claimedAt)
 					if dbErr != nil {
 						logger.Error(fmt.Errorf("failed to mark message %s as completed: %w", messageID, dbErr))
 					}
@@ -588,16 +846,42 @@ func (l *Listener) processQueue(ctx context.Context, processor *queueProcessor)
 					zap.String("channel", processor.channel),
 					zap.Duration("duration", time.Since(startTime)))
 
-			}(msg.id, msg.payload)
+			}(msg.id, msg.payload, msg.attemptCount, msg.claimedAt)
 		}
 
 		// If no messages found, stop processing until next notification
-		if len(messages) == 0 {
+		if !hasMessages {
 			return
 		}
 	}
 }
 
+// runHandler runs the handler, converting a panic into an error so that the job is
+// retried like any other failure. The stack is returned when the handler panicked
+func runHandler(ctx context.Context, handler NotificationHandler, notification *pgconn.Notification) (panicStack string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicStack = string(debug.Stack())
+			err = fmt.Errorf("panic in handler: %v", r)
+			logger.Error(err, zap.String("channel", notification.Channel), zap.String("stack", panicStack))
+		}
+	}()
+
+	return "", handler(ctx, notification)
+}
+
+// inFlightIDs returns the IDs of the messages with a handler running on this worker
+func (l *Listener) inFlightIDs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids := make([]string, 0, len(l.inFlight))
+	for id := range l.inFlight {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 // getQueueLock returns the lock channel for a queue and lockKey, creating it if it doesn't exist
 func (l *Listener) getQueueLock(queueName, lockKey string) chan struct{} {
 	l.mu.Lock()
@@ -627,6 +911,7 @@ func (l *Listener) reconnect(ctx context.Context) error {
 
 	// Log reconnection attempt
 	logger.Info("Database connection lost, attempting to reconnect...")
+	l.listening.Store(false)
 
 	for maxAttempts == 0 || attempt < maxAttempts {
 		attempt++
@@ -673,7 +958,7 @@ func (l *Listener) reconnect(ctx context.Context) error {
 				// Successfully resubscribe to all channels
 				resubscribeSuccess := true
 
-				for channel := range l.handlers {
+				for _, channel := range l.listenChannels() {
 					// Use a short timeout for each LISTEN command
 					listenCtx, listenCancel := context.WithTimeout(ctx, 5*time.Second)
 
@@ -726,11 +1011,11 @@ func (l *Listener) reconnect(ctx context.Context) error {
 				}
 
 				logger.Info("Successfully reconnected and resubscribed to all channels")
+				l.listening.Store(true)
 
 				// Immediately check for any pending work in queues
 				for _, processor := range l.processors {
-					if !processor.processing {
-						processor.processing = true
+					if processor.processing.CompareAndSwap(false, true) {
 						go l.processQueue(ctx, processor)
 					}
 				}
@@ -769,6 +1054,86 @@ func (l *Listener) reconnect(ctx context.Context) error {
 	return fmt.Errorf("failed to reconnect after %d attempts", attempt)
 }
 
+// Drain stops claiming new messages and waits up to timeout for in-flight handlers to
+// finish. Messages that are still being processed at the deadline are cancelled and released
+// so that another worker can pick them up immediately instead of waiting for maxDuration
+func (l *Listener) Drain(timeout time.Duration) error {
+	l.draining.Store(true)
+
+	l.mu.Lock()
+	inFlightCount := len(l.inFlight)
+	l.mu.Unlock()
+
+	logger.Info("Draining listener",
+		zap.Int("inFlight", inFlightCount),
+		zap.Duration("timeout", timeout))
+
+	done := make(chan struct{})
+	go func() {
+		l.inFlightWg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		logger.Info("All in-flight messages finished")
+		return nil
+	case <-timer.C:
+	}
+
+	l.mu.Lock()
+	unfinished := make([]string, 0, len(l.inFlight))
+	for id := range l.inFlight {
+		unfinished = append(unfinished, id)
+	}
+	l.mu.Unlock()
+
+	logger.Warn("Drain deadline reached, releasing unfinished messages",
+		zap.Strings("ids", unfinished))
+
+	// Stop the handlers first, so that none of them records its result on a job that another worker claimed
+	for _, id := range unfinished {
+		l.cancelMessage(id, errJobReleased)
+	}
+
+	if err := l.releaseMessages(unfinished); err != nil {
+		return fmt.Errorf("failed to release unfinished messages: %w", err)
+	}
+
+	return nil
+}
+
+// releaseMessages clears processing_started_at on messages that have not completed so
+// they are available to be claimed again right away
+func (l *Listener) releaseMessages(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, l.pgURI)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET processing_started_at = NULL
+		WHERE id = ANY($1)
+		AND completed_at IS NULL`, WorkQueueTable), ids)
+	if err != nil {
+		return fmt.Errorf("failed to release messages: %w", err)
+	}
+
+	return nil
+}
+
 // Stop gracefully shuts down the listener
 func (l *Listener) Stop(ctx context.Context) error {
 	if l.conn != nil {
@@ -0,0 +1,94 @@
+package listener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/testhelpers"
+)
+
+func TestHandlerPastMaxDurationIsNotCancelled(t *testing.T) {
+	pgURI := testhelpers.StartPostgres(t, testhelpers.WorkQueueSchema)
+	if err := persistence.InitPostgres(persistence.PostgresOpts{URI: pgURI}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := persistence.EnqueueWork(ctx, "test_channel", map[string]string{"id": "slow"}); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	causes := make(chan error, 1)
+	handler := func(ctx context.Context, notification *pgconn.Notification) error {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		causes <- context.Cause(ctx)
+		return nil
+	}
+
+	maxDuration := time.Second
+	l := newListener(pgURI)
+	if err := l.AddHandler(ctx, "test_channel", 2, maxDuration, handler, nil, DefaultRetryPolicy); err != nil {
+		t.Fatal(err)
+	}
+	processor := l.processors["test_channel"]
+	defer processor.pollTicker.Stop()
+
+	l.processQueue(ctx, processor)
+	<-started
+
+	// run past maxDuration, then poll the queue and check for lost claims the way the listener does
+	time.Sleep(2 * maxDuration)
+	l.processQueue(ctx, processor)
+
+	l.mu.Lock()
+	running := map[string]time.Time{}
+	for id, claimedAt := range l.inFlight {
+		running[id] = claimedAt
+	}
+	l.mu.Unlock()
+	if len(running) != 1 {
+		t.Fatalf("got %d messages in flight, want 1", len(running))
+	}
+
+	cancelled, lost, err := l.listCancelledOrLost(ctx, running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled) != 0 || len(lost) != 0 {
+		t.Fatalf("got cancelled %v and lost %v, want none", cancelled, lost)
+	}
+
+	close(release)
+	if cause := <-causes; cause != nil {
+		t.Fatalf("handler was cancelled with %v", cause)
+	}
+	l.inFlightWg.Wait()
+
+	conn, err := pgx.Connect(ctx, pgURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+
+	var completed bool
+	var attemptCount int
+	if err := conn.QueryRow(ctx, `SELECT completed_at IS NOT NULL, COALESCE(attempt_count, 0) FROM work_queue WHERE channel = 'test_channel'`).Scan(&completed, &attemptCount); err != nil {
+		t.Fatal(err)
+	}
+	if !completed {
+		t.Error("message was not completed")
+	}
+	if attemptCount != 0 {
+		t.Errorf("attempt count = %d, want 0", attemptCount)
+	}
+}
@@ -20,7 +20,7 @@ type conversionNextFilePayload struct {
 	ConversionID string `json:"conversionId"`
 }
 
-func handleConversionNextFileNotification(ctx context.Context, payload string) error {
+func handleConversionNextFileNotification(ctx context.Context, payload string) (err error) {
 	logger.Info("Received conversion file notification",
 		zap.String("payload", payload))
 
@@ -29,10 +29,19 @@ func handleConversionNextFileNotification(ctx context.Context, payload string) e
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
+	defer func() {
+		if err != nil && isWorkCancelled(ctx) {
+			if cancelErr := markConversionCancelled(context.WithoutCancel(ctx), p.ConversionID); cancelErr != nil {
+				logger.Error(fmt.Errorf("failed to mark conversion as cancelled: %w", cancelErr))
+			}
+		}
+	}()
+
 	w, err := workspace.GetWorkspace(ctx, p.WorkspaceID)
 	if err != nil {
 		return fmt.Errorf("failed to get workspace: %w", err)
 	}
+	ctx = llm.WithUsageScope(ctx, llm.UsageScope{WorkspaceID: w.ID})
 
 	c, err := workspace.GetConversion(ctx, p.ConversionID)
 	if err != nil {
@@ -61,6 +70,14 @@ func handleConversionNextFileNotification(ctx context.Context, payload string) e
 		UserIDs: userIDs,
 	}
 
+	quotaErr, err := checkLLMQuota(ctx, w.ID, lastChatMessageID(c.ChatMessageIDs))
+	if err != nil {
+		return err
+	}
+	if quotaErr != nil {
+		return markConversionQuotaExceeded(ctx, p.ConversionID, quotaErr)
+	}
+
 	if err := workspace.SetConversionFileStatus(ctx, cf.ID, workspacetypes.ConversionFileStatusConverting); err != nil {
 		return fmt.Errorf("failed to set conversion file status: %w", err)
 	}
@@ -35,12 +35,21 @@ func handleNewPlanNotification(ctx context.Context, payload string) error {
 	if err != nil {
 		return fmt.Errorf("error getting plan: %w", err)
 	}
+	ctx = llm.WithUsageScope(ctx, llm.PlanUsageScope(plan))
 
 	w, err := workspace.GetWorkspace(ctx, plan.WorkspaceID)
 	if err != nil {
 		return fmt.Errorf("error getting workspace: %w", err)
 	}
 
+	quotaErr, err := checkLLMQuota(ctx, w.ID, lastChatMessageID(plan.ChatMessageIDs))
+	if err != nil {
+		return err
+	}
+	if quotaErr != nil {
+		return markPlanQuotaExceeded(ctx, plan.ID, quotaErr)
+	}
+
 	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, w.ID)
 	if err != nil {
 		return fmt.Errorf("error getting user IDs for workspace: %w", err)
@@ -205,11 +214,17 @@ func createUpdatePlan(ctx context.Context, streamCh chan string, doneCh chan err
 		}
 	}
 
+	renderFindings, err := workspace.ListRenderedFileFindings(ctx, w.ID, w.CurrentRevision)
+	if err != nil {
+		return fmt.Errorf("failed to list rendered file findings: %w", err)
+	}
+
 	opts := llm.CreatePlanOpts{
-		ChatMessages:  chatMessages,
-		Chart:         &w.Charts[0],
-		RelevantFiles: finalRelevantFiles,
-		IsUpdate:      true,
+		ChatMessages:   chatMessages,
+		Chart:          &w.Charts[0],
+		RelevantFiles:  finalRelevantFiles,
+		IsUpdate:       true,
+		RenderFindings: renderFindings,
 	}
 
 	if err := llm.CreatePlan(ctx, streamCh, doneCh, opts); err != nil {
@@ -35,6 +35,8 @@ func handleNewIntentNotification(ctx context.Context, payload string) error {
 	}
 
 	logger.Debug("chat message", zap.Any("chatMessage", chatMessage))
+	ctx = llm.WithUsageScope(ctx, llm.UsageScope{WorkspaceID: chatMessage.WorkspaceID, ChatMessageID: chatMessage.ID})
+
 	w, err := workspace.GetWorkspace(ctx, chatMessage.WorkspaceID)
 	if err != nil {
 		return fmt.Errorf("failed to get workspace: %w", err)
@@ -0,0 +1,161 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/replicatedhq/chartsmith/pkg/llm"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/realtime"
+	realtimetypes "github.com/replicatedhq/chartsmith/pkg/realtime/types"
+	"github.com/replicatedhq/chartsmith/pkg/workspace"
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"go.uber.org/zap"
+)
+
+// checkLLMQuota returns the quota that the workspace or the user that sent chatMessageID is over, or nil if
+// there isn't one. When there is, the limit is explained in the response to chatMessageID so that the user
+// isn't left waiting
+func checkLLMQuota(ctx context.Context, workspaceID string, chatMessageID string) (*llm.QuotaExceededError, error) {
+	scope := llm.UsageScope{
+		WorkspaceID:   workspaceID,
+		ChatMessageID: chatMessageID,
+	}
+
+	var chatMessage *workspacetypes.Chat
+	if chatMessageID != "" {
+		var err error
+		chatMessage, err = workspace.GetChatMessage(ctx, chatMessageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat message: %w", err)
+		}
+		scope.UserID = chatMessage.SentByUserID
+	}
+
+	err := llm.CheckQuota(ctx, scope)
+	if err == nil {
+		return nil, nil
+	}
+
+	quotaErr, ok := llm.IsQuotaExceeded(err)
+	if !ok {
+		return nil, fmt.Errorf("failed to check llm quota: %w", err)
+	}
+
+	logger.Info("LLM quota exceeded",
+		zap.String("workspaceID", workspaceID),
+		zap.String("userID", scope.UserID),
+		zap.String("chatMessageID", chatMessageID),
+		zap.String("quota", quotaErr.Error()))
+
+	if chatMessage == nil {
+		return quotaErr, nil
+	}
+
+	if err := workspace.AppendChatMessageResponse(ctx, chatMessageID, quotaErr.Message()); err != nil {
+		return nil, fmt.Errorf("failed to write quota response: %w", err)
+	}
+
+	chatMessage, err = workspace.GetChatMessage(ctx, chatMessageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat message: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	e := realtimetypes.ChatMessageUpdatedEvent{
+		WorkspaceID: workspaceID,
+		ChatMessage: chatMessage,
+	}
+	if err := realtime.SendEvent(ctx, realtimetypes.Recipient{UserIDs: userIDs}, e); err != nil {
+		return nil, fmt.Errorf("failed to send chat message update: %w", err)
+	}
+
+	return quotaErr, nil
+}
+
+// markPlanQuotaExceeded sets the status of a plan that wasn't created because of quotaErr and notifies
+// the workspace users
+func markPlanQuotaExceeded(ctx context.Context, planID string, quotaErr *llm.QuotaExceededError) error {
+	if err := workspace.UpdatePlanStatus(ctx, planID, workspacetypes.PlanStatusQuotaExceeded); err != nil {
+		return fmt.Errorf("failed to update plan status: %w", err)
+	}
+
+	plan, err := workspace.GetPlan(ctx, nil, planID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, plan.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	realtimeRecipient := realtimetypes.Recipient{
+		UserIDs: userIDs,
+	}
+
+	if err := realtime.SendEvent(ctx, realtimeRecipient, realtimetypes.PlanUpdatedEvent{
+		WorkspaceID: plan.WorkspaceID,
+		Plan:        plan,
+	}); err != nil {
+		return fmt.Errorf("failed to send plan update: %w", err)
+	}
+
+	return sendQuotaExceededEvent(ctx, realtimeRecipient, plan.WorkspaceID, "plan", plan.ID, quotaErr)
+}
+
+// markConversionQuotaExceeded sets the status of a conversion that was stopped because of quotaErr and
+// notifies the workspace users
+func markConversionQuotaExceeded(ctx context.Context, conversionID string, quotaErr *llm.QuotaExceededError) error {
+	if err := workspace.SetConversionStatus(ctx, conversionID, workspacetypes.ConversionStatusQuotaExceeded); err != nil {
+		return fmt.Errorf("failed to set conversion status: %w", err)
+	}
+
+	c, err := workspace.GetConversion(ctx, conversionID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversion: %w", err)
+	}
+
+	userIDs, err := workspace.ListUserIDsForWorkspace(ctx, c.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list user IDs for workspace: %w", err)
+	}
+
+	realtimeRecipient := realtimetypes.Recipient{
+		UserIDs: userIDs,
+	}
+
+	if err := realtime.SendEvent(ctx, realtimeRecipient, realtimetypes.ConversionStatusEvent{
+		WorkspaceID: c.WorkspaceID,
+		Conversion:  *c,
+	}); err != nil {
+		return fmt.Errorf("failed to send conversion status event: %w", err)
+	}
+
+	return sendQuotaExceededEvent(ctx, realtimeRecipient, c.WorkspaceID, "conversion", c.ID, quotaErr)
+}
+
+func sendQuotaExceededEvent(ctx context.Context, realtimeRecipient realtimetypes.Recipient, workspaceID string, kind string, id string, quotaErr *llm.QuotaExceededError) error {
+	e := realtimetypes.QuotaExceededEvent{
+		WorkspaceID: workspaceID,
+		Kind:        kind,
+		ID:          id,
+		Message:     quotaErr.Message(),
+	}
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send quota exceeded event: %w", err)
+	}
+	return nil
+}
+
+// lastChatMessageID returns the most recent of a plan or conversion's chat messages
+func lastChatMessageID(chatMessageIDs []string) string {
+	if len(chatMessageIDs) == 0 {
+		return ""
+	}
+	return chatMessageIDs[len(chatMessageIDs)-1]
+}
@@ -5,16 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	helmutils "github.com/replicatedhq/chartsmith/helm-utils"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/metrics"
 	"github.com/replicatedhq/chartsmith/pkg/realtime"
 	realtimetypes "github.com/replicatedhq/chartsmith/pkg/realtime/types"
+	"github.com/replicatedhq/chartsmith/pkg/tracing"
+	"github.com/replicatedhq/chartsmith/pkg/validation"
 	"github.com/replicatedhq/chartsmith/pkg/workspace"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -24,11 +30,13 @@ type renderWorkspacePayload struct {
 	RevisionNumber    int    `json:"revisionNumber"`
 	ChatMessageID     string `json:"chatMessageId"`
 	UsePendingContent *bool  `json:"usePendingContent"`
+	// ValuesProfiles are the values profiles to render with, for requests that create the render job
+	ValuesProfiles []string `json:"valuesProfiles"`
 }
 
 // Note: ensureActiveConnection is now defined in heartbeat.go
 
-func handleRenderWorkspaceNotification(ctx context.Context, payload string) error {
+func handleRenderWorkspaceNotification(ctx context.Context, payload string) (err error) {
 	startTime := time.Now()
 
 	// Add panic recovery
@@ -66,10 +74,24 @@ func handleRenderWorkspaceNotification(ctx context.Context, payload string) erro
 		return fmt.Errorf("failed to unmarshal render workspace notification: %w", err)
 	}
 
+	defer func() {
+		if err != nil && p.ID != "" && isWorkCancelled(ctx) {
+			if cancelErr := markRenderCancelled(context.WithoutCancel(ctx), p.ID); cancelErr != nil {
+				logger.Error(fmt.Errorf("failed to mark render as cancelled: %w", cancelErr))
+			}
+		}
+	}()
+
 	// Handle request from TypeScript side with workspaceId and revisionNumber
 	if p.ID == "" && p.WorkspaceID != "" && p.RevisionNumber > 0 {
 		// Create a new render job for this workspace/revision
 		chatMessageID := p.ChatMessageID // Use the provided chat message ID
+		if len(p.ValuesProfiles) > 0 {
+			if err := workspace.EnqueueRenderWorkspaceForRevisionWithValuesProfiles(ctx, p.WorkspaceID, p.RevisionNumber, chatMessageID, p.ValuesProfiles); err != nil {
+				return fmt.Errorf("failed to enqueue values profiles render job from TS request: %w", err)
+			}
+			return nil
+		}
 		if err := workspace.EnqueueRenderWorkspaceForRevision(ctx, p.WorkspaceID, p.RevisionNumber, chatMessageID); err != nil {
 			return fmt.Errorf("failed to enqueue render job from TS request: %w", err)
 		}
@@ -243,6 +265,35 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 	dbCtx, dbCancel := context.WithTimeout(ctx, 30*time.Second)
 	defer dbCancel()
 
+	valuesYAML := ""
+	if renderedChart.ValuesProfile != "" {
+		valuesProfile, err := workspace.GetValuesProfile(dbCtx, w.ID, renderedChart.ValuesProfile)
+		if err != nil {
+			err = fmt.Errorf("failed to get values profile %s: %w", renderedChart.ValuesProfile, err)
+			logger.Error(err, zap.String("workspaceID", w.ID))
+
+			// Update the rendered chart to mark it as failed
+			workspace.FinishRenderedChart(context.Background(), renderedChart.ID,
+				"", "", "", "", "",
+				fmt.Sprintf("Database operation failed: %v", err), false)
+
+			return err
+		}
+
+		// the profile was deleted after the render was requested, which doesn't stop the other renders
+		if valuesProfile == nil {
+			logger.Warn("Values profile not found, skipping render",
+				zap.String("workspaceID", w.ID),
+				zap.String("valuesProfile", renderedChart.ValuesProfile))
+
+			return workspace.FinishRenderedChart(context.Background(), renderedChart.ID,
+				"", "", "", "", "",
+				fmt.Sprintf("Values profile not found: %s", renderedChart.ValuesProfile), false)
+		}
+
+		valuesYAML = valuesProfile.ValuesYAML
+	}
+
 	userIDs, err := workspace.ListUserIDsForWorkspace(dbCtx, w.ID)
 	if err != nil {
 		// Check for timeout or cancellation
@@ -267,10 +318,17 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 		UserIDs: userIDs,
 	}
 
+	if !helmutils.UseExecRenderer() {
+		return renderChartNative(ctx, renderedChart, renderedWorkspace, w, chart, valuesYAML, realtimeRecipient)
+	}
+
 	renderChannels := helmutils.RenderChannels{
 		DepUpdateCmd:       make(chan string, 1),
 		DepUpdateStderr:    make(chan string, 1),
 		DepUpdateStdout:    make(chan string, 1),
+		HelmLintCmd:        make(chan string, 1),
+		HelmLintStderr:     make(chan string, 1),
+		HelmLintStdout:     make(chan string, 1),
 		HelmTemplateCmd:    make(chan string, 1),
 		HelmTemplateStderr: make(chan string, 1),
 		HelmTemplateStdout: make(chan string, 1),
@@ -282,7 +340,12 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 	go func(usePendingContent bool) {
 		files := chart.Files
 
-		err := helmutils.RenderChartExec(files, "", renderChannels)
+		renderCtx, span := tracing.StartSpan(ctx, "helm render",
+			trace.WithAttributes(attribute.Int("helm.chart_files", len(files)), attribute.String("helm.values_profile", renderedChart.ValuesProfile)))
+		renderStart := time.Now()
+		err := helmutils.RenderChartExec(renderCtx, files, valuesYAML, renderChannels)
+		metrics.ObserveHelmRender(time.Since(renderStart), err)
+		tracing.EndSpan(span, err)
 		if err != nil {
 			done <- err
 			return
@@ -316,6 +379,13 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 
 	renderedFiles := []workspacetypes.RenderedFile{}
 
+	// helm names the templates in its output after the name in Chart.yaml, which can differ from the name of
+	// the chart in the workspace. Without a Chart.yaml the render fails anyway
+	chartName, err := helmutils.ChartName(chart.Files)
+	if err != nil {
+		chartName = chart.Name
+	}
+
 	for {
 		select {
 		case err := <-renderChannels.Done:
@@ -325,6 +395,16 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				logger.Errorf("Render error: %v", err)
 			}
 
+			if err := finishHelmLint(ctx, renderedChart, chartName); err != nil {
+				return err
+			}
+
+			findings := map[string][]workspacetypes.RenderFinding{}
+			if isSuccess {
+				findings = validateRenderedTemplates(renderedChart, w, helmutils.SplitManifest(chartName, renderedChart.HelmTemplateStdout))
+				isSuccess = validation.CountErrors(findings) == 0
+			}
+
 			if err := workspace.FinishRenderedChart(ctx, renderedChart.ID, renderedChart.DepupdateCommand, renderedChart.DepupdateStdout, renderedChart.DepupdateStderr, renderedChart.HelmTemplateCommand, renderedChart.HelmTemplateStdout, renderedChart.HelmTemplateStderr, isSuccess); err != nil {
 				return fmt.Errorf("failed to finish rendered chart: %w", err)
 			}
@@ -337,6 +417,10 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				DepUpdateCommand:    renderedChart.DepupdateCommand,
 				DepUpdateStdout:     renderedChart.DepupdateStdout,
 				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
+				HelmLintFindings:    renderedChart.HelmLintFindings,
 				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
 				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
 				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
@@ -351,27 +435,15 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				return fmt.Errorf("failed to send render stream event: %w", err)
 			}
 
-			updatedRenderedFiles, err := parseRenderedFiles(ctx, renderedChart.HelmTemplateStdout, chart.Name, &renderedFiles, workspaceFiles)
-			if err != nil {
+			if _, err := parseRenderedFiles(ctx, renderedChart.HelmTemplateStdout, chart.Name, &renderedFiles, workspaceFiles); err != nil {
 				return fmt.Errorf("failed to parse rendered files: %w", err)
 			}
 
-			for _, file := range updatedRenderedFiles {
-				if file.ID != "" {
-					e := realtimetypes.RenderFileEvent{
-						WorkspaceID:   w.ID,
-						RenderID:      renderedWorkspace.ID,
-						RenderChartID: renderedChart.ID,
-						RenderedFile:  file,
-					}
-
-					if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
-						return fmt.Errorf("failed to send render updated event: %w", err)
-					}
-				}
-
-				if err := workspace.SetRenderedFileContents(ctx, w.ID, renderedWorkspace.RevisionNumber, file.FilePath, file.RenderedContent); err != nil {
-					return fmt.Errorf("failed to set rendered file contents: %w", err)
+			// every file is sent again with its findings, not only the files that changed in the last output
+			for _, file := range renderedFiles {
+				file.Findings = findings[file.FilePath]
+				if err := saveRenderedFile(ctx, w, renderedWorkspace, renderedChart, realtimeRecipient, file); err != nil {
+					return err
 				}
 			}
 
@@ -387,6 +459,9 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				DepUpdateCommand:    renderedChart.DepupdateCommand,
 				DepUpdateStdout:     renderedChart.DepupdateStdout,
 				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
 				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
 				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
 				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
@@ -410,6 +485,9 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				DepUpdateCommand:    renderedChart.DepupdateCommand,
 				DepUpdateStdout:     renderedChart.DepupdateStdout,
 				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
 				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
 				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
 				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
@@ -433,6 +511,9 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				DepUpdateCommand:    renderedChart.DepupdateCommand,
 				DepUpdateStdout:     renderedChart.DepupdateStdout,
 				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
 				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
 				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
 				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
@@ -446,6 +527,84 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				return fmt.Errorf("failed to set rendered chart depUpdateStderr: %w", err)
 			}
 
+		case helmLintCommand := <-renderChannels.HelmLintCmd:
+			renderedChart.HelmLintCommand += helmLintCommand
+
+			e := realtimetypes.RenderStreamEvent{
+				WorkspaceID:         w.ID,
+				RenderID:            renderedWorkspace.ID,
+				RenderChartID:       renderedChart.ID,
+				DepUpdateCommand:    renderedChart.DepupdateCommand,
+				DepUpdateStdout:     renderedChart.DepupdateStdout,
+				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
+				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
+				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
+				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
+			}
+
+			if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+				return fmt.Errorf("failed to send render stream event: %w", err)
+			}
+
+			if err := workspace.SetRenderedChartHelmLintCommand(ctx, renderedChart.ID, renderedChart.HelmLintCommand); err != nil {
+				return fmt.Errorf("failed to set rendered chart helmLintCommand: %w", err)
+			}
+
+		case helmLintStdout := <-renderChannels.HelmLintStdout:
+			renderedChart.HelmLintStdout += helmLintStdout
+
+			e := realtimetypes.RenderStreamEvent{
+				WorkspaceID:         w.ID,
+				RenderID:            renderedWorkspace.ID,
+				RenderChartID:       renderedChart.ID,
+				DepUpdateCommand:    renderedChart.DepupdateCommand,
+				DepUpdateStdout:     renderedChart.DepupdateStdout,
+				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
+				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
+				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
+				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
+			}
+
+			if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+				return fmt.Errorf("failed to send render stream event: %w", err)
+			}
+
+			if err := workspace.SetRenderedChartHelmLintStdout(ctx, renderedChart.ID, renderedChart.HelmLintStdout); err != nil {
+				return fmt.Errorf("failed to set rendered chart helmLintStdout: %w", err)
+			}
+
+		case helmLintStderr := <-renderChannels.HelmLintStderr:
+			renderedChart.HelmLintStderr += helmLintStderr
+
+			e := realtimetypes.RenderStreamEvent{
+				WorkspaceID:         w.ID,
+				RenderID:            renderedWorkspace.ID,
+				RenderChartID:       renderedChart.ID,
+				DepUpdateCommand:    renderedChart.DepupdateCommand,
+				DepUpdateStdout:     renderedChart.DepupdateStdout,
+				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
+				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
+				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
+				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
+			}
+
+			if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+				return fmt.Errorf("failed to send render stream event: %w", err)
+			}
+
+			if err := workspace.SetRenderedChartHelmLintStderr(ctx, renderedChart.ID, renderedChart.HelmLintStderr); err != nil {
+				return fmt.Errorf("failed to set rendered chart helmLintStderr: %w", err)
+			}
+
 		case helmTemplateCommand := <-renderChannels.HelmTemplateCmd:
 			renderedChart.HelmTemplateCommand += helmTemplateCommand
 
@@ -456,6 +615,9 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 				DepUpdateCommand:    renderedChart.DepupdateCommand,
 				DepUpdateStdout:     renderedChart.DepupdateStdout,
 				DepUpdateStderr:     renderedChart.DepupdateStderr,
+				HelmLintCommand:     renderedChart.HelmLintCommand,
+				HelmLintStdout:      renderedChart.HelmLintStdout,
+				HelmLintStderr:      renderedChart.HelmLintStderr,
 				HelmTemplateCommand: renderedChart.HelmTemplateCommand,
 				HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
 				HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
@@ -497,7 +659,7 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 					}
 				}
 
-				if err := workspace.SetRenderedFileContents(ctx, w.ID, renderedWorkspace.RevisionNumber, file.FilePath, file.RenderedContent); err != nil {
+				if err := workspace.SetRenderedFileContents(ctx, w.ID, renderedWorkspace.RevisionNumber, renderedChart.ValuesProfile, file.FilePath, file.RenderedContent); err != nil {
 					return fmt.Errorf("failed to set rendered file contents: %w", err)
 				}
 			}
@@ -515,6 +677,191 @@ func renderChart(ctx context.Context, renderedChart *workspacetypes.RenderedChar
 	}
 }
 
+// renderChartNative renders the chart in process with the helm SDK. The result is saved and sent to the
+// client like a render with the helm binary, but all at once when the render completes
+func renderChartNative(ctx context.Context, renderedChart *workspacetypes.RenderedChart, renderedWorkspace *workspacetypes.Rendered, w *workspacetypes.Workspace, chart *workspacetypes.Chart, valuesYAML string, realtimeRecipient realtimetypes.Recipient) error {
+	// no helm command runs, so the steps are labelled as what the helm SDK does in process
+	renderedChart.DepupdateCommand = nativeRenderStep("load chart and dependencies", "")
+	renderedChart.HelmLintCommand = nativeRenderStep("lint", renderedChart.ValuesProfile)
+	renderedChart.HelmTemplateCommand = nativeRenderStep("template", renderedChart.ValuesProfile)
+
+	files := chart.Files
+
+	lintStdout, err := helmutils.LintChartNative(files, valuesYAML)
+	if err != nil {
+		logger.Warn("Failed to lint chart", zap.String("chartID", chart.ID), zap.Error(err))
+		renderedChart.HelmLintStderr = err.Error() + "\n"
+	}
+	renderedChart.HelmLintStdout = lintStdout
+
+	// lint messages name templates after the name in Chart.yaml, which can differ from the name of the chart
+	// in the workspace
+	chartName, err := helmutils.ChartName(files)
+	if err != nil {
+		chartName = chart.Name
+	}
+	if err := finishHelmLint(ctx, renderedChart, chartName); err != nil {
+		return err
+	}
+
+	var progress strings.Builder
+	renderCtx, span := tracing.StartSpan(ctx, "helm render",
+		trace.WithAttributes(attribute.Int("helm.chart_files", len(files)), attribute.String("helm.values_profile", renderedChart.ValuesProfile), attribute.String("helm.renderer", "native")))
+	renderStart := time.Now()
+	templates, renderErr := helmutils.RenderChartNative(renderCtx, files, valuesYAML, &progress)
+	metrics.ObserveHelmRender(time.Since(renderStart), renderErr)
+	tracing.EndSpan(span, renderErr)
+
+	renderedChart.DepupdateStdout = progress.String()
+
+	isSuccess := renderErr == nil
+	findings := map[string][]workspacetypes.RenderFinding{}
+	if renderErr != nil {
+		logger.Errorf("Render error: %v", renderErr)
+		renderedChart.HelmTemplateStderr = renderErr.Error() + "\n"
+	} else {
+		renderedChart.HelmTemplateStdout = helmutils.FormatRenderedTemplates(templates)
+		findings = validateRenderedTemplates(renderedChart, w, templates)
+		isSuccess = validation.CountErrors(findings) == 0
+	}
+
+	if err := workspace.FinishRenderedChart(ctx, renderedChart.ID, renderedChart.DepupdateCommand, renderedChart.DepupdateStdout, renderedChart.DepupdateStderr, renderedChart.HelmTemplateCommand, renderedChart.HelmTemplateStdout, renderedChart.HelmTemplateStderr, isSuccess); err != nil {
+		return fmt.Errorf("failed to finish rendered chart: %w", err)
+	}
+
+	now := time.Now()
+	e := realtimetypes.RenderStreamEvent{
+		WorkspaceID:         w.ID,
+		RenderID:            renderedWorkspace.ID,
+		RenderChartID:       renderedChart.ID,
+		DepUpdateCommand:    renderedChart.DepupdateCommand,
+		DepUpdateStdout:     renderedChart.DepupdateStdout,
+		DepUpdateStderr:     renderedChart.DepupdateStderr,
+		HelmLintCommand:     renderedChart.HelmLintCommand,
+		HelmLintStdout:      renderedChart.HelmLintStdout,
+		HelmLintStderr:      renderedChart.HelmLintStderr,
+		HelmLintFindings:    renderedChart.HelmLintFindings,
+		HelmTemplateCommand: renderedChart.HelmTemplateCommand,
+		HelmTemplateStdout:  renderedChart.HelmTemplateStdout,
+		HelmTemplateStderr:  renderedChart.HelmTemplateStderr,
+		CompletedAt:         &now,
+	}
+
+	if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+		return fmt.Errorf("failed to send render stream event: %w", err)
+	}
+
+	if renderErr != nil {
+		return nil
+	}
+
+	filesCtx, filesCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer filesCancel()
+
+	workspaceFiles, err := workspace.ListFiles(filesCtx, w.ID, renderedWorkspace.RevisionNumber, chart.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	fileIDs := map[string]string{}
+	for _, workspaceFile := range workspaceFiles {
+		fileIDs[workspaceFile.FilePath] = workspaceFile.ID
+	}
+
+	paths := make([]string, 0, len(templates))
+	for path := range templates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		file := workspacetypes.RenderedFile{
+			ID:              fileIDs[path],
+			FilePath:        path,
+			RenderedContent: templates[path],
+			Findings:        findings[path],
+		}
+
+		if err := saveRenderedFile(ctx, w, renderedWorkspace, renderedChart, realtimeRecipient, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nativeRenderStep returns the label of a step of an in process render, which is shown where the command of a
+// render with the helm binary is
+func nativeRenderStep(step string, valuesProfile string) string {
+	label := fmt.Sprintf("helm SDK %s (in process)", step)
+	if valuesProfile != "" {
+		label += fmt.Sprintf(" with values profile %s", valuesProfile)
+	}
+	return label
+}
+
+// finishHelmLint parses the findings in the output of helm lint and saves them with the output. Lint findings
+// are shown with the render but don't fail it
+func finishHelmLint(ctx context.Context, renderedChart *workspacetypes.RenderedChart, chartName string) error {
+	renderedChart.HelmLintFindings = helmutils.ParseLintOutput(chartName, renderedChart.HelmLintStdout)
+
+	if err := workspace.FinishRenderedChartHelmLint(ctx, renderedChart.ID, renderedChart.HelmLintCommand, renderedChart.HelmLintStdout, renderedChart.HelmLintStderr, renderedChart.HelmLintFindings); err != nil {
+		return fmt.Errorf("failed to finish helm lint: %w", err)
+	}
+
+	return nil
+}
+
+// validateRenderedTemplates checks the rendered templates against the schemas of the Kubernetes version of the
+// workspace, and for the policy rules the workspace hasn't disabled. Schema errors are added to the helm template
+// stderr of the rendered chart. Validation that can't run, e.g. for a Kubernetes version without schemas, is
+// reported in the stderr too but doesn't fail the render
+func validateRenderedTemplates(renderedChart *workspacetypes.RenderedChart, w *workspacetypes.Workspace, templates map[string]string) map[string][]workspacetypes.RenderFinding {
+	findings, err := validation.ValidateSchemas(templates, w.KubernetesVersion)
+	if err != nil {
+		logger.Warn("Failed to validate rendered manifests", zap.String("workspaceID", w.ID), zap.Error(err))
+		renderedChart.HelmTemplateStderr += fmt.Sprintf("Rendered manifests were not validated: %v\n", err)
+		findings = map[string][]workspacetypes.RenderFinding{}
+	}
+
+	if errorCount := validation.CountErrors(findings); errorCount > 0 {
+		renderedChart.HelmTemplateStderr += fmt.Sprintf("%d rendered manifests don't match their schema:\n%s", errorCount, validation.Summary(findings))
+	}
+
+	// policy findings are warnings, so they don't fail the render
+	for path, policyFindings := range validation.LintPolicies(templates, w.DisabledPolicyRules) {
+		findings[path] = append(findings[path], policyFindings...)
+	}
+
+	return findings
+}
+
+// saveRenderedFile saves the content and findings of a rendered file and sends it to the client
+func saveRenderedFile(ctx context.Context, w *workspacetypes.Workspace, renderedWorkspace *workspacetypes.Rendered, renderedChart *workspacetypes.RenderedChart, realtimeRecipient realtimetypes.Recipient, file workspacetypes.RenderedFile) error {
+	if file.ID != "" {
+		e := realtimetypes.RenderFileEvent{
+			WorkspaceID:   w.ID,
+			RenderID:      renderedWorkspace.ID,
+			RenderChartID: renderedChart.ID,
+			RenderedFile:  file,
+		}
+
+		if err := realtime.SendEvent(ctx, realtimeRecipient, e); err != nil {
+			return fmt.Errorf("failed to send render updated event: %w", err)
+		}
+	}
+
+	if err := workspace.SetRenderedFileContents(ctx, w.ID, renderedWorkspace.RevisionNumber, renderedChart.ValuesProfile, file.FilePath, file.RenderedContent); err != nil {
+		return fmt.Errorf("failed to set rendered file contents: %w", err)
+	}
+
+	if err := workspace.SetRenderedFileFindings(ctx, w.ID, renderedWorkspace.RevisionNumber, renderedChart.ValuesProfile, file.FilePath, file.Findings); err != nil {
+		return fmt.Errorf("failed to set rendered file findings: %w", err)
+	}
+
+	return nil
+}
+
 func parseRenderedFiles(ctx context.Context, stdout string, chartName string, renderedFiles *[]workspacetypes.RenderedFile, workspaceFiles []workspacetypes.File) ([]workspacetypes.RenderedFile, error) {
 	// Add panic recovery
 	defer func() {
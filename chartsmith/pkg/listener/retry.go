@@ -0,0 +1,121 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RetryPolicy controls how many times a job on a channel is attempted and how long
+// to wait between attempts before the job is moved to the dead letter table
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+const (
+	WorkQueueDeadTable = "work_queue_dead"
+)
+
+// DefaultRetryPolicy is used by channels that don't need anything special
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     5 * time.Minute,
+}
+
+// backoff returns the delay before the next attempt, given the number of attempts
+// that have already failed. The delay doubles each attempt and is capped at MaxBackoff
+func (p RetryPolicy) backoff(failedAttempts int) time.Duration {
+	if failedAttempts < 1 {
+		failedAttempts = 1
+	}
+
+	delay := p.InitialBackoff
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// isExhausted returns true when a job that has failed failedAttempts times should
+// not be attempted again
+func (p RetryPolicy) isExhausted(failedAttempts int) bool {
+	if p.MaxAttempts <= 0 {
+		return false
+	}
+	return failedAttempts >= p.MaxAttempts
+}
+
+// errJobNotClaimed is returned when a job is no longer claimed by the attempt that is updating it, because it
+// was released or claimed again by another worker after it timed out
+var errJobNotClaimed = errors.New("job is no longer claimed by this attempt")
+
+// scheduleRetry releases a failed job so that it will be picked up again after the backoff. claimedAt is the
+// processing_started_at of the attempt, the job isn't changed if it has been claimed again since
+func scheduleRetry(ctx context.Context, conn *pgx.Conn, id string, claimedAt time.Time, failedAttempts int, delay time.Duration, lastError string) error {
+	tag, err := conn.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET processing_started_at = NULL,
+			last_error = $2,
+			attempt_count = $3,
+			next_attempt_at = NOW() + $4::interval
+		WHERE id = $1
+		AND processing_started_at = $5`, WorkQueueTable),
+		id, lastError, failedAttempts, delay.String(), claimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errJobNotClaimed
+	}
+
+	return nil
+}
+
+// moveToDeadLetter moves a job out of the work queue and into the dead letter table. claimedAt is the
+// processing_started_at of the attempt, the job isn't moved if it has been claimed again since
+func moveToDeadLetter(ctx context.Context, conn *pgx.Conn, id string, claimedAt time.Time, failedAttempts int, lastError string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, channel, payload, created_at, failed_at, attempt_count, last_error)
+		SELECT id, channel, payload, created_at, NOW(), $2, $3
+		FROM %s
+		WHERE id = $1
+		AND processing_started_at = $4
+		FOR UPDATE`, WorkQueueDeadTable, WorkQueueTable),
+		id, failedAttempts, lastError, claimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert dead letter: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errJobNotClaimed
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, WorkQueueTable), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete work: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package listener
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 5 * time.Second,
+		MaxBackoff:     time.Minute,
+	}
+
+	tests := []struct {
+		name           string
+		policy         RetryPolicy
+		failedAttempts int
+		want           time.Duration
+	}{
+		{name: "no failed attempts", policy: policy, failedAttempts: 0, want: 5 * time.Second},
+		{name: "first failure", policy: policy, failedAttempts: 1, want: 5 * time.Second},
+		{name: "second failure", policy: policy, failedAttempts: 2, want: 10 * time.Second},
+		{name: "fourth failure", policy: policy, failedAttempts: 4, want: 40 * time.Second},
+		{name: "capped", policy: policy, failedAttempts: 5, want: time.Minute},
+		{name: "capped without overflow", policy: policy, failedAttempts: 100, want: time.Minute},
+		{
+			name:           "initial backoff above max",
+			policy:         RetryPolicy{InitialBackoff: 2 * time.Minute, MaxBackoff: time.Minute},
+			failedAttempts: 1,
+			want:           time.Minute,
+		},
+		{
+			name:           "no max",
+			policy:         RetryPolicy{InitialBackoff: time.Second},
+			failedAttempts: 11,
+			want:           1024 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.failedAttempts); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.failedAttempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyIsExhausted(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         RetryPolicy
+		failedAttempts int
+		want           bool
+	}{
+		{name: "attempts left", policy: DefaultRetryPolicy, failedAttempts: 4, want: false},
+		{name: "last attempt failed", policy: DefaultRetryPolicy, failedAttempts: 5, want: true},
+		{name: "past max", policy: DefaultRetryPolicy, failedAttempts: 6, want: true},
+		{name: "unlimited", policy: RetryPolicy{}, failedAttempts: 1000, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.isExhausted(tt.failedAttempts); got != tt.want {
+				t.Errorf("isExhausted(%d) = %v, want %v", tt.failedAttempts, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,113 +8,195 @@ import (
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"go.uber.org/zap"
 )
 
-func StartListeners(ctx context.Context) error {
+// applyPlanRetryPolicy is more conservative than the default because each
+// attempt makes a number of expensive llm calls
+var applyPlanRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     10 * time.Minute,
+}
+
+// renderWorkspaceRetryPolicy gives up quickly, a chart that fails to render
+// will almost always fail the same way again
+var renderWorkspaceRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// DefaultDrainTimeout is how long in-flight handlers are given to finish on shutdown
+const DefaultDrainTimeout = 30 * time.Second
+
+type StartListenersOpts struct {
+	// DrainTimeout is how long to wait for in-flight handlers after ctx is done
+	// before releasing their jobs back to the queue
+	DrainTimeout time.Duration
+	// HealthAddr is the address to serve /healthz and /readyz on. They are not served if empty
+	HealthAddr string
+	// Config overrides the channel defaults and can limit the channels this worker handles
+	Config *WorkerConfig
+}
+
+// StartListeners registers all handlers and processes work until ctx is done, then
+// drains in-flight handlers before returning
+func StartListeners(ctx context.Context, opts StartListenersOpts) error {
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = DefaultDrainTimeout
+	}
+
+	// Handlers run with a context that outlives ctx so that a shutdown signal doesn't
+	// abort work that can finish within the drain timeout
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
 	l := NewListener()
-	l.AddHandler(ctx, "new_intent", 5, time.Second*10, func(notification *pgconn.Notification) error {
+
+	// addHandler registers the handler with the channel's effective config, unless the
+	// worker is configured to not handle the channel
+	addHandler := func(channel string, defaults ChannelConfig, handler NotificationHandler, lockKeyExtractor LockKeyExtractor, retryPolicy RetryPolicy) {
+		if !opts.Config.handles(channel) {
+			logger.Info("Skipping channel not handled by this worker", zap.String("channel", channel))
+			return
+		}
+
+		effective := opts.Config.resolve(channel, defaults)
+		logger.Info("Registering channel",
+			zap.String("channel", channel),
+			zap.Int("maxWorkers", effective.MaxWorkers),
+			zap.Duration("maxDuration", effective.MaxDuration))
+
+		l.AddHandler(workCtx, channel, effective.MaxWorkers, effective.MaxDuration, handler, lockKeyExtractor, retryPolicy)
+	}
+
+	addHandler("new_intent", ChannelConfig{MaxWorkers: 5, MaxDuration: 2 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleNewIntentNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle new intent notification: %w", err))
 			return fmt.Errorf("failed to handle new intent notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "new_summarize", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("new_summarize", ChannelConfig{MaxWorkers: 5, MaxDuration: 2 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleNewSummarizeNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle new summarize notification: %w", err))
 			return fmt.Errorf("failed to handle new summarize notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "new_plan", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("new_plan", ChannelConfig{MaxWorkers: 5, MaxDuration: 10 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleNewPlanNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle new plan notification: %w", err))
 			return fmt.Errorf("failed to handle new plan notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "new_converational", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("new_converational", ChannelConfig{MaxWorkers: 5, MaxDuration: 10 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleConverationalNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle new converational notification: %w", err))
 			return fmt.Errorf("failed to handle new converational notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "execute_plan", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("execute_plan", ChannelConfig{MaxWorkers: 5, MaxDuration: 15 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleExecutePlanNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle execute plan notification: %w", err))
 			return fmt.Errorf("failed to handle execute plan notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "apply_plan", 10, time.Minute*10, func(notification *pgconn.Notification) error {
+	addHandler("apply_plan", ChannelConfig{MaxWorkers: 10, MaxDuration: 10 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleApplyPlanNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle apply plan notification: %w", err))
 			return fmt.Errorf("failed to handle apply plan notification: %w", err)
 		}
 		return nil
-	}, applyPlanLockKeyExtractor)
+	}, applyPlanLockKeyExtractor, applyPlanRetryPolicy)
 
-	l.AddHandler(ctx, "render_workspace", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	// Longer than the 10 minute timeout of the render itself
+	addHandler("render_workspace", ChannelConfig{MaxWorkers: 5, MaxDuration: 15 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleRenderWorkspaceNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle render workspace notification: %w", err))
 			return fmt.Errorf("failed to handle render workspace notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, renderWorkspaceRetryPolicy)
 
-	l.AddHandler(ctx, "new_conversion", 5, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("new_conversion", ChannelConfig{MaxWorkers: 5, MaxDuration: 10 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleNewConversionNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle new conversion notification: %w", err))
 			return fmt.Errorf("failed to handle new conversion notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "conversion_next_file", 10, time.Second*10, func(notification *pgconn.Notification) error {
+	// Includes the time spent waiting for the other files of the conversion
+	addHandler("conversion_next_file", ChannelConfig{MaxWorkers: 10, MaxDuration: 30 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleConversionNextFileNotificationWithLock(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle conversion file notification: %w", err))
 			return fmt.Errorf("failed to handle conversion file notification: %w", err)
 		}
 		return nil
-	}, conversionFileLockKeyExtractor)
+	}, conversionFileLockKeyExtractor, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "conversion_normalize_values", 10, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("conversion_normalize_values", ChannelConfig{MaxWorkers: 10, MaxDuration: 10 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleConversionNormalizeValuesNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle conversion normalize values notification: %w", err))
 			return fmt.Errorf("failed to handle conversion normalize values notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
-	l.AddHandler(ctx, "conversion_simplify", 10, time.Second*10, func(notification *pgconn.Notification) error {
+	addHandler("conversion_simplify", ChannelConfig{MaxWorkers: 10, MaxDuration: 20 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handleConversionSimplifyNotificationWithLock(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle conversion simplify notification: %w", err))
 			return fmt.Errorf("failed to handle conversion simplify notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
 
 	// Add handler for workspace publishing with high concurrency (20 concurrent workers)
-	l.AddHandler(ctx, "publish_workspace", 20, time.Minute*5, func(notification *pgconn.Notification) error {
+	addHandler("publish_workspace", ChannelConfig{MaxWorkers: 20, MaxDuration: 5 * time.Minute}, func(ctx context.Context, notification *pgconn.Notification) error {
 		if err := handlePublishWorkspaceNotification(ctx, notification.Payload); err != nil {
 			logger.Error(fmt.Errorf("failed to handle publish workspace notification: %w", err))
 			return fmt.Errorf("failed to handle publish workspace notification: %w", err)
 		}
 		return nil
-	}, nil)
+	}, nil, DefaultRetryPolicy)
+
+	if opts.Config != nil {
+		for _, channel := range opts.Config.Channels {
+			if _, ok := l.handlers[channel]; !ok {
+				return fmt.Errorf("unknown channel %q in worker config", channel)
+			}
+		}
+	}
+
+	if opts.HealthAddr != "" {
+		// keep serving health while draining so that readiness reports it
+		go func() {
+			if err := l.serveHealth(workCtx, opts.HealthAddr); err != nil {
+				logger.Error(fmt.Errorf("health server error: %w", err))
+			}
+		}()
+	}
 
 	l.Start(ctx)
-	defer l.Stop(ctx)
+	defer l.Stop(context.Background())
 
 	// wait for ctx to be done
 	<-ctx.Done()
 
+	if err := l.Drain(opts.DrainTimeout); err != nil {
+		logger.Error(fmt.Errorf("failed to drain listener: %w", err))
+	}
+
 	return nil
 }
 
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+)
+
+// anthropicProvider sends requests to the Anthropic messages API
+type anthropicProvider struct{}
+
+var _ Provider = anthropicProvider{}
+
+// newAnthropicClient creates an Anthropic client
+func newAnthropicClient(ctx context.Context) (*anthropic.Client, error) {
+	if param.Get().AnthropicAPIKey == "" {
+		return nil, fmt.Errorf("%w: ANTHROPIC_API_KEY environment variable not set", errProviderUnavailable)
+	}
+	client := anthropic.NewClient(
+		option.WithAPIKey(param.Get().AnthropicAPIKey),
+	)
+
+	return client, nil
+}
+
+func (anthropicProvider) Name() string {
+	return providerAnthropic
+}
+
+func (anthropicProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	client, err := newAnthropicClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	llmReq := startLLMRequest(ctx, providerAnthropic, req.Model)
+	message, err := client.Messages.New(ctx, newAnthropicParams(req))
+	if err != nil {
+		llmReq.end(0, 0, err)
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+	llmReq.end(message.Usage.InputTokens, message.Usage.OutputTokens, nil)
+
+	return newAnthropicResponse(message), nil
+}
+
+func (anthropicProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (resp *Response, err error) {
+	client, err := newAnthropicClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	llmReq := startLLMRequest(ctx, providerAnthropic, req.Model)
+	message := anthropic.Message{}
+	defer func() {
+		llmReq.end(message.Usage.InputTokens, message.Usage.OutputTokens, err)
+	}()
+
+	stream := client.Messages.NewStreaming(ctx, newAnthropicParams(req))
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("failed to accumulate message: %w", err)
+		}
+
+		switch event := event.AsUnion().(type) {
+		case anthropic.ContentBlockDeltaEvent:
+			if err := sendText(ctx, streamCh, event.Delta.Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to stream message: %w", err)
+	}
+
+	return newAnthropicResponse(&message), nil
+}
+
+// newAnthropicParams converts req to the messages API format. System messages become the system
+// prompt, and consecutive tool results are sent together in a single user message
+func newAnthropicParams(req Request) anthropic.MessageNewParams {
+	system := []anthropic.TextBlockParam{}
+	messages := []anthropic.MessageParam{}
+	toolResults := []anthropic.ContentBlockParamUnion{}
+
+	flushToolResults := func() {
+		if len(toolResults) == 0 {
+			return
+		}
+		messages = append(messages, anthropic.MessageParam{
+			Role:    anthropic.F(anthropic.MessageParamRoleUser),
+			Content: anthropic.F(toolResults),
+		})
+		toolResults = []anthropic.ContentBlockParamUnion{}
+	}
+
+	for _, message := range req.Messages {
+		if message.Role != RoleTool {
+			flushToolResults()
+		}
+
+		switch message.Role {
+		case RoleSystem:
+			system = append(system, anthropic.NewTextBlock(message.Content))
+		case RoleUser:
+			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content)))
+		case RoleAssistant:
+			blocks := []anthropic.ContentBlockParamUnion{}
+			if message.Content != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(message.Content))
+			}
+			for _, call := range message.ToolCalls {
+				blocks = append(blocks, anthropic.NewToolUseBlockParam(call.ID, call.Name, call.Arguments))
+			}
+			messages = append(messages, anthropic.NewAssistantMessage(blocks...))
+		case RoleTool:
+			toolResults = append(toolResults, anthropic.NewToolResultBlock(message.ToolCallID, message.Content, false))
+		}
+	}
+	flushToolResults()
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.F(req.Model),
+		MaxTokens: anthropic.F(int64(maxTokens(req))),
+		Messages:  anthropic.F(messages),
+	}
+
+	if len(system) > 0 {
+		params.System = anthropic.F(system)
+	}
+
+	if len(req.Tools) > 0 {
+		tools := make([]anthropic.ToolUnionUnionParam, len(req.Tools))
+		for i, tool := range req.Tools {
+			tools[i] = anthropic.ToolParam{
+				Name:        anthropic.F(tool.Name),
+				Description: anthropic.F(tool.Description),
+				InputSchema: anthropic.F(interface{}(tool.Parameters)),
+			}
+		}
+		params.Tools = anthropic.F(tools)
+	}
+
+	return params
+}
+
+func newAnthropicResponse(message *anthropic.Message) *Response {
+	resp := &Response{
+		Usage: Usage{
+			InputTokens:  message.Usage.InputTokens,
+			OutputTokens: message.Usage.OutputTokens,
+		},
+	}
+
+	for _, block := range message.Content {
+		switch block.Type {
+		case anthropic.ContentBlockTypeText:
+			resp.Content += block.Text
+		case anthropic.ContentBlockTypeToolUse:
+			arguments := json.RawMessage(block.Input)
+			if len(arguments) == 0 {
+				arguments = json.RawMessage("{}")
+			}
+			resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: arguments,
+			})
+		}
+	}
+
+	return resp
+}
@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// chatCompletionsProvider sends requests to an OpenAI-compatible chat completions API. The
+// request and response types are the OpenRouter* types, OpenRouter uses the same format
+type chatCompletionsProvider struct {
+	name string
+	// apiKeyName is the param that holds the API key, for error messages
+	apiKeyName string
+	// endpoint returns the URL of the chat completions endpoint
+	endpoint func() (string, error)
+	// setHeaders adds authentication and any other provider specific headers to req
+	setHeaders func(req *http.Request) error
+}
+
+var _ Provider = &chatCompletionsProvider{}
+
+func (p *chatCompletionsProvider) Name() string {
+	return p.name
+}
+
+func (p *chatCompletionsProvider) Complete(ctx context.Context, req Request) (resp *Response, err error) {
+	llmReq := startLLMRequest(ctx, p.name, req.Model)
+	var usage OpenRouterUsage
+	defer func() {
+		llmReq.end(usage.PromptTokens, usage.CompletionTokens, err)
+	}()
+
+	body, err := p.post(ctx, newChatCompletionsRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var openRouterResp OpenRouterResponse
+	if err := json.NewDecoder(body).Decode(&openRouterResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	usage = openRouterResp.Usage
+
+	if len(openRouterResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in %s response", p.name)
+	}
+
+	message := openRouterResp.Choices[0].Message
+	resp = &Response{
+		Content: message.Content,
+		Usage: Usage{
+			InputTokens:  usage.PromptTokens,
+			OutputTokens: usage.CompletionTokens,
+		},
+	}
+	for _, toolCall := range message.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: openRouterArguments(toolCall.Function.Arguments),
+		})
+	}
+	// Support function_call (legacy) from models that don't use tool_calls
+	if len(resp.ToolCalls) == 0 && message.FunctionCall != nil {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			Name:      message.FunctionCall.Name,
+			Arguments: openRouterArguments(message.FunctionCall.Arguments),
+		})
+	}
+
+	return resp, nil
+}
+
+func (p *chatCompletionsProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (resp *Response, err error) {
+	llmReq := startLLMRequest(ctx, p.name, req.Model)
+	var usage OpenRouterUsage
+	defer func() {
+		llmReq.end(usage.PromptTokens, usage.CompletionTokens, err)
+	}()
+
+	body, err := p.post(ctx, newChatCompletionsRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	content := strings.Builder{}
+	toolCalls := []*OpenRouterToolCall{}
+
+	// The response is a stream of server-sent events, each with a chunk in its data
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			// blank lines between events and keepalive comments like ": OPENROUTER PROCESSING"
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk OpenRouterStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			logger.Error(fmt.Errorf("failed to decode stream chunk: %w", err))
+			continue
+		}
+
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+
+		content.WriteString(delta.Content)
+		if err := sendText(ctx, streamCh, delta.Content); err != nil {
+			return nil, err
+		}
+
+		for _, toolCallDelta := range delta.ToolCalls {
+			for len(toolCalls) <= toolCallDelta.Index {
+				toolCalls = append(toolCalls, &OpenRouterToolCall{Type: "function"})
+			}
+			toolCall := toolCalls[toolCallDelta.Index]
+			if toolCallDelta.ID != "" {
+				toolCall.ID = toolCallDelta.ID
+			}
+			toolCall.Function.Name += toolCallDelta.Function.Name
+			toolCall.Function.Arguments += toolCallDelta.Function.Arguments
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	resp = &Response{
+		Content: content.String(),
+		Usage: Usage{
+			InputTokens:  usage.PromptTokens,
+			OutputTokens: usage.CompletionTokens,
+		},
+	}
+	for _, toolCall := range toolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: openRouterArguments(toolCall.Function.Arguments),
+		})
+	}
+
+	return resp, nil
+}
+
+// newChatCompletionsRequest converts req to the OpenAI chat completions format
+func newChatCompletionsRequest(req Request, stream bool) OpenRouterRequest {
+	maxTokens := maxTokens(req)
+
+	messages := make([]OpenRouterMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		openRouterMessage := OpenRouterMessage{
+			Role:       string(message.Role),
+			Content:    message.Content,
+			ToolCallID: message.ToolCallID,
+		}
+		for _, call := range message.ToolCalls {
+			openRouterMessage.ToolCalls = append(openRouterMessage.ToolCalls, OpenRouterToolCall{
+				ID:   call.ID,
+				Type: "function",
+				Function: OpenRouterFunctionCall{
+					Name:      call.Name,
+					Arguments: string(call.Arguments),
+				},
+			})
+		}
+		messages = append(messages, openRouterMessage)
+	}
+
+	openRouterReq := OpenRouterRequest{
+		Model:     req.Model,
+		Messages:  messages,
+		Stream:    stream,
+		MaxTokens: &maxTokens,
+	}
+
+	if stream {
+		openRouterReq.StreamOptions = &OpenRouterStreamOptions{
+			IncludeUsage: true,
+		}
+	}
+
+	if req.JSON {
+		openRouterReq.ResponseFormat = &OpenRouterResponseFormat{
+			Type: "json_object",
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		for _, tool := range req.Tools {
+			openRouterReq.Tools = append(openRouterReq.Tools, OpenRouterTool{
+				Type: "function",
+				Function: OpenRouterFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			})
+		}
+		openRouterReq.ToolChoice = "auto"
+	}
+
+	return openRouterReq
+}
+
+// post sends the request and returns the body of a successful response, which the caller must close
+func (p *chatCompletionsProvider) post(ctx context.Context, reqBody OpenRouterRequest) (io.ReadCloser, error) {
+	endpoint, err := p.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.setHeaders(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		apiErr := &APIError{
+			Provider:   p.name,
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			logger.Error(fmt.Errorf("%s authentication failed", p.name),
+				zap.Int("status_code", resp.StatusCode),
+				zap.String("body", string(body)))
+			return nil, fmt.Errorf("%w. Please verify your %s is set correctly in your .env file and has valid credits", apiErr, p.apiKeyName)
+		}
+		return nil, apiErr
+	}
+
+	return resp.Body, nil
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -15,96 +14,9 @@ func CleanUpConvertedValuesYAML(ctx context.Context, valuesYAML string) (string,
 }
 
 func CleanUpConvertedValuesYAMLWithModel(ctx context.Context, valuesYAML string, modelID string) (string, error) {
+	ctx = withStage(ctx, StageCleanup)
 	logger.Info("Cleaning up converted values.yaml")
 
-	// Use OpenRouter if model is OpenRouter format
-	if isOpenRouterModel(modelID) {
-		return cleanUpConvertedValuesYAMLOpenRouter(ctx, valuesYAML, modelID)
-	}
-
-	// Use Anthropic
-	client, err := newAnthropicClient(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get anthropic client: %w", err)
-	}
-
-	messages := []anthropic.MessageParam{
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(cleanupConvertedValuesSystemPrompt)),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`
-Here is the converted values.yaml file:
----
-%s
----
-			`, valuesYAML)),
-		),
-	}
-
-	// Default to DefaultModel if modelID is empty
-	if modelID == "" {
-		modelID = DefaultModel
-	}
-
-	response, err := client.Messages.New(context.TODO(), anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		Messages:  anthropic.F(messages),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create message: %w", err)
-	}
-
-	artifacts, err := parseArtifactsInResponse(response.Content[0].Text)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse artifacts: %w", err)
-	}
-
-	if len(artifacts) == 0 {
-		return "", fmt.Errorf("no artifacts found in response")
-	}
-
-	for _, artifact := range artifacts {
-		if artifact.Path == "values.yaml" {
-			if strings.HasPrefix(strings.TrimSpace(artifact.Content), "---") &&
-				strings.Contains(artifact.Content, "+++") &&
-				strings.Contains(artifact.Content, "@@") {
-				// It's a patch, try to apply it safely
-				logger.Info("Received values.yaml as a patch, attempting to apply")
-
-				// Try to apply the patch
-				newContent, err := applyPatch(valuesYAML, artifact.Content)
-				if err != nil {
-					// Patch application failed, fall back to merging approach
-					logger.Warn("Failed to apply patch directly, falling back to content extraction", zap.Error(err))
-
-					// Extract and merge the added content from the patch
-					extractedContent := extractAddedContent(artifact.Content)
-					mergedValues, err := mergeValuesYAML(valuesYAML, extractedContent)
-					if err != nil {
-						logger.Warn("Failed to merge values.yaml, using original content", zap.Error(err))
-					} else {
-						return mergedValues, nil
-					}
-				} else {
-					return artifact.Content, nil
-				}
-
-				return newContent, nil
-			} else {
-				// It's not a patch, use the normal merging approach
-				mergedValues, err := mergeValuesYAML(valuesYAML, artifact.Content)
-				if err != nil {
-					logger.Warn("Failed to merge values.yaml, using original content", zap.Error(err))
-				} else {
-					return mergedValues, nil
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("no values.yaml artifact found in response")
-}
-
-func cleanUpConvertedValuesYAMLOpenRouter(ctx context.Context, valuesYAML string, modelID string) (string, error) {
 	userMessage := fmt.Sprintf(`
 Here is the converted values.yaml file:
 ---
@@ -112,14 +24,17 @@ Here is the converted values.yaml file:
 ---
 	`, valuesYAML)
 
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: cleanupConvertedValuesSystemPrompt},
-		{Role: "user", Content: userMessage},
+	systemPrompt, err := renderPrompt(ctx, promptCleanupConvertedValues, nil)
+	if err != nil {
+		return "", err
 	}
 
-	response, err := callOpenRouter(ctx, modelID, messages, 8192)
+	response, err := complete(ctx, modelID, []Message{
+		{Role: RoleSystem, Content: systemPrompt},
+		{Role: RoleUser, Content: userMessage},
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
+		return "", fmt.Errorf("failed to clean up values.yaml: %w", err)
 	}
 
 	artifacts, err := parseArtifactsInResponse(response)
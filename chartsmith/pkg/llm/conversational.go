@@ -5,32 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/replicatedhq/chartsmith/pkg/recommendations"
 	"github.com/replicatedhq/chartsmith/pkg/workspace"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
 )
 
 func ConversationalChatMessage(ctx context.Context, streamCh chan string, doneCh chan error, w *workspacetypes.Workspace, chatMessage *workspacetypes.Chat, modelID string) error {
-	// Default to DefaultModel if modelID is empty
-	if modelID == "" {
-		modelID = DefaultModel
-	}
-
-	// Determine if we should use OpenRouter or Anthropic
-	if isOpenRouterModel(modelID) {
-		return conversationalChatMessageOpenRouter(ctx, streamCh, doneCh, w, chatMessage, modelID)
-	}
-	
-	// Use Anthropic (existing implementation)
-	client, err := newAnthropicClient(ctx)
+	ctx = withChatResponse(WithUsageScope(withStage(ctx, StageConversational), chatMessageUsageScope(chatMessage)))
+	systemPrompt, err := renderPrompts(ctx, promptChatOnly, promptChatOnlyInstructions)
 	if err != nil {
-		return fmt.Errorf("failed to create anthropic client: %w", err)
+		return err
 	}
 
-	messages := []anthropic.MessageParam{
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(chatOnlySystemPrompt)),
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(chatOnlyInstructions)),
+	messages := []Message{
+		{Role: RoleSystem, Content: systemPrompt},
 	}
 
 	var c *workspacetypes.Chart
@@ -72,14 +60,24 @@ func ConversationalChatMessage(ctx context.Context, streamCh chan string, doneCh
 	relevantFiles = relevantFiles[:maxFiles]
 
 	// add the context of the workspace to the chat
-	messages = append(messages,
-		anthropic.NewAssistantMessage(
-			anthropic.NewTextBlock(fmt.Sprintf(`I am working on a Helm chart that has the following structure: %s`, chartStructure)),
-		),
-	)
+	messages = append(messages, Message{
+		Role:    RoleAssistant,
+		Content: fmt.Sprintf(`I am working on a Helm chart that has the following structure: %s`, chartStructure),
+	})
 
 	for _, file := range relevantFiles {
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(fmt.Sprintf(`File: %s, Content: %s`, file.File.FilePath, file.File.Content))))
+		messages = append(messages, Message{
+			Role:    RoleAssistant,
+			Content: fmt.Sprintf(`File: %s, Content: %s`, file.File.FilePath, file.File.Content),
+		})
+	}
+
+	renderFindings, err := workspace.ListRenderedFileFindings(ctx, w.ID, w.CurrentRevision)
+	if err != nil {
+		return fmt.Errorf("failed to list rendered file findings: %w", err)
+	}
+	if findingsMessage := renderFindingsMessage(renderFindings); findingsMessage != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: findingsMessage})
 	}
 
 	// we need to get the previous plan, and then all followup chat messages since that plan
@@ -98,20 +96,19 @@ func ConversationalChatMessage(ctx context.Context, streamCh chan string, doneCh
 			if chat.ID == chatMessage.ID {
 				continue
 			}
-			messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(chat.Prompt)))
+			messages = append(messages, Message{Role: RoleUser, Content: chat.Prompt})
 		}
 
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(plan.Description)))
-
+		messages = append(messages, Message{Role: RoleAssistant, Content: plan.Description})
 	}
 
-	messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(chatMessage.Prompt)))
+	messages = append(messages, Message{Role: RoleUser, Content: chatMessage.Prompt})
 
-	tools := []anthropic.ToolParam{
+	tools := []Tool{
 		{
-			Name:        anthropic.F("latest_subchart_version"),
-			Description: anthropic.F("Return the latest version of a subchart from name"),
-			InputSchema: anthropic.F(interface{}(map[string]interface{}{
+			Name:        "latest_subchart_version",
+			Description: "Return the latest version of a subchart from name",
+			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"chart_name": map[string]interface{}{
@@ -120,12 +117,12 @@ func ConversationalChatMessage(ctx context.Context, streamCh chan string, doneCh
 					},
 				},
 				"required": []string{"chart_name"},
-			})),
+			},
 		},
 		{
-			Name:        anthropic.F("latest_kubernetes_version"),
-			Description: anthropic.F("Return the latest version of Kubernetes"),
-			InputSchema: anthropic.F(interface{}(map[string]interface{}{
+			Name:        "latest_kubernetes_version",
+			Description: "Return the latest version of Kubernetes",
+			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"semver_field": map[string]interface{}{
@@ -133,222 +130,75 @@ func ConversationalChatMessage(ctx context.Context, streamCh chan string, doneCh
 						"description": "One of 'major', 'minor', or 'patch'",
 					},
 				},
-				"required": []string{"semver_description"},
-			})),
+				"required": []string{"semver_field"},
+			},
 		},
 	}
 
-	toolUnionParams := make([]anthropic.ToolUnionUnionParam, len(tools))
-	for i, tool := range tools {
-		toolUnionParams[i] = tool
+	chain, err := resolveChain(ctx, modelID)
+	if err != nil {
+		doneCh <- err
+		return err
 	}
 
-	for {
-		stream := client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-			Model:     anthropic.F(modelID),
-			MaxTokens: anthropic.F(int64(8192)),
-			Messages:  anthropic.F(messages),
-			Tools:     anthropic.F(toolUnionParams),
-		})
-
-		message := anthropic.Message{}
-		for stream.Next() {
-			event := stream.Current()
-			err := message.Accumulate(event)
-			if err != nil {
-				doneCh <- fmt.Errorf("failed to accumulate message: %w", err)
-				return err
-			}
-
-			switch event := event.AsUnion().(type) {
-			case anthropic.ContentBlockDeltaEvent:
-				if event.Delta.Text != "" {
-					streamCh <- event.Delta.Text
-				}
-			}
-		}
-
-		if stream.Err() != nil {
-			doneCh <- stream.Err()
-			return stream.Err()
-		}
-
-		messages = append(messages, message.ToParam())
-
-		hasToolCalls := false
-		toolResults := []anthropic.ContentBlockParamUnion{}
-
-		for _, block := range message.Content {
-			if block.Type == anthropic.ContentBlockTypeToolUse {
-				hasToolCalls = true
-				var response interface{}
-				switch block.Name {
-				case "latest_kubernetes_version":
-					var input struct {
-						SemverField string `json:"semver_field"`
-					}
-					if err := json.Unmarshal(block.Input, &input); err != nil {
-						doneCh <- fmt.Errorf("failed to unmarshal tool input: %w", err)
-						return err
-					}
-
-					switch input.SemverField {
-					case "major":
-						response = "1"
-					case "minor":
-						response = "1.32"
-					case "patch":
-						response = "1.32.1"
-					}
-				case "latest_subchart_version":
-					var input struct {
-						ChartName string `json:"chart_name"`
-					}
-					if err := json.Unmarshal(block.Input, &input); err != nil {
-						doneCh <- fmt.Errorf("failed to unmarshal tool input: %w", err)
-						return err
-					}
-
-					version, err := recommendations.GetLatestSubchartVersion(input.ChartName)
-					if err != nil && err != recommendations.ErrNoArtifactHubPackage {
-						doneCh <- fmt.Errorf("failed to get latest subchart version: %w", err)
-						return err
-					} else if err == recommendations.ErrNoArtifactHubPackage {
-						response = "?"
-					} else {
-						response = version
-					}
-				}
-
-				b, err := json.Marshal(response)
-				if err != nil {
-					doneCh <- fmt.Errorf("failed to marshal tool response: %w", err)
-					return err
-				}
-
-				toolResults = append(toolResults, anthropic.NewToolResultBlock(block.ID, string(b), false))
-			}
-		}
-
-		if !hasToolCalls {
-			break
-		}
-
-		messages = append(messages, anthropic.MessageParam{
-			Role:    anthropic.F(anthropic.MessageParamRoleUser),
-			Content: anthropic.F(toolResults),
-		})
+	_, err = runWithTools(ctx, chain, Request{
+		Messages: messages,
+		Tools:    tools,
+	}, streamCh, handleConversationalTool)
+	if err != nil {
+		doneCh <- err
+		return err
 	}
 
 	doneCh <- nil
 	return nil
 }
 
-// conversationalChatMessageOpenRouter handles conversational chat using OpenRouter
-func conversationalChatMessageOpenRouter(ctx context.Context, streamCh chan string, doneCh chan error, w *workspacetypes.Workspace, chatMessage *workspacetypes.Chat, modelID string) error {
-	var c *workspacetypes.Chart
-	if len(w.Charts) > 0 {
-		c = &w.Charts[0]
-	}
-
-	chartStructure, err := getChartStructure(ctx, c)
-	if err != nil {
-		return fmt.Errorf("failed to get chart structure: %w", err)
-	}
+// handleConversationalTool runs the tools that are available in conversational chat
+func handleConversationalTool(ctx context.Context, call ToolCall) (string, error) {
+	var response interface{}
 
-	// Build messages in OpenRouter format
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: chatOnlySystemPrompt + "\n\n" + chatOnlyInstructions},
-	}
-
-	// Add chart structure
-	if chartStructure != "" {
-		messages = append(messages, OpenRouterMessage{
-			Role: "assistant",
-			Content: fmt.Sprintf("I am working on a Helm chart that has the following structure: %s", chartStructure),
-		})
-	}
-
-	// Add relevant files (simplified - limit to 10)
-	var chartID *string
-	if len(w.Charts) > 0 {
-		chartID = &w.Charts[0].ID
-	}
-
-	expandedPrompt, err := ExpandPrompt(ctx, chatMessage.Prompt)
-	if err != nil {
-		return fmt.Errorf("failed to expand prompt: %w", err)
-	}
-
-	relevantFiles, err := workspace.ChooseRelevantFilesForChatMessage(
-		ctx,
-		w,
-		workspace.WorkspaceFilter{ChartID: chartID},
-		w.CurrentRevision,
-		expandedPrompt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to choose relevant files: %w", err)
-	}
-
-	maxFiles := 10
-	if len(relevantFiles) < maxFiles {
-		maxFiles = len(relevantFiles)
-	}
-	if maxFiles > 0 {
-		relevantFiles = relevantFiles[:maxFiles]
-	}
-
-	for _, file := range relevantFiles {
-		messages = append(messages, OpenRouterMessage{
-			Role: "assistant",
-			Content: fmt.Sprintf("File: %s, Content: %s", file.File.FilePath, file.File.Content),
-		})
-	}
-
-	// Add previous plan and chat messages
-	plan, err := workspace.GetMostRecentPlan(ctx, w.ID)
-	if err != nil && err != workspace.ErrNoPlan {
-		return fmt.Errorf("failed to get most recent plan: %w", err)
-	}
-
-	if plan != nil {
-		previousChatMessages, err := workspace.ListChatMessagesAfterPlan(ctx, plan.ID)
-		if err != nil {
-			return fmt.Errorf("failed to list chat messages: %w", err)
+	switch call.Name {
+	case "latest_kubernetes_version":
+		var input struct {
+			SemverField string `json:"semver_field"`
+		}
+		if err := json.Unmarshal(call.Arguments, &input); err != nil {
+			return "", fmt.Errorf("failed to unmarshal tool input: %w", err)
 		}
 
-		for _, chat := range previousChatMessages {
-			if chat.ID == chatMessage.ID {
-				continue
-			}
-			messages = append(messages, OpenRouterMessage{
-				Role: "user",
-				Content: chat.Prompt,
-			})
+		switch input.SemverField {
+		case "major":
+			response = "1"
+		case "minor":
+			response = "1.32"
+		case "patch":
+			response = "1.32.1"
+		}
+	case "latest_subchart_version":
+		var input struct {
+			ChartName string `json:"chart_name"`
+		}
+		if err := json.Unmarshal(call.Arguments, &input); err != nil {
+			return "", fmt.Errorf("failed to unmarshal tool input: %w", err)
 		}
 
-		messages = append(messages, OpenRouterMessage{
-			Role: "assistant",
-			Content: plan.Description,
-		})
+		version, err := recommendations.GetLatestSubchartVersion(input.ChartName)
+		if err != nil && err != recommendations.ErrNoArtifactHubPackage {
+			return "", fmt.Errorf("failed to get latest subchart version: %w", err)
+		} else if err == recommendations.ErrNoArtifactHubPackage {
+			response = "?"
+		} else {
+			response = version
+		}
 	}
 
-	// Add current user message
-	messages = append(messages, OpenRouterMessage{
-		Role: "user",
-		Content: chatMessage.Prompt,
-	})
-
-	// Stream the response
-	err = streamOpenRouter(ctx, modelID, messages, 8192, streamCh)
+	b, err := json.Marshal(response)
 	if err != nil {
-		doneCh <- err
-		return err
+		return "", fmt.Errorf("failed to marshal tool response: %w", err)
 	}
 
-	doneCh <- nil
-	return nil
+	return string(b), nil
 }
 
 func getChartStructure(ctx context.Context, c *workspacetypes.Chart) (string, error) {
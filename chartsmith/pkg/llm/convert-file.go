@@ -5,10 +5,7 @@ import (
 	"fmt"
 	"strings"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
-	"github.com/jpoz/groq"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
-	"github.com/replicatedhq/chartsmith/pkg/param"
 	"github.com/sourcegraph/go-diff/diff"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
@@ -23,36 +20,37 @@ type ConvertFileOpts struct {
 
 // ConvertFile is sync and will return a map of path:content
 func ConvertFile(ctx context.Context, opts ConvertFileOpts) (map[string]string, string, error) {
+	ctx = withStage(ctx, StageConvert)
 	logger.Info("Converting file",
 		zap.String("path", opts.Path),
 	)
 
-	// Default to Groq for now (existing behavior)
-	// If modelID is provided and it's an OpenRouter model, use OpenRouter
-	// Otherwise use Groq (existing behavior) or Anthropic if specified
-	if opts.ModelID != "" && isOpenRouterModel(opts.ModelID) {
-		return convertFileUsingOpenRouter(ctx, opts)
+	// Conversions use the fast groq model unless a model is requested
+	modelID := opts.ModelID
+	if modelID == "" {
+		modelID = groqChatModel
 	}
 
-	// For now, keep using Groq as default (existing behavior)
-	// TODO: Support Anthropic model selection if needed
-	return convertFileUsingGroq(ctx, opts)
-}
-
-func convertFileUsingGroq(ctx context.Context, opts ConvertFileOpts) (map[string]string, string, error) {
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+	executePlanPrompt, err := renderPrompt(ctx, promptExecutePlan, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	convertFilePrompt, err := renderPrompt(ctx, promptConvertFile, nil)
+	if err != nil {
+		return nil, "", err
+	}
 
-	messages := []groq.Message{
+	messages := []Message{
 		{
-			Role:    "system",
-			Content: executePlanSystemPrompt,
+			Role:    RoleSystem,
+			Content: executePlanPrompt,
 		},
 		{
-			Role:    "system",
-			Content: convertFileSystemPrompt,
+			Role:    RoleSystem,
+			Content: convertFilePrompt,
 		},
 		{
-			Role: "user",
+			Role: RoleUser,
 			Content: fmt.Sprintf(`
 Here is the existing values.yaml file:
 ---
@@ -61,7 +59,7 @@ Here is the existing values.yaml file:
 			`, opts.ValuesYAML),
 		},
 		{
-			Role: "user",
+			Role: RoleUser,
 			Content: fmt.Sprintf(`
 Convert the following Kubernetes manifest to a helm template:
 ---
@@ -71,106 +69,12 @@ Convert the following Kubernetes manifest to a helm template:
 		},
 	}
 
-	response, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model:    "llama-3.3-70b-versatile",
-		Messages: messages,
-	})
+	response, err := complete(ctx, modelID, messages)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get converted file content: %w", err)
 	}
 
-	artifacts, err := parseArtifactsInResponse(response.Choices[0].Message.Content)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse artifacts: %w", err)
-	}
-
-	updatedValuesYAML := opts.ValuesYAML
-	artifactsMap := make(map[string]string)
-	for _, artifact := range artifacts {
-		if artifact.Path == "values.yaml" {
-			// Check if the content is a unified diff patch
-			if strings.HasPrefix(strings.TrimSpace(artifact.Content), "---") &&
-				strings.Contains(artifact.Content, "+++") &&
-				strings.Contains(artifact.Content, "@@") {
-				// It's a patch, try to apply it safely
-				logger.Info("Received values.yaml as a patch, attempting to apply")
-
-				// Try to apply the patch
-				newContent, err := applyPatch(opts.ValuesYAML, artifact.Content)
-				if err != nil {
-					// Patch application failed, fall back to merging approach
-					logger.Warn("Failed to apply patch directly, falling back to content extraction", zap.Error(err))
-
-					// Extract and merge the added content from the patch
-					extractedContent := extractAddedContent(artifact.Content)
-					mergedValues, err := mergeValuesYAML(opts.ValuesYAML, extractedContent)
-					if err != nil {
-						logger.Warn("Failed to merge values.yaml, using original content", zap.Error(err))
-					} else {
-						updatedValuesYAML = mergedValues
-					}
-				} else {
-					// Patch applied successfully
-					updatedValuesYAML = newContent
-				}
-			} else {
-				// It's not a patch, use the normal merging approach
-				mergedValues, err := mergeValuesYAML(opts.ValuesYAML, artifact.Content)
-				if err != nil {
-					logger.Warn("Failed to merge values.yaml, using original content", zap.Error(err))
-				} else {
-					updatedValuesYAML = mergedValues
-				}
-			}
-		} else {
-			artifactsMap[artifact.Path] = artifact.Content
-		}
-	}
-
-	return artifactsMap, updatedValuesYAML, nil
-}
-
-func convertFileUsingClaude(ctx context.Context, opts ConvertFileOpts) (map[string]string, string, error) {
-	client, err := newAnthropicClient(ctx)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get anthropic client: %w", err)
-	}
-
-	messages := []anthropic.MessageParam{
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(executePlanSystemPrompt)),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(convertFileSystemPrompt)),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`
-Here is the existing values.yaml file:
----
-%s
----
-			`, opts.ValuesYAML)),
-		),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`
-Convert the following Kubernetes manifest to a helm template:
----
-%s
----
-			`, opts.Content)),
-		),
-	}
-
-	// Use model from opts, default to DefaultModel if not set
-	modelID := opts.ModelID
-	if modelID == "" || isOpenRouterModel(modelID) {
-		modelID = DefaultModel
-	}
-
-	response, err := client.Messages.New(context.TODO(), anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		Messages:  anthropic.F(messages),
-	})
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create message: %w", err)
-	}
-
-	artifacts, err := parseArtifactsInResponse(response.Content[0].Text)
+	artifacts, err := parseArtifactsInResponse(response)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to parse artifacts: %w", err)
 	}
@@ -364,46 +268,3 @@ func mergeValuesYAML(existingYAML, newYAML string) (string, error) {
 
 	return string(mergedYAML), nil
 }
-
-// convertFileUsingOpenRouter converts a file using OpenRouter
-func convertFileUsingOpenRouter(ctx context.Context, opts ConvertFileOpts) (map[string]string, string, error) {
-	userMessage := fmt.Sprintf(`
-Here is the existing values.yaml file:
----
-%s
----
-
-Convert the following Kubernetes manifest to a helm template:
----
-%s
----
-	`, opts.ValuesYAML, opts.Content)
-
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: executePlanSystemPrompt + "\n\n" + convertFileSystemPrompt},
-		{Role: "user", Content: userMessage},
-	}
-
-	response, err := callOpenRouter(ctx, opts.ModelID, messages, 8192)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to call OpenRouter API: %w", err)
-	}
-
-	artifacts, err := parseArtifactsInResponse(response)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse artifacts: %w", err)
-	}
-
-	artifactsMap := make(map[string]string)
-	var updatedValuesYAML string
-
-	for _, artifact := range artifacts {
-		if artifact.Path == "values.yaml" {
-			updatedValuesYAML = artifact.Content
-		} else {
-			artifactsMap[artifact.Path] = artifact.Content
-		}
-	}
-
-	return artifactsMap, updatedValuesYAML, nil
-}
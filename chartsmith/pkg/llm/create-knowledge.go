@@ -1,42 +1,5 @@
 package llm
 
-const detailedPlanInstructions = `
-Provide a detailed plan for the high level plan outlined here.
-`
-
-const chatOnlyInstructions = `
-- You will be asked to answer a question.
-- You will be given the question and the context of the question.
-- You will be given the current chat history.
-- You will be asked to answer the question based on the context and the chat history.
-- You can be technical in your response and include inline code snippets identifed with Markdown when appropriate.
-- Never use the <chartsmithArtifact> tag in your response.
-`
-
-const initialPlanInstructions = `
-- Describe a general plan for creating a new helm chart based on the user request.
-- The user will provide a chart to start from. You shoud be inspired by this, but it's not important to copy it exactly.
-- Refer the the process as "creating" a chart, not "editing" a chart.
-- The user is a developer who understands Helm and Kubernetes.
-- You can be technical in your response, but don't write code.
-- Avoid refering to the base chart in your response. For the purpose of this plan, you will describe your plan as if you are creating a new chart.
-- Minimize the use of bullet lists in your response.
-- Be specific when describing the types of environments and versions of Kubernetes and Helm you will support.
-- Be specific when describing any and all end customer requirements you are aware of.
-- Be specific when describing any dependencies you are including.
-`
-
-const updatePlanInstructions = `
-- Describe a general plan for editing an existing helm chart based on the user request.
-- The user already has a chart. You will be given the chart structure and the files that are relevant to the user request.
-- The user is a developer who understands Helm and Kubernetes.
-- You can be technical in your response, but don't write code.
-- Minimize the use of bullet lists in your response.
-- Be specific when describing any changes to the types of environments and versions of Kubernetes and Helm you will support.
-- Be specific when describing any and all changed end customer requirements you are aware of.
-- Be specific when describing any new dependencies you are including or removing.
-`
-
 const createKnowledge = `
 - If the chart is named 'new-chart', rename it to an appopriate name. The word "replicated" is not part of the name.
 - If the chart is named 'new-chart', don't share that we are editing a chart or transforming a chart. Phrase everything as if we are creating a new chart.
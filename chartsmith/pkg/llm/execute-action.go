@@ -7,10 +7,8 @@ import (
 	"strings"
 	"time"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	llmtypes "github.com/replicatedhq/chartsmith/pkg/llm/types"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
-	"github.com/replicatedhq/chartsmith/pkg/param"
 	"github.com/replicatedhq/chartsmith/pkg/persistence"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
 	"github.com/tuvistavie/securerandom"
@@ -86,9 +84,10 @@ func logStrReplaceOperation(ctx context.Context, filePath, oldStr, newStr string
 		old_str_len,
 		new_str_len,
 		context_before,
-		context_after
+		context_after,
+		prompt_version
 	) VALUES (
-		$1, NOW(), $2, $3, $4, $5, $6, $7, $8, $9, $10
+		$1, NOW(), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 	) RETURNING id`
 
 	var returnedID string
@@ -102,7 +101,8 @@ func logStrReplaceOperation(ctx context.Context, filePath, oldStr, newStr string
 		len(oldStr),
 		len(newStr),
 		contextBefore,
-		contextAfter).Scan(&returnedID)
+		contextAfter,
+		promptVersionFor(ctx)).Scan(&returnedID)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert str_replace_log: %w", err)
@@ -435,8 +435,13 @@ func findBestMatchRegion(content, oldStr string, minMatchLen int) (int, int) {
 	return -1, -1
 }
 
-func ExecuteAction(ctx context.Context, actionPlanWithPath llmtypes.ActionPlanWithPath, plan *workspacetypes.Plan, currentContent string, interimContentCh chan string, modelID string) (string, error) {
-	updatedContent := currentContent
+// ExecuteAction edits the file of an action with the text_editor tool. files is the content of the files
+// in the chart by path, without the action's file if it doesn't exist yet. When multi-file actions are
+// enabled the model can also view and edit the other files. Each edit is sent to interimContentCh, and
+// the content of every file that changed is returned once the action is complete
+func ExecuteAction(ctx context.Context, actionPlanWithPath llmtypes.ActionPlanWithPath, plan *workspacetypes.Plan, files map[string]string, interimContentCh chan llmtypes.Artifact, modelID string) (map[string]string, error) {
+	ctx = WithUsageScope(withStage(ctx, StageExecuteAction), PlanUsageScope(plan))
+	session := newTextEditorSession(actionPlanWithPath.Path, files, multiFileActionsEnabled(), interimContentCh)
 	lastActivity := time.Now()
 
 	// Create a goroutine to monitor for activity timeouts and a channel for errors
@@ -476,485 +481,91 @@ func ExecuteAction(ctx context.Context, actionPlanWithPath llmtypes.ActionPlanWi
 	// Make sure to close the activity monitor when we're done
 	defer close(activityDone)
 
-	// Default to DefaultOpenRouterModel if modelID is empty
-	effectiveModelID := modelID
-	if effectiveModelID == "" {
-		effectiveModelID = DefaultOpenRouterModel
-	}
-
-	// Route to appropriate implementation based on model
-	if isOpenRouterModel(effectiveModelID) {
-		return executeActionOpenRouter(ctx, actionPlanWithPath, plan, currentContent, interimContentCh, effectiveModelID, &updatedContent, &lastActivity, activityDone, errCh)
-	}
-
-	// Use Anthropic implementation
-	if param.Get().AnthropicAPIKey == "" {
-		return "", fmt.Errorf("ExecuteAction requires Anthropic API key for tool calling. Please set ANTHROPIC_API_KEY environment variable")
+	executePlanPrompt, err := renderPrompt(ctx, promptExecutePlan, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	client, err := newAnthropicClient(ctx)
+	instructions, err := renderPrompt(ctx, promptDetailedPlanInstructions, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create Anthropic client for action execution: %w", err)
+		return nil, err
 	}
 
-	messages := []anthropic.MessageParam{
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(executePlanSystemPrompt)),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(detailedPlanInstructions)),
+	messages := []Message{
+		{Role: RoleSystem, Content: executePlanPrompt},
+		{Role: RoleUser, Content: instructions},
+		{Role: RoleAssistant, Content: plan.Description},
 	}
 
 	// Add more explicit instructions about the file workflow
 	workflowInstructions := `
 		Important workflow instructions:
 		1. For ANY file operation, ALWAYS use "view" command first to check if a file exists and view its contents.
-		2. Only after viewing, decide whether to use "create" (if file doesn't exist) or "str_replace" (if file exists).
+		2. Only after viewing, decide whether to use "create" (if file doesn't exist) or "str_replace" or "insert" (if file exists).
 		3. Never use "create" on an existing file.
+		4. Use "undo_edit" to revert your last edit to a file if it was wrong.
 		`
 
-	messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(plan.Description)))
-
 	if actionPlanWithPath.Action == "create" {
 		logger.Debug("create file", zap.String("path", actionPlanWithPath.Path))
 		createMessage := fmt.Sprintf("Create the file at %s", actionPlanWithPath.Path)
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(workflowInstructions+createMessage)))
+		messages = append(messages, Message{Role: RoleUser, Content: workflowInstructions + createMessage})
 	} else if actionPlanWithPath.Action == "update" {
 		logger.Debug("update file", zap.String("path", actionPlanWithPath.Path))
 		updateMessage := fmt.Sprintf(`The file at %s needs to be updated according to the plan.`,
 			actionPlanWithPath.Path)
-
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(workflowInstructions+updateMessage)))
+		messages = append(messages, Message{Role: RoleUser, Content: workflowInstructions + updateMessage})
 	}
 
-	tools := []anthropic.ToolParam{
-		{
-			Name: anthropic.F(TextEditor_Sonnet35),
-			InputSchema: anthropic.F(interface{}(map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"command": map[string]interface{}{
-						"type": "string",
-						"enum": []string{"view", "str_replace", "create"},
-					},
-					"path": map[string]interface{}{
-						"type": "string",
-					},
-					"old_str": map[string]interface{}{
-						"type": "string",
-					},
-					"new_str": map[string]interface{}{
-						"type": "string",
-					},
-				},
-			})),
-		},
+	if actionPlanWithPath.Description != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("The change planned for %s: %s", actionPlanWithPath.Path, actionPlanWithPath.Description)})
 	}
 
-	toolUnionParams := make([]anthropic.ToolUnionUnionParam, len(tools))
-	for i, tool := range tools {
-		toolUnionParams[i] = tool
+	if session.multiFile {
+		messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf(`You can also view and edit these files in the chart when the change depends on them, for example to add a helper that %s uses: %s`,
+			actionPlanWithPath.Path, strings.Join(session.otherPaths(), ", "))})
 	}
 
-	var disabled anthropic.ThinkingConfigEnabledType
-	disabled = "disabled"
-
-	for {
-		stream := client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-			Model:     anthropic.F(effectiveModelID),
-			MaxTokens: anthropic.F(int64(8192)),
-			Messages:  anthropic.F(messages),
-			Tools:     anthropic.F(toolUnionParams),
-			Thinking: anthropic.F[anthropic.ThinkingConfigParamUnion](anthropic.ThinkingConfigEnabledParam{
-				Type: anthropic.F(disabled),
-			}),
-		})
-
-		message := anthropic.Message{}
-		for stream.Next() {
-			event := stream.Current()
-			err := message.Accumulate(event)
-			if err != nil {
-				return "", err
-			}
-
-			switch event := event.AsUnion().(type) {
-			case anthropic.ContentBlockDeltaEvent:
-				if event.Delta.Text != "" {
-					fmt.Printf("%s", event.Delta.Text)
-				}
-			}
+	handleTool := func(ctx context.Context, call ToolCall) (string, error) {
+		if call.Name != TextEditor_Sonnet35 {
+			return fmt.Sprintf("Error: Unknown tool %s", call.Name), nil
 		}
 
-		if stream.Err() != nil {
-			return "", stream.Err()
+		var input textEditorInput
+		if err := json.Unmarshal(call.Arguments, &input); err != nil {
+			return "", fmt.Errorf("failed to unmarshal tool input: %w", err)
 		}
 
-		messages = append(messages, message.ToParam())
-
-		hasToolCalls := false
-		toolResults := []anthropic.ContentBlockParamUnion{}
-
-		for _, block := range message.Content {
-			if block.Type == anthropic.ContentBlockTypeToolUse {
-				hasToolCalls = true
-				var response interface{}
-
-				var input struct {
-					Command string `json:"command"`
-					Path    string `json:"path"`
-					OldStr  string `json:"old_str"`
-					NewStr  string `json:"new_str"`
-				}
-
-				if err := json.Unmarshal(block.Input, &input); err != nil {
-					return "", err
-				}
+		// Update last activity timestamp on each tool use
+		lastActivity = time.Now()
 
-				// Update last activity timestamp on each tool use
-				lastActivity = time.Now()
-
-				logger.Info("LLM text_editor tool use",
-					zap.String("command", input.Command),
-					zap.String("path", input.Path),
-					zap.Int("old_str_len", len(input.OldStr)),
-					zap.Int("new_str_len", len(input.NewStr)))
-
-				if input.Command == "view" {
-					if updatedContent == "" {
-						// File doesn't exist yet
-						response = "Error: File does not exist. Use create instead."
-					} else {
-						response = updatedContent
-					}
-				} else if input.Command == "str_replace" {
-					// First check if the string is found in the content for logging
-					found := strings.Contains(updatedContent, input.OldStr)
+		logger.Info("LLM text_editor tool use",
+			zap.String("command", input.Command),
+			zap.String("path", input.Path),
+			zap.Int("old_str_len", len(input.OldStr)),
+			zap.Int("new_str_len", len(input.NewStr)))
 
-					// Log every str_replace operation, successful or not
-					if err := logStrReplaceOperation(ctx, input.Path, input.OldStr, input.NewStr, updatedContent, found); err != nil {
-						logger.Warn("str_replace logging failed", zap.Error(err))
-					}
+		response := session.handle(ctx, input)
 
-					// Perform the actual string replacement with our extracted function
-					logger.Debug("performing string replacement")
-					newContent, success, replaceErr := PerformStringReplacement(updatedContent, input.OldStr, input.NewStr)
-					logger.Debug("string replacement complete", zap.String("success", fmt.Sprintf("%t", success)))
-
-					if !success {
-						// Create error message and update the log
-						errorMsg := "String to replace not found in file"
-						if replaceErr != nil {
-							errorMsg = replaceErr.Error()
-						}
-
-						// Update the error message in the database
-						logger.Debug("updating error message in str_replace log", zap.String("error_msg", errorMsg))
-						if err := UpdateStrReplaceLogErrorMessage(ctx, input.Path, input.OldStr, errorMsg); err != nil {
-							logger.Warn("Failed to update error message in str_replace log", zap.Error(err))
-						}
-
-						response = "Error: String to replace not found in file. Please use smaller, more precise replacements."
-					} else {
-						updatedContent = newContent
-
-						// Send updated content through the channel
-						interimContentCh <- updatedContent
-						response = "Content replaced successfully"
-					}
-				} else if input.Command == "create" {
-					if updatedContent != "" {
-						response = "Error: File already exists. Use view and str_replace instead."
-					} else {
-						updatedContent = input.NewStr
-
-						interimContentCh <- updatedContent
-						response = "Created"
-					}
-				}
-
-				b, err := json.Marshal(response)
-				if err != nil {
-					return "", err
-				}
-
-				toolResults = append(toolResults, anthropic.NewToolResultBlock(block.ID, string(b), false))
-			}
-		}
-
-		if !hasToolCalls {
-			break
+		b, err := json.Marshal(response)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal tool response: %w", err)
 		}
 
-		messages = append(messages, anthropic.MessageParam{
-			Role:    anthropic.F(anthropic.MessageParamRoleUser),
-			Content: anthropic.F(toolResults),
-		})
+		return string(b), nil
 	}
 
-	return updatedContent, nil
-}
-
-// executeActionOpenRouter handles action execution using OpenRouter with function calling
-func executeActionOpenRouter(ctx context.Context, actionPlanWithPath llmtypes.ActionPlanWithPath, plan *workspacetypes.Plan, currentContent string, interimContentCh chan string, modelID string, updatedContent *string, lastActivity *time.Time, activityDone chan struct{}, errCh chan error) (string, error) {
-	if param.Get().OpenRouterAPIKey == "" {
-		return "", fmt.Errorf("ExecuteAction requires OpenRouter API key. Please set OPENROUTER_API_KEY environment variable")
-	}
-
-	// Define the text editor function for OpenRouter
-	textEditorFunction := OpenRouterFunction{
-		Name:        TextEditor_Sonnet35,
-		Description: "Text editor tool for viewing, creating, and modifying files",
-		Parameters: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"command": map[string]interface{}{
-					"type": "string",
-					"enum": []string{"view", "str_replace", "create"},
-				},
-				"path": map[string]interface{}{
-					"type": "string",
-				},
-				"old_str": map[string]interface{}{
-					"type": "string",
-				},
-				"new_str": map[string]interface{}{
-					"type": "string",
-				},
-			},
-			"required": []string{"command", "path"},
-		},
-	}
-
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: executePlanSystemPrompt},
-		{Role: "user", Content: detailedPlanInstructions},
-		{Role: "assistant", Content: plan.Description},
-	}
-
-	workflowInstructions := `
-		Important workflow instructions:
-		1. For ANY file operation, ALWAYS use "view" command first to check if a file exists and view its contents.
-		2. Only after viewing, decide whether to use "create" (if file doesn't exist) or "str_replace" (if file exists).
-		3. Never use "create" on an existing file.
-		`
-
-	if actionPlanWithPath.Action == "create" {
-		logger.Debug("create file", zap.String("path", actionPlanWithPath.Path))
-		createMessage := fmt.Sprintf("Create the file at %s", actionPlanWithPath.Path)
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: workflowInstructions + createMessage})
-	} else if actionPlanWithPath.Action == "update" {
-		logger.Debug("update file", zap.String("path", actionPlanWithPath.Path))
-		updateMessage := fmt.Sprintf(`The file at %s needs to be updated according to the plan.`, actionPlanWithPath.Path)
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: workflowInstructions + updateMessage})
+	chain, err := resolveChain(ctx, modelID)
+	if err != nil {
+		return nil, err
 	}
 
-	for {
-		// Make API call with function calling
-		resp, err := callOpenRouterWithFunctions(ctx, modelID, messages, []OpenRouterFunction{textEditorFunction}, 8192)
-		if err != nil {
-			return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
-		}
-
-		if len(resp.Choices) == 0 {
-			return "", fmt.Errorf("no choices in OpenRouter response")
-		}
-
-		choice := resp.Choices[0]
-		message := choice.Message
-
-		// Add assistant message to conversation
-		assistantMsg := OpenRouterMessage{
-			Role:    "assistant",
-			Content: message.Content,
-		}
-		// Support both tool_calls (new format) and function_call (legacy)
-		if len(message.ToolCalls) > 0 {
-			// For tool_calls format, we'll handle it below
-		} else if message.FunctionCall != nil {
-			assistantMsg.FunctionCall = message.FunctionCall
-		}
-		messages = append(messages, assistantMsg)
-
-		// Check for tool calls (new format) or function call (legacy)
-		var toolCall *OpenRouterToolCall
-		var functionCall *OpenRouterFunctionCall
-		
-		if len(message.ToolCalls) > 0 {
-			// Use first tool call
-			toolCall = &message.ToolCalls[0]
-		} else if message.FunctionCall != nil {
-			functionCall = message.FunctionCall
-		}
-
-		// Process tool call or function call
-		if toolCall != nil && toolCall.Function.Name == TextEditor_Sonnet35 {
-			// Parse function arguments from tool call
-			var input struct {
-				Command string `json:"command"`
-				Path    string `json:"path"`
-				OldStr  string `json:"old_str"`
-				NewStr  string `json:"new_str"`
-			}
-
-			// ToolCall.Function.Arguments is a JSON string
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &input); err != nil {
-				return "", fmt.Errorf("failed to unmarshal tool call input: %w", err)
-			}
-
-			// Update last activity timestamp
-			*lastActivity = time.Now()
-
-			logger.Info("LLM text_editor tool call",
-				zap.String("command", input.Command),
-				zap.String("path", input.Path),
-				zap.Int("old_str_len", len(input.OldStr)),
-				zap.Int("new_str_len", len(input.NewStr)))
-
-			var response interface{}
-
-			if input.Command == "view" {
-				if *updatedContent == "" {
-					response = "Error: File does not exist. Use create instead."
-				} else {
-					response = *updatedContent
-				}
-			} else if input.Command == "str_replace" {
-				found := strings.Contains(*updatedContent, input.OldStr)
-
-				if err := logStrReplaceOperation(ctx, input.Path, input.OldStr, input.NewStr, *updatedContent, found); err != nil {
-					logger.Warn("str_replace logging failed", zap.Error(err))
-				}
-
-				logger.Debug("performing string replacement")
-				newContent, success, replaceErr := PerformStringReplacement(*updatedContent, input.OldStr, input.NewStr)
-				logger.Debug("string replacement complete", zap.String("success", fmt.Sprintf("%t", success)))
-
-				if !success {
-					errorMsg := "String to replace not found in file"
-					if replaceErr != nil {
-						errorMsg = replaceErr.Error()
-					}
-
-					if err := UpdateStrReplaceLogErrorMessage(ctx, input.Path, input.OldStr, errorMsg); err != nil {
-						logger.Warn("Failed to update error message in str_replace log", zap.Error(err))
-					}
-
-					response = "Error: String to replace not found in file. Please use smaller, more precise replacements."
-				} else {
-					*updatedContent = newContent
-					interimContentCh <- *updatedContent
-					response = "Content replaced successfully"
-				}
-			} else if input.Command == "create" {
-				if *updatedContent != "" {
-					response = "Error: File already exists. Use view and str_replace instead."
-				} else {
-					*updatedContent = input.NewStr
-					interimContentCh <- *updatedContent
-					response = "Created"
-				}
-			}
-
-			// Add tool result to messages (OpenAI format)
-			responseJSON, err := json.Marshal(response)
-			if err != nil {
-				return "", fmt.Errorf("failed to marshal tool response: %w", err)
-			}
-
-			messages = append(messages, OpenRouterMessage{
-				Role:    "tool",
-				Content: string(responseJSON),
-				Name:    TextEditor_Sonnet35,
-			})
-
-			// Continue loop to get next response
-			continue
-		} else if functionCall != nil && functionCall.Name == TextEditor_Sonnet35 {
-			// Parse function arguments
-			var input struct {
-				Command string `json:"command"`
-				Path    string `json:"path"`
-				OldStr  string `json:"old_str"`
-				NewStr  string `json:"new_str"`
-			}
-
-			// FunctionCall.Arguments is a JSON string, unmarshal directly
-			if err := json.Unmarshal([]byte(functionCall.Arguments), &input); err != nil {
-				return "", fmt.Errorf("failed to unmarshal function input: %w", err)
-			}
-
-			// Update last activity timestamp
-			*lastActivity = time.Now()
-
-			logger.Info("LLM text_editor function call",
-				zap.String("command", input.Command),
-				zap.String("path", input.Path),
-				zap.Int("old_str_len", len(input.OldStr)),
-				zap.Int("new_str_len", len(input.NewStr)))
-
-			var response interface{}
-
-			if input.Command == "view" {
-				if *updatedContent == "" {
-					response = "Error: File does not exist. Use create instead."
-				} else {
-					response = *updatedContent
-				}
-			} else if input.Command == "str_replace" {
-				found := strings.Contains(*updatedContent, input.OldStr)
-
-				if err := logStrReplaceOperation(ctx, input.Path, input.OldStr, input.NewStr, *updatedContent, found); err != nil {
-					logger.Warn("str_replace logging failed", zap.Error(err))
-				}
-
-				logger.Debug("performing string replacement")
-				newContent, success, replaceErr := PerformStringReplacement(*updatedContent, input.OldStr, input.NewStr)
-				logger.Debug("string replacement complete", zap.String("success", fmt.Sprintf("%t", success)))
-
-				if !success {
-					errorMsg := "String to replace not found in file"
-					if replaceErr != nil {
-						errorMsg = replaceErr.Error()
-					}
-
-					if err := UpdateStrReplaceLogErrorMessage(ctx, input.Path, input.OldStr, errorMsg); err != nil {
-						logger.Warn("Failed to update error message in str_replace log", zap.Error(err))
-					}
-
-					response = "Error: String to replace not found in file. Please use smaller, more precise replacements."
-				} else {
-					*updatedContent = newContent
-					interimContentCh <- *updatedContent
-					response = "Content replaced successfully"
-				}
-			} else if input.Command == "create" {
-				if *updatedContent != "" {
-					response = "Error: File already exists. Use view and str_replace instead."
-				} else {
-					*updatedContent = input.NewStr
-					interimContentCh <- *updatedContent
-					response = "Created"
-				}
-			}
-
-			// Add function result to messages (legacy format)
-			responseJSON, err := json.Marshal(response)
-			if err != nil {
-				return "", fmt.Errorf("failed to marshal function response: %w", err)
-			}
-
-			// For OpenRouter legacy format, function results are added as a message with role "function"
-			messages = append(messages, OpenRouterMessage{
-				Role:    "function",
-				Name:    TextEditor_Sonnet35,
-				Content: string(responseJSON),
-			})
-
-			// Continue loop to get next response
-			continue
-		}
-
-		// No function call, we're done
-		break
+	_, err = runWithTools(ctx, chain, Request{
+		Messages: messages,
+		Tools:    []Tool{textEditorTool},
+	}, nil, handleTool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute action: %w", err)
 	}
 
-	return *updatedContent, nil
+	return session.changedFiles(), nil
 }
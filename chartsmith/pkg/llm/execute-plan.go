@@ -2,9 +2,10 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	types "github.com/replicatedhq/chartsmith/pkg/llm/types"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
@@ -12,6 +13,7 @@ import (
 )
 
 func CreateExecutePlan(ctx context.Context, planActionCreatedCh chan types.ActionPlanWithPath, streamCh chan string, doneCh chan error, w *workspacetypes.Workspace, plan *workspacetypes.Plan, c *workspacetypes.Chart, relevantFiles []workspacetypes.File, modelID string) error {
+	ctx = WithUsageScope(withStage(ctx, StageExecutePlan), PlanUsageScope(plan))
 	logger.Debug("Creating execution plan",
 		zap.String("workspace_id", w.ID),
 		zap.String("chart_id", c.ID),
@@ -19,180 +21,172 @@ func CreateExecutePlan(ctx context.Context, planActionCreatedCh chan types.Actio
 		zap.Int("relevant_files_len", len(relevantFiles)),
 	)
 
-	// Use OpenRouter if model is OpenRouter format
-	if isOpenRouterModel(modelID) {
-		return createExecutePlanOpenRouter(ctx, planActionCreatedCh, streamCh, doneCh, w, plan, c, relevantFiles, modelID)
+	useTools := planActionsUseTools()
+
+	messages, err := detailedPlanMessages(ctx, w, plan, c, relevantFiles, useTools)
+	if err != nil {
+		return err
 	}
 
-	// Use Anthropic
-	client, err := newAnthropicClient(ctx)
+	err = streamDetailedPlan(ctx, planActionCreatedCh, streamCh, c, messages, modelID, useTools)
+	if err != nil && useTools && errors.Is(err, errProviderUnavailable) {
+		// none of the models can call tools, ask for the plan in the tag format instead
+		logger.Warn("Models for detailed plan can't call tools, falling back to tags", zap.Error(err))
+		messages, err = detailedPlanMessages(ctx, w, plan, c, relevantFiles, false)
+		if err != nil {
+			return err
+		}
+		err = streamDetailedPlan(ctx, planActionCreatedCh, streamCh, c, messages, modelID, false)
+	}
 	if err != nil {
+		doneCh <- err
 		return err
 	}
 
-	messages := []anthropic.MessageParam{
-		anthropic.NewAssistantMessage(anthropic.NewTextBlock(detailedPlanSystemPrompt)),
-		anthropic.NewUserMessage(anthropic.NewTextBlock(detailedPlanInstructions)),
+	doneCh <- nil
+
+	// The plan will be set to "applied" status when all actions are complete
+
+	return nil
+}
+
+func detailedPlanMessages(ctx context.Context, w *workspacetypes.Workspace, plan *workspacetypes.Plan, c *workspacetypes.Chart, relevantFiles []workspacetypes.File, useTools bool) ([]Message, error) {
+	planPrompt := promptDetailedPlan
+	if useTools {
+		planPrompt = promptDetailedPlanTools
+	}
+
+	systemPrompt, err := renderPrompts(ctx, planPrompt, promptDetailedPlanInstructions)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []Message{
+		{Role: RoleSystem, Content: systemPrompt},
 	}
 
 	if w.CurrentRevision == 0 {
 		bootsrapChartUserMessage, err := summarizeBootstrapChart(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to summarize bootstrap chart: %w", err)
+			return nil, fmt.Errorf("failed to summarize bootstrap chart: %w", err)
 		}
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(bootsrapChartUserMessage)))
+		messages = append(messages, Message{Role: RoleUser, Content: bootsrapChartUserMessage})
 	} else {
 		chartStructure, err := getChartStructure(ctx, c)
 		if err != nil {
-			return fmt.Errorf("failed to get chart structure: %w", err)
+			return nil, fmt.Errorf("failed to get chart structure: %w", err)
 		}
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`I am working on a Helm chart that has the following structure: %s`, chartStructure))))
+		messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("I am working on a Helm chart that has the following structure: %s", chartStructure)})
 
 		for _, file := range relevantFiles {
-			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`File: %s, Content: %s`, file.FilePath, file.Content))))
+			messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("File: %s, Content: %s", file.FilePath, file.Content)})
 		}
 	}
 
-	messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(plan.Description)))
+	messages = append(messages, Message{Role: RoleUser, Content: plan.Description})
 
-	stream := client.Messages.NewStreaming(context.TODO(), anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		Messages:  anthropic.F(messages),
-	})
+	return messages, nil
+}
 
-	fullResponseWithTags := ""
+// streamDetailedPlan streams the detailed plan to streamCh and sends each file the model adds to the plan to
+// planActionCreatedCh. With useTools, files are added with add_file_action tool calls, and invalid calls are sent
+// back to the model to correct. Files in <chartsmithActionPlan> tags in the text are always accepted too
+func streamDetailedPlan(ctx context.Context, planActionCreatedCh chan types.ActionPlanWithPath, streamCh chan string, c *workspacetypes.Chart, messages []Message, modelID string, useTools bool) error {
+	var mu sync.Mutex
 	actionPlans := make(map[string]types.ActionPlan)
 
-	message := anthropic.Message{}
-	for stream.Next() {
-		event := stream.Current()
-		message.Accumulate(event)
+	// addAction sends the action to the caller unless the path is already in the plan
+	addAction := func(actionPlanWithPath types.ActionPlanWithPath) bool {
+		mu.Lock()
+		defer mu.Unlock()
 
-		switch delta := event.Delta.(type) {
-		case anthropic.ContentBlockDeltaEventDelta:
-			if delta.Text != "" {
-				fullResponseWithTags += delta.Text
-
-				aps, err := parseActionsInResponse(fullResponseWithTags)
-				if err != nil {
-					return fmt.Errorf("error parsing artifacts in response: %w", err)
-				}
-
-				for path, action := range aps {
-					// only add if the full struct is there
-					if path != "" && action.Type != "" && action.Action != "" {
-						// if the item is not already in the map, we need to stream it back to the caller
-						if _, ok := actionPlans[path]; !ok {
-							action.Status = types.ActionPlanStatusPending
-							actionPlanWithPath := types.ActionPlanWithPath{
-								Path:       path,
-								ActionPlan: action,
-							}
-							planActionCreatedCh <- actionPlanWithPath
-						}
-
-						actionPlans[path] = action
-					}
-				}
-			}
+		if _, ok := actionPlans[actionPlanWithPath.Path]; ok {
+			return false
 		}
-	}
 
-	if stream.Err() != nil {
-		doneCh <- stream.Err()
+		actionPlanWithPath.Status = types.ActionPlanStatusPending
+		actionPlans[actionPlanWithPath.Path] = actionPlanWithPath.ActionPlan
+		planActionCreatedCh <- actionPlanWithPath
+		return true
 	}
 
-	doneCh <- nil
-
-	// The plan will be set to "applied" status when all actions are complete
-
-	return nil
-}
-
-// createExecutePlanOpenRouter handles execute plan using OpenRouter
-func createExecutePlanOpenRouter(ctx context.Context, planActionCreatedCh chan types.ActionPlanWithPath, streamCh chan string, doneCh chan error, w *workspacetypes.Workspace, plan *workspacetypes.Plan, c *workspacetypes.Chart, relevantFiles []workspacetypes.File, modelID string) error {
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: detailedPlanSystemPrompt + "\n\n" + detailedPlanInstructions},
-	}
-
-	if w.CurrentRevision == 0 {
-		bootsrapChartUserMessage, err := summarizeBootstrapChart(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to summarize bootstrap chart: %w", err)
+	handleTool := func(ctx context.Context, call ToolCall) (string, error) {
+		if call.Name != planActionToolName {
+			return fmt.Sprintf("Error: Unknown tool %s", call.Name), nil
 		}
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: bootsrapChartUserMessage})
-	} else {
-		chartStructure, err := getChartStructure(ctx, c)
+
+		actionPlanWithPath, err := parsePlanActionInput(call.Arguments, c)
 		if err != nil {
-			return fmt.Errorf("failed to get chart structure: %w", err)
+			logger.Debug("Invalid plan action from model", zap.String("arguments", string(call.Arguments)), zap.Error(err))
+			return fmt.Sprintf("Error: %s", err), nil
 		}
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: fmt.Sprintf("I am working on a Helm chart that has the following structure: %s", chartStructure)})
 
-		for _, file := range relevantFiles {
-			messages = append(messages, OpenRouterMessage{Role: "user", Content: fmt.Sprintf("File: %s, Content: %s", file.FilePath, file.Content)})
+		if !addAction(*actionPlanWithPath) {
+			return fmt.Sprintf("Error: %s is already in the plan, each file can only be added once", actionPlanWithPath.Path), nil
 		}
-	}
 
-	messages = append(messages, OpenRouterMessage{Role: "user", Content: plan.Description})
-
-	// Stream the response and parse actions
-	fullResponseWithTags := ""
-	actionPlans := make(map[string]types.ActionPlan)
+		return fmt.Sprintf("Added %s %s to the plan", actionPlanWithPath.Action, actionPlanWithPath.Path), nil
+	}
 
-	// Use a channel to collect streamed text
+	// Stream the response into textCh so the actions can be parsed as they arrive
 	textCh := make(chan string, 100)
 	errCh := make(chan error, 1)
 
 	go func() {
-		err := streamOpenRouter(ctx, modelID, messages, 8192, textCh)
+		defer close(textCh)
+
+		chain, err := resolveChain(ctx, modelID)
 		if err != nil {
 			errCh <- err
+			return
+		}
+
+		req := Request{
+			Messages: messages,
+		}
+		if useTools {
+			req.Tools = []Tool{planActionTool}
+		}
+
+		if _, err := runWithTools(ctx, chain, req, textCh, handleTool); err != nil {
+			errCh <- err
 		}
-		close(textCh)
 	}()
 
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				// Stream finished
-				doneCh <- nil
-				return nil
-			}
-			fullResponseWithTags += text
-			streamCh <- text
-
-			// Parse actions from accumulated response
-			aps, err := parseActionsInResponse(fullResponseWithTags)
-			if err != nil {
-				// Don't fail on parse errors, just log
-				logger.Error(fmt.Errorf("error parsing actions in response: %w", err))
-				continue
-			}
+	fullResponseWithTags := ""
+
+	for text := range textCh {
+		fullResponseWithTags += text
+		streamCh <- text
 
-			for path, action := range aps {
-				// only add if the full struct is there
-				if path != "" && action.Type != "" && action.Action != "" {
-					// if the item is not already in the map, we need to stream it back to the caller
-					if _, ok := actionPlans[path]; !ok {
-						action.Status = types.ActionPlanStatusPending
-						actionPlanWithPath := types.ActionPlanWithPath{
-							Path:       path,
-							ActionPlan: action,
-						}
-						planActionCreatedCh <- actionPlanWithPath
-					}
-
-					actionPlans[path] = action
+		aps, err := parseActionsInResponse(fullResponseWithTags)
+		if err != nil {
+			// Don't fail on parse errors, the rest of the response may complete the action
+			logger.Error(fmt.Errorf("error parsing actions in response: %w", err))
+			continue
+		}
+
+		for path, action := range aps {
+			// only add if the full struct is there
+			if path != "" && action.Type != "" && action.Action != "" {
+				if action.Action == workspacetypes.ActionFileActionRename && action.NewPath == "" {
+					logger.Debug("Skipping rename without a new path", zap.String("path", path))
+					continue
 				}
+
+				addAction(types.ActionPlanWithPath{
+					Path:       path,
+					ActionPlan: action,
+				})
 			}
-		case err := <-errCh:
-			doneCh <- err
-			return err
-		case <-ctx.Done():
-			doneCh <- ctx.Err()
-			return ctx.Err()
 		}
 	}
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+	}
+
+	return nil
 }
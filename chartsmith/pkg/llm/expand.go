@@ -3,8 +3,6 @@ package llm
 import (
 	"context"
 	"fmt"
-
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 )
 
 func ExpandPrompt(ctx context.Context, prompt string) (string, error) {
@@ -13,17 +11,7 @@ func ExpandPrompt(ctx context.Context, prompt string) (string, error) {
 }
 
 func ExpandPromptWithModel(ctx context.Context, prompt string, modelID string) (string, error) {
-	// Use OpenRouter if model is OpenRouter format
-	if isOpenRouterModel(modelID) {
-		return expandPromptOpenRouter(ctx, prompt, modelID)
-	}
-
-	// Use Anthropic
-	client, err := newAnthropicClient(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to create anthropic client: %w", err)
-	}
-
+	ctx = withStage(ctx, StageExpand)
 	userMessage := fmt.Sprintf(`The following question is about developing a Helm chart.
 There is an existing chart that we will be editing.
 Look at the question, and help decide how to determine the existing files that are relevant to the question.
@@ -40,55 +28,17 @@ Here is the prompt:
 %s
 	`, prompt)
 
-	resp, err := client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		Messages:  anthropic.F([]anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(userMessage))}),
+	expandedPrompt, err := complete(ctx, modelID, []Message{
+		{Role: RoleUser, Content: userMessage},
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to call Anthropic API: %w", err)
-	}
-
-	// Check if response or response.Content is nil or empty
-	if resp == nil {
-		return "", fmt.Errorf("received nil response from Anthropic API")
+		return "", fmt.Errorf("failed to expand prompt: %w", err)
 	}
 
-	if len(resp.Content) == 0 {
-		return "", fmt.Errorf("received empty content from Anthropic API")
+	if expandedPrompt == "" {
+		return "", fmt.Errorf("received empty expanded prompt")
 	}
 
-	expandedPrompt := resp.Content[0].Text
-
 	// we can inject some keywords into the prompt to help the match in the vector search
 	return expandedPrompt, nil
 }
-
-func expandPromptOpenRouter(ctx context.Context, prompt string, modelID string) (string, error) {
-	userMessage := fmt.Sprintf(`The following question is about developing a Helm chart.
-There is an existing chart that we will be editing.
-Look at the question, and help decide how to determine the existing files that are relevant to the question.
-Try to structure the terms to be as specific as possible to avoid nearby matches.
-
-To do this, take the prompt below, and expand it to include specific terms that we should search for in the existing chart.
-
-If there are Kubernetes GVKs that are relevant to the question, include them prominently in the expanded prompt.
-
-The expanded prompt should be a single paragraph, and should be no more than 100 words.
-
-Here is the prompt:
-
-%s
-	`, prompt)
-
-	messages := []OpenRouterMessage{
-		{Role: "user", Content: userMessage},
-	}
-
-	expandedPrompt, err := callOpenRouter(ctx, modelID, messages, 8192)
-	if err != nil {
-		return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
-	}
-
-	return expandedPrompt, nil
-}
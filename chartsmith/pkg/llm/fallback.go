@@ -0,0 +1,440 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/workspace"
+	"go.uber.org/zap"
+)
+
+const (
+	// fallbackModelsEnvName lists the models to try, in order, when the requested model fails, e.g.
+	// CHARTSMITH_LLM_FALLBACK_MODELS=anthropic:claude-sonnet-4-5-20250929,anthropic/claude-sonnet-4.5
+	fallbackModelsEnvName = "CHARTSMITH_LLM_FALLBACK_MODELS"
+
+	// attemptsPerModel is how many times a request is sent to a model before moving on to the next one
+	attemptsPerModel = 3
+	initialBackoff   = 1 * time.Second
+	maxBackoff       = 10 * time.Second
+
+	// a provider's circuit opens after breakerThreshold retryable failures in a row. While it's open,
+	// requests skip the provider. Once breakerCooldown has passed the circuit is half open and a single
+	// probe request is let through. The circuit closes if the probe succeeds and opens again if it fails
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// errProviderUnavailable is wrapped by errors that mean a provider can't serve a request at all,
+// e.g. because it isn't configured, so the next model in the chain is tried without retrying
+var errProviderUnavailable = errors.New("provider unavailable")
+
+// APIError is an error response from the HTTP API of a provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error: %d - %s", e.Provider, e.StatusCode, e.Body)
+}
+
+type errorClass int
+
+const (
+	// errorFatal errors fail the request. Another model would fail the same way
+	errorFatal errorClass = iota
+	// errorRetryable errors are transient. The request is retried and then sent to the next model
+	errorRetryable
+	// errorUnavailable errors mean the provider can't serve the request, so the next model is tried right away
+	errorUnavailable
+)
+
+// classifyError decides whether a request that failed with err should be retried
+func classifyError(ctx context.Context, err error) errorClass {
+	// the caller gave up, so there's no point trying again
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return errorFatal
+	}
+
+	if errors.Is(err, errProviderUnavailable) {
+		return errorUnavailable
+	}
+
+	statusCode := 0
+	var apiErr *APIError
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		statusCode = apiErr.StatusCode
+	} else if errors.As(err, &anthropicErr) {
+		statusCode = anthropicErr.StatusCode
+	}
+
+	switch {
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusConflict,
+		statusCode == http.StatusTooManyRequests, statusCode >= 500:
+		// includes 529, which anthropic returns when it's overloaded
+		return errorRetryable
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden,
+		statusCode == http.StatusNotFound, statusCode == http.StatusPaymentRequired:
+		return errorUnavailable
+	case statusCode != 0:
+		return errorFatal
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return errorRetryable
+	}
+
+	// errors in the middle of a stream, and from clients that don't expose a status code
+	message := strings.ToLower(err.Error())
+	for _, transient := range []string{"overloaded", "rate limit", "timeout", "timed out", "connection reset", "429", "503", "529"} {
+		if strings.Contains(message, transient) {
+			return errorRetryable
+		}
+	}
+
+	return errorFatal
+}
+
+// backoff returns a random delay before retry number attempt, up to a limit that doubles each attempt
+func backoff(attempt int) time.Duration {
+	limit := initialBackoff << attempt
+	if limit <= 0 || limit > maxBackoff {
+		limit = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(limit)))
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops requests to a provider that keeps failing
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	// probing is true while the single request allowed through a half open circuit hasn't finished
+	probing bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*circuitBreaker{}
+)
+
+func breakerFor(providerName string) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[providerName]
+	if !ok {
+		b = &circuitBreaker{}
+		breakers[providerName] = b
+	}
+	return b
+}
+
+// allow returns true if a request can be sent to the provider. Every allowed request must be followed
+// by a call to success, failure or release
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// failure records a retryable failure and returns true if the circuit opened because of it
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.open()
+		return true
+	}
+
+	b.failures++
+	if b.state == breakerOpen || b.failures < breakerThreshold {
+		return false
+	}
+
+	b.open()
+	return true
+}
+
+// release ends a request that failed for a reason that says nothing about the health of the provider,
+// e.g. an invalid request, so that a half open circuit can be probed by the next request
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) open() {
+	b.state = breakerOpen
+	b.openUntil = time.Now().Add(breakerCooldown)
+	b.probing = false
+}
+
+// fallbackModels returns the models to try after the requested model for stage. A list set for
+// the stage, e.g. CHARTSMITH_LLM_FALLBACK_MODELS_EXECUTE_ACTION, replaces the list for all stages
+func fallbackModels(stage Stage) []string {
+	value := ""
+	if stage != "" {
+		value = os.Getenv(fallbackModelsEnvName + "_" + strings.ToUpper(string(stage)))
+	}
+	if value == "" {
+		value = os.Getenv(fallbackModelsEnvName)
+	}
+
+	modelIDs := []string{}
+	for _, modelID := range strings.Split(value, ",") {
+		if modelID = strings.TrimSpace(modelID); modelID != "" {
+			modelIDs = append(modelIDs, modelID)
+		}
+	}
+	return modelIDs
+}
+
+// chainTarget is one model in a fallback chain
+type chainTarget struct {
+	provider Provider
+	model    string
+}
+
+func (t chainTarget) String() string {
+	return t.provider.Name() + ":" + t.model
+}
+
+// modelChain is a Provider that sends a request to the requested model and, if it fails with a
+// retryable error, retries it with backoff and then tries each fallback model for the stage in order
+type modelChain struct {
+	targets []chainTarget
+
+	// served is the index of the target that served the last successful request, or -1
+	served int
+}
+
+var _ Provider = &modelChain{}
+
+// resolveChain returns the fallback chain for modelID in the stage of ctx. The chain is used in place of
+// the provider returned by ResolveModel, and the model of each target replaces the model in requests
+func resolveChain(ctx context.Context, modelID string) (*modelChain, error) {
+	provider, model, err := ResolveModel(modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &modelChain{
+		targets: []chainTarget{{provider: provider, model: model}},
+		served:  -1,
+	}
+
+	for _, fallbackID := range fallbackModels(stageFromContext(ctx)) {
+		provider, model, err := ResolveModel(fallbackID)
+		if err != nil {
+			logger.Warn("Skipping fallback model", zap.String("model", fallbackID), zap.Error(err))
+			continue
+		}
+
+		target := chainTarget{provider: provider, model: model}
+		if !chain.contains(target) {
+			chain.targets = append(chain.targets, target)
+		}
+	}
+
+	return chain, nil
+}
+
+func (c *modelChain) contains(target chainTarget) bool {
+	for _, t := range c.targets {
+		if t.String() == target.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// pinned returns a chain of only the target that served the last successful request, so that the
+// following turns of a conversation with tool calls stay on the same model. ok is false if no request
+// has succeeded yet
+func (c *modelChain) pinned() (*modelChain, bool) {
+	if c.served < 0 {
+		return nil, false
+	}
+
+	return &modelChain{
+		targets: []chainTarget{c.targets[c.served]},
+		served:  0,
+	}, true
+}
+
+// Name is the name of the provider of the requested model
+func (c *modelChain) Name() string {
+	return c.targets[0].provider.Name()
+}
+
+func (c *modelChain) Complete(ctx context.Context, req Request) (*Response, error) {
+	return c.run(ctx, func(target chainTarget) (*Response, bool, error) {
+		req.Model = target.model
+		resp, err := target.provider.Complete(ctx, req)
+		return resp, false, err
+	})
+}
+
+func (c *modelChain) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	return c.run(ctx, func(target chainTarget) (*Response, bool, error) {
+		req.Model = target.model
+
+		// watch for text reaching streamCh, after which the request can't be sent again
+		teeCh := make(chan string)
+		sentCh := make(chan bool)
+		go func() {
+			sent := false
+			for chunk := range teeCh {
+				if err := sendText(ctx, streamCh, chunk); err == nil && streamCh != nil && chunk != "" {
+					sent = true
+				}
+			}
+			sentCh <- sent
+		}()
+
+		resp, err := target.provider.Stream(ctx, req, teeCh)
+		close(teeCh)
+		sent := <-sentCh
+
+		return resp, sent, err
+	})
+}
+
+// run calls send with each target until one succeeds. send returns true if the response was partially
+// streamed, in which case the error is returned instead of trying again
+func (c *modelChain) run(ctx context.Context, send func(target chainTarget) (*Response, bool, error)) (*Response, error) {
+	var lastErr error
+	for i, target := range c.targets {
+		breaker := breakerFor(target.provider.Name())
+
+		for attempt := 0; attempt < attemptsPerModel; attempt++ {
+			if !breaker.allow() {
+				lastErr = fmt.Errorf("%w: circuit open for %s", errProviderUnavailable, target.provider.Name())
+				break
+			}
+
+			if attempt > 0 {
+				select {
+				case <-time.After(backoff(attempt)):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+
+			resp, partial, err := send(target)
+			if err == nil {
+				breaker.success()
+				c.served = i
+				if i > 0 {
+					logger.Info("LLM request served by fallback model",
+						zap.String("requested", c.targets[0].String()),
+						zap.String("model", target.String()))
+				}
+				recordResponseModel(ctx, target)
+				return resp, nil
+			}
+			lastErr = err
+
+			class := classifyError(ctx, err)
+			if class != errorRetryable {
+				breaker.release()
+			} else if breaker.failure() {
+				logger.Warn("Opened circuit for LLM provider", zap.String("provider", target.provider.Name()), zap.Duration("cooldown", breakerCooldown))
+			}
+			if partial || class == errorFatal {
+				return nil, err
+			}
+
+			logger.Warn("LLM request failed",
+				zap.String("model", target.String()),
+				zap.Int("attempt", attempt+1),
+				zap.Bool("retryable", class == errorRetryable),
+				zap.Error(err))
+
+			if class == errorUnavailable {
+				break
+			}
+		}
+	}
+
+	return nil, lastErr
+}
+
+type chatResponseKey struct{}
+
+// withChatResponse marks ctx as generating the response to the chat message in its usage scope, so that
+// the model that served the request is recorded on the chat message
+func withChatResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, chatResponseKey{}, true)
+}
+
+// recordResponseModel saves the model that served a request on the chat message it responded to.
+// Failing to save it doesn't fail the request
+func recordResponseModel(ctx context.Context, target chainTarget) {
+	if isChatResponse, _ := ctx.Value(chatResponseKey{}).(bool); !isChatResponse {
+		return
+	}
+
+	chatMessageID := usageScopeFromContext(ctx).ChatMessageID
+	if chatMessageID == "" {
+		return
+	}
+
+	if err := workspace.SetChatMessageResponseModel(ctx, chatMessageID, target.String()); err != nil {
+		logger.Error(fmt.Errorf("failed to record response model: %w", err))
+	}
+}
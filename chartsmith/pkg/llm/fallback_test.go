@@ -0,0 +1,311 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestClassifyError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want errorClass
+	}{
+		{name: "caller canceled", ctx: canceled, err: &APIError{StatusCode: 503}, want: errorFatal},
+		{name: "context canceled", err: fmt.Errorf("stream: %w", context.Canceled), want: errorFatal},
+		{name: "provider unavailable", err: fmt.Errorf("%w: circuit open for anthropic", errProviderUnavailable), want: errorUnavailable},
+		{name: "rate limited", err: &APIError{StatusCode: 429}, want: errorRetryable},
+		{name: "request timeout", err: &APIError{StatusCode: 408}, want: errorRetryable},
+		{name: "conflict", err: &APIError{StatusCode: 409}, want: errorRetryable},
+		{name: "server error", err: fmt.Errorf("failed to complete: %w", &APIError{StatusCode: 502}), want: errorRetryable},
+		{name: "anthropic overloaded", err: &anthropic.Error{StatusCode: 529}, want: errorRetryable},
+		{name: "unauthorized", err: &APIError{StatusCode: 401}, want: errorUnavailable},
+		{name: "payment required", err: &APIError{StatusCode: 402}, want: errorUnavailable},
+		{name: "forbidden", err: &anthropic.Error{StatusCode: 403}, want: errorUnavailable},
+		{name: "model not found", err: &APIError{StatusCode: 404}, want: errorUnavailable},
+		{name: "bad request", err: &APIError{StatusCode: 400, Body: "overloaded"}, want: errorFatal},
+		{name: "deadline exceeded", err: fmt.Errorf("request: %w", context.DeadlineExceeded), want: errorRetryable},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: errorRetryable},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: errorRetryable},
+		{name: "overloaded in stream", err: errors.New(`stream error: {"type":"overloaded_error"}`), want: errorRetryable},
+		{name: "connection reset", err: errors.New("read: connection reset by peer"), want: errorRetryable},
+		{name: "other error", err: errors.New("invalid tool arguments"), want: errorFatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := classifyError(ctx, tt.err); got != tt.want {
+				t.Errorf("classifyError() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		limit   time.Duration
+	}{
+		{attempt: 0, limit: initialBackoff},
+		{attempt: 1, limit: 2 * initialBackoff},
+		{attempt: 2, limit: 4 * initialBackoff},
+		{attempt: 4, limit: maxBackoff},
+		{attempt: 100, limit: maxBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := backoff(tt.attempt); got < 0 || got >= tt.limit {
+					t.Fatalf("backoff(%d) = %s, want [0, %s)", tt.attempt, got, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	type step struct {
+		// op is allow, success, failure, release or cooldown, which moves the end of the cooldown to now
+		op   string
+		want bool
+	}
+
+	failures := func(n int) []step {
+		steps := []step{}
+		for i := 0; i < n; i++ {
+			steps = append(steps, step{op: "allow", want: true}, step{op: "failure", want: i == breakerThreshold-1})
+		}
+		return steps
+	}
+
+	tests := []struct {
+		name      string
+		steps     []step
+		wantState breakerState
+	}{
+		{
+			name:      "closed below the threshold",
+			steps:     failures(breakerThreshold - 1),
+			wantState: breakerClosed,
+		},
+		{
+			name:      "success resets failures",
+			steps:     append(append(failures(breakerThreshold-1), step{op: "allow", want: true}, step{op: "success"}), failures(breakerThreshold-1)...),
+			wantState: breakerClosed,
+		},
+		{
+			name:      "opens at the threshold",
+			steps:     append(failures(breakerThreshold), step{op: "allow", want: false}),
+			wantState: breakerOpen,
+		},
+		{
+			name: "half open allows a single probe",
+			steps: append(failures(breakerThreshold),
+				step{op: "cooldown"},
+				step{op: "allow", want: true},
+				step{op: "allow", want: false},
+				step{op: "allow", want: false},
+			),
+			wantState: breakerHalfOpen,
+		},
+		{
+			name: "successful probe closes",
+			steps: append(failures(breakerThreshold),
+				step{op: "cooldown"},
+				step{op: "allow", want: true},
+				step{op: "success"},
+				step{op: "allow", want: true},
+				step{op: "allow", want: true},
+			),
+			wantState: breakerClosed,
+		},
+		{
+			name: "failed probe opens again",
+			steps: append(failures(breakerThreshold),
+				step{op: "cooldown"},
+				step{op: "allow", want: true},
+				step{op: "failure", want: true},
+				step{op: "allow", want: false},
+			),
+			wantState: breakerOpen,
+		},
+		{
+			name: "released probe lets the next request probe",
+			steps: append(failures(breakerThreshold),
+				step{op: "cooldown"},
+				step{op: "allow", want: true},
+				step{op: "release"},
+				step{op: "allow", want: true},
+				step{op: "allow", want: false},
+			),
+			wantState: breakerHalfOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &circuitBreaker{}
+			for i, s := range tt.steps {
+				got := false
+				switch s.op {
+				case "allow":
+					got = b.allow()
+				case "success":
+					b.success()
+				case "failure":
+					got = b.failure()
+				case "release":
+					b.release()
+				case "cooldown":
+					b.openUntil = time.Now()
+				}
+				if got != s.want {
+					t.Fatalf("step %d: %s() = %v, want %v", i, s.op, got, s.want)
+				}
+			}
+			if b.state != tt.wantState {
+				t.Errorf("state = %d, want %d", b.state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestFallbackModels(t *testing.T) {
+	tests := []struct {
+		name     string
+		all      string
+		forStage string
+		stage    Stage
+		want     []string
+	}{
+		{name: "none", stage: StageConversational, want: []string{}},
+		{name: "all stages", all: "anthropic:a, openrouter:b ,,", stage: StageConversational, want: []string{"anthropic:a", "openrouter:b"}},
+		{name: "stage replaces all", all: "anthropic:a", forStage: "openrouter:b", stage: StageConversational, want: []string{"openrouter:b"}},
+		{name: "no stage", all: "anthropic:a", forStage: "openrouter:b", want: []string{"anthropic:a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(fallbackModelsEnvName, tt.all)
+			t.Setenv(fallbackModelsEnvName+"_CONVERSATIONAL", tt.forStage)
+
+			if got := fallbackModels(tt.stage); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fallbackModels() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// scriptedProvider returns its errors in order and then succeeds
+type scriptedProvider struct {
+	name  string
+	errs  []error
+	calls []string
+}
+
+func (p *scriptedProvider) Name() string {
+	return p.name
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	p.calls = append(p.calls, req.Model)
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+	return &Response{Content: p.name + ":" + req.Model}, nil
+}
+
+func (p *scriptedProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	return p.Complete(ctx, req)
+}
+
+func TestModelChainPinned(t *testing.T) {
+	tests := []struct {
+		name        string
+		primaryErrs []error
+		wantContent string
+		wantErr     bool
+		wantPinned  string
+	}{
+		{
+			name:        "primary serves",
+			wantContent: "test-primary:model-a",
+			wantPinned:  "test-primary:model-a",
+		},
+		{
+			name:        "unavailable primary falls back",
+			primaryErrs: []error{&APIError{StatusCode: 401}},
+			wantContent: "test-fallback:model-b",
+			wantPinned:  "test-fallback:model-b",
+		},
+		{
+			name:        "fatal error fails",
+			primaryErrs: []error{&APIError{StatusCode: 400}},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &scriptedProvider{name: "test-primary", errs: tt.primaryErrs}
+			fallback := &scriptedProvider{name: "test-fallback"}
+			chain := &modelChain{
+				targets: []chainTarget{{provider: primary, model: "model-a"}, {provider: fallback, model: "model-b"}},
+				served:  -1,
+			}
+
+			if _, ok := chain.pinned(); ok {
+				t.Fatal("pinned() before a request succeeded = true, want false")
+			}
+
+			resp, err := chain.Complete(context.Background(), Request{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, ok := chain.pinned(); ok {
+					t.Error("pinned() after a failed request = true, want false")
+				}
+				return
+			}
+			if resp.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", resp.Content, tt.wantContent)
+			}
+
+			pinned, ok := chain.pinned()
+			if !ok {
+				t.Fatal("pinned() = false, want true")
+			}
+			if len(pinned.targets) != 1 || pinned.targets[0].String() != tt.wantPinned {
+				t.Fatalf("pinned targets = %v, want [%s]", pinned.targets, tt.wantPinned)
+			}
+
+			resp, err = pinned.Complete(context.Background(), Request{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Content != tt.wantPinned {
+				t.Errorf("pinned content = %q, want %q", resp.Content, tt.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/replicatedhq/chartsmith/pkg/embedding"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// FixtureMode controls whether LLM requests are recorded to or replayed from fixtures
+type FixtureMode string
+
+const (
+	// FixtureModeOff sends requests to the providers as usual
+	FixtureModeOff FixtureMode = ""
+	// FixtureModeRecord sends requests to the providers and saves each request and response
+	FixtureModeRecord FixtureMode = "record"
+	// FixtureModeReplay serves saved responses and never calls a provider
+	FixtureModeReplay FixtureMode = "replay"
+)
+
+// fixture is a recorded request and the response to it. Streamed requests also record the
+// chunks in the order they were sent so that replays stream the same way
+type fixture struct {
+	Provider string    `json:"provider"`
+	Stream   bool      `json:"stream"`
+	Request  Request   `json:"request"`
+	Chunks   []string  `json:"chunks,omitempty"`
+	Response *Response `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// fixturesEnabled turns off prompt A/B splits, which are keyed by random IDs, so that the same
+// scenario always renders the same prompts
+var fixturesEnabled atomic.Bool
+
+// EnableFixtures wraps every registered provider so that requests are recorded to, or replayed
+// from, a JSON file per request in dir. Files are named by a hash of the provider and the normalized
+// request. Embeddings are recorded to and replayed from the embeddings directory in dir
+func EnableFixtures(mode FixtureMode, dir string) error {
+	switch mode {
+	case FixtureModeOff:
+		return nil
+	case FixtureModeRecord:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create fixtures dir: %w", err)
+		}
+	case FixtureModeReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("failed to read fixtures dir: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown fixture mode %q", mode)
+	}
+
+	if err := embedding.EnableFixtures(string(mode), filepath.Join(dir, "embeddings")); err != nil {
+		return fmt.Errorf("failed to enable embedding fixtures: %w", err)
+	}
+	fixturesEnabled.Store(true)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for name, provider := range providers {
+		providers[name] = &fixtureProvider{
+			provider: provider,
+			mode:     mode,
+			dir:      dir,
+		}
+	}
+
+	logger.Info("LLM fixtures enabled", zap.String("mode", string(mode)), zap.String("dir", dir))
+	return nil
+}
+
+// fixtureProvider records or replays the requests to the provider it wraps
+type fixtureProvider struct {
+	provider Provider
+	mode     FixtureMode
+	dir      string
+}
+
+var _ Provider = &fixtureProvider{}
+
+func (p *fixtureProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *fixtureProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	if p.mode == FixtureModeReplay {
+		return p.replay(ctx, req, false, nil)
+	}
+
+	resp, err := p.provider.Complete(ctx, req)
+	p.record(ctx, req, false, nil, resp, err)
+	return resp, err
+}
+
+func (p *fixtureProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	if p.mode == FixtureModeReplay {
+		return p.replay(ctx, req, true, streamCh)
+	}
+
+	// tee the chunks so they can be recorded
+	chunks := []string{}
+	teeCh := make(chan string)
+	teeDone := make(chan struct{})
+	go func() {
+		defer close(teeDone)
+		for chunk := range teeCh {
+			chunks = append(chunks, chunk)
+			if err := sendText(ctx, streamCh, chunk); err != nil {
+				// keep draining so the provider doesn't block
+				continue
+			}
+		}
+	}()
+
+	resp, err := p.provider.Stream(ctx, req, teeCh)
+	close(teeCh)
+	<-teeDone
+
+	p.record(ctx, req, true, chunks, resp, err)
+	return resp, err
+}
+
+func (p *fixtureProvider) replay(ctx context.Context, req Request, stream bool, streamCh chan<- string) (*Response, error) {
+	path, err := p.path(ctx, req, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no fixture recorded for %s request to %s at %s, record it with fixture mode %q", p.Name(), req.Model, path, FixtureModeRecord)
+		}
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	f := fixture{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	_, restore := scopeReplacers(ctx)
+	for _, chunk := range f.Chunks {
+		if err := sendText(ctx, streamCh, restore.Replace(chunk)); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Error != "" {
+		return nil, errors.New(f.Error)
+	}
+
+	return replaceResponse(f.Response, restore), nil
+}
+
+// record saves the fixture. Failing to save one doesn't fail the request
+func (p *fixtureProvider) record(ctx context.Context, req Request, stream bool, chunks []string, resp *Response, respErr error) {
+	// IDs in the response are saved as placeholders too, and replaced with the IDs of the replay
+	normalize, _ := scopeReplacers(ctx)
+	normalizedChunks := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		normalizedChunks[i] = normalize.Replace(chunk)
+	}
+
+	f := fixture{
+		Provider: p.Name(),
+		Stream:   stream,
+		Request:  normalizeRequest(ctx, req),
+		Chunks:   normalizedChunks,
+		Response: replaceResponse(resp, normalize),
+	}
+	if respErr != nil {
+		f.Error = respErr.Error()
+	}
+
+	path, err := p.path(ctx, req, stream)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to record fixture: %w", err))
+		return
+	}
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to marshal fixture: %w", err))
+		return
+	}
+
+	// write to a temp file first so concurrent identical requests never leave a partial fixture
+	tmp, err := os.CreateTemp(p.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to create fixture: %w", err))
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		logger.Error(fmt.Errorf("failed to write fixture: %w", err))
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		logger.Error(fmt.Errorf("failed to write fixture: %w", err))
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		logger.Error(fmt.Errorf("failed to save fixture: %w", err))
+		return
+	}
+
+	logger.Debug("Recorded LLM fixture", zap.String("path", path))
+}
+
+// path returns the fixture file for a request. Normalized requests that differ in any field, including
+// whether they are streamed, get different files
+func (p *fixtureProvider) path(ctx context.Context, req Request, stream bool) (string, error) {
+	b, err := json.Marshal(struct {
+		Provider string  `json:"provider"`
+		Stream   bool    `json:"stream"`
+		Request  Request `json:"request"`
+	}{
+		Provider: p.Name(),
+		Stream:   stream,
+		Request:  normalizeRequest(ctx, req),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	hash := sha256.Sum256(b)
+	return filepath.Join(p.dir, fmt.Sprintf("%s-%x.json", p.Name(), hash[:12])), nil
+}
+
+// scopeReplacers returns a replacer from the IDs in the usage scope of ctx to placeholders, and one from
+// the placeholders back to the IDs
+func scopeReplacers(ctx context.Context) (*strings.Replacer, *strings.Replacer) {
+	scope := usageScopeFromContext(ctx)
+	normalize, restore := []string{}, []string{}
+	for _, id := range []struct {
+		value       string
+		placeholder string
+	}{
+		{scope.WorkspaceID, "<workspace-id>"},
+		{scope.PlanID, "<plan-id>"},
+		{scope.ChatMessageID, "<chat-message-id>"},
+		{scope.UserID, "<user-id>"},
+	} {
+		if id.value != "" {
+			normalize = append(normalize, id.value, id.placeholder)
+			restore = append(restore, id.placeholder, id.value)
+		}
+	}
+	return strings.NewReplacer(normalize...), strings.NewReplacer(restore...)
+}
+
+// replaceResponse returns a copy of resp with the text of its content and tool calls replaced
+func replaceResponse(resp *Response, replacer *strings.Replacer) *Response {
+	if resp == nil {
+		return nil
+	}
+
+	replaced := *resp
+	replaced.Content = replacer.Replace(resp.Content)
+	if len(resp.ToolCalls) > 0 {
+		replaced.ToolCalls = make([]ToolCall, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			call.Arguments = json.RawMessage(replacer.Replace(string(call.Arguments)))
+			replaced.ToolCalls[i] = call
+		}
+	}
+	return &replaced
+}
+
+// normalizeRequest returns a copy of req without the values that change each time the same scenario runs:
+//   - the IDs in the usage scope of ctx, which are random, are replaced with placeholders
+//   - tool call IDs, which are generated by the provider, are numbered in the order they appear
+func normalizeRequest(ctx context.Context, req Request) Request {
+	replacer, _ := scopeReplacers(ctx)
+
+	toolCallIDs := map[string]string{}
+	toolCallID := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if normalized, ok := toolCallIDs[id]; ok {
+			return normalized
+		}
+		normalized := fmt.Sprintf("toolcall-%d", len(toolCallIDs))
+		toolCallIDs[id] = normalized
+		return normalized
+	}
+
+	normalized := req
+	normalized.Messages = make([]Message, len(req.Messages))
+	for i, message := range req.Messages {
+		message.Content = replacer.Replace(message.Content)
+		if len(message.ToolCalls) > 0 {
+			toolCalls := make([]ToolCall, len(message.ToolCalls))
+			for j, call := range message.ToolCalls {
+				call.ID = toolCallID(call.ID)
+				call.Arguments = json.RawMessage(replacer.Replace(string(call.Arguments)))
+				toolCalls[j] = call
+			}
+			message.ToolCalls = toolCalls
+		}
+		message.ToolCallID = toolCallID(message.ToolCallID)
+		normalized.Messages[i] = message
+	}
+
+	return normalized
+}
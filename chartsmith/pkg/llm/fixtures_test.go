@@ -0,0 +1,255 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tuvistavie/securerandom"
+)
+
+// go test ./pkg/llm -run TestFixtureReplay -update-fixtures records testdata/llm-fixtures again from fakeProvider
+var updateFixtures = flag.Bool("update-fixtures", false, "record the LLM fixtures in testdata from the fake provider")
+
+const fixturesDir = "testdata/llm-fixtures"
+
+// fakeProvider answers with the last message it was sent. When tools are offered, the first turn calls
+// the lookup tool with a random ID, like a real provider would
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p fakeProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	return p.respond(req)
+}
+
+func (p fakeProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	resp, err := p.respond(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if err := sendText(ctx, streamCh, word); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (fakeProvider) respond(req Request) (*Response, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if len(req.Tools) > 0 && last.Role != RoleTool {
+		id, err := securerandom.Hex(8)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{
+			Content:   "looking it up",
+			ToolCalls: []ToolCall{{ID: "toolu_" + id, Name: "lookup", Arguments: json.RawMessage(`{"key":"replicas"}`)}},
+			Usage:     Usage{InputTokens: 10, OutputTokens: 5},
+		}, nil
+	}
+	return &Response{
+		Content: "you said " + last.Content,
+		Usage:   Usage{InputTokens: 10, OutputTokens: 5},
+	}, nil
+}
+
+// replayOnlyProvider fails the test if a replay reaches the provider
+type replayOnlyProvider struct {
+	t *testing.T
+}
+
+func (p replayOnlyProvider) Name() string {
+	return "fake"
+}
+
+func (p replayOnlyProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	p.t.Fatalf("replay called the provider")
+	return nil, nil
+}
+
+func (p replayOnlyProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	p.t.Fatalf("replay called the provider")
+	return nil, nil
+}
+
+// randomScope returns a usage scope with new IDs, which are different in each run like in production
+func randomScope(t *testing.T) UsageScope {
+	ids := make([]string, 3)
+	for i := range ids {
+		id, err := securerandom.Hex(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	return UsageScope{WorkspaceID: ids[0], PlanID: ids[1], ChatMessageID: ids[2]}
+}
+
+func TestFixtureReplay(t *testing.T) {
+	mode := FixtureModeReplay
+	var provider Provider = replayOnlyProvider{t: t}
+	if *updateFixtures {
+		mode = FixtureModeRecord
+		provider = fakeProvider{}
+		if err := os.RemoveAll(fixturesDir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(fixturesDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &fixtureProvider{provider: provider, mode: mode, dir: fixturesDir}
+
+	tests := []struct {
+		name       string
+		run        func(ctx context.Context, scope UsageScope) (*Response, []string, error)
+		wantChunks []string
+		wantResp   string
+	}{
+		{
+			name: "complete",
+			run: func(ctx context.Context, scope UsageScope) (*Response, []string, error) {
+				resp, err := p.Complete(ctx, Request{
+					Model:    "fake-model",
+					Messages: []Message{{Role: RoleUser, Content: "add an ingress to workspace " + scope.WorkspaceID}},
+				})
+				return resp, nil, err
+			},
+			wantResp: "you said add an ingress to workspace <workspace-id>",
+		},
+		{
+			name: "stream",
+			run: func(ctx context.Context, scope UsageScope) (*Response, []string, error) {
+				streamCh := make(chan string, 100)
+				resp, err := p.Stream(ctx, Request{
+					Model:    "fake-model",
+					Messages: []Message{{Role: RoleUser, Content: "explain plan " + scope.PlanID}},
+				}, streamCh)
+				close(streamCh)
+				chunks := []string{}
+				for chunk := range streamCh {
+					chunks = append(chunks, chunk)
+				}
+				return resp, chunks, err
+			},
+			wantChunks: []string{"you ", "said ", "explain ", "plan ", "<plan-id>"},
+			wantResp:   "you said explain plan <plan-id>",
+		},
+		{
+			name: "tool loop",
+			run: func(ctx context.Context, scope UsageScope) (*Response, []string, error) {
+				resp, err := runWithTools(ctx, p, Request{
+					Model:    "fake-model",
+					Messages: []Message{{Role: RoleUser, Content: "how many replicas for " + scope.ChatMessageID}},
+					Tools:    []Tool{{Name: "lookup", Parameters: map[string]interface{}{"type": "object"}}},
+				}, nil, func(ctx context.Context, call ToolCall) (string, error) {
+					return "3 replicas", nil
+				})
+				return resp, nil, err
+			},
+			wantResp: "you said 3 replicas",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := randomScope(t)
+			ctx := WithUsageScope(context.Background(), scope)
+
+			resp, chunks, err := tt.run(ctx, scope)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the responses were recorded with other IDs, which are replaced with the IDs of this run
+			placeholders := strings.NewReplacer(scope.WorkspaceID, "<workspace-id>", scope.PlanID, "<plan-id>")
+			if got := placeholders.Replace(resp.Content); got != tt.wantResp {
+				t.Errorf("response = %q, want %q", got, tt.wantResp)
+			}
+			if tt.wantChunks != nil {
+				got := []string{}
+				for _, chunk := range chunks {
+					got = append(got, placeholders.Replace(chunk))
+				}
+				if !reflect.DeepEqual(got, tt.wantChunks) {
+					t.Errorf("chunks = %q, want %q", got, tt.wantChunks)
+				}
+			}
+		})
+	}
+}
+
+func TestFixtureReplayMissing(t *testing.T) {
+	p := &fixtureProvider{provider: replayOnlyProvider{t: t}, mode: FixtureModeReplay, dir: t.TempDir()}
+
+	_, err := p.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "not recorded"}}})
+	if err == nil || !strings.Contains(err.Error(), "no fixture recorded") {
+		t.Fatalf("error = %v, want a missing fixture error", err)
+	}
+}
+
+func TestNormalizeRequest(t *testing.T) {
+	request := func(scope UsageScope, toolCallID string) Request {
+		return Request{
+			Messages: []Message{
+				{Role: RoleUser, Content: fmt.Sprintf("workspace %s, plan %s, message %s", scope.WorkspaceID, scope.PlanID, scope.ChatMessageID)},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: toolCallID, Name: "lookup", Arguments: json.RawMessage(`{"plan":"` + scope.PlanID + `"}`)}}},
+				{Role: RoleTool, ToolCallID: toolCallID, Content: "done"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		scopeA    UsageScope
+		scopeB    UsageScope
+		wantEqual bool
+	}{
+		{
+			name:      "different IDs",
+			scopeA:    UsageScope{WorkspaceID: "ws1", PlanID: "plan1", ChatMessageID: "msg1"},
+			scopeB:    UsageScope{WorkspaceID: "ws2", PlanID: "plan2", ChatMessageID: "msg2"},
+			wantEqual: true,
+		},
+		{
+			name:      "different content",
+			scopeA:    UsageScope{WorkspaceID: "ws1", PlanID: "plan1", ChatMessageID: "msg1"},
+			scopeB:    UsageScope{WorkspaceID: "ws1", PlanID: "plan1", ChatMessageID: "msg1"},
+			wantEqual: false,
+		},
+	}
+
+	p := &fixtureProvider{provider: fakeProvider{}, mode: FixtureModeReplay, dir: "dir"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := request(tt.scopeA, "toolu_a")
+			b := request(tt.scopeB, "toolu_b")
+			if !tt.wantEqual {
+				b.Messages[2].Content = "failed"
+			}
+
+			pathA, err := p.path(WithUsageScope(context.Background(), tt.scopeA), a, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pathB, err := p.path(WithUsageScope(context.Background(), tt.scopeB), b, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (pathA == pathB) != tt.wantEqual {
+				t.Errorf("paths %s and %s, want equal %v", filepath.Base(pathA), filepath.Base(pathB), tt.wantEqual)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jpoz/groq"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+)
+
+// groqProvider sends requests to the Groq chat completions API. The groq client doesn't
+// support tool calling, so requests with tools are rejected
+type groqProvider struct{}
+
+var _ Provider = groqProvider{}
+
+func (groqProvider) Name() string {
+	return providerGroq
+}
+
+func (groqProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	params, err := newGroqParams(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+
+	llmReq := startLLMRequest(ctx, providerGroq, req.Model)
+	response, err := client.CreateChatCompletion(params)
+	if err != nil {
+		llmReq.end(0, 0, err)
+		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	usage := Usage{
+		InputTokens:  groqTokens(response.Usage.PromptTokens),
+		OutputTokens: groqTokens(response.Usage.CompletionTokens),
+	}
+	llmReq.end(usage.InputTokens, usage.OutputTokens, nil)
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in groq response")
+	}
+
+	return &Response{
+		Content: response.Choices[0].Message.Content,
+		Usage:   usage,
+	}, nil
+}
+
+func (groqProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (resp *Response, err error) {
+	params, err := newGroqParams(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+
+	// groq doesn't report usage on streams
+	llmReq := startLLMRequest(ctx, providerGroq, req.Model)
+	defer func() {
+		llmReq.end(0, 0, err)
+	}()
+
+	chatCompletion, err := client.CreateChatCompletion(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+
+	content := strings.Builder{}
+	for delta := range chatCompletion.Stream {
+		if len(delta.Choices) == 0 {
+			continue
+		}
+		content.WriteString(delta.Choices[0].Delta.Content)
+		if err := sendText(ctx, streamCh, delta.Choices[0].Delta.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Response{
+		Content: content.String(),
+	}, nil
+}
+
+func newGroqParams(req Request, stream bool) (groq.CompletionCreateParams, error) {
+	if len(req.Tools) > 0 {
+		return groq.CompletionCreateParams{}, fmt.Errorf("%w: groq provider does not support tool calling", errProviderUnavailable)
+	}
+
+	messages := make([]groq.Message, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		messages = append(messages, groq.Message{
+			Role:    string(message.Role),
+			Content: message.Content,
+		})
+	}
+
+	params := groq.CompletionCreateParams{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   stream,
+	}
+
+	if req.JSON {
+		params.ResponseFormat = groq.ResponseFormat{
+			Type: "json_object",
+		}
+	}
+
+	return params, nil
+}
+
+// groqTokens returns a token count from a groq response, which leaves out the counts it doesn't have
+func groqTokens(count *int) int64 {
+	if count == nil {
+		return 0
+	}
+	return int64(*count)
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"github.com/replicatedhq/chartsmith/pkg/metrics"
+	"github.com/replicatedhq/chartsmith/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	providerAnthropic  = "anthropic"
+	providerOpenRouter = "openrouter"
+	providerGroq       = "groq"
+	providerOllama     = "ollama"
+	providerLocal      = "local"
+)
+
+// llmRequest records the metrics, span and usage for a single request to an LLM provider
+type llmRequest struct {
+	ctx      context.Context
+	provider string
+	model    string
+	stage    Stage
+	scope    UsageScope
+	// promptVersion is the version of the prompts selected for the stage
+	promptVersion string
+	start         time.Time
+	span          trace.Span
+}
+
+// startLLMRequest is called right before a request is sent. The span is a child of any span in ctx
+func startLLMRequest(ctx context.Context, provider string, model string) *llmRequest {
+	ctx, span := tracing.StartSpan(ctx, "llm "+provider,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", provider),
+			attribute.String("gen_ai.request.model", model),
+			attribute.String("chartsmith.llm.stage", string(stageFromContext(ctx))),
+		))
+
+	return &llmRequest{
+		ctx:           ctx,
+		provider:      provider,
+		model:         model,
+		stage:         stageFromContext(ctx),
+		scope:         usageScopeFromContext(ctx),
+		promptVersion: promptVersionFor(ctx),
+		start:         time.Now(),
+		span:          span,
+	}
+}
+
+// end is called once the response has been received, or streamed in full. Token counts
+// that the provider didn't report should be passed as 0
+func (r *llmRequest) end(inputTokens int64, outputTokens int64, err error) {
+	latency := time.Since(r.start)
+	metrics.ObserveLLMRequest(r.provider, r.model, latency, inputTokens, outputTokens, err)
+	recordUsage(r.ctx, r, inputTokens, outputTokens, latency, err)
+	recordPromptVersion(r.ctx, r)
+
+	r.span.SetAttributes(
+		attribute.Int64("gen_ai.usage.input_tokens", inputTokens),
+		attribute.Int64("gen_ai.usage.output_tokens", outputTokens),
+	)
+	tracing.EndSpan(r.span, err)
+}
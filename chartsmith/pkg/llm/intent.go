@@ -5,92 +5,47 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/jpoz/groq"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
-	"github.com/replicatedhq/chartsmith/pkg/param"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
 	"go.uber.org/zap"
 )
 
 func GetChatMessageIntent(ctx context.Context, prompt string, isInitialPrompt bool, messageFromPersona *workspacetypes.ChatMessageFromPersona) (*workspacetypes.Intent, error) {
+	ctx = withStage(ctx, StageIntent)
 	logger.Debug("GetChatMessageIntent",
 		zap.String("prompt", prompt),
 		zap.Bool("isInitialPrompt", isInitialPrompt))
 
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
-
 	// deepseek r1 recommends no system prompt, include everything in the user prompt
-	userMessage := ""
+	promptName := ""
 
 	if messageFromPersona == nil || *messageFromPersona == workspacetypes.ChatMessageFromPersonaAuto {
-		userMessage = fmt.Sprintf(`%s
-
-		Given this, my request is:
-
-		%s
-
-		Determine if the prompt is a question, a request for information, or a request to perform an action.
-
-		You will respond with a JSON object containing the following fields:
-		- isConversational: true if the prompt is a question or request for information, false otherwise
-		- isPlan: true if the prompt is a request to perform an update to the chart templates or files, false otherwise
-		- isOffTopic: true if the prompt is off topic, false otherwise
-		- isChartDeveloper: true if the question is related to planning a change to the chart, false otherwise
-		- isChartOperator: true if the question is about how to use the Helm chart in a Kubernetes cluster, false otherwise
-		- isProceed: true if the prompt is a clear request to execute previous instructions with no requsted changes, false otherwise
-		- isRender: true if the prompt is a request to render or test or validate the chart, false otherwise
-
-		Important: Do not respond with anything other than the JSON object.`,
-			commonSystemPrompt, prompt)
-
+		promptName = promptIntentAuto
 	} else if *messageFromPersona == workspacetypes.ChatMessageFromPersonaDeveloper {
-		userMessage = fmt.Sprintf(`%s
-
-		Given this, my request is:
-
-		%s
-
-		Determine if the prompt is a question, a request for information, or a request to perform an action.
-
-		You will respond with a JSON object containing the following fields:
-		- isConversational: true if the prompt is a question or request for information, false otherwise
-		- isPlan: true if the prompt is a request to perform an update to the chart templates or files, false otherwise
-		- isOffTopic: true if the prompt is off topic, false otherwise
-		- isChartDeveloper: true if it's possible to answer this question as if it was asked by the chat developer, false if otherwise
-		- isProceed: true if the prompt is a clear request to execute previous instructions with no requsted changes, false otherwise
-		- isRender: true if the prompt is a request to render or test or validate the chart, false otherwise
-
-		Important: Do not respond with anything other than the JSON object.`,
-			commonSystemPrompt, prompt)
-
+		promptName = promptIntentDeveloper
 	} else if *messageFromPersona == workspacetypes.ChatMessageFromPersonaOperator {
-		userMessage = fmt.Sprintf(`%s
-
-		Given this, my request is:
-
-		%s
-
-		Determine if the prompt is a question, a request for information, or a request to perform an action.
-
-		You will respond with a JSON object containing the following fields:
-		- isConversational: true if the prompt is a question or request for information, false otherwise
-		- isPlan: true if the prompt is a request to perform an update to the chart templates or files, false otherwise
-		- isOffTopic: true if the prompt is off topic, false otherwise
-		- isChartOperator: true if it's possible to answer this question as if it was asked by the chat operator and can be completed without making any changes to the chart templates or files, false if otherwise
+		promptName = promptIntentOperator
+	}
 
-		Important: Do not respond with anything other than the JSON object.`,
-			endUserSystemPrompt, prompt)
+	userMessage := ""
+	if promptName != "" {
+		var err error
+		userMessage, err = renderPrompt(ctx, promptName, struct{ Prompt string }{Prompt: prompt})
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	chain, err := resolveChain(ctx, groqChatModel)
+	if err != nil {
+		return nil, err
 	}
 
-	response, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model: "llama-3.3-70b-versatile",
-		ResponseFormat: groq.ResponseFormat{
-			Type: "json_object",
-		},
-		Messages: []groq.Message{
+	response, err := chain.Complete(ctx, Request{
+		JSON: true,
+		Messages: []Message{
 			{
-				Role:    "user",
+				Role:    RoleUser,
 				Content: userMessage,
 			},
 		},
@@ -100,7 +55,7 @@ func GetChatMessageIntent(ctx context.Context, prompt string, isInitialPrompt bo
 	}
 
 	var parsedResponse map[string]interface{}
-	err = json.Unmarshal([]byte(response.Choices[0].Message.Content), &parsedResponse)
+	err = json.Unmarshal([]byte(response.Content), &parsedResponse)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
@@ -143,132 +98,108 @@ func GetChatMessageIntent(ctx context.Context, prompt string, isInitialPrompt bo
 }
 
 func FeedbackOnNotDeveloperIntentWhenRequested(ctx context.Context, streamCh chan string, doneCh chan error, chatMessage *workspacetypes.Chat) error {
+	ctx = withChatResponse(WithUsageScope(withStage(ctx, StageIntent), chatMessageUsageScope(chatMessage)))
 	logger.Debug("FeedbackOnNotDeveloperIntentWhenRequested",
 		zap.String("prompt", chatMessage.Prompt),
 	)
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+	systemPrompt, err := renderPrompt(ctx, promptFeedbackNotDeveloper, nil)
+	if err != nil {
+		return err
+	}
 
-	chatCompletion, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model:  "llama-3.3-70b-versatile",
-		Stream: true,
-		Messages: []groq.Message{
-			{
-				Role:    "system",
-				Content: "You are Chartsmith, an expert Helm chart developer. You are currently pairing with a user who is trying to create a Helm chart. They asked you the following question and asked you to answer it as a developer. However, you are unable to answer the question as a developer. Explain to the user that the message cannot be answered as a chart developer and why.",
-			},
-			{
-				Role:    "user",
-				Content: chatMessage.Prompt,
-			},
+	_, err = stream(ctx, groqChatModel, []Message{
+		{
+			Role:    RoleSystem,
+			Content: systemPrompt,
 		},
-	})
-
+		{
+			Role:    RoleUser,
+			Content: chatMessage.Prompt,
+		},
+	}, streamCh)
 	if err != nil {
 		return fmt.Errorf("failed to get chat message intent: %w", err)
 	}
 
-	for delta := range chatCompletion.Stream {
-		streamCh <- delta.Choices[0].Delta.Content
-	}
-
 	doneCh <- nil
 	return nil
 }
 
 func FeedbackOnNotOperatorIntentWhenRequested(ctx context.Context, streamCh chan string, doneCh chan error, chatMessage *workspacetypes.Chat) error {
+	ctx = withChatResponse(WithUsageScope(withStage(ctx, StageIntent), chatMessageUsageScope(chatMessage)))
 	logger.Debug("FeedbackOnNotOperatorIntentWhenRequested",
 		zap.String("prompt", chatMessage.Prompt),
 	)
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+	systemPrompt, err := renderPrompt(ctx, promptFeedbackNotOperator, nil)
+	if err != nil {
+		return err
+	}
 
-	chatCompletion, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model:  "llama-3.3-70b-versatile",
-		Stream: true,
-		Messages: []groq.Message{
-			{
-				Role:    "system",
-				Content: "You are Chartsmith, an expert Helm chart developer. You are currently pairing with a user who is trying to create a Helm chart. They asked you the following question and asked you to answer it as an operator. However, you are unable to answer the question as an operator. Explain to the user that the message cannot be answered as a chart operator / end-user and why.",
-			},
-			{
-				Role:    "user",
-				Content: chatMessage.Prompt,
-			},
+	_, err = stream(ctx, groqChatModel, []Message{
+		{
+			Role:    RoleSystem,
+			Content: systemPrompt,
 		},
-	})
-
+		{
+			Role:    RoleUser,
+			Content: chatMessage.Prompt,
+		},
+	}, streamCh)
 	if err != nil {
 		return fmt.Errorf("failed to get chat message intent: %w", err)
 	}
 
-	for delta := range chatCompletion.Stream {
-		streamCh <- delta.Choices[0].Delta.Content
-	}
-
 	doneCh <- nil
 	return nil
 }
 
 func FeedbackOnAmbiguousIntent(ctx context.Context, streamCh chan string, doneCh chan error, chatMessage *workspacetypes.Chat) error {
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+	ctx = withChatResponse(WithUsageScope(withStage(ctx, StageIntent), chatMessageUsageScope(chatMessage)))
+	systemPrompt, err := renderPrompt(ctx, promptFeedbackAmbiguous, nil)
+	if err != nil {
+		return err
+	}
 
-	chatCompletion, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model:  "llama-3.3-70b-versatile",
-		Stream: true,
-		Messages: []groq.Message{
-			{
-				Role:    "system",
-				Content: "You are Chartsmith, an expert Helm chart developer. You are currently pairing with a user who is trying to create a Helm chart. You are given a prompt from the user, and you are unable to figure out it's intent. Politelty ask the user to clarify their message.",
-			},
-			{
-				Role:    "user",
-				Content: chatMessage.Prompt,
-			},
+	_, err = stream(ctx, groqChatModel, []Message{
+		{
+			Role:    RoleSystem,
+			Content: systemPrompt,
 		},
-	})
-
+		{
+			Role:    RoleUser,
+			Content: chatMessage.Prompt,
+		},
+	}, streamCh)
 	if err != nil {
 		return fmt.Errorf("failed to get chat message intent: %w", err)
 	}
 
-	for delta := range chatCompletion.Stream {
-		streamCh <- delta.Choices[0].Delta.Content
-	}
-
 	doneCh <- nil
 	return nil
 }
 
 func DeclineOffTopicChatMessage(ctx context.Context, streamCh chan string, doneCh chan error, chatMessage *workspacetypes.Chat) error {
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
+	ctx = withChatResponse(WithUsageScope(withStage(ctx, StageIntent), chatMessageUsageScope(chatMessage)))
+	systemPrompt, err := renderPrompt(ctx, promptDeclineOffTopic, nil)
+	if err != nil {
+		return err
+	}
 
-	chatCompletion, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model:  "llama-3.3-70b-versatile",
-		Stream: true,
-		Messages: []groq.Message{
-			{
-				Role:    "system",
-				Content: "You are Chartsmith, an expert Helm chart developer. You are currently pairing with a user who is trying to create a Helm chart. You are given a prompt from the user and you need to decline the prompt because it is off topic.",
-			},
-			{
-				Role:    "user",
-				Content: chatMessage.Prompt,
-			},
+	_, err = stream(ctx, groqChatModel, []Message{
+		{
+			Role:    RoleSystem,
+			Content: systemPrompt,
 		},
-	})
-
+		{
+			Role:    RoleUser,
+			Content: chatMessage.Prompt,
+		},
+	}, streamCh)
 	if err != nil {
 		doneCh <- fmt.Errorf("failed to decline off-topic chat message: %w", err)
 		return fmt.Errorf("failed to decline off-topic chat message: %w", err)
 	}
 
-	// anthropic and groq work differently here, and we want to limit that
-	// to this llm package.
-	// so we need to make sure we only send the delta to the streamCh
-
-	for delta := range chatCompletion.Stream {
-		streamCh <- delta.Choices[0].Delta.Content
-	}
-
 	doneCh <- nil
 	return nil
 }
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/replicatedhq/chartsmith/pkg/param"
+)
+
+// newLocalProvider returns the provider for a self-hosted OpenAI-compatible API, such as
+// ollama, vLLM or the llama.cpp server, at CHARTSMITH_LOCAL_LLM_BASE_URL
+func newLocalProvider() *chatCompletionsProvider {
+	return &chatCompletionsProvider{
+		name:       providerLocal,
+		apiKeyName: "CHARTSMITH_LOCAL_LLM_API_KEY",
+		endpoint: func() (string, error) {
+			baseURL := param.Get().LocalLLMBaseURL
+			if baseURL == "" {
+				return "", fmt.Errorf("%w: CHARTSMITH_LOCAL_LLM_BASE_URL environment variable not set", errProviderUnavailable)
+			}
+			return strings.TrimSuffix(baseURL, "/") + "/chat/completions", nil
+		},
+		setHeaders: func(req *http.Request) error {
+			// most local servers don't require a key
+			if key := param.Get().LocalLLMAPIKey; key != "" {
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+			}
+			return nil
+		},
+	}
+}
+
+// localModelOverride returns the local model that replaces every requested model, if one is configured
+func localModelOverride() string {
+	p := param.Get()
+	if p.LocalLLMBaseURL == "" {
+		return ""
+	}
+	return p.LocalLLMModel
+}
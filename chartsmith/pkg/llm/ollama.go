@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	ollama "github.com/ollama/ollama/api"
+)
+
+const ollamaBaseURL = "https://1732d04b677e.ngrok.app"
+
+// ollamaProvider sends requests to the chat API of an ollama server. Tool calling isn't supported
+type ollamaProvider struct{}
+
+var _ Provider = ollamaProvider{}
+
+func (ollamaProvider) Name() string {
+	return providerOllama
+}
+
+func (p ollamaProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	return p.chat(ctx, req, false, nil)
+}
+
+func (p ollamaProvider) Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error) {
+	return p.chat(ctx, req, true, streamCh)
+}
+
+func (ollamaProvider) chat(ctx context.Context, req Request, stream bool, streamCh chan<- string) (resp *Response, err error) {
+	if len(req.Tools) > 0 {
+		return nil, fmt.Errorf("%w: ollama provider does not support tool calling", errProviderUnavailable)
+	}
+
+	baseURL, err := url.Parse(ollamaBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ollama URL: %w", err)
+	}
+
+	client := ollama.NewClient(baseURL, http.DefaultClient)
+
+	messages := make([]ollama.Message, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		messages = append(messages, ollama.Message{
+			Role:    string(message.Role),
+			Content: message.Content,
+		})
+	}
+
+	chatReq := &ollama.ChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   &stream,
+		Options: map[string]interface{}{
+			"num_predict": maxTokens(req),
+		},
+	}
+	if req.JSON {
+		chatReq.Format = json.RawMessage(`"json"`)
+	}
+
+	llmReq := startLLMRequest(ctx, providerOllama, req.Model)
+	var usage Usage
+	defer func() {
+		llmReq.end(usage.InputTokens, usage.OutputTokens, err)
+	}()
+
+	content := strings.Builder{}
+	err = client.Chat(ctx, chatReq, func(chatResp ollama.ChatResponse) error {
+		content.WriteString(chatResp.Message.Content)
+		if chatResp.Done {
+			usage.InputTokens = int64(chatResp.PromptEvalCount)
+			usage.OutputTokens = int64(chatResp.EvalCount)
+		}
+		return sendText(ctx, streamCh, chatResp.Message.Content)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to chat: %w", err)
+	}
+
+	return &Response{
+		Content: content.String(),
+		Usage:   usage,
+	}, nil
+}
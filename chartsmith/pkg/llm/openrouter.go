@@ -1,12 +1,10 @@
 package llm
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 
 	"github.com/replicatedhq/chartsmith/pkg/logger"
 	"github.com/replicatedhq/chartsmith/pkg/param"
@@ -21,6 +19,8 @@ type OpenRouterMessage struct {
 	Content      interface{}             `json:"content,omitempty"` // Can be string or array
 	FunctionCall *OpenRouterFunctionCall `json:"function_call,omitempty"`
 	Name         string                  `json:"name,omitempty"` // For function responses
+	ToolCalls    []OpenRouterToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID   string                  `json:"tool_call_id,omitempty"` // For tool responses
 }
 
 // OpenRouterFunctionCall represents a function call in OpenRouter format
@@ -46,6 +46,25 @@ type OpenRouterRequest struct {
 	FunctionCall interface{}          `json:"function_call,omitempty"` // "auto", "none", or {"name": "function_name"}
 	Tools        []OpenRouterTool     `json:"tools,omitempty"`         // For newer models (OpenAI format)
 	ToolChoice   interface{}          `json:"tool_choice,omitempty"`   // "auto", "none", or {"type": "function", "function": {"name": "..."}}
+	// StreamOptions asks for usage to be included in the final chunk of a stream
+	StreamOptions  *OpenRouterStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *OpenRouterResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenRouterStreamOptions represents the stream options for OpenRouter API
+type OpenRouterStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenRouterResponseFormat represents the response format for OpenRouter API, e.g. "json_object"
+type OpenRouterResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// OpenRouterUsage represents the token usage reported by OpenRouter
+type OpenRouterUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
 }
 
 // OpenRouterTool represents a tool definition (OpenAI format)
@@ -73,156 +92,61 @@ type OpenRouterResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage OpenRouterUsage `json:"usage"`
 }
 
 // OpenRouterStreamChunk represents a streaming chunk from OpenRouter
 type OpenRouterStreamChunk struct {
 	Choices []struct {
 		Delta struct {
-			Content      string                  `json:"content,omitempty"`
-			FunctionCall *OpenRouterFunctionCall `json:"function_call,omitempty"`
+			Content      string                    `json:"content,omitempty"`
+			FunctionCall *OpenRouterFunctionCall   `json:"function_call,omitempty"`
+			ToolCalls    []OpenRouterToolCallDelta `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices"`
+	Usage *OpenRouterUsage `json:"usage,omitempty"`
 }
 
-// newOpenRouterClient creates an HTTP client for OpenRouter API calls
-func newOpenRouterClient() (*http.Client, error) {
-	key := param.Get().OpenRouterAPIKey
-	if key == "" {
-		return nil, fmt.Errorf("OPENROUTER_API_KEY environment variable not set")
-	}
-	logger.Debug("OpenRouter API key detected",
-		zap.Int("key_length", len(key)),
-		zap.String("key_preview", maskAPIKey(key)))
-	return http.DefaultClient, nil
+// OpenRouterToolCallDelta is part of a tool call in a streaming chunk. The arguments of
+// a call are split across chunks that share the same index
+type OpenRouterToolCallDelta struct {
+	Index    int                    `json:"index"`
+	ID       string                 `json:"id,omitempty"`
+	Function OpenRouterFunctionCall `json:"function"`
 }
 
-// callOpenRouter makes a non-streaming call to OpenRouter API
-func callOpenRouter(ctx context.Context, model string, messages []OpenRouterMessage, maxTokens int) (string, error) {
-	client, err := newOpenRouterClient()
-	if err != nil {
-		return "", err
-	}
-
-	reqBody := OpenRouterRequest{
-		Model:     model,
-		Messages:  messages,
-		Stream:    false,
-		MaxTokens: &maxTokens,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", OpenRouterAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", param.Get().OpenRouterAPIKey))
-	req.Header.Set("HTTP-Referer", "https://chartsmith.ai")
-	req.Header.Set("X-Title", "ChartSmith")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == http.StatusUnauthorized {
-			logger.Error(fmt.Errorf("OpenRouter authentication failed"),
-				zap.Int("status_code", resp.StatusCode),
-				zap.String("body", string(body)),
-				zap.String("key_preview", maskAPIKey(param.Get().OpenRouterAPIKey)))
-		}
-		return "", fmt.Errorf("OpenRouter API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	var openRouterResp OpenRouterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openRouterResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
+// newOpenRouterProvider returns the provider for the OpenRouter chat completions API
+func newOpenRouterProvider() *chatCompletionsProvider {
+	return &chatCompletionsProvider{
+		name:       providerOpenRouter,
+		apiKeyName: "OPENROUTER_API_KEY",
+		endpoint: func() (string, error) {
+			return OpenRouterAPIURL, nil
+		},
+		setHeaders: func(req *http.Request) error {
+			key := param.Get().OpenRouterAPIKey
+			if key == "" {
+				return fmt.Errorf("%w: OPENROUTER_API_KEY environment variable not set", errProviderUnavailable)
+			}
+			logger.Debug("OpenRouter API key detected",
+				zap.Int("key_length", len(key)),
+				zap.String("key_preview", maskAPIKey(key)))
 
-	if len(openRouterResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in OpenRouter response")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+			req.Header.Set("HTTP-Referer", "https://chartsmith.ai")
+			req.Header.Set("X-Title", "ChartSmith")
+			return nil
+		},
 	}
-
-	return openRouterResp.Choices[0].Message.Content, nil
 }
 
-// streamOpenRouter makes a streaming call to OpenRouter API
-func streamOpenRouter(ctx context.Context, model string, messages []OpenRouterMessage, maxTokens int, streamCh chan<- string) error {
-	client, err := newOpenRouterClient()
-	if err != nil {
-		return err
-	}
-
-	reqBody := OpenRouterRequest{
-		Model:     model,
-		Messages:  messages,
-		Stream:    true,
-		MaxTokens: &maxTokens,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", OpenRouterAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", param.Get().OpenRouterAPIKey))
-	req.Header.Set("HTTP-Referer", "https://chartsmith.ai")
-	req.Header.Set("X-Title", "ChartSmith")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == http.StatusUnauthorized {
-			logger.Error(fmt.Errorf("OpenRouter authentication failed"),
-				zap.Int("status_code", resp.StatusCode),
-				zap.String("body", string(body)),
-				zap.String("key_preview", maskAPIKey(param.Get().OpenRouterAPIKey)))
-		}
-		return fmt.Errorf("OpenRouter API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var chunk OpenRouterStreamChunk
-		if err := decoder.Decode(&chunk); err != nil {
-			if err == io.EOF {
-				break
-			}
-			logger.Error(fmt.Errorf("failed to decode stream chunk: %w", err))
-			break
-		}
-
-		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-			select {
-			case streamCh <- chunk.Choices[0].Delta.Content:
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
+// openRouterArguments converts the JSON string arguments of a tool call, which can be empty
+func openRouterArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" {
+		return json.RawMessage("{}")
 	}
-
-	return nil
+	return json.RawMessage(arguments)
 }
 
 // isOpenRouterModel checks if a model ID is an OpenRouter model (contains a slash)
@@ -249,74 +173,3 @@ func maskAPIKey(key string) string {
 	}
 	return fmt.Sprintf("%s...%s", key[:4], key[len(key)-4:])
 }
-
-// callOpenRouterWithFunctions makes a non-streaming call to OpenRouter API with function calling support
-// Uses tools format (OpenAI-compatible) which is preferred by OpenRouter
-func callOpenRouterWithFunctions(ctx context.Context, model string, messages []OpenRouterMessage, functions []OpenRouterFunction, maxTokens int) (*OpenRouterResponse, error) {
-	client, err := newOpenRouterClient()
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert functions to tools format (OpenAI-compatible)
-	tools := make([]OpenRouterTool, len(functions))
-	for i, fn := range functions {
-		tools[i] = OpenRouterTool{
-			Type:     "function",
-			Function: fn,
-		}
-	}
-
-	reqBody := OpenRouterRequest{
-		Model:      model,
-		Messages:   messages,
-		Stream:     false,
-		MaxTokens:  &maxTokens,
-		Tools:      tools,
-		ToolChoice: "auto",
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", OpenRouterAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", param.Get().OpenRouterAPIKey))
-	req.Header.Set("HTTP-Referer", "https://chartsmith.ai")
-	req.Header.Set("X-Title", "ChartSmith")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == 401 {
-			logger.Error(fmt.Errorf("OpenRouter authentication failed"),
-				zap.Int("status_code", resp.StatusCode),
-				zap.String("body", string(body)),
-				zap.String("key_preview", maskAPIKey(param.Get().OpenRouterAPIKey)))
-			return nil, fmt.Errorf("OpenRouter API authentication failed (401): %s. Please verify your OPENROUTER_API_KEY is set correctly in your .env file and has valid credits", string(body))
-		}
-		return nil, fmt.Errorf("OpenRouter API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	var openRouterResp OpenRouterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openRouterResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(openRouterResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in OpenRouter response")
-	}
-
-	return &openRouterResp, nil
-}
@@ -7,6 +7,8 @@ import (
 	types "github.com/replicatedhq/chartsmith/pkg/llm/types"
 )
 
+var newPathAttrRegex = regexp.MustCompile(`\snewPath="([^"]+)"`)
+
 type HelmResponse struct {
 	Title     string
 	Artifacts []types.Artifact
@@ -108,7 +110,7 @@ func (p *Parser) ParsePlan(chunk string) {
 			continue
 		}
 		actionType := match[1] // "file"
-		action := match[2]     // "create" or "update"
+		action := match[2]     // "create", "update", "delete" or "rename"
 		path := match[3]       // file path
 		// strip any leading /
 		path = strings.TrimPrefix(path, "/")
@@ -128,6 +130,11 @@ func (p *Parser) ParsePlan(chunk string) {
 				Action: action,
 			}
 
+			// renames name the destination in a newPath attribute
+			if newPathMatch := newPathAttrRegex.FindStringSubmatch(match[0]); len(newPathMatch) > 1 {
+				actionPlan.NewPath = strings.TrimPrefix(newPathMatch[1], "/")
+			}
+
 			p.result.Actions[path] = actionPlan
 
 		}
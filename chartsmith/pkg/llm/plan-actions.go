@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	types "github.com/replicatedhq/chartsmith/pkg/llm/types"
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+const (
+	// planActionToolName is the tool the model calls to add a file to a detailed plan
+	planActionToolName = "add_file_action"
+
+	// planActionFormatEnvName selects how the model returns the files in a detailed plan. "tools", the
+	// default, uses tool calls. "tags" uses <chartsmithActionPlan> tags in the text, for models that
+	// can't call tools
+	planActionFormatEnvName = "CHARTSMITH_LLM_PLAN_ACTION_FORMAT"
+	planActionFormatTags    = "tags"
+)
+
+// planActionsUseTools returns false if detailed plans are configured to use the tag format
+func planActionsUseTools() bool {
+	return os.Getenv(planActionFormatEnvName) != planActionFormatTags
+}
+
+var planActionTool = Tool{
+	Name:        planActionToolName,
+	Description: "Add a file that will be created, updated, deleted or renamed to the plan. Call this once for each file",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path of the file relative to the root of the chart, e.g. templates/deployment.yaml",
+			},
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"create", "update", "delete", "rename"},
+			},
+			"new_path": map[string]interface{}{
+				"type":        "string",
+				"description": "For the rename action, the path the file is moved to, relative to the root of the chart",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "A short description of the change to make to the file",
+			},
+		},
+		"required": []string{"path", "action", "description"},
+	},
+}
+
+// planActionInput is the input to the add_file_action tool
+type planActionInput struct {
+	Path        string `json:"path"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	NewPath     string `json:"new_path"`
+}
+
+// parsePlanActionInput validates the arguments of an add_file_action call against the files in the
+// chart. The error is meant to be sent back to the model so that it can correct the call
+func parsePlanActionInput(arguments json.RawMessage, c *workspacetypes.Chart) (*types.ActionPlanWithPath, error) {
+	var input planActionInput
+	if err := json.Unmarshal(arguments, &input); err != nil {
+		return nil, fmt.Errorf("the input is not a valid JSON object: %v", err)
+	}
+
+	filePath, err := cleanPlanActionPath("path", input.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(input.Description) == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+
+	newPath := ""
+	exists := chartHasFile(c, filePath)
+
+	switch input.Action {
+	case workspacetypes.ActionFileActionCreate:
+		if exists {
+			return nil, fmt.Errorf("%s already exists, use the update action to change it", filePath)
+		}
+	case workspacetypes.ActionFileActionUpdate, workspacetypes.ActionFileActionDelete:
+		if !exists {
+			return nil, fmt.Errorf("%s does not exist, use the create action to add it", filePath)
+		}
+	case workspacetypes.ActionFileActionRename:
+		if !exists {
+			return nil, fmt.Errorf("%s does not exist and can't be renamed", filePath)
+		}
+		newPath, err = cleanPlanActionPath("new_path", input.NewPath)
+		if err != nil {
+			return nil, err
+		}
+		if newPath == filePath {
+			return nil, fmt.Errorf("new_path must be different from path")
+		}
+		if chartHasFile(c, newPath) {
+			return nil, fmt.Errorf("%s already exists and can't be replaced by a rename", newPath)
+		}
+	default:
+		return nil, fmt.Errorf("action %q is not valid, it must be one of create, update, delete or rename", input.Action)
+	}
+
+	return &types.ActionPlanWithPath{
+		Path: filePath,
+		ActionPlan: types.ActionPlan{
+			Type:        "file",
+			Action:      input.Action,
+			Description: strings.TrimSpace(input.Description),
+			NewPath:     newPath,
+		},
+	}, nil
+}
+
+// cleanPlanActionPath returns p relative to the root of the chart, or an error naming the field it came from
+func cleanPlanActionPath(field string, p string) (string, error) {
+	filePath := strings.TrimPrefix(strings.TrimSpace(p), "/")
+	switch {
+	case filePath == "":
+		return "", fmt.Errorf("%s is required", field)
+	case path.Clean(filePath) != filePath || strings.HasPrefix(filePath, "../") || filePath == "..":
+		return "", fmt.Errorf("%s %q must be a clean path relative to the root of the chart", field, p)
+	}
+	return filePath, nil
+}
+
+func chartHasFile(c *workspacetypes.Chart, filePath string) bool {
+	for _, file := range c.Files {
+		if file.FilePath == filePath {
+			return true
+		}
+	}
+	return false
+}
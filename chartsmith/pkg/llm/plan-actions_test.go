@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	types "github.com/replicatedhq/chartsmith/pkg/llm/types"
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+func TestParsePlanActionInput(t *testing.T) {
+	chart := &workspacetypes.Chart{
+		Files: []workspacetypes.File{
+			{FilePath: "Chart.yaml"},
+			{FilePath: "values.yaml"},
+			{FilePath: "templates/deployment.yaml"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      *types.ActionPlanWithPath
+		wantErr   string
+	}{
+		{
+			name:      "create",
+			arguments: `{"path": "templates/service.yaml", "action": "create", "description": " Add a service "}`,
+			want: &types.ActionPlanWithPath{
+				Path:       "templates/service.yaml",
+				ActionPlan: types.ActionPlan{Type: "file", Action: "create", Description: "Add a service"},
+			},
+		},
+		{
+			name:      "update with leading slash",
+			arguments: `{"path": "/values.yaml", "action": "update", "description": "Add replicaCount"}`,
+			want: &types.ActionPlanWithPath{
+				Path:       "values.yaml",
+				ActionPlan: types.ActionPlan{Type: "file", Action: "update", Description: "Add replicaCount"},
+			},
+		},
+		{
+			name:      "delete",
+			arguments: `{"path": "templates/deployment.yaml", "action": "delete", "description": "Remove the deployment"}`,
+			want: &types.ActionPlanWithPath{
+				Path:       "templates/deployment.yaml",
+				ActionPlan: types.ActionPlan{Type: "file", Action: "delete", Description: "Remove the deployment"},
+			},
+		},
+		{
+			name:      "rename",
+			arguments: `{"path": "templates/deployment.yaml", "action": "rename", "new_path": "templates/app.yaml", "description": "Rename the deployment"}`,
+			want: &types.ActionPlanWithPath{
+				Path:       "templates/deployment.yaml",
+				ActionPlan: types.ActionPlan{Type: "file", Action: "rename", Description: "Rename the deployment", NewPath: "templates/app.yaml"},
+			},
+		},
+		{
+			name:      "not json",
+			arguments: `{"path": `,
+			wantErr:   "not a valid JSON object",
+		},
+		{
+			name:      "no path",
+			arguments: `{"action": "create", "description": "Add a file"}`,
+			wantErr:   "path is required",
+		},
+		{
+			name:      "no description",
+			arguments: `{"path": "templates/service.yaml", "action": "create", "description": "  "}`,
+			wantErr:   "description is required",
+		},
+		{
+			name:      "create existing file",
+			arguments: `{"path": "values.yaml", "action": "create", "description": "Add values"}`,
+			wantErr:   "values.yaml already exists, use the update action",
+		},
+		{
+			name:      "update missing file",
+			arguments: `{"path": "templates/service.yaml", "action": "update", "description": "Change the port"}`,
+			wantErr:   "templates/service.yaml does not exist, use the create action",
+		},
+		{
+			name:      "delete missing file",
+			arguments: `{"path": "templates/service.yaml", "action": "delete", "description": "Remove the service"}`,
+			wantErr:   "templates/service.yaml does not exist",
+		},
+		{
+			name:      "rename missing file",
+			arguments: `{"path": "templates/service.yaml", "action": "rename", "new_path": "templates/svc.yaml", "description": "Rename"}`,
+			wantErr:   "does not exist and can't be renamed",
+		},
+		{
+			name:      "rename without new path",
+			arguments: `{"path": "templates/deployment.yaml", "action": "rename", "description": "Rename"}`,
+			wantErr:   "new_path is required",
+		},
+		{
+			name:      "rename to the same path",
+			arguments: `{"path": "templates/deployment.yaml", "action": "rename", "new_path": "/templates/deployment.yaml", "description": "Rename"}`,
+			wantErr:   "new_path must be different from path",
+		},
+		{
+			name:      "rename over existing file",
+			arguments: `{"path": "templates/deployment.yaml", "action": "rename", "new_path": "values.yaml", "description": "Rename"}`,
+			wantErr:   "values.yaml already exists and can't be replaced",
+		},
+		{
+			name:      "rename outside the chart",
+			arguments: `{"path": "templates/deployment.yaml", "action": "rename", "new_path": "../deployment.yaml", "description": "Rename"}`,
+			wantErr:   `new_path "../deployment.yaml" must be a clean path`,
+		},
+		{
+			name:      "invalid action",
+			arguments: `{"path": "values.yaml", "action": "patch", "description": "Patch values"}`,
+			wantErr:   `action "patch" is not valid`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlanActionInput(json.RawMessage(tt.arguments), chart)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePlanActionInput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanPlanActionPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{name: "relative", path: "templates/deployment.yaml", want: "templates/deployment.yaml"},
+		{name: "leading slash", path: "/templates/deployment.yaml", want: "templates/deployment.yaml"},
+		{name: "surrounding space", path: "  values.yaml\n", want: "values.yaml"},
+		{name: "hidden file", path: ".helmignore", want: ".helmignore"},
+		{name: "empty", path: "", wantErr: "path is required"},
+		{name: "only a slash", path: "/", wantErr: "path is required"},
+		{name: "parent", path: "..", wantErr: "must be a clean path"},
+		{name: "outside the chart", path: "../other/values.yaml", wantErr: "must be a clean path"},
+		{name: "parent in the middle", path: "templates/../values.yaml", wantErr: "must be a clean path"},
+		{name: "current directory", path: "./values.yaml", wantErr: "must be a clean path"},
+		{name: "double slash", path: "templates//deployment.yaml", wantErr: "must be a clean path"},
+		{name: "trailing slash", path: "templates/", wantErr: "must be a clean path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanPlanActionPath("path", tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("cleanPlanActionPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
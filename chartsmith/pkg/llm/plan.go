@@ -3,9 +3,9 @@ package llm
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
 	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
 	"go.uber.org/zap"
@@ -17,9 +17,12 @@ type CreatePlanOpts struct {
 	RelevantFiles []workspacetypes.File
 	IsUpdate      bool
 	ModelID       string
+	// RenderFindings are the findings of the last render of the revision, keyed by the path of the rendered file
+	RenderFindings map[string][]workspacetypes.RenderFinding
 }
 
 func CreatePlan(ctx context.Context, streamCh chan string, doneCh chan error, opts CreatePlanOpts) error {
+	ctx = withChatResponse(withStage(ctx, StagePlan))
 	fileNameArgs := []string{}
 	for _, file := range opts.RelevantFiles {
 		fileNameArgs = append(fileNameArgs, file.FilePath)
@@ -30,48 +33,39 @@ func CreatePlan(ctx context.Context, streamCh chan string, doneCh chan error, op
 		zap.Bool("isUpdate", opts.IsUpdate),
 	)
 
-	// Use model from opts, default to DefaultModel if not set
-	modelID := opts.ModelID
-	if modelID == "" {
-		modelID = DefaultModel
-	}
-
-	// Use OpenRouter if model is OpenRouter format
-	if isOpenRouterModel(modelID) {
-		return createPlanOpenRouter(ctx, streamCh, doneCh, opts, modelID)
-	}
-
-	// Use Anthropic
-	client, err := newAnthropicClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create anthropic client: %w", err)
-	}
-
 	chartStructure, err := getChartStructure(ctx, opts.Chart)
 	if err != nil {
 		return fmt.Errorf("failed to get chart structure: %w", err)
 	}
 
-	messages := []anthropic.MessageParam{}
+	messages := []Message{}
 
 	if !opts.IsUpdate {
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(initialPlanSystemPrompt)))
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(initialPlanInstructions)))
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`Chart structure: %s`, chartStructure))))
-
+		systemPrompt, err := renderPrompts(ctx, promptInitialPlan, promptInitialPlanInstructions)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, Message{Role: RoleSystem, Content: systemPrompt})
+		messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("Chart structure: %s", chartStructure)})
 	} else {
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(updatePlanSystemPrompt)))
-		messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(updatePlanInstructions)))
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`Chart structure: %s`, chartStructure))))
+		systemPrompt, err := renderPrompts(ctx, promptUpdatePlan, promptUpdatePlanInstructions)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, Message{Role: RoleSystem, Content: systemPrompt})
+		messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("Chart structure: %s", chartStructure)})
 		for _, file := range opts.RelevantFiles {
-			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf(`File: %s, Content: %s`, file.FilePath, file.Content))))
+			messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("File: %s, Content: %s", file.FilePath, file.Content)})
+		}
+		if findingsMessage := renderFindingsMessage(opts.RenderFindings); findingsMessage != "" {
+			messages = append(messages, Message{Role: RoleUser, Content: findingsMessage})
 		}
 	}
 
 	for _, chatMessage := range opts.ChatMessages {
-		messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(chatMessage.Prompt)))
+		messages = append(messages, Message{Role: RoleUser, Content: chatMessage.Prompt})
 		if chatMessage.Response != "" {
-			messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(chatMessage.Response)))
+			messages = append(messages, Message{Role: RoleAssistant, Content: chatMessage.Response})
 		}
 	}
 
@@ -80,95 +74,48 @@ func CreatePlan(ctx context.Context, streamCh chan string, doneCh chan error, op
 		verb = "edit"
 	}
 	initialUserMessage := fmt.Sprintf("Describe the plan only (do not write code) to %s a helm chart based on the previous discussion. ", verb)
+	messages = append(messages, Message{Role: RoleUser, Content: initialUserMessage})
 
-	messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(initialUserMessage)))
-
-	// tools := []anthropic.ToolParam{
-	// 	{
-	// 		Name:        anthropic.F("recommended_dependency"),
-	// 		Description: anthropic.F("Recommend a specific subchart or version of a subchart given a requirement"),
-	// 		InputSchema: anthropic.F(interface{}(map[string]interface{}{
-	// 			"type": "object",
-	// 			"properties": map[string]interface{}{
-	// 				"requirement": map[string]interface{}{
-	// 					"type":        "string",
-	// 					"description": "The requirement to recommend a dependency for, e.g. Redis, Mysql",
-	// 				},
-	// 			},
-	// 			"required": []string{"requirement"},
-	// 		})),
-	// 	},
-	// }
-
-	stream := client.Messages.NewStreaming(context.TODO(), anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		// Tools:     anthropic.F(tools),
-		Messages: anthropic.F(messages),
-	})
-
-	message := anthropic.Message{}
-	for stream.Next() {
-		event := stream.Current()
-		message.Accumulate(event)
-
-		switch delta := event.Delta.(type) {
-		case anthropic.ContentBlockDeltaEventDelta:
-			if delta.Text != "" {
-				streamCh <- delta.Text
-			}
-		}
-	}
-
-	if stream.Err() != nil {
-		doneCh <- stream.Err()
+	if _, err := stream(ctx, opts.ModelID, messages, streamCh); err != nil {
+		doneCh <- err
+		return err
 	}
 
 	doneCh <- nil
 	return nil
 }
 
-// createPlanOpenRouter handles plan creation using OpenRouter
-func createPlanOpenRouter(ctx context.Context, streamCh chan string, doneCh chan error, opts CreatePlanOpts, modelID string) error {
-	chartStructure, err := getChartStructure(ctx, opts.Chart)
-	if err != nil {
-		return fmt.Errorf("failed to get chart structure: %w", err)
+// renderFindingsMessage describes the errors and warnings found in the last render, so that they can be fixed
+// when the user asks for it. It's empty when there are none
+func renderFindingsMessage(findings map[string][]workspacetypes.RenderFinding) string {
+	paths := make([]string, 0, len(findings))
+	for path := range findings {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
 
-	messages := []OpenRouterMessage{}
-
-	if !opts.IsUpdate {
-		messages = append(messages, OpenRouterMessage{Role: "system", Content: initialPlanSystemPrompt + "\n\n" + initialPlanInstructions})
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: fmt.Sprintf("Chart structure: %s", chartStructure)})
-	} else {
-		messages = append(messages, OpenRouterMessage{Role: "system", Content: updatePlanSystemPrompt + "\n\n" + updatePlanInstructions})
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: fmt.Sprintf("Chart structure: %s", chartStructure)})
-		for _, file := range opts.RelevantFiles {
-			messages = append(messages, OpenRouterMessage{Role: "user", Content: fmt.Sprintf("File: %s, Content: %s", file.FilePath, file.Content)})
-		}
-	}
+	lines := []string{}
+	for _, path := range paths {
+		for _, finding := range findings[path] {
+			if finding.Severity == workspacetypes.FindingSeverityInfo {
+				continue
+			}
 
-	for _, chatMessage := range opts.ChatMessages {
-		messages = append(messages, OpenRouterMessage{Role: "user", Content: chatMessage.Prompt})
-		if chatMessage.Response != "" {
-			messages = append(messages, OpenRouterMessage{Role: "assistant", Content: chatMessage.Response})
+			resource := finding.Kind
+			if finding.Name != "" {
+				resource += "/" + finding.Name
+			}
+			line := fmt.Sprintf("- %s: %s (%s %s) %s", path, finding.Severity, finding.Source, finding.Rule, resource)
+			if finding.Field != "" {
+				line += " " + finding.Field
+			}
+			lines = append(lines, line+": "+finding.Message)
 		}
 	}
 
-	verb := "create"
-	if opts.IsUpdate {
-		verb = "edit"
-	}
-	initialUserMessage := fmt.Sprintf("Describe the plan only (do not write code) to %s a helm chart based on the previous discussion. ", verb)
-	messages = append(messages, OpenRouterMessage{Role: "user", Content: initialUserMessage})
-
-	// Stream the response
-	err = streamOpenRouter(ctx, modelID, messages, 8192, streamCh)
-	if err != nil {
-		doneCh <- err
-		return err
+	if len(lines) == 0 {
+		return ""
 	}
 
-	doneCh <- nil
-	return nil
+	return fmt.Sprintf("The last render of the chart found these problems in the rendered files. The rendered files are named after the templates that render them:\n%s", strings.Join(lines, "\n"))
 }
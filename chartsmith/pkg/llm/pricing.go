@@ -0,0 +1,41 @@
+package llm
+
+// modelPrice is the list price of a model in USD per million tokens
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// modelPrices are keyed by the model name sent to the provider. Update this when prices change
+// or a model is added, calls to models that aren't listed are recorded without a cost
+var modelPrices = map[string]modelPrice{
+	// anthropic
+	Model_Sonnet37: {input: 3, output: 15},
+	Model_Sonnet35: {input: 3, output: 15},
+
+	// openrouter
+	"anthropic/claude-sonnet-4.5": {input: 3, output: 15},
+	"anthropic/claude-haiku-4.5":  {input: 1, output: 5},
+	"google/gemini-3-pro-preview": {input: 2, output: 12},
+	"openai/gpt-5.1-codex":        {input: 1.25, output: 10},
+	"x-ai/grok-code-fast-1":       {input: 0.2, output: 1.5},
+
+	// groq
+	groqChatModel:      {input: 0.59, output: 0.79},
+	groqReasoningModel: {input: 0.75, output: 0.99},
+}
+
+// costUSD returns the cost of a request, and false if the price of the model isn't known
+func costUSD(provider string, model string, inputTokens int64, outputTokens int64) (float64, bool) {
+	// self-hosted models don't have a per-token cost
+	if provider == providerLocal || provider == providerOllama {
+		return 0, true
+	}
+
+	price, ok := modelPrices[model]
+	if !ok {
+		return 0, false
+	}
+
+	return (float64(inputTokens)*price.input + float64(outputTokens)*price.output) / 1_000_000, true
+}
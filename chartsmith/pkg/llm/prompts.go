@@ -0,0 +1,305 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/workspace"
+	"go.uber.org/zap"
+)
+
+// The prompts are text templates in prompts/<version>/<name>.tmpl. A version only needs the files it
+// changes, the rest are inherited from basePromptVersion. Templates include each other by file name,
+// e.g. {{ template "common.tmpl" . }}, so changing common.tmpl in a version changes every prompt
+const (
+	basePromptVersion = "v1"
+
+	// promptVersionEnvName sets the version used by default, basePromptVersion if it's not set
+	promptVersionEnvName = "CHARTSMITH_PROMPT_VERSION"
+
+	// promptABEnvPrefix assigns a percentage of the requests for a stage to another version, e.g.
+	// CHARTSMITH_PROMPT_AB_EXECUTE_ACTION=v2:20 uses v2 for 20% of the plans that execute actions
+	promptABEnvPrefix = "CHARTSMITH_PROMPT_AB_"
+)
+
+// the names of the templates, without .tmpl
+const (
+	promptChatOnly                 = "chat-only"
+	promptChatOnlyInstructions     = "chat-only-instructions"
+	promptInitialPlan              = "initial-plan"
+	promptInitialPlanInstructions  = "initial-plan-instructions"
+	promptUpdatePlan               = "update-plan"
+	promptUpdatePlanInstructions   = "update-plan-instructions"
+	promptDetailedPlan             = "detailed-plan"
+	promptDetailedPlanTools        = "detailed-plan-tools"
+	promptDetailedPlanInstructions = "detailed-plan-instructions"
+	promptExecutePlan              = "execute-plan"
+	promptConvertFile              = "convert-file"
+	promptCleanupConvertedValues   = "cleanup-converted-values"
+	promptIntentAuto               = "intent-auto"
+	promptIntentDeveloper          = "intent-developer"
+	promptIntentOperator           = "intent-operator"
+	promptFeedbackNotDeveloper     = "feedback-not-developer"
+	promptFeedbackNotOperator      = "feedback-not-operator"
+	promptFeedbackAmbiguous        = "feedback-ambiguous"
+	promptDeclineOffTopic          = "decline-off-topic"
+)
+
+//go:embed prompts
+var promptFiles embed.FS
+
+var (
+	promptsOnce     sync.Once
+	promptTemplates map[string]*template.Template
+	promptsErr      error
+)
+
+// loadPrompts parses the templates of every version in prompts/
+func loadPrompts() (map[string]*template.Template, error) {
+	promptsOnce.Do(func() {
+		base, err := template.ParseFS(promptFiles, "prompts/"+basePromptVersion+"/*.tmpl")
+		if err != nil {
+			promptsErr = fmt.Errorf("failed to parse %s prompts: %w", basePromptVersion, err)
+			return
+		}
+
+		templates := map[string]*template.Template{basePromptVersion: base}
+
+		entries, err := fs.ReadDir(promptFiles, "prompts")
+		if err != nil {
+			promptsErr = fmt.Errorf("failed to list prompt versions: %w", err)
+			return
+		}
+
+		for _, entry := range entries {
+			version := entry.Name()
+			if !entry.IsDir() || version == basePromptVersion {
+				continue
+			}
+
+			t, err := base.Clone()
+			if err != nil {
+				promptsErr = fmt.Errorf("failed to clone %s prompts: %w", basePromptVersion, err)
+				return
+			}
+			if _, err := t.ParseFS(promptFiles, "prompts/"+version+"/*.tmpl"); err != nil {
+				promptsErr = fmt.Errorf("failed to parse %s prompts: %w", version, err)
+				return
+			}
+			templates[version] = t
+		}
+
+		promptTemplates = templates
+	})
+
+	return promptTemplates, promptsErr
+}
+
+// PromptVersions returns the versions of the prompts, sorted
+func PromptVersions() ([]string, error) {
+	templates, err := loadPrompts()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for version := range templates {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// renderPrompt renders the prompt called name in the version selected for ctx. data is available to
+// the template, e.g. as {{ .Prompt }}
+func renderPrompt(ctx context.Context, name string, data interface{}) (string, error) {
+	templates, err := loadPrompts()
+	if err != nil {
+		return "", err
+	}
+
+	version := promptVersionFor(ctx)
+	t, ok := templates[version]
+	if !ok {
+		t = templates[basePromptVersion]
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name+".tmpl", data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt %s: %w", version, name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// renderPrompts renders each prompt without data and joins them with a blank line
+func renderPrompts(ctx context.Context, names ...string) (string, error) {
+	rendered := []string{}
+	for _, name := range names {
+		prompt, err := renderPrompt(ctx, name, nil)
+		if err != nil {
+			return "", err
+		}
+		rendered = append(rendered, prompt)
+	}
+	return strings.Join(rendered, "\n\n"), nil
+}
+
+// promptVersionFor returns the prompt version for the stage of ctx. When an A/B split is configured
+// for the stage, the version is chosen by hashing the plan, chat message or workspace in the usage
+// scope, so that every request for the same plan or message uses the same version. Splits are ignored
+// while fixtures are recorded or replayed, since the IDs they hash are different in each run
+func promptVersionFor(ctx context.Context) string {
+	version := defaultPromptVersion()
+
+	stage := stageFromContext(ctx)
+	if stage == "" || fixturesEnabled.Load() {
+		return version
+	}
+
+	value := os.Getenv(promptABEnvPrefix + strings.ToUpper(string(stage)))
+	if value == "" {
+		return version
+	}
+
+	candidate, percent, err := parsePromptAB(value)
+	if err != nil {
+		logger.Warn("Ignoring invalid prompt A/B setting", zap.String("stage", string(stage)), zap.Error(err))
+		return version
+	}
+	if !promptVersionExists(candidate) {
+		logger.Warn("Ignoring prompt A/B setting for unknown version", zap.String("stage", string(stage)), zap.String("version", candidate))
+		return version
+	}
+
+	scope := usageScopeFromContext(ctx)
+	key := scope.PlanID
+	if key == "" {
+		key = scope.ChatMessageID
+	}
+	if key == "" {
+		key = scope.WorkspaceID
+	}
+	if key == "" {
+		return version
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(string(stage) + ":" + key))
+	if int(h.Sum32()%100) < percent {
+		return candidate
+	}
+
+	return version
+}
+
+func defaultPromptVersion() string {
+	version := os.Getenv(promptVersionEnvName)
+	if version == "" {
+		return basePromptVersion
+	}
+	if !promptVersionExists(version) {
+		logger.Warn("Unknown prompt version, using the base version", zap.String("version", version))
+		return basePromptVersion
+	}
+	return version
+}
+
+func promptVersionExists(version string) bool {
+	templates, err := loadPrompts()
+	if err != nil {
+		return false
+	}
+	_, ok := templates[version]
+	return ok
+}
+
+// parsePromptAB parses an A/B setting in the form <version>:<percent>
+func parsePromptAB(value string) (string, int, error) {
+	version, percentValue, ok := strings.Cut(value, ":")
+	if !ok || version == "" {
+		return "", 0, fmt.Errorf("%q is not in the form <version>:<percent>", value)
+	}
+
+	percent, err := strconv.Atoi(percentValue)
+	if err != nil || percent < 0 || percent > 100 {
+		return "", 0, fmt.Errorf("%q is not a percentage between 0 and 100", percentValue)
+	}
+
+	return version, percent, nil
+}
+
+// recordPromptVersion saves the prompt version of a request on the chat message and plan it was made
+// for, keyed by stage. Failing to save it doesn't fail the request
+func recordPromptVersion(ctx context.Context, r *llmRequest) {
+	if r.stage == "" || r.promptVersion == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if r.scope.ChatMessageID != "" {
+		if err := workspace.SetChatMessagePromptVersion(ctx, r.scope.ChatMessageID, string(r.stage), r.promptVersion); err != nil {
+			logger.Error(fmt.Errorf("failed to record chat message prompt version: %w", err))
+		}
+	}
+
+	if r.scope.PlanID != "" {
+		if err := workspace.SetPlanPromptVersion(ctx, r.scope.PlanID, string(r.stage), r.promptVersion); err != nil {
+			logger.Error(fmt.Errorf("failed to record plan prompt version: %w", err))
+		}
+	}
+}
+
+// StrReplaceReportRow is the str_replace success of the execute_action prompts of one version
+type StrReplaceReportRow struct {
+	PromptVersion string
+	Replacements  int64
+	Failures      int64
+}
+
+// GetStrReplaceReport counts the str_replace edits logged since the given time and how many failed, by prompt version
+func GetStrReplaceReport(ctx context.Context, since time.Time) ([]StrReplaceReportRow, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT coalesce(prompt_version, ''), count(*), count(*) FILTER (WHERE NOT found OR error_message IS NOT NULL)
+		FROM str_replace_log
+		WHERE created_at >= $1
+		GROUP BY 1
+		ORDER BY 1`
+	rows, err := conn.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query str_replace log: %w", err)
+	}
+	defer rows.Close()
+
+	var report []StrReplaceReportRow
+	for rows.Next() {
+		var row StrReplaceReportRow
+		if err := rows.Scan(&row.PromptVersion, &row.Replacements, &row.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan str_replace log: %w", err)
+		}
+		report = append(report, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate str_replace log: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestParsePromptAB(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantVersion string
+		wantPercent int
+		wantErr     bool
+	}{
+		{value: "v2:20", wantVersion: "v2", wantPercent: 20},
+		{value: "v2:0", wantVersion: "v2", wantPercent: 0},
+		{value: "v2:100", wantVersion: "v2", wantPercent: 100},
+		{value: "v2", wantErr: true},
+		{value: ":20", wantErr: true},
+		{value: "v2:", wantErr: true},
+		{value: "v2:twenty", wantErr: true},
+		{value: "v2:-1", wantErr: true},
+		{value: "v2:101", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			version, percent, err := parsePromptAB(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || percent != tt.wantPercent {
+				t.Errorf("parsePromptAB() = %q, %d, want %q, %d", version, percent, tt.wantVersion, tt.wantPercent)
+			}
+		})
+	}
+}
+
+// withTestPromptVersion adds a copy of the base prompts as another version for the duration of a test
+func withTestPromptVersion(t *testing.T, version string) {
+	templates, err := loadPrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone, err := templates[basePromptVersion].Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates[version] = clone
+	t.Cleanup(func() { delete(templates, version) })
+}
+
+func TestPromptVersionFor(t *testing.T) {
+	withTestPromptVersion(t, "vtest")
+
+	scoped := func(stage Stage, scope UsageScope) context.Context {
+		return WithUsageScope(withStage(context.Background(), stage), scope)
+	}
+
+	tests := []struct {
+		name           string
+		defaultVersion string
+		ab             string
+		fixtures       bool
+		ctx            context.Context
+		want           string
+	}{
+		{name: "base", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "default version", defaultVersion: "vtest", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "vtest"},
+		{name: "unknown default version", defaultVersion: "v9", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "all requests", ab: "vtest:100", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "vtest"},
+		{name: "no requests", ab: "vtest:0", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "keyed by chat message", ab: "vtest:100", ctx: scoped(StageExecuteAction, UsageScope{ChatMessageID: "m1"}), want: "vtest"},
+		{name: "keyed by workspace", ab: "vtest:100", ctx: scoped(StageExecuteAction, UsageScope{WorkspaceID: "w1"}), want: "vtest"},
+		{name: "nothing to key by", ab: "vtest:100", ctx: scoped(StageExecuteAction, UsageScope{}), want: "v1"},
+		{name: "no stage", ab: "vtest:100", ctx: WithUsageScope(context.Background(), UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "other stage", ab: "vtest:100", ctx: scoped(StageConversational, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "invalid split", ab: "vtest", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "unknown version", ab: "v9:100", ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+		{name: "fixtures", ab: "vtest:100", fixtures: true, ctx: scoped(StageExecuteAction, UsageScope{PlanID: "p1"}), want: "v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(promptVersionEnvName, tt.defaultVersion)
+			t.Setenv(promptABEnvPrefix+"EXECUTE_ACTION", tt.ab)
+
+			enabled := fixturesEnabled.Load()
+			fixturesEnabled.Store(tt.fixtures)
+			defer fixturesEnabled.Store(enabled)
+
+			if got := promptVersionFor(tt.ctx); got != tt.want {
+				t.Errorf("promptVersionFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptVersionForSplit(t *testing.T) {
+	withTestPromptVersion(t, "vtest")
+	t.Setenv(promptVersionEnvName, "")
+	t.Setenv(promptABEnvPrefix+"EXECUTE_ACTION", "vtest:20")
+
+	candidates := 0
+	for i := 0; i < 1000; i++ {
+		ctx := WithUsageScope(withStage(context.Background(), StageExecuteAction), UsageScope{PlanID: fmt.Sprintf("plan-%d", i)})
+
+		version := promptVersionFor(ctx)
+		if again := promptVersionFor(ctx); again != version {
+			t.Fatalf("plan-%d got %q and then %q", i, version, again)
+		}
+		if version == "vtest" {
+			candidates++
+		}
+	}
+
+	if candidates < 150 || candidates > 250 {
+		t.Errorf("%d of 1000 plans got the 20%% version", candidates)
+	}
+}
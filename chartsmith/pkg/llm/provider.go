@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Role is the author of a Message
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// Message is a provider-neutral chat message. Each Provider converts these to its own API format
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant message asked to run
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// ToolCallID is the ID of the call that a tool message is the result of
+	ToolCallID string `json:"toolCallId,omitempty"`
+}
+
+// Tool describes a function that the model can call. Parameters is a JSON schema object
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall is a request from the model to run a tool. Arguments is a JSON object
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Request is a single request to a model
+type Request struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Tools     []Tool    `json:"tools,omitempty"`
+	MaxTokens int       `json:"maxTokens,omitempty"`
+	// JSON asks the model to respond with a JSON object. Providers that can't enforce this ignore it
+	JSON bool `json:"json,omitempty"`
+}
+
+// Response is the full response to a Request
+type Response struct {
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	Usage     Usage      `json:"usage"`
+}
+
+// Usage is the number of tokens a request used. Counts the provider didn't report are 0
+type Usage struct {
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+}
+
+// Provider sends requests to the models of a single LLM API
+type Provider interface {
+	// Name is the name of the provider, used in the model registry, metrics and traces
+	Name() string
+
+	// Complete sends the request and waits for the full response
+	Complete(ctx context.Context, req Request) (*Response, error)
+
+	// Stream sends the request and writes text to streamCh as it's generated. streamCh can be nil.
+	// The full response, including any tool calls, is returned once the stream ends
+	Stream(ctx context.Context, req Request, streamCh chan<- string) (*Response, error)
+}
+
+// defaultMaxTokens is used when a Request doesn't set MaxTokens
+const defaultMaxTokens = 8192
+
+// ToolHandler runs a tool call and returns the result to send back to the model
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
+
+// runWithTools streams the request, runs any tool calls the model makes with handleTool and sends
+// the results back until the model responds without calling a tool. The last response is returned.
+// When provider is a fallback chain, the turns after the first are sent to the model that served the
+// first turn, since the tool calls and their results are in that model's format
+func runWithTools(ctx context.Context, provider Provider, req Request, streamCh chan<- string, handleTool ToolHandler) (*Response, error) {
+	messages := append([]Message{}, req.Messages...)
+
+	for {
+		req.Messages = messages
+		resp, err := provider.Stream(ctx, req, streamCh)
+		if err != nil {
+			return nil, err
+		}
+
+		if chain, ok := provider.(*modelChain); ok {
+			if pinned, ok := chain.pinned(); ok {
+				provider = pinned
+			}
+		}
+
+		if len(resp.ToolCalls) == 0 {
+			return resp, nil
+		}
+
+		messages = append(messages, Message{
+			Role:      RoleAssistant,
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+
+		for _, call := range resp.ToolCalls {
+			result, err := handleTool(ctx, call)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run tool %s: %w", call.Name, err)
+			}
+
+			messages = append(messages, Message{
+				Role:       RoleTool,
+				Content:    result,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
+
+// complete sends a one-shot request to modelID, falling back to the fallback models of the stage
+func complete(ctx context.Context, modelID string, messages []Message) (string, error) {
+	chain, err := resolveChain(ctx, modelID)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := chain.Complete(ctx, Request{
+		Messages: messages,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to call %s: %w", chain.Name(), err)
+	}
+
+	return resp.Content, nil
+}
+
+// stream streams the response from modelID to streamCh, falling back to the fallback models of the stage
+func stream(ctx context.Context, modelID string, messages []Message, streamCh chan<- string) (*Response, error) {
+	chain, err := resolveChain(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := chain.Stream(ctx, Request{
+		Messages: messages,
+	}, streamCh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream from %s: %w", chain.Name(), err)
+	}
+
+	return resp, nil
+}
+
+// sendText writes text to streamCh unless streamCh is nil or ctx is done
+func sendText(ctx context.Context, streamCh chan<- string, text string) error {
+	if streamCh == nil || text == "" {
+		return nil
+	}
+
+	select {
+	case streamCh <- text:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func maxTokens(req Request) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return defaultMaxTokens
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/replicatedhq/chartsmith/pkg/param"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+)
+
+// QuotaExceededError is returned by CheckQuota when a user or workspace has used up one of its LLM quotas
+type QuotaExceededError struct {
+	// Subject is "user" or "workspace"
+	Subject string
+	// Quota describes the limit that was reached, e.g. "100000 tokens per day"
+	Quota string
+	// Resets describes when more requests will be allowed
+	Resets string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s llm quota of %s exceeded", e.Subject, e.Quota)
+}
+
+// Message explains the limit to the user, for use as a chat response
+func (e *QuotaExceededError) Message() string {
+	whose := "your account"
+	if e.Subject == "workspace" {
+		whose = "this workspace"
+	}
+	return fmt.Sprintf("I can't work on this right now because %s has reached its limit of %s. Please try again %s.", whose, e.Quota, e.Resets)
+}
+
+// IsQuotaExceeded returns the QuotaExceededError in err's chain, if any
+func IsQuotaExceeded(err error) (*QuotaExceededError, bool) {
+	var quotaErr *QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return quotaErr, true
+	}
+	return nil, false
+}
+
+// CheckQuota returns a QuotaExceededError if the user or workspace in scope has used up any of the
+// quotas configured in params. Usage is read from llm_usage over a rolling window, so it's shared
+// by all workers. The user defaults to the creator of the workspace
+func CheckQuota(ctx context.Context, scope UsageScope) error {
+	p := param.Get()
+	if p.LLMUserTokensPerDay == 0 && p.LLMWorkspaceTokensPerDay == 0 &&
+		p.LLMUserRequestsPerMinute == 0 && p.LLMWorkspaceRequestsPerMinute == 0 {
+		return nil
+	}
+
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	userID := scope.UserID
+	if userID == "" && scope.WorkspaceID != "" {
+		var createdBy sql.NullString
+		err := conn.QueryRow(ctx, `SELECT created_by_user_id FROM workspace WHERE id = $1`, scope.WorkspaceID).Scan(&createdBy)
+		if err != nil && err != pgx.ErrNoRows {
+			return fmt.Errorf("failed to get workspace user: %w", err)
+		}
+		userID = createdBy.String
+	}
+
+	query := `SELECT
+		coalesce(sum(input_tokens + output_tokens) FILTER (WHERE user_id = $1), 0)::bigint,
+		count(*) FILTER (WHERE user_id = $1 AND created_at >= now() - interval '1 minute'),
+		coalesce(sum(input_tokens + output_tokens) FILTER (WHERE workspace_id = $2), 0)::bigint,
+		count(*) FILTER (WHERE workspace_id = $2 AND created_at >= now() - interval '1 minute')
+		FROM llm_usage
+		WHERE created_at >= now() - interval '1 day' AND (user_id = $1 OR workspace_id = $2)`
+
+	var userTokens, userRequests, workspaceTokens, workspaceRequests int64
+	if err := conn.QueryRow(ctx, query, userID, scope.WorkspaceID).Scan(&userTokens, &userRequests, &workspaceTokens, &workspaceRequests); err != nil {
+		return fmt.Errorf("failed to get llm usage: %w", err)
+	}
+
+	if userID != "" {
+		if p.LLMUserRequestsPerMinute > 0 && userRequests >= p.LLMUserRequestsPerMinute {
+			return requestsPerMinuteExceeded("user", p.LLMUserRequestsPerMinute)
+		}
+		if p.LLMUserTokensPerDay > 0 && userTokens >= p.LLMUserTokensPerDay {
+			return tokensPerDayExceeded("user", p.LLMUserTokensPerDay)
+		}
+	}
+
+	if scope.WorkspaceID != "" {
+		if p.LLMWorkspaceRequestsPerMinute > 0 && workspaceRequests >= p.LLMWorkspaceRequestsPerMinute {
+			return requestsPerMinuteExceeded("workspace", p.LLMWorkspaceRequestsPerMinute)
+		}
+		if p.LLMWorkspaceTokensPerDay > 0 && workspaceTokens >= p.LLMWorkspaceTokensPerDay {
+			return tokensPerDayExceeded("workspace", p.LLMWorkspaceTokensPerDay)
+		}
+	}
+
+	return nil
+}
+
+func requestsPerMinuteExceeded(subject string, limit int64) error {
+	return &QuotaExceededError{
+		Subject: subject,
+		Quota:   fmt.Sprintf("%d model requests per minute", limit),
+		Resets:  "in a minute",
+	}
+}
+
+func tokensPerDayExceeded(subject string, limit int64) error {
+	return &QuotaExceededError{
+		Subject: subject,
+		Quota:   fmt.Sprintf("%d tokens in 24 hours", limit),
+		Resets:  "later, as usage from the last 24 hours expires",
+	}
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestQuotaExceededError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantExceeds bool
+		wantError   string
+		wantMessage string
+	}{
+		{
+			name:        "user requests",
+			err:         requestsPerMinuteExceeded("user", 10),
+			wantExceeds: true,
+			wantError:   "user llm quota of 10 model requests per minute exceeded",
+			wantMessage: "I can't work on this right now because your account has reached its limit of 10 model requests per minute. Please try again in a minute.",
+		},
+		{
+			name:        "wrapped workspace tokens",
+			err:         fmt.Errorf("failed to check quota: %w", tokensPerDayExceeded("workspace", 500000)),
+			wantExceeds: true,
+			wantError:   "workspace llm quota of 500000 tokens in 24 hours exceeded",
+			wantMessage: "I can't work on this right now because this workspace has reached its limit of 500000 tokens in 24 hours. Please try again later, as usage from the last 24 hours expires.",
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed to get llm usage"),
+		},
+		{
+			name: "nil",
+			err:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaErr, ok := IsQuotaExceeded(tt.err)
+			if ok != tt.wantExceeds {
+				t.Fatalf("IsQuotaExceeded() = %v, want %v", ok, tt.wantExceeds)
+			}
+			if !ok {
+				return
+			}
+			if got := quotaErr.Error(); got != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got, tt.wantError)
+			}
+			if got := quotaErr.Message(); got != tt.wantMessage {
+				t.Errorf("Message() = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// groqChatModel is the fast model used for intent detection and file conversion
+	groqChatModel = "llama-3.3-70b-versatile"
+	// groqReasoningModel is a reasoning model available on groq
+	groqReasoningModel = "deepseek-r1-distill-llama-70b"
+	// ollamaCodeModel is a small code model available on ollama
+	ollamaCodeModel = "codellama:7b"
+)
+
+var (
+	registryMu sync.RWMutex
+	providers  = map[string]Provider{}
+	// models maps model IDs that can't be routed by their format to the provider that serves them
+	models = map[string]string{}
+)
+
+func init() {
+	RegisterProvider(anthropicProvider{})
+	RegisterProvider(newOpenRouterProvider())
+	RegisterProvider(groqProvider{})
+	RegisterProvider(ollamaProvider{})
+	RegisterProvider(newLocalProvider())
+
+	RegisterModel(groqChatModel, providerGroq)
+	RegisterModel(groqReasoningModel, providerGroq)
+	RegisterModel(ollamaCodeModel, providerOllama)
+}
+
+// RegisterProvider makes a provider available to ResolveModel, replacing any provider with the same name
+func RegisterProvider(provider Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	providers[provider.Name()] = provider
+}
+
+// RegisterModel routes modelID to the named provider
+func RegisterModel(modelID string, providerName string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	models[modelID] = providerName
+}
+
+// ResolveModel returns the provider for modelID and the model name to send to it. Model IDs are resolved in order:
+//   - "local:<model>" always uses the local OpenAI-compatible server
+//   - when CHARTSMITH_LOCAL_LLM_BASE_URL and CHARTSMITH_LOCAL_LLM_MODEL are set, every other
+//     model ID resolves to the local model so that no hosted API is needed
+//   - a model registered with RegisterModel
+//   - "<provider>:<model>", e.g. "groq:llama-3.3-70b-versatile" or "ollama:codellama:7b"
+//   - "<vendor>/<model>" is an OpenRouter model, e.g. "anthropic/claude-sonnet-4.5"
+//   - anything else is an Anthropic model
+//
+// An empty modelID resolves DefaultModel
+func ResolveModel(modelID string) (Provider, string, error) {
+	if modelID == "" {
+		modelID = DefaultModel
+	}
+
+	if localModel := localModelOverride(); localModel != "" && !strings.HasPrefix(modelID, providerLocal+":") {
+		modelID = providerLocal + ":" + localModel
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providerName, model := providerAnthropic, modelID
+	if name, ok := models[modelID]; ok {
+		providerName = name
+	} else if name, rest, ok := strings.Cut(modelID, ":"); ok && providers[name] != nil {
+		providerName, model = name, rest
+	} else if isOpenRouterModel(modelID) {
+		providerName = providerOpenRouter
+	}
+
+	provider, ok := providers[providerName]
+	if !ok {
+		return nil, "", fmt.Errorf("no provider %q registered for model %s", providerName, modelID)
+	}
+
+	return provider, model, nil
+}
@@ -4,18 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/jackc/pgx/v5"
-	"github.com/jpoz/groq"
-	"github.com/ollama/ollama/api"
-	ollama "github.com/ollama/ollama/api"
 	"github.com/replicatedhq/chartsmith/pkg/logger"
-	"github.com/replicatedhq/chartsmith/pkg/param"
 	"github.com/replicatedhq/chartsmith/pkg/persistence"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
@@ -37,6 +30,7 @@ func SummarizeContent(ctx context.Context, content string) (string, error) {
 }
 
 func SummarizeContentWithModel(ctx context.Context, content string, modelID string) (string, error) {
+	ctx = withStage(ctx, StageSummarize)
 	if content == "" {
 		return "", nil
 	}
@@ -68,26 +62,12 @@ func SummarizeContentWithModel(ctx context.Context, content string, modelID stri
 		return "", fmt.Errorf("rate limiter wait failed: %w", err)
 	}
 
-	// Use OpenRouter if model is OpenRouter format
-	if isOpenRouterModel(modelID) {
-		summary, err := summarizeContentWithOpenRouter(ctx, content, modelID)
-		if err != nil {
-			return "", fmt.Errorf("failed to summarize content with OpenRouter: %w", err)
-		}
-		// Cache the successful result
-		insertQuery := `INSERT INTO summary_cache (content_sha256, summary) VALUES ($1, $2)`
-		if _, err := conn.Exec(ctx, insertQuery, fmt.Sprintf("%x", sha256), summary); err != nil {
-			logger.Error(fmt.Errorf("failed to insert summary into cache: %w", err))
-		}
-		return summary, nil
-	}
-
 	// Try up to 3 times with exponential backoff
 	var summary string
 	var lastErr error
 	for i := 0; i < 3; i++ {
 		var err error
-		summary, err = summarizeContentWithClaude(ctx, content, modelID)
+		summary, err = summarize(ctx, content, modelID)
 		if err == nil {
 			break
 		}
@@ -115,100 +95,21 @@ func SummarizeContentWithModel(ctx context.Context, content string, modelID stri
 	return summary, nil
 }
 
-func summarizeContentWithClaude(ctx context.Context, content string, modelID string) (string, error) {
-	// Default to DefaultModel if modelID is empty
-	if modelID == "" {
-		modelID = DefaultModel
-	}
-
-	client, err := newAnthropicClient(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to create anthropic client: %w", err)
-	}
-
+func summarize(ctx context.Context, content string, modelID string) (string, error) {
 	userMessage := "My helm chart includes the following file. Summarize it, including all names, variables, etc that it uses: " + content
 
-	logger.Debug("Sending request to Claude API")
+	logger.Debug("Sending summarize request", zap.String("model", modelID))
 	startTime := time.Now()
 
-	resp, err := client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.F(modelID),
-		MaxTokens: anthropic.F(int64(8192)),
-		Messages:  anthropic.F([]anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(userMessage))}),
+	summary, err := complete(ctx, modelID, []Message{
+		{Role: RoleUser, Content: userMessage},
 	})
-
 	if err != nil {
 		return "", fmt.Errorf("failed to summarize content: %w", err)
 	}
 
-	logger.Debug("Received response from Claude API",
+	logger.Debug("Received summarize response",
 		zap.Duration("duration", time.Since(startTime)))
 
-	return resp.Content[0].Text, nil
-}
-
-func summarizeContentWithGroq(ctx context.Context, content string) (string, error) {
-	client := groq.NewClient(groq.WithAPIKey(param.Get().GroqAPIKey))
-
-	userMessage := "My helm chart includes the following file. Summarize it, including all names, variables, etc that it uses: " + content
-
-	chatCompletion, err := client.CreateChatCompletion(groq.CompletionCreateParams{
-		Model: "deepseek-r1-distill-llama-70b",
-		Messages: []groq.Message{
-			{
-				Role:    "user",
-				Content: userMessage,
-			},
-		},
-	})
-
-	if err != nil {
-		return "", fmt.Errorf("failed to summarize content: %w", err)
-	}
-
-	return strings.TrimSpace(chatCompletion.Choices[0].Message.Content), nil
-}
-
-func summarizeContentWithOllama(ctx context.Context, content string) (string, error) {
-	baseURL, err := url.Parse("https://1732d04b677e.ngrok.app")
-	if err != nil {
-		return "", fmt.Errorf("failed to parse ollama URL: %w", err)
-	}
-
-	client := ollama.NewClient(baseURL, http.DefaultClient)
-
-	userMessage := "My helm chart includes the following file. Summarize it, including all names, variables, etc that it uses: " + content
-
-	req := &ollama.GenerateRequest{
-		Model:  "codellama:7b",
-		Prompt: userMessage,
-		Stream: new(bool),
-	}
-
-	var summary string
-	respFunc := func(resp api.GenerateResponse) error {
-		summary = resp.Response
-		return nil
-	}
-
-	if err := client.Generate(ctx, req, respFunc); err != nil {
-		return "", fmt.Errorf("failed to summarize content: %w", err)
-	}
-
-	return summary, nil
-}
-
-func summarizeContentWithOpenRouter(ctx context.Context, content string, modelID string) (string, error) {
-	userMessage := "My helm chart includes the following file. Summarize it, including all names, variables, etc that it uses: " + content
-
-	messages := []OpenRouterMessage{
-		{Role: "user", Content: userMessage},
-	}
-
-	summary, err := callOpenRouter(ctx, modelID, messages, 8192)
-	if err != nil {
-		return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
-	}
-
 	return strings.TrimSpace(summary), nil
 }
@@ -0,0 +1,267 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	llmtypes "github.com/replicatedhq/chartsmith/pkg/llm/types"
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// multiFileActionsEnvName lets the model view and edit the other files in the chart, e.g. the
+// _helpers.tpl a template depends on, while it executes the action for one file
+const multiFileActionsEnvName = "CHARTSMITH_LLM_MULTI_FILE_ACTIONS"
+
+func multiFileActionsEnabled() bool {
+	return os.Getenv(multiFileActionsEnvName) == "true"
+}
+
+var textEditorTool = Tool{
+	Name:        TextEditor_Sonnet35,
+	Description: "Text editor tool for viewing, creating, and modifying files. view with a view_range returns the lines prefixed with their line numbers",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"view", "str_replace", "create", "insert", "undo_edit"},
+			},
+			"path": map[string]interface{}{
+				"type": "string",
+			},
+			"old_str": map[string]interface{}{
+				"type": "string",
+			},
+			"new_str": map[string]interface{}{
+				"type": "string",
+			},
+			"insert_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "For insert, the line after which new_str is inserted. 0 inserts at the beginning of the file",
+			},
+			"view_range": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "integer"},
+				"description": "For view, the first and last line to show, starting at 1. A last line of -1 shows the rest of the file",
+			},
+		},
+		"required": []string{"command", "path"},
+	},
+}
+
+// textEditorInput is the input to the text_editor tool
+type textEditorInput struct {
+	Command    string `json:"command"`
+	Path       string `json:"path"`
+	OldStr     string `json:"old_str"`
+	NewStr     string `json:"new_str"`
+	InsertLine *int   `json:"insert_line"`
+	ViewRange  []int  `json:"view_range"`
+}
+
+// fileVersion is the content of a file before an edit, kept for undo_edit
+type fileVersion struct {
+	content string
+	exists  bool
+}
+
+// textEditorSession is the working copy of the files that one action can edit. Edits are kept in
+// memory and only returned when the action completes, so that they can be saved together
+type textEditorSession struct {
+	// path is the file of the action, the only file that can be created
+	path string
+	// multiFile allows the other files in the chart to be viewed and edited
+	multiFile bool
+
+	files    map[string]string
+	original map[string]string
+	history  map[string][]fileVersion
+
+	interimContentCh chan llmtypes.Artifact
+}
+
+func newTextEditorSession(path string, files map[string]string, multiFile bool, interimContentCh chan llmtypes.Artifact) *textEditorSession {
+	s := &textEditorSession{
+		path:             path,
+		multiFile:        multiFile,
+		files:            map[string]string{},
+		original:         map[string]string{},
+		history:          map[string][]fileVersion{},
+		interimContentCh: interimContentCh,
+	}
+
+	for filePath, content := range files {
+		s.files[filePath] = content
+		s.original[filePath] = content
+	}
+
+	return s
+}
+
+// otherPaths returns the paths of the files other than the action's file, sorted
+func (s *textEditorSession) otherPaths() []string {
+	paths := []string{}
+	for filePath := range s.files {
+		if filePath != s.path {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// changedFiles returns the content of each file that is different from when the session started
+func (s *textEditorSession) changedFiles() map[string]string {
+	changed := map[string]string{}
+	for filePath, content := range s.files {
+		if original, ok := s.original[filePath]; !ok || original != content {
+			changed[filePath] = content
+		}
+	}
+	return changed
+}
+
+// handle runs one text_editor command. Mistakes are returned as an error message for the model
+func (s *textEditorSession) handle(ctx context.Context, input textEditorInput) string {
+	filePath := strings.TrimPrefix(input.Path, "/")
+	if filePath != s.path && !s.multiFile {
+		return fmt.Sprintf("Error: Only %s can be viewed or edited in this action.", s.path)
+	}
+
+	content, exists := s.files[filePath]
+
+	switch input.Command {
+	case "view":
+		if !exists {
+			return "Error: File does not exist. Use create instead."
+		}
+		if len(input.ViewRange) == 0 {
+			return content
+		}
+		return viewRange(content, input.ViewRange)
+
+	case "str_replace":
+		if !exists {
+			return "Error: File does not exist. Use create instead."
+		}
+
+		// First check if the string is found in the content for logging
+		found := strings.Contains(content, input.OldStr)
+
+		// Log every str_replace operation, successful or not
+		if err := logStrReplaceOperation(ctx, filePath, input.OldStr, input.NewStr, content, found); err != nil {
+			logger.Warn("str_replace logging failed", zap.Error(err))
+		}
+
+		newContent, success, replaceErr := PerformStringReplacement(content, input.OldStr, input.NewStr)
+		if !success {
+			errorMsg := "String to replace not found in file"
+			if replaceErr != nil {
+				errorMsg = replaceErr.Error()
+			}
+
+			if err := UpdateStrReplaceLogErrorMessage(ctx, filePath, input.OldStr, errorMsg); err != nil {
+				logger.Warn("Failed to update error message in str_replace log", zap.Error(err))
+			}
+
+			return "Error: String to replace not found in file. Please use smaller, more precise replacements."
+		}
+
+		s.edit(filePath, newContent)
+		return "Content replaced successfully"
+
+	case "create":
+		if exists {
+			return "Error: File already exists. Use view and str_replace instead."
+		}
+		if filePath != s.path {
+			return fmt.Sprintf("Error: Only %s can be created in this action.", s.path)
+		}
+
+		s.edit(filePath, input.NewStr)
+		return "Created"
+
+	case "insert":
+		if !exists {
+			return "Error: File does not exist. Use create instead."
+		}
+		if input.InsertLine == nil {
+			return "Error: insert_line is required for insert."
+		}
+
+		lines := strings.Split(content, "\n")
+		if *input.InsertLine < 0 || *input.InsertLine > len(lines) {
+			return fmt.Sprintf("Error: insert_line must be between 0 and %d.", len(lines))
+		}
+
+		newLines := append([]string{}, lines[:*input.InsertLine]...)
+		newLines = append(newLines, strings.Split(input.NewStr, "\n")...)
+		newLines = append(newLines, lines[*input.InsertLine:]...)
+
+		s.edit(filePath, strings.Join(newLines, "\n"))
+		return "Inserted"
+
+	case "undo_edit":
+		versions := s.history[filePath]
+		if len(versions) == 0 {
+			return "Error: There are no edits to undo for this file."
+		}
+
+		previous := versions[len(versions)-1]
+		s.history[filePath] = versions[:len(versions)-1]
+
+		if previous.exists {
+			s.files[filePath] = previous.content
+		} else {
+			delete(s.files, filePath)
+		}
+		s.sendInterim(filePath)
+		return "Last edit undone"
+	}
+
+	return fmt.Sprintf("Error: Unknown command %s", input.Command)
+}
+
+// edit replaces the content of a file, saving the previous content for undo_edit
+func (s *textEditorSession) edit(filePath string, newContent string) {
+	content, exists := s.files[filePath]
+	s.history[filePath] = append(s.history[filePath], fileVersion{content: content, exists: exists})
+	s.files[filePath] = newContent
+	s.sendInterim(filePath)
+}
+
+// sendInterim sends the current content of a file, or that it no longer exists
+func (s *textEditorSession) sendInterim(filePath string) {
+	if s.interimContentCh == nil {
+		return
+	}
+
+	content, exists := s.files[filePath]
+	s.interimContentCh <- llmtypes.Artifact{Path: filePath, Content: content, Deleted: !exists}
+}
+
+// viewRange returns the lines of content in viewRange, prefixed with their line numbers
+func viewRange(content string, viewRange []int) string {
+	lines := strings.Split(content, "\n")
+	if len(viewRange) != 2 {
+		return "Error: view_range must be the first and last line to show."
+	}
+
+	start, end := viewRange[0], viewRange[1]
+	if end == -1 {
+		end = len(lines)
+	}
+	if start < 1 || start > len(lines) || end < start || end > len(lines) {
+		return fmt.Sprintf("Error: view_range must be within lines 1 to %d.", len(lines))
+	}
+
+	var sb strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&sb, "%6d\t%s\n", i, lines[i-1])
+	}
+	return sb.String()
+}
@@ -0,0 +1,190 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmtypes "github.com/replicatedhq/chartsmith/pkg/llm/types"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestTextEditorSession(t *testing.T) {
+	files := map[string]string{
+		"templates/deployment.yaml": "kind: Deployment\nmetadata:\n  name: app",
+		"templates/_helpers.tpl":    "{{- define \"name\" -}}app{{- end -}}",
+	}
+
+	type step struct {
+		input textEditorInput
+		want  string
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		multiFile   bool
+		steps       []step
+		wantChanged map[string]string
+		wantInterim []llmtypes.Artifact
+	}{
+		{
+			name: "view",
+			path: "templates/deployment.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "view", Path: "/templates/deployment.yaml"}, want: "kind: Deployment\nmetadata:\n  name: app"},
+				{input: textEditorInput{Command: "view", Path: "templates/deployment.yaml", ViewRange: []int{2, -1}}, want: "     2\tmetadata:\n     3\t  name: app\n"},
+				{input: textEditorInput{Command: "view", Path: "templates/deployment.yaml", ViewRange: []int{1, 1}}, want: "     1\tkind: Deployment\n"},
+				{input: textEditorInput{Command: "view", Path: "templates/deployment.yaml", ViewRange: []int{2, 4}}, want: "Error: view_range must be within lines 1 to 3."},
+				{input: textEditorInput{Command: "view", Path: "templates/deployment.yaml", ViewRange: []int{2}}, want: "Error: view_range must be the first and last line to show."},
+			},
+			wantChanged: map[string]string{},
+		},
+		{
+			name: "other files need multi-file actions",
+			path: "templates/deployment.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "view", Path: "templates/_helpers.tpl"}, want: "Error: Only templates/deployment.yaml can be viewed or edited in this action."},
+			},
+			wantChanged: map[string]string{},
+		},
+		{
+			name: "create",
+			path: "templates/service.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "view", Path: "templates/service.yaml"}, want: "Error: File does not exist. Use create instead."},
+				{input: textEditorInput{Command: "create", Path: "templates/service.yaml", NewStr: "kind: Service"}, want: "Created"},
+				{input: textEditorInput{Command: "create", Path: "templates/service.yaml", NewStr: "kind: Service"}, want: "Error: File already exists. Use view and str_replace instead."},
+			},
+			wantChanged: map[string]string{"templates/service.yaml": "kind: Service"},
+			wantInterim: []llmtypes.Artifact{{Path: "templates/service.yaml", Content: "kind: Service"}},
+		},
+		{
+			name:      "create only the action's file",
+			path:      "templates/deployment.yaml",
+			multiFile: true,
+			steps: []step{
+				{input: textEditorInput{Command: "create", Path: "templates/service.yaml", NewStr: "kind: Service"}, want: "Error: Only templates/deployment.yaml can be created in this action."},
+			},
+			wantChanged: map[string]string{},
+		},
+		{
+			name: "insert",
+			path: "templates/deployment.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", NewStr: "apiVersion: apps/v1"}, want: "Error: insert_line is required for insert."},
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", InsertLine: intPtr(4), NewStr: "spec: {}"}, want: "Error: insert_line must be between 0 and 3."},
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", InsertLine: intPtr(0), NewStr: "apiVersion: apps/v1"}, want: "Inserted"},
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", InsertLine: intPtr(4), NewStr: "spec:\n  replicas: 1"}, want: "Inserted"},
+			},
+			wantChanged: map[string]string{"templates/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\nspec:\n  replicas: 1"},
+			wantInterim: []llmtypes.Artifact{
+				{Path: "templates/deployment.yaml", Content: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app"},
+				{Path: "templates/deployment.yaml", Content: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\nspec:\n  replicas: 1"},
+			},
+		},
+		{
+			name:      "undo edits",
+			path:      "templates/deployment.yaml",
+			multiFile: true,
+			steps: []step{
+				{input: textEditorInput{Command: "undo_edit", Path: "templates/deployment.yaml"}, want: "Error: There are no edits to undo for this file."},
+				{input: textEditorInput{Command: "insert", Path: "templates/_helpers.tpl", InsertLine: intPtr(1), NewStr: "# helpers"}, want: "Inserted"},
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", InsertLine: intPtr(0), NewStr: "# one"}, want: "Inserted"},
+				{input: textEditorInput{Command: "insert", Path: "templates/deployment.yaml", InsertLine: intPtr(0), NewStr: "# two"}, want: "Inserted"},
+				{input: textEditorInput{Command: "undo_edit", Path: "templates/deployment.yaml"}, want: "Last edit undone"},
+				{input: textEditorInput{Command: "undo_edit", Path: "templates/deployment.yaml"}, want: "Last edit undone"},
+				{input: textEditorInput{Command: "undo_edit", Path: "templates/deployment.yaml"}, want: "Error: There are no edits to undo for this file."},
+			},
+			wantChanged: map[string]string{"templates/_helpers.tpl": "{{- define \"name\" -}}app{{- end -}}\n# helpers"},
+			wantInterim: []llmtypes.Artifact{
+				{Path: "templates/_helpers.tpl", Content: "{{- define \"name\" -}}app{{- end -}}\n# helpers"},
+				{Path: "templates/deployment.yaml", Content: "# one\nkind: Deployment\nmetadata:\n  name: app"},
+				{Path: "templates/deployment.yaml", Content: "# two\n# one\nkind: Deployment\nmetadata:\n  name: app"},
+				{Path: "templates/deployment.yaml", Content: "# one\nkind: Deployment\nmetadata:\n  name: app"},
+				{Path: "templates/deployment.yaml", Content: "kind: Deployment\nmetadata:\n  name: app"},
+			},
+		},
+		{
+			name: "undo create",
+			path: "templates/service.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "create", Path: "templates/service.yaml", NewStr: "kind: Service"}, want: "Created"},
+				{input: textEditorInput{Command: "undo_edit", Path: "templates/service.yaml"}, want: "Last edit undone"},
+				{input: textEditorInput{Command: "view", Path: "templates/service.yaml"}, want: "Error: File does not exist. Use create instead."},
+			},
+			wantChanged: map[string]string{},
+			wantInterim: []llmtypes.Artifact{
+				{Path: "templates/service.yaml", Content: "kind: Service"},
+				{Path: "templates/service.yaml", Deleted: true},
+			},
+		},
+		{
+			name: "str_replace in a missing file",
+			path: "templates/service.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "str_replace", Path: "templates/service.yaml", OldStr: "a", NewStr: "b"}, want: "Error: File does not exist. Use create instead."},
+			},
+			wantChanged: map[string]string{},
+		},
+		{
+			name: "unknown command",
+			path: "templates/deployment.yaml",
+			steps: []step{
+				{input: textEditorInput{Command: "delete", Path: "templates/deployment.yaml"}, want: "Error: Unknown command delete"},
+			},
+			wantChanged: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interimCh := make(chan llmtypes.Artifact, 100)
+			s := newTextEditorSession(tt.path, files, tt.multiFile, interimCh)
+
+			for i, step := range tt.steps {
+				if got := s.handle(context.Background(), step.input); got != step.want {
+					t.Fatalf("step %d: handle(%s) = %q, want %q", i, step.input.Command, got, step.want)
+				}
+			}
+			close(interimCh)
+
+			if got := s.changedFiles(); !reflect.DeepEqual(got, tt.wantChanged) {
+				t.Errorf("changedFiles() = %q, want %q", got, tt.wantChanged)
+			}
+
+			interim := []llmtypes.Artifact{}
+			for artifact := range interimCh {
+				interim = append(interim, artifact)
+			}
+			if tt.wantInterim == nil {
+				tt.wantInterim = []llmtypes.Artifact{}
+			}
+			if !reflect.DeepEqual(interim, tt.wantInterim) {
+				t.Errorf("interim artifacts = %+v, want %+v", interim, tt.wantInterim)
+			}
+		})
+	}
+
+	// the session works on a copy of the files
+	if !strings.HasPrefix(files["templates/deployment.yaml"], "kind: Deployment") {
+		t.Errorf("session changed the files it was created with")
+	}
+}
+
+func TestTextEditorSessionOtherPaths(t *testing.T) {
+	s := newTextEditorSession("templates/deployment.yaml", map[string]string{
+		"values.yaml":               "",
+		"templates/deployment.yaml": "",
+		"Chart.yaml":                "",
+	}, true, nil)
+
+	want := []string{"Chart.yaml", "values.yaml"}
+	if got := s.otherPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("otherPaths() = %q, want %q", got, want)
+	}
+}
@@ -14,12 +14,17 @@ const (
 )
 
 type ActionPlan struct {
-	Type   string           `json:"type"`
-	Action string           `json:"action"`
-	Status ActionPlanStatus `json:"status"`
+	Type        string           `json:"type"`
+	Action      string           `json:"action"`
+	Status      ActionPlanStatus `json:"status"`
+	Description string           `json:"description,omitempty"`
+	// NewPath is the path a file is moved to by a rename action
+	NewPath string `json:"newPath,omitempty"`
 }
 
 type Artifact struct {
 	Path    string
 	Content string
+	// Deleted is set when an edit that created the file was undone
+	Deleted bool
 }
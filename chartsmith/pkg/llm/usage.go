@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"github.com/tuvistavie/securerandom"
+	"go.uber.org/zap"
+)
+
+// Stage is the step of the pipeline that an LLM request was made for
+type Stage string
+
+const (
+	StageIntent         Stage = "intent"
+	StagePlan           Stage = "plan"
+	StageExpand         Stage = "expand"
+	StageExecutePlan    Stage = "execute_plan"
+	StageExecuteAction  Stage = "execute_action"
+	StageConversational Stage = "conversational"
+	StageConvert        Stage = "convert"
+	StageCleanup        Stage = "cleanup"
+	StageSummarize      Stage = "summarize"
+)
+
+// UsageScope identifies what an LLM request was made for, so that its token usage and cost
+// can be attributed. Empty fields are recorded as NULL
+type UsageScope struct {
+	WorkspaceID   string
+	UserID        string
+	ChatMessageID string
+	PlanID        string
+}
+
+type usageScopeKey struct{}
+type usageStageKey struct{}
+
+// WithUsageScope returns a context that attributes LLM requests made with it to scope. Fields
+// that are empty in scope are inherited from any scope already in ctx
+func WithUsageScope(ctx context.Context, scope UsageScope) context.Context {
+	parent := usageScopeFromContext(ctx)
+	if scope.WorkspaceID == "" {
+		scope.WorkspaceID = parent.WorkspaceID
+	}
+	if scope.UserID == "" {
+		scope.UserID = parent.UserID
+	}
+	if scope.ChatMessageID == "" {
+		scope.ChatMessageID = parent.ChatMessageID
+	}
+	if scope.PlanID == "" {
+		scope.PlanID = parent.PlanID
+	}
+	return context.WithValue(ctx, usageScopeKey{}, scope)
+}
+
+// chatMessageUsageScope attributes requests to a chat message, the user that sent it and its workspace
+func chatMessageUsageScope(chatMessage *workspacetypes.Chat) UsageScope {
+	return UsageScope{
+		WorkspaceID:   chatMessage.WorkspaceID,
+		UserID:        chatMessage.SentByUserID,
+		ChatMessageID: chatMessage.ID,
+	}
+}
+
+// PlanUsageScope attributes requests to a plan, its workspace and the chat message that most recently updated it
+func PlanUsageScope(plan *workspacetypes.Plan) UsageScope {
+	scope := UsageScope{
+		WorkspaceID: plan.WorkspaceID,
+		PlanID:      plan.ID,
+	}
+	if len(plan.ChatMessageIDs) > 0 {
+		scope.ChatMessageID = plan.ChatMessageIDs[len(plan.ChatMessageIDs)-1]
+	}
+	return scope
+}
+
+func usageScopeFromContext(ctx context.Context) UsageScope {
+	scope, _ := ctx.Value(usageScopeKey{}).(UsageScope)
+	return scope
+}
+
+// withStage tags the LLM requests made with ctx with stage
+func withStage(ctx context.Context, stage Stage) context.Context {
+	return context.WithValue(ctx, usageStageKey{}, stage)
+}
+
+func stageFromContext(ctx context.Context) Stage {
+	stage, _ := ctx.Value(usageStageKey{}).(Stage)
+	return stage
+}
+
+// recordUsage stores the token usage and cost of a request in llm_usage. Usage is recorded even
+// when ctx was canceled by the request, and failing to record it doesn't fail the request
+func recordUsage(ctx context.Context, r *llmRequest, inputTokens int64, outputTokens int64, latency time.Duration, reqErr error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	id, err := securerandom.Hex(6)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to generate llm usage id: %w", err))
+		return
+	}
+
+	cost, ok := costUSD(r.provider, r.model, inputTokens, outputTokens)
+	if !ok {
+		logger.Debug("No price for model, recording usage without a cost",
+			zap.String("provider", r.provider),
+			zap.String("model", r.model))
+	}
+
+	errorText := ""
+	if reqErr != nil {
+		errorText = reqErr.Error()
+	}
+
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	// the user is the one who created the workspace, unless the caller knows better
+	query := `INSERT INTO llm_usage
+		(id, created_at, provider, model, stage, workspace_id, user_id, chat_message_id, plan_id,
+		input_tokens, output_tokens, latency_ms, cost_usd, error, prompt_version)
+		VALUES ($1, now(), $2, $3, $4, $5, COALESCE($6, (SELECT created_by_user_id FROM workspace WHERE id = $5)), $7, $8,
+		$9, $10, $11, $12, $13, $14)`
+	_, err = conn.Exec(ctx, query,
+		id,
+		r.provider,
+		r.model,
+		sql.NullString{String: string(r.stage), Valid: r.stage != ""},
+		sql.NullString{String: r.scope.WorkspaceID, Valid: r.scope.WorkspaceID != ""},
+		sql.NullString{String: r.scope.UserID, Valid: r.scope.UserID != ""},
+		sql.NullString{String: r.scope.ChatMessageID, Valid: r.scope.ChatMessageID != ""},
+		sql.NullString{String: r.scope.PlanID, Valid: r.scope.PlanID != ""},
+		inputTokens,
+		outputTokens,
+		latency.Milliseconds(),
+		sql.NullFloat64{Float64: cost, Valid: ok},
+		sql.NullString{String: errorText, Valid: errorText != ""},
+		r.promptVersion,
+	)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to record llm usage: %w", err))
+	}
+}
+
+// UsageGroupBy is the column that a usage report is grouped by
+type UsageGroupBy string
+
+const (
+	UsageGroupByWorkspace UsageGroupBy = "workspace"
+	UsageGroupByUser      UsageGroupBy = "user"
+	UsageGroupByPlan      UsageGroupBy = "plan"
+	// UsageGroupByPromptVersion groups by stage and prompt version, to compare prompt versions
+	UsageGroupByPromptVersion UsageGroupBy = "prompt_version"
+)
+
+var usageGroupByColumns = map[UsageGroupBy]string{
+	UsageGroupByWorkspace:     "workspace_id",
+	UsageGroupByUser:          "user_id",
+	UsageGroupByPlan:          "plan_id",
+	UsageGroupByPromptVersion: "stage || ':' || coalesce(prompt_version, '')",
+}
+
+// UsageReportRow is the total usage of one workspace, user, plan or prompt version
+type UsageReportRow struct {
+	// Key is the ID of the workspace, user or plan, and empty for requests that weren't attributed to one.
+	// For prompt versions it's <stage>:<version>
+	Key          string
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+	// UnpricedRequests is the number of requests to models without a price, which aren't included in CostUSD
+	UnpricedRequests int64
+}
+
+// GetUsageReport totals the usage recorded since the given time, most expensive first
+func GetUsageReport(ctx context.Context, groupBy UsageGroupBy, since time.Time) ([]UsageReportRow, error) {
+	column, ok := usageGroupByColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown group by %q", groupBy)
+	}
+
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := fmt.Sprintf(`SELECT %s, count(*), sum(input_tokens)::bigint, sum(output_tokens)::bigint,
+		coalesce(sum(cost_usd), 0)::double precision, count(*) - count(cost_usd)
+		FROM llm_usage
+		WHERE created_at >= $1
+		GROUP BY %s
+		ORDER BY 5 DESC, 2 DESC`, column, column)
+	rows, err := conn.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query llm usage: %w", err)
+	}
+	defer rows.Close()
+
+	var report []UsageReportRow
+	for rows.Next() {
+		var row UsageReportRow
+		var key sql.NullString
+		if err := rows.Scan(&key, &row.Requests, &row.InputTokens, &row.OutputTokens, &row.CostUSD, &row.UnpricedRequests); err != nil {
+			return nil, fmt.Errorf("failed to scan llm usage: %w", err)
+		}
+		row.Key = key.String
+		report = append(report, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate llm usage: %w", err)
+	}
+
+	return report, nil
+}
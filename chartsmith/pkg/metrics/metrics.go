@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "chartsmith"
+
+var (
+	queueTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "work_queue",
+		Name:      "depth",
+		Help:      "Number of uncompleted jobs in the work queue",
+	}, []string{"channel"})
+
+	queueInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "work_queue",
+		Name:      "in_flight",
+		Help:      "Number of jobs in the work queue that have been claimed by a worker",
+	}, []string{"channel"})
+
+	queueAvailable = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "work_queue",
+		Name:      "available",
+		Help:      "Number of jobs in the work queue that are ready to be claimed",
+	}, []string{"channel"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "handler",
+		Name:      "duration_seconds",
+		Help:      "Time spent running a work queue handler",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"channel", "outcome"})
+
+	handlerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "handler",
+		Name:      "failures_total",
+		Help:      "Number of work queue handler runs that returned an error or panicked",
+	}, []string{"channel"})
+
+	llmRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "request_duration_seconds",
+		Help:      "Time spent waiting on an LLM request, including streaming the response",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model", "outcome"})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Number of tokens used by LLM requests",
+	}, []string{"provider", "model", "direction"})
+
+	helmRenderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "helm",
+		Name:      "render_duration_seconds",
+		Help:      "Time spent rendering a chart with helm",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"outcome"})
+
+	realtimePublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "realtime",
+		Name:      "publish_failures_total",
+		Help:      "Number of messages that could not be published to Centrifugo",
+	})
+)
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// SetQueueStats records the most recent queue statistics for a channel
+func SetQueueStats(channel string, total int, inFlight int, available int) {
+	queueTotal.WithLabelValues(channel).Set(float64(total))
+	queueInFlight.WithLabelValues(channel).Set(float64(inFlight))
+	queueAvailable.WithLabelValues(channel).Set(float64(available))
+}
+
+// ObserveHandler records a single run of a handler
+func ObserveHandler(channel string, duration time.Duration, err error) {
+	handlerDuration.WithLabelValues(channel, outcome(err)).Observe(duration.Seconds())
+	if err != nil {
+		handlerFailures.WithLabelValues(channel).Inc()
+	}
+}
+
+// ObserveLLMRequest records the latency and token usage of a single LLM request.
+// Token counts that the provider didn't report should be passed as 0
+func ObserveLLMRequest(provider string, model string, duration time.Duration, inputTokens int64, outputTokens int64, err error) {
+	llmRequestDuration.WithLabelValues(provider, model, outcome(err)).Observe(duration.Seconds())
+	if inputTokens > 0 {
+		llmTokens.WithLabelValues(provider, model, "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		llmTokens.WithLabelValues(provider, model, "output").Add(float64(outputTokens))
+	}
+}
+
+// ObserveHelmRender records the time it took to render a chart
+func ObserveHelmRender(duration time.Duration, err error) {
+	helmRenderDuration.WithLabelValues(outcome(err)).Observe(duration.Seconds())
+}
+
+// IncRealtimePublishFailures counts a message that failed to publish to Centrifugo
+func IncRealtimePublishFailures() {
+	realtimePublishFailures.Inc()
+}
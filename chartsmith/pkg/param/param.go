@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -24,6 +25,14 @@ var paramLookup = map[string]string{
 	"CHARTSMITH_TOKEN_ENCRYPTION":   "/chartsmith/token_encryption",
 	"CHARTSMITH_SLACK_TOKEN":        "/chartsmith/slack_token",
 	"CHARTSMITH_SLACK_CHANNEL":      "/chartsmith/slack_channel",
+	"CHARTSMITH_LOCAL_LLM_BASE_URL": "",
+	"CHARTSMITH_LOCAL_LLM_API_KEY":  "",
+	"CHARTSMITH_LOCAL_LLM_MODEL":    "",
+
+	"CHARTSMITH_LLM_USER_TOKENS_PER_DAY":           "",
+	"CHARTSMITH_LLM_WORKSPACE_TOKENS_PER_DAY":      "",
+	"CHARTSMITH_LLM_USER_REQUESTS_PER_MINUTE":      "",
+	"CHARTSMITH_LLM_WORKSPACE_REQUESTS_PER_MINUTE": "",
 }
 
 type Params struct {
@@ -37,6 +46,16 @@ type Params struct {
 	TokenEncryption   string
 	SlackToken        string
 	SlackChannel      string
+	// LocalLLMBaseURL is the base URL of an OpenAI-compatible API, e.g. http://localhost:11434/v1
+	LocalLLMBaseURL string
+	LocalLLMAPIKey  string
+	// LocalLLMModel, when set, is used for every LLM call instead of the hosted models
+	LocalLLMModel string
+	// LLM quotas are enforced separately for each user and each workspace. 0 is unlimited
+	LLMUserTokensPerDay           int64
+	LLMWorkspaceTokensPerDay      int64
+	LLMUserRequestsPerMinute      int64
+	LLMWorkspaceRequestsPerMinute int64
 }
 
 func Get() Params {
@@ -71,6 +90,26 @@ func Init(sess *session.Session) error {
 		TokenEncryption:   paramsMap["CHARTSMITH_TOKEN_ENCRYPTION"],
 		SlackToken:        paramsMap["CHARTSMITH_SLACK_TOKEN"],
 		SlackChannel:      paramsMap["CHARTSMITH_SLACK_CHANNEL"],
+		LocalLLMBaseURL:   paramsMap["CHARTSMITH_LOCAL_LLM_BASE_URL"],
+		LocalLLMAPIKey:    paramsMap["CHARTSMITH_LOCAL_LLM_API_KEY"],
+		LocalLLMModel:     paramsMap["CHARTSMITH_LOCAL_LLM_MODEL"],
+	}
+
+	limits := map[string]*int64{
+		"CHARTSMITH_LLM_USER_TOKENS_PER_DAY":           &params.LLMUserTokensPerDay,
+		"CHARTSMITH_LLM_WORKSPACE_TOKENS_PER_DAY":      &params.LLMWorkspaceTokensPerDay,
+		"CHARTSMITH_LLM_USER_REQUESTS_PER_MINUTE":      &params.LLMUserRequestsPerMinute,
+		"CHARTSMITH_LLM_WORKSPACE_REQUESTS_PER_MINUTE": &params.LLMWorkspaceRequestsPerMinute,
+	}
+	for envName, limit := range limits {
+		if paramsMap[envName] == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(paramsMap[envName], 10, 64)
+		if err != nil || value < 0 {
+			return fmt.Errorf("%s must be a non-negative integer, got %q", envName, paramsMap[envName])
+		}
+		*limit = value
 	}
 
 	return nil
@@ -0,0 +1,64 @@
+package param
+
+import (
+	"testing"
+)
+
+func TestInitLLMQuotas(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Params
+		wantErr bool
+	}{
+		{
+			name: "unlimited",
+			env:  map[string]string{},
+			want: Params{},
+		},
+		{
+			name: "limits",
+			env: map[string]string{
+				"CHARTSMITH_LLM_USER_TOKENS_PER_DAY":           "100000",
+				"CHARTSMITH_LLM_WORKSPACE_TOKENS_PER_DAY":      "500000",
+				"CHARTSMITH_LLM_USER_REQUESTS_PER_MINUTE":      "10",
+				"CHARTSMITH_LLM_WORKSPACE_REQUESTS_PER_MINUTE": "0",
+			},
+			want: Params{
+				LLMUserTokensPerDay:      100000,
+				LLMWorkspaceTokensPerDay: 500000,
+				LLMUserRequestsPerMinute: 10,
+			},
+		},
+		{
+			name:    "not a number",
+			env:     map[string]string{"CHARTSMITH_LLM_USER_TOKENS_PER_DAY": "lots"},
+			wantErr: true,
+		},
+		{
+			name:    "negative",
+			env:     map[string]string{"CHARTSMITH_LLM_USER_REQUESTS_PER_MINUTE": "-1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("USE_EC2_PARAMETERS", "")
+			for envName := range paramLookup {
+				t.Setenv(envName, tt.env[envName])
+			}
+
+			err := Init(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := Get(); got != tt.want {
+				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
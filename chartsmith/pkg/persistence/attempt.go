@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tuvistavie/securerandom"
+)
+
+// JobAttempt is a single run of a work_queue job by a worker
+type JobAttempt struct {
+	ID         string
+	JobID      string
+	Channel    string
+	WorkerHost string
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Duration   time.Duration
+	Error      string
+	PanicStack string
+}
+
+// RecordJobAttempt stores the outcome of running a job. An empty Error means the attempt succeeded
+func RecordJobAttempt(ctx context.Context, attempt JobAttempt) error {
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	id, err := securerandom.Hex(6)
+	if err != nil {
+		return fmt.Errorf("failed to generate id: %w", err)
+	}
+
+	// started_at and ended_at are timestamps without time zone, stored in UTC like NOW()
+	query := `INSERT INTO work_queue_attempt
+		(id, job_id, channel, worker_host, started_at, ended_at, duration_ms, error, panic_stack)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = conn.Exec(ctx, query,
+		id,
+		attempt.JobID,
+		attempt.Channel,
+		attempt.WorkerHost,
+		attempt.StartedAt.UTC(),
+		attempt.EndedAt.UTC(),
+		attempt.Duration.Milliseconds(),
+		sql.NullString{String: attempt.Error, Valid: attempt.Error != ""},
+		sql.NullString{String: attempt.PanicStack, Valid: attempt.PanicStack != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert job attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetJobHistory returns every recorded attempt for a job, oldest first
+func GetJobHistory(ctx context.Context, jobID string) ([]JobAttempt, error) {
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT id, job_id, channel, worker_host, started_at, ended_at, duration_ms, error, panic_stack
+		FROM work_queue_attempt
+		WHERE job_id = $1
+		ORDER BY started_at ASC`
+	rows, err := conn.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job history: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []JobAttempt
+	for rows.Next() {
+		var attempt JobAttempt
+		var durationMs int64
+		var errorText sql.NullString
+		var panicStack sql.NullString
+		if err := rows.Scan(&attempt.ID, &attempt.JobID, &attempt.Channel, &attempt.WorkerHost,
+			&attempt.StartedAt, &attempt.EndedAt, &durationMs, &errorText, &panicStack); err != nil {
+			return nil, fmt.Errorf("failed to scan job attempt: %w", err)
+		}
+		attempt.Duration = time.Duration(durationMs) * time.Millisecond
+		attempt.Error = errorText.String
+		attempt.PanicStack = panicStack.String
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate job history: %w", err)
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordJobAttempt(t *testing.T) {
+	startTestPostgres(t)
+
+	ctx := context.Background()
+	startedAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("UTC-8", -8*60*60))
+	attempts := []JobAttempt{
+		{JobID: "job1", Channel: "test_channel", WorkerHost: "worker-a", StartedAt: startedAt, EndedAt: startedAt.Add(time.Minute), Duration: time.Minute, Error: "failed"},
+		// started later in absolute time, but earlier on the wall clock
+		{JobID: "job1", Channel: "test_channel", WorkerHost: "worker-b", StartedAt: startedAt.Add(2 * time.Minute).In(time.FixedZone("UTC-12", -12*60*60)), EndedAt: startedAt.Add(3 * time.Minute).UTC(), Duration: time.Minute},
+	}
+	for _, attempt := range attempts {
+		if err := RecordJobAttempt(ctx, attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := GetJobHistory(ctx, "job1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(attempts) {
+		t.Fatalf("got %d attempts, want %d", len(history), len(attempts))
+	}
+
+	for i, want := range attempts {
+		got := history[i]
+		if got.WorkerHost != want.WorkerHost {
+			t.Errorf("attempt %d: worker host = %q, want %q", i, got.WorkerHost, want.WorkerHost)
+		}
+		if !got.StartedAt.Equal(want.StartedAt) {
+			t.Errorf("attempt %d: started at = %s, want %s", i, got.StartedAt, want.StartedAt.UTC())
+		}
+		if !got.EndedAt.Equal(want.EndedAt) {
+			t.Errorf("attempt %d: ended at = %s, want %s", i, got.EndedAt, want.EndedAt.UTC())
+		}
+		if got.Error != want.Error {
+			t.Errorf("attempt %d: error = %q, want %q", i, got.Error, want.Error)
+		}
+	}
+}
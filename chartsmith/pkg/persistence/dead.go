@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DeadWork is a job that exhausted its retry policy and was moved out of work_queue
+type DeadWork struct {
+	ID           string
+	Channel      string
+	Payload      []byte
+	CreatedAt    time.Time
+	FailedAt     time.Time
+	AttemptCount int
+	LastError    string
+}
+
+// ListDeadWork returns the dead letter jobs, most recent first. If channel is empty,
+// jobs from all channels are returned
+func ListDeadWork(ctx context.Context, channel string) ([]DeadWork, error) {
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT id, channel, payload, created_at, failed_at, attempt_count, last_error
+		FROM work_queue_dead
+		WHERE ($1 = '' OR channel = $1)
+		ORDER BY failed_at DESC`
+	rows, err := conn.Query(ctx, query, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead work: %w", err)
+	}
+	defer rows.Close()
+
+	var deadWork []DeadWork
+	for rows.Next() {
+		var dw DeadWork
+		var attemptCount sql.NullInt64
+		var lastError sql.NullString
+		if err := rows.Scan(&dw.ID, &dw.Channel, &dw.Payload, &dw.CreatedAt, &dw.FailedAt, &attemptCount, &lastError); err != nil {
+			return nil, fmt.Errorf("failed to scan dead work: %w", err)
+		}
+		dw.AttemptCount = int(attemptCount.Int64)
+		dw.LastError = lastError.String
+		deadWork = append(deadWork, dw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dead work: %w", err)
+	}
+
+	return deadWork, nil
+}
+
+// RequeueDeadWork moves a dead letter job back into work_queue with a reset attempt count
+// and notifies the channel so that a worker picks it up right away
+func RequeueDeadWork(ctx context.Context, id string) error {
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the original row was deleted when the job was moved to the dead letter table,
+	// so the id can be reused and the job history stays together
+	var channel string
+	err = tx.QueryRow(ctx, `INSERT INTO work_queue (id, channel, payload, created_at, attempt_count)
+		SELECT id, channel, payload, NOW(), 0 FROM work_queue_dead WHERE id = $1
+		RETURNING channel`, id).Scan(&channel)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("dead work %s not found", id)
+		}
+		return fmt.Errorf("failed to requeue work: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM work_queue_dead WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead work: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, id)
+	if err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
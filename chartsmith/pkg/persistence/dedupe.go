@@ -0,0 +1,130 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DedupeBehavior controls what happens when work is enqueued with a dedupe key and
+// there are pending (not yet claimed) jobs on the same channel with the same key
+type DedupeBehavior string
+
+const (
+	// DedupeReplace drops the pending jobs and queues the new job at the back of the queue
+	DedupeReplace DedupeBehavior = "replace"
+	// DedupeAbsorb folds the new job into the oldest pending job, which keeps its place
+	// in the queue but runs with the new payload
+	DedupeAbsorb DedupeBehavior = "absorb"
+)
+
+var (
+	dedupeBehaviorsMu sync.RWMutex
+	dedupeBehaviors   = map[string]DedupeBehavior{
+		// a render of the latest pending content makes any older pending render redundant
+		"render_workspace": DedupeReplace,
+		// embeddings only depend on the file, there's no reason to lose the job's place in line
+		"new_summarize": DedupeAbsorb,
+	}
+)
+
+// SetDedupeBehavior sets the dedupe behavior for a channel. Channels without a behavior use DedupeReplace
+func SetDedupeBehavior(channel string, behavior DedupeBehavior) {
+	dedupeBehaviorsMu.Lock()
+	defer dedupeBehaviorsMu.Unlock()
+
+	dedupeBehaviors[channel] = behavior
+}
+
+func getDedupeBehavior(channel string) DedupeBehavior {
+	dedupeBehaviorsMu.RLock()
+	defer dedupeBehaviorsMu.RUnlock()
+
+	if behavior, ok := dedupeBehaviors[channel]; ok {
+		return behavior
+	}
+	return DedupeReplace
+}
+
+// EnqueueWorkWithDedupeKey adds a job to the work queue, deduplicating it against pending jobs
+// on the same channel with the same key using the channel's DedupeBehavior. It returns the
+// payloads that will not be processed because of the dedupe, so that callers can clean up
+// anything they created for those jobs
+func EnqueueWorkWithDedupeKey(ctx context.Context, channel string, payload interface{}, dedupeKey string) ([]json.RawMessage, error) {
+	if dedupeKey == "" {
+		return nil, fmt.Errorf("dedupe key is required")
+	}
+
+	return enqueueWork(ctx, channel, payload, nil, dedupeKey)
+}
+
+// dedupePendingWork applies the channel's dedupe behavior within tx. When absorbed is true, the
+// new job was folded into an existing job and must not be inserted
+func dedupePendingWork(ctx context.Context, tx pgx.Tx, id string, channel string, payload interface{}, dedupeKey string) (dropped []json.RawMessage, absorbed bool, err error) {
+	// serialize enqueues for the same key so that two concurrent enqueues can't both
+	// see no pending jobs
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, channel, dedupeKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock dedupe key: %w", err)
+	}
+
+	switch getDedupeBehavior(channel) {
+	case DedupeAbsorb:
+		var previousPayload json.RawMessage
+		query := `UPDATE work_queue AS wq
+			SET payload = $3
+			FROM (
+				SELECT id, payload
+				FROM work_queue
+				WHERE channel = $1
+				AND dedupe_key = $2
+				AND processing_started_at IS NULL
+				AND completed_at IS NULL
+				ORDER BY created_at ASC
+				LIMIT 1
+				FOR UPDATE
+			) AS pending
+			WHERE wq.id = pending.id
+			RETURNING pending.payload`
+		err := tx.QueryRow(ctx, query, channel, dedupeKey, payload).Scan(&previousPayload)
+		if err == pgx.ErrNoRows {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to absorb pending work: %w", err)
+		}
+
+		return []json.RawMessage{previousPayload}, true, nil
+
+	default:
+		query := `UPDATE work_queue
+			SET completed_at = NOW(),
+				last_error = 'superseded by ' || $3
+			WHERE channel = $1
+			AND dedupe_key = $2
+			AND processing_started_at IS NULL
+			AND completed_at IS NULL
+			RETURNING payload`
+		rows, err := tx.Query(ctx, query, channel, dedupeKey, id)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to replace pending work: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var previousPayload json.RawMessage
+			if err := rows.Scan(&previousPayload); err != nil {
+				return nil, false, fmt.Errorf("failed to scan replaced work: %w", err)
+			}
+			dropped = append(dropped, previousPayload)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, false, fmt.Errorf("failed to iterate replaced work: %w", err)
+		}
+
+		return dropped, false, nil
+	}
+}
@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGetDedupeBehavior(t *testing.T) {
+	SetDedupeBehavior("test_absorb", DedupeAbsorb)
+	SetDedupeBehavior("test_replace", DedupeReplace)
+
+	tests := []struct {
+		channel string
+		want    DedupeBehavior
+	}{
+		{channel: "render_workspace", want: DedupeReplace},
+		{channel: "new_summarize", want: DedupeAbsorb},
+		{channel: "test_absorb", want: DedupeAbsorb},
+		{channel: "test_replace", want: DedupeReplace},
+		{channel: "unknown_channel", want: DedupeReplace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			if got := getDedupeBehavior(tt.channel); got != tt.want {
+				t.Errorf("getDedupeBehavior(%q) = %q, want %q", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+// pendingPayloads returns the names in the payloads of the jobs on channel that haven't completed, oldest first
+func pendingPayloads(t *testing.T, ctx context.Context, channel string) []string {
+	t.Helper()
+
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `SELECT payload->>'name' FROM work_queue WHERE channel = $1 AND completed_at IS NULL ORDER BY created_at`, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// payloadNames returns the names in payloads
+func payloadNames(t *testing.T, payloads []json.RawMessage) []string {
+	t.Helper()
+
+	names := []string{}
+	for _, payload := range payloads {
+		var p struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestEnqueueWorkWithDedupeKey(t *testing.T) {
+	startTestPostgres(t)
+	SetDedupeBehavior("test_dedupe_absorb", DedupeAbsorb)
+	SetDedupeBehavior("test_dedupe_replace", DedupeReplace)
+
+	type enqueue struct {
+		name        string
+		key         string
+		wantDropped []string
+	}
+	tests := []struct {
+		name        string
+		channel     string
+		enqueues    []enqueue
+		wantPending []string
+	}{
+		{
+			name:    "replace",
+			channel: "test_dedupe_replace",
+			enqueues: []enqueue{
+				{name: "a", key: "k1", wantDropped: []string{}},
+				{name: "b", key: "k1", wantDropped: []string{"a"}},
+				{name: "c", key: "k2", wantDropped: []string{}},
+				{name: "d", key: "k1", wantDropped: []string{"b"}},
+			},
+			wantPending: []string{"c", "d"},
+		},
+		{
+			name:    "absorb",
+			channel: "test_dedupe_absorb",
+			enqueues: []enqueue{
+				{name: "a", key: "k1", wantDropped: []string{}},
+				{name: "b", key: "k2", wantDropped: []string{}},
+				{name: "c", key: "k1", wantDropped: []string{"a"}},
+				{name: "d", key: "k1", wantDropped: []string{"c"}},
+			},
+			wantPending: []string{"d", "b"},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, e := range tt.enqueues {
+				dropped, err := EnqueueWorkWithDedupeKey(ctx, tt.channel, map[string]string{"name": e.name}, e.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := payloadNames(t, dropped); !reflect.DeepEqual(got, e.wantDropped) {
+					t.Errorf("enqueue %s dropped %v, want %v", e.name, got, e.wantDropped)
+				}
+			}
+
+			if got := pendingPayloads(t, ctx, tt.channel); !reflect.DeepEqual(got, tt.wantPending) {
+				t.Errorf("pending = %v, want %v", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestEnqueueWorkWithDedupeKeyConcurrent(t *testing.T) {
+	startTestPostgres(t)
+	SetDedupeBehavior("test_dedupe_concurrent", DedupeReplace)
+
+	// without the lock on the key, enqueues that run at the same time all see no pending job
+	ctx := context.Background()
+	const enqueues = 10
+	errs := make(chan error, enqueues)
+	var wg sync.WaitGroup
+	for i := 0; i < enqueues; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := EnqueueWorkWithDedupeKey(ctx, "test_dedupe_concurrent", map[string]string{"name": fmt.Sprintf("job%d", i)}, "k1")
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if pending := pendingPayloads(t, ctx, "test_dedupe_concurrent"); len(pending) != 1 {
+		t.Errorf("got %d pending jobs %v, want 1", len(pending), pending)
+	}
+}
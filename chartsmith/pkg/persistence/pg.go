@@ -46,6 +46,8 @@ func InitPostgres(opts PostgresOpts) error {
 	poolConfig.MaxConnIdleTime = 15 * time.Minute
 	// Set health check interval
 	poolConfig.HealthCheckPeriod = 1 * time.Minute
+	// Add a span for each query that is part of a trace
+	poolConfig.ConnConfig.Tracer = queryTracer{}
 	
 	logger.Info("Initializing database connection pool", 
 		zap.Int32("MaxConns", poolConfig.MaxConns),
@@ -63,6 +65,19 @@ func InitPostgres(opts PostgresOpts) error {
 	return nil
 }
 
+// Ping checks that a connection can be acquired from the pool and is usable
+func Ping(ctx context.Context) error {
+	if pool == nil {
+		return errors.New("Postgres pool is not initialized")
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping Postgres pool: %w", err)
+	}
+
+	return nil
+}
+
 func MustGeUnpooledPostgresSession() *pgx.Conn {
 	if connStr == "" {
 		panic("Postgres is not initialized")
@@ -2,29 +2,155 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/replicatedhq/chartsmith/pkg/tracing"
 	"github.com/tuvistavie/securerandom"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// EnqueueWork adds a job to the work queue to be processed as soon as a worker is available
 func EnqueueWork(ctx context.Context, channel string, payload interface{}) error {
+	_, err := enqueueWork(ctx, channel, payload, nil, "")
+	return err
+}
+
+// EnqueueWorkAt adds a job to the work queue that won't be processed before runAt
+func EnqueueWorkAt(ctx context.Context, channel string, payload interface{}, runAt time.Time) error {
+	// run_after is a timestamp without time zone that is compared to NOW() in UTC, the
+	// zone of runAt would otherwise be dropped and its wall clock stored as is
+	runAt = runAt.UTC()
+	_, err := enqueueWork(ctx, channel, payload, &runAt, "")
+	return err
+}
+
+// EnqueueWorkAfter adds a job to the work queue that won't be processed until delay has passed
+func EnqueueWorkAfter(ctx context.Context, channel string, payload interface{}, delay time.Duration) error {
+	return EnqueueWorkAt(ctx, channel, payload, time.Now().Add(delay))
+}
+
+func enqueueWork(ctx context.Context, channel string, payload interface{}, runAfter *time.Time, dedupeKey string) (dropped []json.RawMessage, err error) {
+	ctx, span := tracing.StartSpan(ctx, "enqueue "+channel,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("work_queue.channel", channel)))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
 	conn := MustGetPooledPostgresSession()
 	defer conn.Release()
 
 	id, err := securerandom.Hex(6)
 	if err != nil {
-		return fmt.Errorf("failed to generate id: %w", err)
+		return nil, fmt.Errorf("failed to generate id: %w", err)
 	}
+	span.SetAttributes(attribute.String("work_queue.id", id))
 
-	_, err = conn.Exec(ctx, `INSERT INTO work_queue (id, channel, payload, created_at) VALUES ($1, $2, $3, NOW())`, id, channel, payload)
+	// the handler continues the trace from the context stored in the payload
+	payload, err = tracing.InjectPayload(ctx, payload)
 	if err != nil {
-		return fmt.Errorf("failed to insert work: %w", err)
+		return nil, fmt.Errorf("failed to add trace context to payload: %w", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if dedupeKey != "" {
+		var absorbed bool
+		dropped, absorbed, err = dedupePendingWork(ctx, tx, id, channel, payload, dedupeKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dedupe work: %w", err)
+		}
+
+		if absorbed {
+			if err := tx.Commit(ctx); err != nil {
+				return nil, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+			return dropped, nil
+		}
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO work_queue (id, channel, payload, created_at, run_after, dedupe_key) VALUES ($1, $2, $3, NOW(), $4, $5)`,
+		id, channel, payload, runAfter, sql.NullString{String: dedupeKey, Valid: dedupeKey != ""})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert work: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// scheduled work is picked up by the worker's periodic poll once it's due
+	if runAfter != nil && runAfter.After(time.Now()) {
+		return dropped, nil
 	}
 
 	_, err = conn.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, id)
 	if err != nil {
-		return fmt.Errorf("failed to notify: %w", err)
+		return nil, fmt.Errorf("failed to notify: %w", err)
+	}
+
+	return dropped, nil
+}
+
+// WorkCancelChannel is notified with the job id when cancellation of a job is requested
+const WorkCancelChannel = "work_queue_cancel"
+
+// CancelWork requests cancellation of a job. A job that is running has its handler context
+// cancelled, and a job that hasn't started yet is cancelled as soon as a worker claims it
+func CancelWork(ctx context.Context, jobID string) error {
+	ids, err := requestCancellation(ctx, `id = $1`, jobID)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("work %s not found, already completed or already cancelled", jobID)
 	}
 
 	return nil
 }
+
+// CancelWorkForPayload requests cancellation of the unfinished jobs on channels that have value for key in
+// their payload, and returns their IDs. This is how the jobs of a plan, render or conversion are found
+func CancelWorkForPayload(ctx context.Context, channels []string, key string, value string) ([]string, error) {
+	return requestCancellation(ctx, `channel = ANY($1) AND payload->>$2 = $3`, channels, key, value)
+}
+
+func requestCancellation(ctx context.Context, where string, args ...interface{}) ([]string, error) {
+	conn := MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `UPDATE work_queue SET cancel_requested_at = NOW() WHERE `+where+` AND completed_at IS NULL AND cancel_requested_at IS NULL RETURNING id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request cancellation: %w", err)
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan cancelled work: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to request cancellation: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := conn.Exec(ctx, `SELECT pg_notify($1, $2)`, WorkCancelChannel, id); err != nil {
+			return nil, fmt.Errorf("failed to notify: %w", err)
+		}
+	}
+
+	return ids, nil
+}
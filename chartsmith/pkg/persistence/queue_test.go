@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/chartsmith/pkg/testhelpers"
+)
+
+// startTestPostgres points the connection pool at an empty database with the work queue tables
+func startTestPostgres(t *testing.T, schema ...string) {
+	t.Helper()
+
+	pgURI := testhelpers.StartPostgres(t, append([]string{testhelpers.WorkQueueSchema}, schema...)...)
+	if err := InitPostgres(PostgresOpts{URI: pgURI}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnqueueWorkAt(t *testing.T) {
+	startTestPostgres(t)
+
+	tests := []struct {
+		name    string
+		runAt   time.Time
+		wantDue bool
+	}{
+		{name: "future utc", runAt: time.Now().Add(time.Hour).UTC(), wantDue: false},
+		{name: "future ahead of utc", runAt: time.Now().Add(time.Hour).In(time.FixedZone("UTC+5", 5*60*60)), wantDue: false},
+		{name: "future behind utc", runAt: time.Now().Add(time.Hour).In(time.FixedZone("UTC-8", -8*60*60)), wantDue: false},
+		{name: "past ahead of utc", runAt: time.Now().Add(-time.Minute).In(time.FixedZone("UTC+5", 5*60*60)), wantDue: true},
+		{name: "past behind utc", runAt: time.Now().Add(-time.Minute).In(time.FixedZone("UTC-8", -8*60*60)), wantDue: true},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := "test_enqueue_at"
+			if err := EnqueueWorkAt(ctx, channel, map[string]string{"name": tt.name}, tt.runAt); err != nil {
+				t.Fatal(err)
+			}
+
+			conn := MustGetPooledPostgresSession()
+			defer conn.Release()
+
+			var runAfter time.Time
+			var due bool
+			query := `SELECT run_after, run_after <= NOW() FROM work_queue WHERE channel = $1 AND payload->>'name' = $2`
+			if err := conn.QueryRow(ctx, query, channel, tt.name).Scan(&runAfter, &due); err != nil {
+				t.Fatal(err)
+			}
+
+			if !runAfter.Equal(tt.runAt.Truncate(time.Microsecond)) {
+				t.Errorf("run_after = %s, want %s", runAfter, tt.runAt.UTC())
+			}
+			if due != tt.wantDue {
+				t.Errorf("due = %v, want %v", due, tt.wantDue)
+			}
+		})
+	}
+}
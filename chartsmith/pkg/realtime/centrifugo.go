@@ -5,11 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/replicatedhq/chartsmith/pkg/logger"
+	"github.com/replicatedhq/chartsmith/pkg/metrics"
 	"github.com/replicatedhq/chartsmith/pkg/persistence"
 	"github.com/replicatedhq/chartsmith/pkg/realtime/types"
 	"github.com/tuvistavie/securerandom"
@@ -58,6 +58,7 @@ func SendEvent(ctx context.Context, r types.Recipient, e types.Event) error {
 
 		userChannelName := fmt.Sprintf("%s#%s", e.GetChannelName(), userID)
 		if err := sendMessage(userChannelName, messageData); err != nil {
+			metrics.IncRealtimePublishFailures()
 			logger.Errorf("Failed to send message to user %s: %v", userID, err)
 		}
 	}
@@ -162,12 +163,12 @@ func sendMessage(channelName string, data map[string]interface{}) error {
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Fatalf("Error encoding JSON: %v", err)
+		return fmt.Errorf("error encoding JSON: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Fatalf("Error creating request: %v", err)
+		return fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -175,12 +176,12 @@ func sendMessage(channelName string, data map[string]interface{}) error {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Fatalf("Error sending request to Centrifugo server: %v", err)
+		return fmt.Errorf("error sending request to Centrifugo server: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Failed to send message, status code: %d", resp.StatusCode)
+		return fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
 	}
 
 	return nil
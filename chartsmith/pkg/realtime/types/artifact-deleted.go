@@ -0,0 +1,24 @@
+package types
+
+import (
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+var _ Event = ArtifactDeletedEvent{}
+
+type ArtifactDeletedEvent struct {
+	WorkspaceID   string               `json:"workspaceId"`
+	WorkspaceFile *workspacetypes.File `json:"file"`
+}
+
+func (e ArtifactDeletedEvent) GetMessageData() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"eventType":   "artifact-deleted",
+		"workspaceId": e.WorkspaceID,
+		"file":        e.WorkspaceFile,
+	}, nil
+}
+
+func (e ArtifactDeletedEvent) GetChannelName() string {
+	return e.WorkspaceID
+}
@@ -0,0 +1,26 @@
+package types
+
+var _ Event = QuotaExceededEvent{}
+
+// QuotaExceededEvent is sent when a plan or conversion is stopped because the workspace or its
+// user reached an LLM quota. Message explains the limit to the user
+type QuotaExceededEvent struct {
+	WorkspaceID string `json:"workspaceId"`
+	Kind        string `json:"kind"`
+	ID          string `json:"id"`
+	Message     string `json:"quotaMessage"`
+}
+
+func (e QuotaExceededEvent) GetMessageData() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"workspaceId":  e.WorkspaceID,
+		"eventType":    "quota-exceeded",
+		"kind":         e.Kind,
+		"id":           e.ID,
+		"quotaMessage": e.Message,
+	}, nil
+}
+
+func (e QuotaExceededEvent) GetChannelName() string {
+	return e.WorkspaceID
+}
@@ -1,18 +1,26 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	workspacetypes "github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
 
 type RenderStreamEvent struct {
-	WorkspaceID         string     `json:"workspaceId"`
-	RenderID            string     `json:"renderId"`
-	RenderChartID       string     `json:"renderChartId"`
-	CompletedAt         *time.Time `json:"completedAt,omitempty"`
-	DepUpdateCommand    string     `json:"depUpdateCommand,omitempty"`
-	DepUpdateStdout     string     `json:"depUpdateStdout,omitempty"`
-	DepUpdateStderr     string     `json:"depUpdateStderr,omitempty"`
-	HelmTemplateCommand string     `json:"helmTemplateCommand,omitempty"`
-	HelmTemplateStdout  string     `json:"helmTemplateStdout,omitempty"`
-	HelmTemplateStderr  string     `json:"helmTemplateStderr,omitempty"`
+	WorkspaceID         string                       `json:"workspaceId"`
+	RenderID            string                       `json:"renderId"`
+	RenderChartID       string                       `json:"renderChartId"`
+	CompletedAt         *time.Time                   `json:"completedAt,omitempty"`
+	DepUpdateCommand    string                       `json:"depUpdateCommand,omitempty"`
+	DepUpdateStdout     string                       `json:"depUpdateStdout,omitempty"`
+	DepUpdateStderr     string                       `json:"depUpdateStderr,omitempty"`
+	HelmLintCommand     string                       `json:"helmLintCommand,omitempty"`
+	HelmLintStdout      string                       `json:"helmLintStdout,omitempty"`
+	HelmLintStderr      string                       `json:"helmLintStderr,omitempty"`
+	HelmLintFindings    []workspacetypes.LintFinding `json:"helmLintFindings,omitempty"`
+	HelmTemplateCommand string                       `json:"helmTemplateCommand,omitempty"`
+	HelmTemplateStdout  string                       `json:"helmTemplateStdout,omitempty"`
+	HelmTemplateStderr  string                       `json:"helmTemplateStderr,omitempty"`
 }
 
 func (e RenderStreamEvent) GetMessageData() (map[string]interface{}, error) {
@@ -25,6 +33,10 @@ func (e RenderStreamEvent) GetMessageData() (map[string]interface{}, error) {
 		"depUpdateCommand":    e.DepUpdateCommand,
 		"depUpdateStdout":     e.DepUpdateStdout,
 		"depUpdateStderr":     e.DepUpdateStderr,
+		"helmLintCommand":     e.HelmLintCommand,
+		"helmLintStdout":      e.HelmLintStdout,
+		"helmLintStderr":      e.HelmLintStderr,
+		"helmLintFindings":    e.HelmLintFindings,
 		"helmTemplateCommand": e.HelmTemplateCommand,
 		"helmTemplateStdout":  e.HelmTemplateStdout,
 		"helmTemplateStderr":  e.HelmTemplateStderr,
@@ -0,0 +1,24 @@
+package types
+
+var _ Event = WorkCancelledEvent{}
+
+// WorkCancelledEvent is sent when a plan, render or conversion is stopped because
+// cancellation of its job was requested
+type WorkCancelledEvent struct {
+	WorkspaceID string `json:"workspaceId"`
+	Kind        string `json:"kind"`
+	ID          string `json:"id"`
+}
+
+func (e WorkCancelledEvent) GetMessageData() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"workspaceId": e.WorkspaceID,
+		"eventType":   "work-cancelled",
+		"kind":        e.Kind,
+		"id":          e.ID,
+	}, nil
+}
+
+func (e WorkCancelledEvent) GetChannelName() string {
+	return e.WorkspaceID
+}
@@ -0,0 +1,114 @@
+package testhelpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// WorkQueueSchema creates the work queue tables, matching db/schema/tables
+const WorkQueueSchema = `
+CREATE TABLE work_queue (
+	id text NOT NULL PRIMARY KEY,
+	channel text NOT NULL,
+	payload jsonb,
+	created_at timestamp NOT NULL,
+	completed_at timestamp,
+	processing_started_at timestamp,
+	attempt_count integer,
+	last_error text,
+	next_attempt_at timestamp,
+	cancel_requested_at timestamp,
+	run_after timestamp,
+	dedupe_key text
+);
+CREATE UNIQUE INDEX work_queue_channel_idx ON work_queue (channel, created_at);
+CREATE INDEX work_queue_dedupe_idx ON work_queue (channel, dedupe_key);
+
+CREATE TABLE work_queue_dead (
+	id text NOT NULL PRIMARY KEY,
+	channel text NOT NULL,
+	payload jsonb,
+	created_at timestamp NOT NULL,
+	failed_at timestamp NOT NULL,
+	attempt_count integer,
+	last_error text
+);
+
+CREATE TABLE work_queue_attempt (
+	id text NOT NULL PRIMARY KEY,
+	job_id text NOT NULL,
+	channel text NOT NULL,
+	worker_host text NOT NULL,
+	started_at timestamp NOT NULL,
+	ended_at timestamp NOT NULL,
+	duration_ms bigint NOT NULL,
+	error text,
+	panic_stack text
+);
+`
+
+// StartPostgres starts an empty postgres for the test, creates the schema and returns the
+// connection string. The test is skipped when there is no container runtime
+func StartPostgres(t *testing.T, schema ...string) string {
+	t.Helper()
+	skipWithoutContainerRuntime(t)
+
+	ctx := context.Background()
+	pgContainer, err := postgres.Run(ctx,
+		"pgvector/pgvector:pg16",
+		postgres.WithDatabase("test-db"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.
+				ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second)),
+	)
+	testcontainers.CleanupContainer(t, pgContainer)
+	if err != nil {
+		t.Fatalf("failed to start postgres: %v", err)
+	}
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	for _, s := range schema {
+		if _, err := conn.Exec(ctx, s); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+
+	return connStr
+}
+
+// skipWithoutContainerRuntime skips the test when docker can't be reached. Looking up the
+// provider panics instead of failing when there is no docker host at all
+func skipWithoutContainerRuntime(t *testing.T) {
+	t.Helper()
+
+	var notFound interface{}
+	func() {
+		defer func() {
+			notFound = recover()
+		}()
+		testcontainers.SkipIfProviderIsNotHealthy(t)
+	}()
+	if notFound != nil {
+		t.Skipf("no container runtime: %v", notFound)
+	}
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PayloadKey is the key in a work_queue payload that holds the W3C trace context of the
+// span that enqueued the job
+const PayloadKey = "traceContext"
+
+var propagator = propagation.TraceContext{}
+
+// InjectPayload adds the trace context in ctx to payload, which must marshal to a JSON object.
+// The payload is returned unchanged when ctx has no span
+func InjectPayload(ctx context.Context, payload interface{}) (interface{}, error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return payload, nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		// not an object, there's nowhere to put the trace context
+		return payload, nil
+	}
+
+	traceContext, err := json.Marshal(carrier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trace context: %w", err)
+	}
+	fields[PayloadKey] = traceContext
+
+	return fields, nil
+}
+
+// ExtractPayload returns ctx with the remote span context from a payload that was
+// created by InjectPayload. ctx is returned unchanged if the payload has no trace context
+func ExtractPayload(ctx context.Context, payload []byte) context.Context {
+	var p struct {
+		TraceContext map[string]string `json:"traceContext"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil || len(p.TraceContext) == 0 {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, propagation.MapCarrier(p.TraceContext))
+}
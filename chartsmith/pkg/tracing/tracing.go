@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/replicatedhq/chartsmith"
+
+type Opts struct {
+	// ServiceName is reported as service.name on every span
+	ServiceName string
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector, e.g. localhost:4318
+	OTLPEndpoint string
+	// Insecure disables TLS when sending to the collector
+	Insecure bool
+}
+
+// ShutdownFunc flushes any buffered spans and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Init exports spans over OTLP/HTTP to the collector in opts
+func Init(ctx context.Context, opts Opts) (ShutdownFunc, error) {
+	clientOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(opts.OTLPEndpoint),
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource(opts.ServiceName)),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// InitWithExporter sends every span to exporter as soon as it ends. This is meant for tests,
+// which can pass a tracetest.InMemoryExporter and inspect the spans that were recorded
+func InitWithExporter(serviceName string, exporter sdktrace.SpanExporter) ShutdownFunc {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(newResource(serviceName)),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown
+}
+
+func newResource(serviceName string) *resource.Resource {
+	return resource.NewSchemaless(attribute.String("service.name", serviceName))
+}
+
+// Tracer returns the tracer used for all chartsmith spans. Spans are dropped until Init is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a span that is a child of any span in ctx
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// EndSpan records err on span, if there is one, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// crdSchemas are the schemas of the custom resources defined by CRDs, keyed by apiVersion and kind
+type crdSchemas map[string]*gojsonschema.Schema
+
+func (c crdSchemas) lookup(apiVersion string, kind string) (*gojsonschema.Schema, bool) {
+	s, ok := c[apiVersion+"/"+kind]
+	return s, ok
+}
+
+// collectCRDSchemas compiles the openAPIV3Schema of each version of the CRDs in manifests. CRDs with
+// schemas that can't be compiled are skipped, so their resources aren't validated
+func collectCRDSchemas(manifests []*manifest) crdSchemas {
+	schemas := crdSchemas{}
+	for _, m := range manifests {
+		if m.kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		spec, _ := m.object["spec"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		names, _ := spec["names"].(map[string]interface{})
+		kind, _ := names["kind"].(string)
+		if group == "" || kind == "" {
+			continue
+		}
+
+		versions, _ := spec["versions"].([]interface{})
+		for _, v := range versions {
+			version, _ := v.(map[string]interface{})
+			name, _ := version["name"].(string)
+
+			// apiextensions.k8s.io/v1beta1 CRDs can have one schema for every version
+			openAPIV3Schema := nestedMap(version, "schema", "openAPIV3Schema")
+			if openAPIV3Schema == nil {
+				openAPIV3Schema = nestedMap(spec, "validation", "openAPIV3Schema")
+			}
+			if name == "" || openAPIV3Schema == nil {
+				continue
+			}
+
+			compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(closeSchema(openAPIV3Schema, true)))
+			if err != nil {
+				continue
+			}
+
+			apiVersion := schema.GroupVersion{Group: group, Version: name}.String()
+			schemas[apiVersion+"/"+kind] = compiled
+		}
+	}
+
+	return schemas
+}
+
+// validateCustomResource checks a custom resource against the schema of its CRD
+func validateCustomResource(m *manifest, s *gojsonschema.Schema) []types.RenderFinding {
+	result, err := s.Validate(gojsonschema.NewGoLoader(m.object))
+	if err != nil {
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "invalid-value", "", err.Error())}
+	}
+
+	findings := []types.RenderFinding{}
+	for _, resultErr := range result.Errors() {
+		findings = append(findings, m.finding(types.FindingSeverityError, "crd-schema", resultErr.Field(),
+			fmt.Sprintf("%s: %s", resultErr.Field(), resultErr.Description())))
+	}
+	return findings
+}
+
+// closeSchema returns a copy of an openAPIV3Schema that rejects unknown fields. The API server prunes
+// fields that a structural schema doesn't have, so they are almost always misspelled. The root of the
+// schema gets the fields every resource has
+func closeSchema(s map[string]interface{}, root bool) map[string]interface{} {
+	closed := map[string]interface{}{}
+	for key, value := range s {
+		closed[key] = value
+	}
+
+	properties, hasProperties := s["properties"].(map[string]interface{})
+	if hasProperties {
+		closedProperties := map[string]interface{}{}
+		for name, property := range properties {
+			if propertySchema, ok := property.(map[string]interface{}); ok {
+				closedProperties[name] = closeSchema(propertySchema, false)
+			} else {
+				closedProperties[name] = property
+			}
+		}
+
+		if root {
+			for _, name := range []string{"apiVersion", "kind"} {
+				if _, ok := closedProperties[name]; !ok {
+					closedProperties[name] = map[string]interface{}{"type": "string"}
+				}
+			}
+			if _, ok := closedProperties["metadata"]; !ok {
+				closedProperties["metadata"] = map[string]interface{}{"type": "object"}
+			}
+		}
+
+		closed["properties"] = closedProperties
+
+		preserveUnknown, _ := s["x-kubernetes-preserve-unknown-fields"].(bool)
+		if _, ok := s["additionalProperties"]; !ok && !preserveUnknown {
+			closed["additionalProperties"] = false
+		}
+	}
+
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		closed["items"] = closeSchema(items, false)
+	}
+
+	if additional, ok := s["additionalProperties"].(map[string]interface{}); ok {
+		closed["additionalProperties"] = closeSchema(additional, false)
+	}
+
+	return closed
+}
+
+func nestedMap(object map[string]interface{}, fields ...string) map[string]interface{} {
+	current := object
+	for _, field := range fields {
+		next, ok := current[field].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
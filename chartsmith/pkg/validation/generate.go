@@ -0,0 +1,322 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// schemaBundle is the schemas of the built-in kinds of a version of Kubernetes. The definitions are JSON
+// schemas that reference each other with #/definitions/<name>
+type schemaBundle struct {
+	KubernetesVersion string `json:"kubernetesVersion"`
+	Source            string `json:"source"`
+
+	// Kinds are the definitions of the kinds served by this version, keyed by apiVersion and kind
+	Kinds map[string]string `json:"kinds"`
+
+	// Removed are the kinds that Kubernetes doesn't serve anymore, keyed by apiVersion and kind
+	Removed map[string]removedKind `json:"removed"`
+
+	Definitions map[string]interface{} `json:"definitions"`
+}
+
+type removedKind struct {
+	Removed     string `json:"removed"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// smdType is a type in the structured-merge-diff schema that client-go's apply configurations are generated with
+type smdType struct {
+	NamedType string   `json:"namedType,omitempty"`
+	Scalar    string   `json:"scalar,omitempty"`
+	List      *smdList `json:"list,omitempty"`
+	Map       *smdMap  `json:"map,omitempty"`
+}
+
+type smdList struct {
+	ElementType smdType `json:"elementType"`
+}
+
+type smdMap struct {
+	Fields      []smdField `json:"fields,omitempty"`
+	ElementType *smdType   `json:"elementType,omitempty"`
+}
+
+type smdField struct {
+	Name string  `json:"name"`
+	Type smdType `json:"type"`
+}
+
+type smdNamedType struct {
+	Name string `json:"name"`
+	smdType
+}
+
+var (
+	groupNamePattern       = regexp.MustCompile(`const GroupName = "([^"]*)"`)
+	knownTypePattern       = regexp.MustCompile(`&([A-Z]\w*)\{\}`)
+	introducedPattern      = regexp.MustCompile(`func \(in \*(\w+)\) APILifecycleIntroduced\(\) \(major, minor int\) \{\s*return (\d+), (\d+)`)
+	removedPattern         = regexp.MustCompile(`func \(in \*(\w+)\) APILifecycleRemoved\(\) \(major, minor int\) \{\s*return (\d+), (\d+)`)
+	replacementPattern     = regexp.MustCompile(`func \(in \*(\w+)\) APILifecycleReplacement\(\) schema\.GroupVersionKind \{\s*return schema\.GroupVersionKind\{Group: "([^"]*)", Version: "([^"]*)"`)
+	clientGoVersionPattern = regexp.MustCompile(`^v0\.(\d+)\.\d+`)
+)
+
+// GenerateKubernetesSchemas generates the schemas of the built-in kinds of the Kubernetes version that a
+// version of k8s.io/client-go (e.g. v0.32.0 for 1.32) was released with. The kinds, their groups and when
+// prerelease versions were removed come from k8s.io/api, and their types from the schema of client-go's apply
+// configurations. clientGoDir and apiDir are the modules' directories. It returns the Kubernetes version and
+// the bundle to save as schemas/<version>.json
+func GenerateKubernetesSchemas(clientGoVersion string, clientGoDir string, apiDir string) (string, []byte, error) {
+	match := clientGoVersionPattern.FindStringSubmatch(clientGoVersion)
+	if match == nil {
+		return "", nil, fmt.Errorf("client-go version %q is not in the form v0.<minor>.<patch>", clientGoVersion)
+	}
+	minor, _ := strconv.Atoi(match[1])
+	target := kubernetesVersion(minor)
+
+	types, err := readSMDSchema(filepath.Join(clientGoDir, "applyconfigurations", "internal", "internal.go"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	definitions := map[string]interface{}{}
+	for _, t := range types {
+		definitions[t.Name] = smdToJSONSchema(t.smdType, strings.HasPrefix(t.Name, "__untyped"))
+	}
+
+	bundle := schemaBundle{
+		KubernetesVersion: target.String(),
+		Source:            "k8s.io/client-go@" + clientGoVersion,
+		Kinds:             map[string]string{},
+		Removed:           map[string]removedKind{},
+	}
+
+	registers, err := filepath.Glob(filepath.Join(apiDir, "*", "*", "register.go"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find api groups: %w", err)
+	}
+
+	for _, register := range registers {
+		packageDir := filepath.Dir(register)
+		group, version := filepath.Base(filepath.Dir(packageDir)), filepath.Base(packageDir)
+
+		registerSource, err := os.ReadFile(register)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s: %w", register, err)
+		}
+		groupName := groupNamePattern.FindSubmatch(registerSource)
+		if groupName == nil {
+			continue
+		}
+
+		apiVersion := version
+		if len(groupName[1]) > 0 {
+			apiVersion = string(groupName[1]) + "/" + version
+		}
+
+		lifecycle, err := readLifecycle(filepath.Join(packageDir, "zz_generated.prerelease-lifecycle.go"))
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, knownType := range knownTypePattern.FindAllSubmatch(registerSource, -1) {
+			kind := string(knownType[1])
+			definition := fmt.Sprintf("io.k8s.api.%s.%s.%s", group, version, kind)
+			if _, ok := definitions[definition]; !ok {
+				continue
+			}
+
+			key := apiVersion + "/" + kind
+			l := lifecycle[kind]
+			switch {
+			case l.removed != 0 && target >= l.removed:
+				bundle.Removed[key] = removedKind{Removed: l.removed.String(), Replacement: l.replacement}
+			case l.introduced != 0 && target < l.introduced:
+			default:
+				bundle.Kinds[key] = definition
+			}
+		}
+	}
+
+	if len(bundle.Kinds) == 0 {
+		return "", nil, fmt.Errorf("no kinds found in %s", apiDir)
+	}
+
+	bundle.Definitions = reachableDefinitions(definitions, bundle.Kinds)
+
+	marshalled, err := json.MarshalIndent(bundle, "", " ")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal schemas: %w", err)
+	}
+
+	return target.String(), append(marshalled, '\n'), nil
+}
+
+// readSMDSchema reads the schema in the source of client-go's apply configurations
+func readSMDSchema(internalGo string) ([]smdNamedType, error) {
+	source, err := os.ReadFile(internalGo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client-go schema: %w", err)
+	}
+
+	_, schemaYAML, ok := strings.Cut(string(source), "typed.YAMLObject(`")
+	if !ok {
+		return nil, fmt.Errorf("no schema found in %s", internalGo)
+	}
+	schemaYAML, _, _ = strings.Cut(schemaYAML, "`)")
+
+	schema := struct {
+		Types []smdNamedType `json:"types"`
+	}{}
+	if err := yaml.Unmarshal([]byte(schemaYAML), &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse client-go schema: %w", err)
+	}
+
+	return schema.Types, nil
+}
+
+type lifecycle struct {
+	introduced  kubernetesVersion
+	removed     kubernetesVersion
+	replacement string
+}
+
+// readLifecycle reads when the prerelease kinds of an API version were introduced and removed, keyed by kind.
+// GA versions don't have a lifecycle
+func readLifecycle(path string) (map[string]lifecycle, error) {
+	lifecycles := map[string]lifecycle{}
+
+	source, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lifecycles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, match := range introducedPattern.FindAllStringSubmatch(string(source), -1) {
+		l := lifecycles[match[1]]
+		minor, _ := strconv.Atoi(match[3])
+		l.introduced = kubernetesVersion(minor)
+		lifecycles[match[1]] = l
+	}
+	for _, match := range removedPattern.FindAllStringSubmatch(string(source), -1) {
+		l := lifecycles[match[1]]
+		minor, _ := strconv.Atoi(match[3])
+		l.removed = kubernetesVersion(minor)
+		lifecycles[match[1]] = l
+	}
+	for _, match := range replacementPattern.FindAllStringSubmatch(string(source), -1) {
+		l := lifecycles[match[1]]
+		l.replacement = match[3]
+		if match[2] != "" {
+			l.replacement = match[2] + "/" + match[3]
+		}
+		lifecycles[match[1]] = l
+	}
+
+	return lifecycles, nil
+}
+
+// smdToJSONSchema converts a structured-merge-diff type to a JSON schema. Objects with fields don't allow other
+// fields, like the API server decoding a manifest strictly. Untyped types allow anything
+func smdToJSONSchema(t smdType, untyped bool) map[string]interface{} {
+	switch {
+	case untyped:
+		return map[string]interface{}{}
+
+	case t.NamedType != "":
+		if strings.HasPrefix(t.NamedType, "__untyped") {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.NamedType}
+
+	case t.Scalar != "":
+		switch t.Scalar {
+		case "string":
+			return map[string]interface{}{"type": "string"}
+		case "numeric":
+			return map[string]interface{}{"type": "number"}
+		case "boolean":
+			return map[string]interface{}{"type": "boolean"}
+		default:
+			// e.g. IntOrString, Quantity and Time
+			return map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+		}
+
+	case t.List != nil:
+		return map[string]interface{}{"type": "array", "items": smdToJSONSchema(t.List.ElementType, false)}
+
+	case t.Map != nil:
+		s := map[string]interface{}{"type": "object"}
+		if len(t.Map.Fields) > 0 {
+			properties := map[string]interface{}{}
+			for _, field := range t.Map.Fields {
+				properties[field.Name] = smdToJSONSchema(field.Type, false)
+			}
+			s["properties"] = properties
+			s["additionalProperties"] = false
+		}
+		if t.Map.ElementType != nil {
+			s["additionalProperties"] = smdToJSONSchema(*t.Map.ElementType, false)
+		}
+		return s
+	}
+
+	return map[string]interface{}{}
+}
+
+// reachableDefinitions returns the definitions that the kinds use
+func reachableDefinitions(definitions map[string]interface{}, kinds map[string]string) map[string]interface{} {
+	reachable := map[string]interface{}{}
+
+	pending := []string{}
+	for _, definition := range kinds {
+		pending = append(pending, definition)
+	}
+	sort.Strings(pending)
+
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, ok := reachable[name]; ok {
+			continue
+		}
+		definition, ok := definitions[name]
+		if !ok {
+			continue
+		}
+		reachable[name] = definition
+		pending = append(pending, schemaRefs(definition)...)
+	}
+
+	return reachable
+}
+
+// schemaRefs returns the names of the definitions that a schema references
+func schemaRefs(s interface{}) []string {
+	refs := []string{}
+	switch s := s.(type) {
+	case map[string]interface{}:
+		for key, value := range s {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refs = append(refs, strings.TrimPrefix(ref, "#/definitions/"))
+				continue
+			}
+			refs = append(refs, schemaRefs(value)...)
+		}
+	case []interface{}:
+		for _, value := range s {
+			refs = append(refs, schemaRefs(value)...)
+		}
+	}
+	return refs
+}
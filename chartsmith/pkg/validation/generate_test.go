@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSMDToJSONSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       smdType
+		untyped bool
+		want    map[string]interface{}
+	}{
+		{name: "untyped", t: smdType{Scalar: "string"}, untyped: true, want: map[string]interface{}{}},
+		{name: "named type", t: smdType{NamedType: "io.k8s.api.core.v1.PodSpec"}, want: map[string]interface{}{"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}},
+		{name: "untyped named type", t: smdType{NamedType: "__untyped_atomic_"}, want: map[string]interface{}{}},
+		{name: "string", t: smdType{Scalar: "string"}, want: map[string]interface{}{"type": "string"}},
+		{name: "numeric", t: smdType{Scalar: "numeric"}, want: map[string]interface{}{"type": "number"}},
+		{name: "boolean", t: smdType{Scalar: "boolean"}, want: map[string]interface{}{"type": "boolean"}},
+		{name: "untyped scalar", t: smdType{Scalar: "untyped"}, want: map[string]interface{}{"type": []string{"string", "number", "boolean"}}},
+		{
+			name: "list",
+			t:    smdType{List: &smdList{ElementType: smdType{Scalar: "string"}}},
+			want: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		{
+			name: "object with fields",
+			t:    smdType{Map: &smdMap{Fields: []smdField{{Name: "replicas", Type: smdType{Scalar: "numeric"}}}}},
+			want: map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"replicas": map[string]interface{}{"type": "number"}},
+				"additionalProperties": false,
+			},
+		},
+		{
+			name: "map",
+			t:    smdType{Map: &smdMap{ElementType: &smdType{Scalar: "string"}}},
+			want: map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+		},
+		{name: "empty", t: smdType{}, want: map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := smdToJSONSchema(tt.t, tt.untyped); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("smdToJSONSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachableDefinitions(t *testing.T) {
+	definitions := map[string]interface{}{
+		"Deployment":     map[string]interface{}{"properties": map[string]interface{}{"spec": map[string]interface{}{"$ref": "#/definitions/DeploymentSpec"}}},
+		"DeploymentSpec": map[string]interface{}{"items": []interface{}{map[string]interface{}{"$ref": "#/definitions/PodSpec"}}},
+		"PodSpec":        map[string]interface{}{"$ref": "#/definitions/Deployment"},
+		"Unused":         map[string]interface{}{},
+	}
+
+	got := reachableDefinitions(definitions, map[string]string{"apps/v1/Deployment": "Deployment"})
+	if len(got) != 3 || got["Unused"] != nil {
+		t.Errorf("reachableDefinitions() = %v, want Deployment, DeploymentSpec and PodSpec", got)
+	}
+}
@@ -0,0 +1,243 @@
+package validation
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// schemaFiles are the schemas of the built-in kinds of each supported version of Kubernetes, generated with the
+// kubernetes-schemas command
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// kubernetesVersion is a 1.x minor version of Kubernetes
+type kubernetesVersion int
+
+func (v kubernetesVersion) String() string {
+	return fmt.Sprintf("1.%d", int(v))
+}
+
+// parseKubernetesVersion parses versions such as 1.29, v1.29 and 1.29.3
+func parseKubernetesVersion(version string) (kubernetesVersion, error) {
+	if version == "" {
+		version = DefaultKubernetesVersion
+	}
+
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("kubernetes version %q is not in the form 1.<minor>", version)
+	}
+
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return 0, fmt.Errorf("kubernetes version %q is not in the form 1.<minor>", version)
+	}
+
+	return kubernetesVersion(minor), nil
+}
+
+// SupportedKubernetesVersions returns the versions of Kubernetes that manifests can be validated against
+func SupportedKubernetesVersions() []string {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil
+	}
+
+	versions := []kubernetesVersion{}
+	for _, entry := range entries {
+		version, err := parseKubernetesVersion(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	supported := make([]string, 0, len(versions))
+	for _, version := range versions {
+		supported = append(supported, version.String())
+	}
+	return supported
+}
+
+// kubernetesSchemas are the schemas of the built-in kinds of a version of Kubernetes. The schema of a kind is
+// compiled the first time a manifest of the kind is validated
+type kubernetesSchemas struct {
+	bundle schemaBundle
+	groups map[string]bool
+
+	mu       sync.Mutex
+	compiled map[string]*gojsonschema.Schema
+}
+
+var (
+	loadedSchemasMu sync.Mutex
+	loadedSchemas   = map[kubernetesVersion]*kubernetesSchemas{}
+)
+
+// loadKubernetesSchemas returns the schemas of a version of Kubernetes, or an error if it isn't supported
+func loadKubernetesSchemas(version kubernetesVersion) (*kubernetesSchemas, error) {
+	loadedSchemasMu.Lock()
+	defer loadedSchemasMu.Unlock()
+
+	if s, ok := loadedSchemas[version]; ok {
+		return s, nil
+	}
+
+	data, err := schemaFiles.ReadFile(path.Join("schemas", version.String()+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes %s is not supported, manifests can be validated against %s", version, strings.Join(SupportedKubernetesVersions(), ", "))
+	}
+
+	s := &kubernetesSchemas{
+		groups:   map[string]bool{},
+		compiled: map[string]*gojsonschema.Schema{},
+	}
+	if err := json.Unmarshal(data, &s.bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse schemas of kubernetes %s: %w", version, err)
+	}
+
+	for key := range s.bundle.Kinds {
+		s.groups[groupOf(path.Dir(key))] = true
+	}
+	for key := range s.bundle.Removed {
+		s.groups[groupOf(path.Dir(key))] = true
+	}
+
+	loadedSchemas[version] = s
+	return s, nil
+}
+
+// isBuiltin returns true if the group of apiVersion is built into Kubernetes
+func (s *kubernetesSchemas) isBuiltin(apiVersion string) bool {
+	return s.groups[groupOf(apiVersion)]
+}
+
+// validate checks a manifest of a built-in kind against the schema of its kind
+func (s *kubernetesSchemas) validate(m *manifest) []types.RenderFinding {
+	key := m.apiVersion + "/" + m.kind
+
+	if removed, ok := s.bundle.Removed[key]; ok {
+		message := fmt.Sprintf("%s %s was removed in Kubernetes %s and isn't served by %s", m.apiVersion, m.kind, removed.Removed, s.bundle.KubernetesVersion)
+		if removed.Replacement != "" {
+			message += fmt.Sprintf(". Use %s instead", removed.Replacement)
+		}
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "removed-api", "apiVersion", message)}
+	}
+
+	definition, ok := s.bundle.Kinds[key]
+	if !ok {
+		if servedBy := s.apiVersionsOf(m.kind); len(servedBy) > 0 {
+			return []types.RenderFinding{m.finding(types.FindingSeverityError, "unavailable-api", "apiVersion",
+				fmt.Sprintf("%s %s isn't served by Kubernetes %s. Use %s instead", m.apiVersion, m.kind, s.bundle.KubernetesVersion, strings.Join(servedBy, " or ")))}
+		}
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "unknown-kind", "kind",
+			fmt.Sprintf("%s is not a kind in %s", m.kind, m.apiVersion))}
+	}
+
+	compiled, err := s.compile(definition)
+	if err != nil {
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "invalid-value", "", err.Error())}
+	}
+
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(withoutNulls(m.object)))
+	if err != nil {
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "invalid-value", "", err.Error())}
+	}
+
+	findings := []types.RenderFinding{}
+	for _, resultErr := range result.Errors() {
+		field := resultErr.Field()
+		if field == "(root)" {
+			field = ""
+		}
+
+		if resultErr.Type() == "additional_property_not_allowed" {
+			property, _ := resultErr.Details()["property"].(string)
+			if field != "" {
+				property = field + "." + property
+			}
+			findings = append(findings, m.finding(types.FindingSeverityError, "unknown-field", property,
+				fmt.Sprintf("unknown field %q", property)))
+			continue
+		}
+
+		findings = append(findings, m.finding(types.FindingSeverityError, "invalid-value", field,
+			fmt.Sprintf("%s: %s", resultErr.Field(), resultErr.Description())))
+	}
+	return findings
+}
+
+// compile returns the compiled schema of a definition
+func (s *kubernetesSchemas) compile(definition string) (*gojsonschema.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if compiled, ok := s.compiled[definition]; ok {
+		return compiled, nil
+	}
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(map[string]interface{}{
+		"$ref":        "#/definitions/" + definition,
+		"definitions": s.bundle.Definitions,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema of %s: %w", definition, err)
+	}
+
+	s.compiled[definition] = compiled
+	return compiled, nil
+}
+
+// apiVersionsOf returns the apiVersions that serve a kind
+func (s *kubernetesSchemas) apiVersionsOf(kind string) []string {
+	apiVersions := []string{}
+	for key := range s.bundle.Kinds {
+		if path.Base(key) == kind {
+			apiVersions = append(apiVersions, path.Dir(key))
+		}
+	}
+	sort.Strings(apiVersions)
+	return apiVersions
+}
+
+func groupOf(apiVersion string) string {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return ""
+	}
+	return gv.Group
+}
+
+// withoutNulls returns a copy of a manifest without null fields, which the API server treats as unset
+func withoutNulls(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := map[string]interface{}{}
+		for key, v := range value {
+			if v == nil {
+				continue
+			}
+			copied[key] = withoutNulls(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, 0, len(value))
+		for _, v := range value {
+			copied = append(copied, withoutNulls(v))
+		}
+		return copied
+	}
+	return value
+}
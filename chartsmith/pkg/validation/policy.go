@@ -0,0 +1,274 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+const (
+	PolicyRuleMissingResources    = "missing-resources"
+	PolicyRuleLatestImageTag      = "latest-image-tag"
+	PolicyRulePrivilegedContainer = "privileged-container"
+	PolicyRuleMissingProbes       = "missing-probes"
+	PolicyRuleHostPathVolume      = "host-path-volume"
+	PolicyRuleUnmatchedSelector   = "unmatched-service-selector"
+)
+
+// PolicyRule is a best practice that rendered manifests are checked for
+type PolicyRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PolicyRules are the rules that LintPolicies runs. Each of them is enabled unless a workspace disables it
+var PolicyRules = []PolicyRule{
+	{Name: PolicyRuleMissingResources, Description: "Containers should set CPU and memory requests and limits"},
+	{Name: PolicyRuleLatestImageTag, Description: "Images should be pinned to a tag other than latest, or a digest"},
+	{Name: PolicyRulePrivilegedContainer, Description: "Containers shouldn't run privileged"},
+	{Name: PolicyRuleMissingProbes, Description: "Containers of long running workloads should have liveness and readiness probes"},
+	{Name: PolicyRuleHostPathVolume, Description: "Pods shouldn't mount directories of the node with hostPath volumes"},
+	{Name: PolicyRuleUnmatchedSelector, Description: "The selector of a Service should match the labels of a pod template in the chart"},
+}
+
+// IsPolicyRule returns true if name is the name of one of the PolicyRules
+func IsPolicyRule(name string) bool {
+	for _, rule := range PolicyRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyRulesJSON returns the PolicyRules as the JSON file that the app reads them from
+func PolicyRulesJSON() ([]byte, error) {
+	marshalled, err := json.MarshalIndent(PolicyRules, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy rules: %w", err)
+	}
+	return append(marshalled, '\n'), nil
+}
+
+// podTemplate is the pod spec of a manifest, with the path of the spec in the manifest
+type podTemplate struct {
+	manifest    *manifest
+	namespace   string
+	labels      map[string]interface{}
+	spec        map[string]interface{}
+	specField   string
+	longRunning bool
+}
+
+// LintPolicies checks the rendered templates, keyed by path, for the PolicyRules that aren't in disabledRules.
+// The findings are warnings, keyed by the path of the template they were found in. Templates that can't be
+// parsed are left to ValidateSchemas
+func LintPolicies(templates map[string]string, disabledRules []string) map[string][]types.RenderFinding {
+	enabled := map[string]bool{}
+	for _, rule := range PolicyRules {
+		enabled[rule.Name] = true
+	}
+	for _, rule := range disabledRules {
+		delete(enabled, rule)
+	}
+
+	paths := make([]string, 0, len(templates))
+	for path := range templates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	manifests := []*manifest{}
+	for _, path := range paths {
+		parsed, _ := parseManifests(path, templates[path])
+		manifests = append(manifests, parsed...)
+	}
+
+	findings := map[string][]types.RenderFinding{}
+	add := func(m *manifest, rule string, field string, message string) {
+		if !enabled[rule] {
+			return
+		}
+		finding := m.finding(types.FindingSeverityWarning, rule, field, message)
+		finding.Source = types.FindingSourcePolicy
+		findings[m.filePath] = append(findings[m.filePath], finding)
+	}
+
+	podTemplates := []podTemplate{}
+	for _, m := range manifests {
+		if pod, ok := podTemplateOf(m); ok {
+			podTemplates = append(podTemplates, pod)
+			lintPodTemplate(pod, add)
+		}
+	}
+
+	for _, m := range manifests {
+		if m.kind == "Service" {
+			lintServiceSelector(m, podTemplates, add)
+		}
+	}
+
+	return findings
+}
+
+// podTemplateOf returns the pod spec of pods and the kinds that create pods
+func podTemplateOf(m *manifest) (podTemplate, bool) {
+	pod := podTemplate{
+		manifest:  m,
+		namespace: namespaceOf(m.object),
+	}
+
+	switch m.kind {
+	case "Pod":
+		pod.labels = nestedMap(m.object, "metadata", "labels")
+		pod.spec = nestedMap(m.object, "spec")
+		pod.specField = "spec"
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController":
+		pod.labels = nestedMap(m.object, "spec", "template", "metadata", "labels")
+		pod.spec = nestedMap(m.object, "spec", "template", "spec")
+		pod.specField = "spec.template.spec"
+		pod.longRunning = true
+	case "Job":
+		pod.labels = nestedMap(m.object, "spec", "template", "metadata", "labels")
+		pod.spec = nestedMap(m.object, "spec", "template", "spec")
+		pod.specField = "spec.template.spec"
+	case "CronJob":
+		pod.labels = nestedMap(m.object, "spec", "jobTemplate", "spec", "template", "metadata", "labels")
+		pod.spec = nestedMap(m.object, "spec", "jobTemplate", "spec", "template", "spec")
+		pod.specField = "spec.jobTemplate.spec.template.spec"
+	default:
+		return pod, false
+	}
+
+	return pod, pod.spec != nil
+}
+
+func lintPodTemplate(pod podTemplate, add func(m *manifest, rule string, field string, message string)) {
+	m := pod.manifest
+
+	volumes, _ := pod.spec["volumes"].([]interface{})
+	for i, v := range volumes {
+		volume, _ := v.(map[string]interface{})
+		if _, ok := volume["hostPath"]; ok {
+			add(m, PolicyRuleHostPathVolume, fmt.Sprintf("%s.volumes[%d].hostPath", pod.specField, i),
+				fmt.Sprintf("Volume %q mounts a directory of the node with hostPath", volume["name"]))
+		}
+	}
+
+	for _, containersField := range []string{"initContainers", "containers"} {
+		containers, _ := pod.spec[containersField].([]interface{})
+		for i, c := range containers {
+			container, _ := c.(map[string]interface{})
+			name, _ := container["name"].(string)
+			field := fmt.Sprintf("%s.%s[%d]", pod.specField, containersField, i)
+
+			image, _ := container["image"].(string)
+			if image != "" && usesLatestTag(image) {
+				add(m, PolicyRuleLatestImageTag, field+".image",
+					fmt.Sprintf("Container %q uses image %s, which isn't pinned to a tag other than latest", name, image))
+			}
+
+			if privileged, _ := nestedMap(container, "securityContext")["privileged"].(bool); privileged {
+				add(m, PolicyRulePrivilegedContainer, field+".securityContext.privileged",
+					fmt.Sprintf("Container %q runs privileged", name))
+			}
+
+			if missing := missingResources(container); len(missing) > 0 {
+				add(m, PolicyRuleMissingResources, field+".resources",
+					fmt.Sprintf("Container %q doesn't set %s", name, strings.Join(missing, ", ")))
+			}
+
+			// init containers run to completion, and jobs aren't restarted when they are unhealthy
+			if containersField != "containers" || !pod.longRunning {
+				continue
+			}
+			missingProbes := []string{}
+			for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+				if _, ok := container[probe]; !ok {
+					missingProbes = append(missingProbes, probe)
+				}
+			}
+			if len(missingProbes) > 0 {
+				add(m, PolicyRuleMissingProbes, field,
+					fmt.Sprintf("Container %q doesn't have a %s", name, strings.Join(missingProbes, " or ")))
+			}
+		}
+	}
+}
+
+// lintServiceSelector checks that the selector of a Service matches the labels of at least one pod template
+// in the same namespace. Services without a selector have their endpoints managed some other way
+func lintServiceSelector(m *manifest, podTemplates []podTemplate, add func(m *manifest, rule string, field string, message string)) {
+	if serviceType, _ := nestedMap(m.object, "spec")["type"].(string); serviceType == "ExternalName" {
+		return
+	}
+
+	selector := nestedMap(m.object, "spec", "selector")
+	if len(selector) == 0 {
+		return
+	}
+
+	namespace := namespaceOf(m.object)
+	for _, pod := range podTemplates {
+		if pod.namespace == namespace && labelsMatch(selector, pod.labels) {
+			return
+		}
+	}
+
+	pairs := []string{}
+	for key, value := range selector {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(pairs)
+
+	add(m, PolicyRuleUnmatchedSelector, "spec.selector",
+		fmt.Sprintf("The selector %s doesn't match the labels of any pod template in the chart", strings.Join(pairs, ",")))
+}
+
+// usesLatestTag returns true for images without a digest that don't have a tag or are tagged latest
+func usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+
+	// the registry can have a port, so the tag is after the last colon in the last part of the name
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i == -1 || name[i+1:] == "latest"
+}
+
+// missingResources returns the requests and limits that a container doesn't set
+func missingResources(container map[string]interface{}) []string {
+	missing := []string{}
+	for _, kind := range []string{"requests", "limits"} {
+		values := nestedMap(container, "resources", kind)
+		for _, resource := range []string{"cpu", "memory"} {
+			if _, ok := values[resource]; !ok {
+				missing = append(missing, kind+"."+resource)
+			}
+		}
+	}
+	return missing
+}
+
+func labelsMatch(selector map[string]interface{}, labels map[string]interface{}) bool {
+	for key, value := range selector {
+		if labelValue, ok := labels[key]; !ok || fmt.Sprint(labelValue) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceOf returns the namespace of a manifest. Manifests without one are installed in the release
+// namespace, which is assumed to be default like it is for helm template
+func namespaceOf(object map[string]interface{}) string {
+	namespace, _ := nestedMap(object, "metadata")["namespace"].(string)
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
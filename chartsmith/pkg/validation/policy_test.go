@@ -0,0 +1,177 @@
+package validation
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+func TestPolicyRulesJSONIsGenerated(t *testing.T) {
+	want, err := PolicyRulesJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile("../../chartsmith-app/lib/workspace/policy-rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("chartsmith-app/lib/workspace/policy-rules.json is out of date, run make policy-rules")
+	}
+}
+
+func TestLintPolicies(t *testing.T) {
+	tests := []struct {
+		name          string
+		templates     map[string]string
+		disabledRules []string
+		want          []testFinding
+	}{
+		{
+			name: "best practices",
+			templates: map[string]string{
+				"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: registry.example.com:5000/web@sha256:abc
+          livenessProbe: {}
+          readinessProbe: {}
+          resources:
+            requests: {cpu: 100m, memory: 64Mi}
+            limits: {cpu: 500m, memory: 128Mi}
+`,
+				"templates/service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector:\n    app: web\n",
+			},
+			want: []testFinding{},
+		},
+		{
+			name: "container rules",
+			templates: map[string]string{
+				"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: busybox
+      containers:
+        - name: web
+          image: registry.example.com:5000/web:latest
+          securityContext:
+            privileged: true
+          readinessProbe: {}
+          resources:
+            requests: {cpu: 100m, memory: 64Mi}
+      volumes:
+        - name: data
+          emptyDir: {}
+        - name: docker
+          hostPath:
+            path: /var/run/docker.sock
+`,
+			},
+			want: []testFinding{
+				{path: "templates/deployment.yaml", rule: PolicyRuleHostPathVolume, field: "spec.template.spec.volumes[1].hostPath"},
+				{path: "templates/deployment.yaml", rule: PolicyRuleLatestImageTag, field: "spec.template.spec.containers[0].image"},
+				{path: "templates/deployment.yaml", rule: PolicyRuleLatestImageTag, field: "spec.template.spec.initContainers[0].image"},
+				{path: "templates/deployment.yaml", rule: PolicyRuleMissingProbes, field: "spec.template.spec.containers[0]"},
+				{path: "templates/deployment.yaml", rule: PolicyRuleMissingResources, field: "spec.template.spec.containers[0].resources"},
+				{path: "templates/deployment.yaml", rule: PolicyRuleMissingResources, field: "spec.template.spec.initContainers[0].resources"},
+				{path: "templates/deployment.yaml", rule: PolicyRulePrivilegedContainer, field: "spec.template.spec.containers[0].securityContext.privileged"},
+			},
+		},
+		{
+			name: "disabled rules",
+			templates: map[string]string{
+				"templates/job.yaml": "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\nspec:\n  template:\n    spec:\n      containers:\n        - name: migrate\n          image: migrate\n",
+			},
+			disabledRules: []string{PolicyRuleMissingResources},
+			want: []testFinding{
+				{path: "templates/job.yaml", rule: PolicyRuleLatestImageTag, field: "spec.template.spec.containers[0].image"},
+			},
+		},
+		{
+			name: "cron job",
+			templates: map[string]string{
+				"templates/cronjob.yaml": "apiVersion: batch/v1\nkind: CronJob\nmetadata:\n  name: backup\nspec:\n  jobTemplate:\n    spec:\n      template:\n        spec:\n          containers:\n            - name: backup\n              image: backup:1.0\n",
+			},
+			want: []testFinding{
+				{path: "templates/cronjob.yaml", rule: PolicyRuleMissingResources, field: "spec.jobTemplate.spec.template.spec.containers[0].resources"},
+			},
+		},
+		{
+			name: "unmatched service selector",
+			templates: map[string]string{
+				"templates/pod.yaml":      "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n  namespace: other\n  labels:\n    app: web\nspec:\n  containers: []\n",
+				"templates/service.yaml":  "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector:\n    app: web\n",
+				"templates/external.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: db\nspec:\n  type: ExternalName\n  selector:\n    app: db\n",
+				"templates/headless.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: endpoints\nspec:\n  clusterIP: None\n",
+			},
+			want: []testFinding{
+				{path: "templates/service.yaml", rule: PolicyRuleUnmatchedSelector, field: "spec.selector"},
+			},
+		},
+		{
+			name: "invalid yaml",
+			templates: map[string]string{
+				"templates/deployment.yaml": "kind: [\n",
+			},
+			want: []testFinding{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := LintPolicies(tt.templates, tt.disabledRules)
+			for _, fileFindings := range findings {
+				for _, finding := range fileFindings {
+					if finding.Source != types.FindingSourcePolicy || finding.Severity != types.FindingSeverityWarning {
+						t.Errorf("finding %+v is not a policy warning", finding)
+					}
+				}
+			}
+			if got := testFindings(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesLatestTag(t *testing.T) {
+	tests := []struct {
+		image string
+		want  bool
+	}{
+		{image: "nginx", want: true},
+		{image: "nginx:latest", want: true},
+		{image: "nginx:1.27", want: false},
+		{image: "registry.example.com:5000/nginx", want: true},
+		{image: "registry.example.com:5000/nginx:1.27", want: false},
+		{image: "nginx@sha256:abc", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := usesLatestTag(tt.image); got != tt.want {
+				t.Errorf("usesLatestTag(%q) = %v, want %v", tt.image, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package validation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultKubernetesVersion is the version manifests are validated against when a workspace doesn't choose one
+const DefaultKubernetesVersion = "1.32"
+
+// manifest is one YAML document rendered from a file
+type manifest struct {
+	filePath string
+	object   map[string]interface{}
+
+	apiVersion string
+	kind       string
+	name       string
+}
+
+func (m *manifest) finding(severity string, rule string, field string, message string) types.RenderFinding {
+	return types.RenderFinding{
+		Source:   types.FindingSourceSchema,
+		Severity: severity,
+		Rule:     rule,
+		Kind:     m.kind,
+		Name:     m.name,
+		Field:    field,
+		Message:  message,
+	}
+}
+
+// ValidateSchemas checks each manifest in the rendered templates, keyed by path, against the schema of its
+// kind in kubernetesVersion. Custom resources are checked against the CRDs in the templates. The findings
+// are keyed by the path of the template they were found in, and only templates with findings are included
+func ValidateSchemas(templates map[string]string, kubernetesVersion string) (map[string][]types.RenderFinding, error) {
+	target, err := parseKubernetesVersion(kubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	builtin, err := loadKubernetesSchemas(target)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := map[string][]types.RenderFinding{}
+	manifests := []*manifest{}
+
+	paths := make([]string, 0, len(templates))
+	for path := range templates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parsed, parseFindings := parseManifests(path, templates[path])
+		manifests = append(manifests, parsed...)
+		findings[path] = append(findings[path], parseFindings...)
+	}
+
+	crds := collectCRDSchemas(manifests)
+
+	for _, m := range manifests {
+		findings[m.filePath] = append(findings[m.filePath], validateManifest(m, builtin, crds)...)
+	}
+
+	for path, fileFindings := range findings {
+		if len(fileFindings) == 0 {
+			delete(findings, path)
+		}
+	}
+
+	return findings, nil
+}
+
+// CountErrors returns the number of findings with error severity
+func CountErrors(findings map[string][]types.RenderFinding) int {
+	count := 0
+	for _, fileFindings := range findings {
+		for _, finding := range fileFindings {
+			if finding.Severity == types.FindingSeverityError {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Summary describes the errors in findings in a line for each, to add to the output of a render
+func Summary(findings map[string][]types.RenderFinding) string {
+	paths := make([]string, 0, len(findings))
+	for path := range findings {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		for _, finding := range findings[path] {
+			if finding.Severity != types.FindingSeverityError {
+				continue
+			}
+			resource := finding.Kind
+			if finding.Name != "" {
+				resource += "/" + finding.Name
+			}
+			fmt.Fprintf(&sb, "%s: %s: %s\n", path, resource, finding.Message)
+		}
+	}
+	return sb.String()
+}
+
+func validateManifest(m *manifest, builtin *kubernetesSchemas, crds crdSchemas) []types.RenderFinding {
+	if m.apiVersion == "" || m.kind == "" {
+		return []types.RenderFinding{m.finding(types.FindingSeverityError, "missing-type", "", "apiVersion and kind are required")}
+	}
+
+	if builtin.isBuiltin(m.apiVersion) {
+		return builtin.validate(m)
+	}
+
+	if schema, ok := crds.lookup(m.apiVersion, m.kind); ok {
+		return validateCustomResource(m, schema)
+	}
+
+	if m.kind == "CustomResourceDefinition" || m.kind == "APIService" {
+		return nil
+	}
+
+	return []types.RenderFinding{m.finding(types.FindingSeverityInfo, "unknown-kind", "",
+		fmt.Sprintf("There is no schema for %s %s, it was not validated", m.apiVersion, m.kind))}
+}
+
+// parseManifests splits the content of a rendered template into its YAML documents
+func parseManifests(path string, content string) ([]*manifest, []types.RenderFinding) {
+	manifests := []*manifest{}
+	findings := []types.RenderFinding{}
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			findings = append(findings, types.RenderFinding{
+				Source:   types.FindingSourceSchema,
+				Severity: types.FindingSeverityError,
+				Rule:     "invalid-yaml",
+				Message:  err.Error(),
+			})
+			break
+		}
+
+		object := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &object); err != nil {
+			findings = append(findings, types.RenderFinding{
+				Source:   types.FindingSourceSchema,
+				Severity: types.FindingSeverityError,
+				Rule:     "invalid-yaml",
+				Message:  err.Error(),
+			})
+			continue
+		}
+
+		// documents that are only comments
+		if len(object) == 0 {
+			continue
+		}
+
+		m := &manifest{
+			filePath: path,
+			object:   object,
+		}
+		m.apiVersion, _ = object["apiVersion"].(string)
+		m.kind, _ = object["kind"].(string)
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			m.name, _ = metadata["name"].(string)
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	return manifests, findings
+}
@@ -0,0 +1,282 @@
+package validation
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: web
+  annotations: null
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+          ports:
+            - containerPort: 80
+          resources:
+            limits:
+              cpu: 500m
+              memory: 128Mi
+`
+
+const testCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:
+                size:
+                  type: integer
+`
+
+// testFinding is the part of a finding that identifies it
+type testFinding struct {
+	path  string
+	rule  string
+	field string
+}
+
+func testFindings(findings map[string][]types.RenderFinding) []testFinding {
+	got := []testFinding{}
+	for path, fileFindings := range findings {
+		for _, finding := range fileFindings {
+			got = append(got, testFinding{path: path, rule: finding.Rule, field: finding.Field})
+		}
+	}
+	sort.Slice(got, func(i, j int) bool {
+		if got[i].path != got[j].path {
+			return got[i].path < got[j].path
+		}
+		if got[i].rule != got[j].rule {
+			return got[i].rule < got[j].rule
+		}
+		return got[i].field < got[j].field
+	})
+	return got
+}
+
+func TestValidateSchemas(t *testing.T) {
+	tests := []struct {
+		name              string
+		templates         map[string]string
+		kubernetesVersion string
+		want              []testFinding
+		wantMessage       string
+		wantErr           string
+	}{
+		{
+			name:      "valid",
+			templates: map[string]string{"templates/deployment.yaml": testDeployment},
+			want:      []testFinding{},
+		},
+		{
+			name: "comments only",
+			templates: map[string]string{
+				"templates/empty.yaml": "# nothing is rendered\n---\n",
+			},
+			want: []testFinding{},
+		},
+		{
+			name: "unknown field",
+			templates: map[string]string{
+				"templates/deployment.yaml": strings.Replace(testDeployment, "  replicas: 2", "  replica: 2", 1),
+			},
+			want:        []testFinding{{path: "templates/deployment.yaml", rule: "unknown-field", field: "spec.replica"}},
+			wantMessage: `unknown field "spec.replica"`,
+		},
+		{
+			name: "invalid value",
+			templates: map[string]string{
+				"templates/deployment.yaml": strings.Replace(testDeployment, "  replicas: 2", "  replicas: [2]", 1),
+			},
+			want: []testFinding{{path: "templates/deployment.yaml", rule: "invalid-value", field: "spec.replicas"}},
+		},
+		{
+			name: "int or string",
+			templates: map[string]string{
+				"templates/service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports:\n    - port: 80\n      targetPort: http\n",
+			},
+			want: []testFinding{},
+		},
+		{
+			name: "removed api",
+			templates: map[string]string{
+				"templates/deployment.yaml": strings.Replace(testDeployment, "apps/v1", "extensions/v1beta1", 1),
+			},
+			want:        []testFinding{{path: "templates/deployment.yaml", rule: "removed-api", field: "apiVersion"}},
+			wantMessage: "extensions/v1beta1 Deployment was removed in Kubernetes 1.16 and isn't served by 1.32. Use apps/v1 instead",
+		},
+		{
+			name: "unavailable api",
+			templates: map[string]string{
+				"templates/deployment.yaml": strings.Replace(testDeployment, "apps/v1", "apps/v2", 1),
+			},
+			want:        []testFinding{{path: "templates/deployment.yaml", rule: "unavailable-api", field: "apiVersion"}},
+			wantMessage: "apps/v2 Deployment isn't served by Kubernetes 1.32. Use apps/v1 instead",
+		},
+		{
+			name: "unknown kind",
+			templates: map[string]string{
+				"templates/widget.yaml": "apiVersion: apps/v1\nkind: Widget\nmetadata:\n  name: w\n",
+			},
+			want: []testFinding{{path: "templates/widget.yaml", rule: "unknown-kind", field: "kind"}},
+		},
+		{
+			name: "custom resource",
+			templates: map[string]string{
+				"templates/crd.yaml":    testCRD,
+				"templates/widget.yaml": "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\nspec:\n  size: 3\n---\napiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: x\nspec:\n  size: large\n  colour: red\n",
+			},
+			want: []testFinding{
+				{path: "templates/widget.yaml", rule: "crd-schema", field: "spec"},
+				{path: "templates/widget.yaml", rule: "crd-schema", field: "spec.size"},
+			},
+		},
+		{
+			name: "custom resource without a CRD",
+			templates: map[string]string{
+				"templates/monitor.yaml": "apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: m\n",
+			},
+			want: []testFinding{{path: "templates/monitor.yaml", rule: "unknown-kind", field: ""}},
+		},
+		{
+			name: "missing type",
+			templates: map[string]string{
+				"templates/configmap.yaml": "metadata:\n  name: config\n",
+			},
+			want: []testFinding{{path: "templates/configmap.yaml", rule: "missing-type", field: ""}},
+		},
+		{
+			name: "invalid yaml",
+			templates: map[string]string{
+				"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: [\n",
+			},
+			want: []testFinding{{path: "templates/configmap.yaml", rule: "invalid-yaml", field: ""}},
+		},
+		{
+			name:              "other supported version",
+			templates:         map[string]string{"templates/deployment.yaml": testDeployment},
+			kubernetesVersion: "v1.34.1",
+			want:              []testFinding{},
+		},
+		{
+			name:              "unsupported version",
+			templates:         map[string]string{"templates/deployment.yaml": testDeployment},
+			kubernetesVersion: "1.20",
+			wantErr:           "kubernetes 1.20 is not supported, manifests can be validated against 1.32, 1.34",
+		},
+		{
+			name:              "invalid version",
+			templates:         map[string]string{"templates/deployment.yaml": testDeployment},
+			kubernetesVersion: "latest",
+			wantErr:           `kubernetes version "latest" is not in the form 1.<minor>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := ValidateSchemas(tt.templates, tt.kubernetesVersion)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := testFindings(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("findings = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantMessage != "" {
+				for _, fileFindings := range findings {
+					if got := fileFindings[0].Message; got != tt.wantMessage {
+						t.Errorf("message = %q, want %q", got, tt.wantMessage)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    kubernetesVersion
+		wantErr bool
+	}{
+		{version: "", want: 32},
+		{version: "1.29", want: 29},
+		{version: "v1.29", want: 29},
+		{version: "1.29.3", want: 29},
+		{version: "2.0", wantErr: true},
+		{version: "1", wantErr: true},
+		{version: "1.x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := parseKubernetesVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseKubernetesVersion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	findings := map[string][]types.RenderFinding{
+		"templates/service.yaml": {
+			{Severity: types.FindingSeverityError, Kind: "Service", Name: "web", Message: `unknown field "spec.port"`},
+		},
+		"templates/deployment.yaml": {
+			{Severity: types.FindingSeverityWarning, Kind: "Deployment", Name: "app", Message: "Container \"web\" runs privileged"},
+			{Severity: types.FindingSeverityError, Kind: "Deployment", Message: "spec.replicas: Invalid type"},
+		},
+	}
+
+	want := "templates/deployment.yaml: Deployment: spec.replicas: Invalid type\ntemplates/service.yaml: Service/web: unknown field \"spec.port\"\n"
+	if got := Summary(findings); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := CountErrors(findings); got != 2 {
+		t.Errorf("CountErrors() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,45 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+)
+
+var (
+	// planChannels are the work queue channels of the jobs that create and apply a plan
+	planChannels = []string{"new_plan", "execute_plan", "apply_plan"}
+
+	// conversionChannels are the work queue channels of the jobs of each step of a conversion
+	conversionChannels = []string{"new_conversion", "conversion_next_file", "conversion_normalize_values", "conversion_simplify"}
+)
+
+// CancelPlan requests cancellation of the unfinished jobs of a plan. The worker running them marks the
+// plan as cancelled
+func CancelPlan(ctx context.Context, planID string) ([]string, error) {
+	ids, err := persistence.CancelWorkForPayload(ctx, planChannels, "planId", planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel plan work: %w", err)
+	}
+	return ids, nil
+}
+
+// CancelRender requests cancellation of the job of a render. The worker running it fails the render
+func CancelRender(ctx context.Context, renderID string) ([]string, error) {
+	ids, err := persistence.CancelWorkForPayload(ctx, []string{"render_workspace"}, "id", renderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel render work: %w", err)
+	}
+	return ids, nil
+}
+
+// CancelConversion requests cancellation of the unfinished jobs of a conversion. The worker running them
+// marks the conversion as cancelled
+func CancelConversion(ctx context.Context, conversionID string) ([]string, error) {
+	ids, err := persistence.CancelWorkForPayload(ctx, conversionChannels, "conversionId", conversionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel conversion work: %w", err)
+	}
+	return ids, nil
+}
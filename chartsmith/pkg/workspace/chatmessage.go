@@ -93,7 +93,9 @@ func GetChatMessage(ctx context.Context, chatMessageId string) (*types.Chat, err
 		workspace_chat.response_conversion_id,
 		workspace_chat.response_rollback_to_revision_number,
 		workspace_chat.revision_number,
-		workspace_chat.message_from_persona
+		workspace_chat.message_from_persona,
+		workspace_chat.response_model,
+		workspace_chat.sent_by
 	FROM
 		workspace_chat
 	WHERE
@@ -114,6 +116,8 @@ func GetChatMessage(ctx context.Context, chatMessageId string) (*types.Chat, err
 	var responseConversionID sql.NullString
 	var responseRollbackToRevisionNumber sql.NullInt64
 	var messageFromPersona sql.NullString
+	var responseModel sql.NullString
+	var sentBy sql.NullString
 	err := row.Scan(
 		&chat.ID,
 		&chat.WorkspaceID,
@@ -133,12 +137,16 @@ func GetChatMessage(ctx context.Context, chatMessageId string) (*types.Chat, err
 		&responseRollbackToRevisionNumber,
 		&chat.RevisionNumber,
 		&messageFromPersona,
+		&responseModel,
+		&sentBy,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan chat message in getChatMessage: %w", err)
 	}
 
 	chat.Response = response.String
+	chat.ResponseModel = responseModel.String
+	chat.SentByUserID = sentBy.String
 
 	if messageFromPersona.Valid {
 		persona := types.ChatMessageFromPersona(messageFromPersona.String)
@@ -23,7 +23,9 @@ func GetFile(ctx context.Context, fileID string, revisionNumber int) (*types.Fil
 		workspace_id,
 		file_path,
 		content,
-		content_pending
+		content_pending,
+		pending_delete,
+		file_path_pending
 	FROM
 		workspace_file
 	WHERE
@@ -35,8 +37,9 @@ func GetFile(ctx context.Context, fileID string, revisionNumber int) (*types.Fil
 
 	// Use pgtype.Array which is designed to handle PostgreSQL arrays properly
 	var contentPending sql.NullString
+	var filePathPending sql.NullString
 
-	err := row.Scan(&file.ID, &file.RevisionNumber, &chartID, &file.WorkspaceID, &file.FilePath, &file.Content, &contentPending)
+	err := row.Scan(&file.ID, &file.RevisionNumber, &chartID, &file.WorkspaceID, &file.FilePath, &file.Content, &contentPending, &file.PendingDelete, &filePathPending)
 	if err != nil {
 		return nil, fmt.Errorf("error scanning file: %w", err)
 	}
@@ -44,6 +47,9 @@ func GetFile(ctx context.Context, fileID string, revisionNumber int) (*types.Fil
 	if contentPending.Valid {
 		file.ContentPending = &contentPending.String
 	}
+	if filePathPending.Valid {
+		file.FilePathPending = &filePathPending.String
+	}
 
 	file.ChartID = chartID.String
 	return &file, nil
@@ -66,7 +72,7 @@ func ListFiles(ctx context.Context, workspaceID string, revisionNumber int, char
 	conn := persistence.MustGetPooledPostgresSession()
 	defer conn.Release()
 
-	query := `SELECT id, revision_number, chart_id, workspace_id, file_path, content, content_pending FROM workspace_file WHERE chart_id = $1 AND workspace_id = $2 AND revision_number = $3`
+	query := `SELECT id, revision_number, chart_id, workspace_id, file_path, content, content_pending, pending_delete, file_path_pending FROM workspace_file WHERE chart_id = $1 AND workspace_id = $2 AND revision_number = $3`
 	rows, err := conn.Query(ctx, query, chartID, workspaceID, revisionNumber)
 	if err != nil {
 		return nil, err
@@ -79,8 +85,9 @@ func ListFiles(ctx context.Context, workspaceID string, revisionNumber int, char
 		var chartID sql.NullString
 
 		var contentPending sql.NullString
+		var filePathPending sql.NullString
 
-		err := rows.Scan(&file.ID, &file.RevisionNumber, &chartID, &file.WorkspaceID, &file.FilePath, &file.Content, &contentPending)
+		err := rows.Scan(&file.ID, &file.RevisionNumber, &chartID, &file.WorkspaceID, &file.FilePath, &file.Content, &contentPending, &file.PendingDelete, &filePathPending)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning file row: %w", err)
 		}
@@ -88,6 +95,9 @@ func ListFiles(ctx context.Context, workspaceID string, revisionNumber int, char
 		if contentPending.Valid {
 			file.ContentPending = &contentPending.String
 		}
+		if filePathPending.Valid {
+			file.FilePathPending = &filePathPending.String
+		}
 
 		file.ChartID = chartID.String
 		files = append(files, file)
@@ -105,6 +115,12 @@ func min(a, b int) int {
 }
 
 func SetFileContentPending(ctx context.Context, path string, revisionNumber int, chartID string, workspaceID string, contentPending string) error {
+	return SetFilesContentPending(ctx, revisionNumber, chartID, workspaceID, map[string]string{path: contentPending})
+}
+
+// SetFilesContentPending sets the pending content of several files in one transaction, creating the files
+// that don't exist yet, so that edits that depend on each other are saved together or not at all
+func SetFilesContentPending(ctx context.Context, revisionNumber int, chartID string, workspaceID string, contentPending map[string]string) error {
 	// Create dedicated database context with timeout
 	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -119,11 +135,28 @@ func SetFileContentPending(ctx context.Context, path string, revisionNumber int,
 	}
 	defer tx.Rollback(dbCtx)
 
-	// get the file id - only filter by path and revision for maximum compatibility with different chart structures
-	query := `SELECT id FROM workspace_file WHERE file_path = $1 AND revision_number = $2 AND workspace_id = $3`
-	row := tx.QueryRow(dbCtx, query, path, revisionNumber, workspaceID)
+	for path, content := range contentPending {
+		if err := setFileContentPending(dbCtx, tx, path, revisionNumber, chartID, workspaceID, content); err != nil {
+			return fmt.Errorf("error setting content pending for %s: %w", path, err)
+		}
+	}
+
+	// Try to commit the transaction
+	if err := tx.Commit(dbCtx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func setFileContentPending(ctx context.Context, tx pgx.Tx, path string, revisionNumber int, chartID string, workspaceID string, contentPending string) error {
+	// get the file id - only filter by path and revision for maximum compatibility with different chart structures.
+	// A file with a pending rename is found by the path it's being renamed to
+	query := `SELECT id FROM workspace_file WHERE revision_number = $2 AND workspace_id = $3 AND
+		((file_path = $1 AND file_path_pending IS NULL) OR file_path_pending = $1)`
+	row := tx.QueryRow(ctx, query, path, revisionNumber, workspaceID)
 	var fileID string
-	err = row.Scan(&fileID)
+	err := row.Scan(&fileID)
 
 	// More specific error handling
 	if err != nil {
@@ -136,9 +169,9 @@ func SetFileContentPending(ctx context.Context, path string, revisionNumber int,
 
 	// set the content pending
 	if fileID != "" {
-		// Update existing file
-		query = `UPDATE workspace_file SET content_pending = $1 WHERE id = $2 AND revision_number = $3`
-		_, err := tx.Exec(dbCtx, query, contentPending, fileID, revisionNumber)
+		// Update existing file. Writing to a file that an earlier action deleted creates it again
+		query = `UPDATE workspace_file SET content_pending = $1, pending_delete = false WHERE id = $2 AND revision_number = $3`
+		_, err := tx.Exec(ctx, query, contentPending, fileID, revisionNumber)
 		if err != nil {
 			return fmt.Errorf("error updating file content pending: %w", err)
 		}
@@ -150,16 +183,78 @@ func SetFileContentPending(ctx context.Context, path string, revisionNumber int,
 		}
 
 		query = `INSERT INTO workspace_file (id, revision_number, chart_id, workspace_id, file_path, content, content_pending) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-		_, err = tx.Exec(dbCtx, query, id, revisionNumber, chartID, workspaceID, path, "", contentPending)
+		_, err = tx.Exec(ctx, query, id, revisionNumber, chartID, workspaceID, path, "", contentPending)
 		if err != nil {
 			return fmt.Errorf("error inserting file: %w", err)
 		}
 	}
 
-	// Try to commit the transaction
-	if err := tx.Commit(dbCtx); err != nil {
-		return fmt.Errorf("error committing transaction: %w", err)
+	return nil
+}
+
+// StageFileDelete marks the file at path in a revision to be deleted when the change is accepted and
+// returns it
+func StageFileDelete(ctx context.Context, workspaceID string, revisionNumber int, path string) (*types.File, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_file SET pending_delete = true
+		WHERE workspace_id = $1 AND revision_number = $2 AND
+			((file_path = $3 AND file_path_pending IS NULL) OR file_path_pending = $3)
+		RETURNING id`
+
+	var fileID string
+	err := conn.QueryRow(ctx, query, workspaceID, revisionNumber, path).Scan(&fileID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("file %s not found in revision %d", path, revisionNumber)
+		}
+		return nil, fmt.Errorf("error staging file delete: %w", err)
 	}
 
-	return nil
+	return GetFile(ctx, fileID, revisionNumber)
+}
+
+// StageFileRename marks the file at oldPath in a revision to be moved to newPath when the change is accepted
+// and returns it. The file keeps its ID
+func StageFileRename(ctx context.Context, workspaceID string, revisionNumber int, oldPath string, newPath string) (*types.File, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM workspace_file WHERE workspace_id = $1 AND revision_number = $2 AND NOT pending_delete AND
+		((file_path = $3 AND file_path_pending IS NULL) OR file_path_pending = $3))`
+	if err := tx.QueryRow(ctx, query, workspaceID, revisionNumber, newPath).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking for existing file: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("file %s already exists in revision %d", newPath, revisionNumber)
+	}
+
+	// renaming a file back to where it is drops the pending rename
+	query = `UPDATE workspace_file SET file_path_pending = NULLIF($4, file_path)
+		WHERE workspace_id = $1 AND revision_number = $2 AND NOT pending_delete AND
+			((file_path = $3 AND file_path_pending IS NULL) OR file_path_pending = $3)
+		RETURNING id`
+
+	var fileID string
+	err = tx.QueryRow(ctx, query, workspaceID, revisionNumber, oldPath, newPath).Scan(&fileID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("file %s not found in revision %d", oldPath, revisionNumber)
+		}
+		return nil, fmt.Errorf("error staging file rename: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return GetFile(ctx, fileID, revisionNumber)
 }
@@ -196,7 +196,9 @@ func listActionFiles(ctx context.Context, tx pgx.Tx, planID string) ([]types.Act
 	query := `SELECT
 		action,
 		path,
-		status
+		status,
+		description,
+		new_path
 	FROM workspace_plan_action_file WHERE plan_id = $1 ORDER BY created_at ASC`
 
 	rows, err := tx.Query(ctx, query, planID)
@@ -207,10 +209,13 @@ func listActionFiles(ctx context.Context, tx pgx.Tx, planID string) ([]types.Act
 	var actionFiles []types.ActionFile
 	for rows.Next() {
 		var actionFile types.ActionFile
-		err := rows.Scan(&actionFile.Action, &actionFile.Path, &actionFile.Status)
+		var description, newPath sql.NullString
+		err := rows.Scan(&actionFile.Action, &actionFile.Path, &actionFile.Status, &description, &newPath)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning action file: %w", err)
 		}
+		actionFile.Description = description.String
+		actionFile.NewPath = newPath.String
 		actionFiles = append(actionFiles, actionFile)
 	}
 
@@ -253,6 +258,20 @@ func UpdatePlanStatus(ctx context.Context, planID string, status types.PlanStatu
 	return nil
 }
 
+// SetPlanPromptVersion records the version of the prompts used for stage while creating or applying a plan
+func SetPlanPromptVersion(ctx context.Context, planID string, stage string, version string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_plan SET prompt_versions = coalesce(prompt_versions, '{}'::jsonb) || jsonb_build_object($2::text, $3::text)
+		WHERE id = $1 AND prompt_versions->>$2 IS DISTINCT FROM $3`
+	_, err := conn.Exec(ctx, query, planID, stage, version)
+	if err != nil {
+		return fmt.Errorf("error updating plan prompt versions: %w", err)
+	}
+	return nil
+}
+
 func UpdatePlanActionFiles(ctx context.Context, tx pgx.Tx, planID string, actionFiles []types.ActionFile) error {
 	_, err := tx.Exec(ctx, `DELETE FROM workspace_plan_action_file WHERE plan_id = $1`, planID)
 	if err != nil {
@@ -260,10 +279,12 @@ func UpdatePlanActionFiles(ctx context.Context, tx pgx.Tx, planID string, action
 	}
 
 	for _, actionFile := range actionFiles {
-		query := `INSERT INTO workspace_plan_action_file (plan_id, action, path, status, created_at) VALUES ($1, $2, $3, $4, $5)
+		query := `INSERT INTO workspace_plan_action_file (plan_id, action, path, status, created_at, description, new_path) VALUES ($1, $2, $3, $4, $5, $6, $7)
 	ON CONFLICT (plan_id, path) DO UPDATE SET status = EXCLUDED.status`
 
-		_, err := tx.Exec(ctx, query, planID, actionFile.Action, actionFile.Path, actionFile.Status, time.Now())
+		_, err := tx.Exec(ctx, query, planID, actionFile.Action, actionFile.Path, actionFile.Status, time.Now(),
+			sql.NullString{String: actionFile.Description, Valid: actionFile.Description != ""},
+			sql.NullString{String: actionFile.NewPath, Valid: actionFile.NewPath != ""})
 		if err != nil {
 			return fmt.Errorf("error updating plan action files: %w", err)
 		}
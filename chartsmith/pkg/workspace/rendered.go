@@ -3,8 +3,10 @@ package workspace
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -105,7 +107,7 @@ func GetRendered(ctx context.Context, id string) (*types.Rendered, error) {
 
 	rendered.CompletedAt = &completedAt.Time
 	
-	query = `SELECT id, chart_id, is_success, dep_update_command, dep_update_stdout, dep_update_stderr, helm_template_command, helm_template_stdout, helm_template_stderr, created_at, completed_at FROM workspace_rendered_chart WHERE workspace_render_id = $1`
+	query = `SELECT id, chart_id, values_profile, is_success, dep_update_command, dep_update_stdout, dep_update_stderr, helm_lint_command, helm_lint_stdout, helm_lint_stderr, helm_lint_findings, helm_template_command, helm_template_stdout, helm_template_stderr, created_at, completed_at FROM workspace_rendered_chart WHERE workspace_render_id = $1`
 	
 	logger.Debug("Executing second query for charts", 
 		zap.String("id", id),
@@ -137,9 +139,15 @@ func GetRendered(ctx context.Context, id string) (*types.Rendered, error) {
 			
 		var renderedChart types.RenderedChart
 
+		var valuesProfile sql.NullString
+
 		var depUpdateCommand sql.NullString
 		var depUpdateStdout sql.NullString
 		var depUpdateStderr sql.NullString
+		var helmLintCommand sql.NullString
+		var helmLintStdout sql.NullString
+		var helmLintStderr sql.NullString
+		var helmLintFindings []byte
 		var helmTemplateCommand sql.NullString
 		var helmTemplateStdout sql.NullString
 		var helmTemplateStderr sql.NullString
@@ -150,7 +158,7 @@ func GetRendered(ctx context.Context, id string) (*types.Rendered, error) {
 			zap.String("id", id),
 			zap.Int("rowNumber", rowCount))
 			
-		if err := rows.Scan(&renderedChart.ID, &renderedChart.ChartID, &renderedChart.IsSuccess, &depUpdateCommand, &depUpdateStdout, &depUpdateStderr, &helmTemplateCommand, &helmTemplateStdout, &helmTemplateStderr, &renderedChart.CreatedAt, &completedAt); err != nil {
+		if err := rows.Scan(&renderedChart.ID, &renderedChart.ChartID, &valuesProfile, &renderedChart.IsSuccess, &depUpdateCommand, &depUpdateStdout, &depUpdateStderr, &helmLintCommand, &helmLintStdout, &helmLintStderr, &helmLintFindings, &helmTemplateCommand, &helmTemplateStdout, &helmTemplateStderr, &renderedChart.CreatedAt, &completedAt); err != nil {
 			logger.Error(fmt.Errorf("failed to scan chart row: %w", err),
 				zap.String("id", id),
 				zap.Int("rowNumber", rowCount))
@@ -162,9 +170,18 @@ func GetRendered(ctx context.Context, id string) (*types.Rendered, error) {
 			zap.String("chartID", renderedChart.ChartID),
 			zap.Int("rowNumber", rowCount))
 
+		renderedChart.ValuesProfile = valuesProfile.String
 		renderedChart.DepupdateCommand = depUpdateCommand.String
 		renderedChart.DepupdateStdout = depUpdateStdout.String
 		renderedChart.DepupdateStderr = depUpdateStderr.String
+		renderedChart.HelmLintCommand = helmLintCommand.String
+		renderedChart.HelmLintStdout = helmLintStdout.String
+		renderedChart.HelmLintStderr = helmLintStderr.String
+		if len(helmLintFindings) > 0 {
+			if err := json.Unmarshal(helmLintFindings, &renderedChart.HelmLintFindings); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal helm lint findings: %w", err)
+			}
+		}
 		renderedChart.HelmTemplateCommand = helmTemplateCommand.String
 		renderedChart.HelmTemplateStdout = helmTemplateStdout.String
 		renderedChart.HelmTemplateStderr = helmTemplateStderr.String
@@ -241,6 +258,68 @@ func SetRenderedChartDepUpdateStderr(ctx context.Context, renderedChartID string
 	return nil
 }
 
+func SetRenderedChartHelmLintCommand(ctx context.Context, renderedChartID string, helmLintCommand string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_rendered_chart SET helm_lint_command = $2 WHERE id = $1`
+	_, err := conn.Exec(ctx, query, renderedChartID, helmLintCommand)
+	if err != nil {
+		return fmt.Errorf("failed to update rendered chart helmLintCommand: %w", err)
+	}
+
+	return nil
+}
+
+func SetRenderedChartHelmLintStdout(ctx context.Context, renderedChartID string, helmLintStdout string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_rendered_chart SET helm_lint_stdout = $2 WHERE id = $1`
+	_, err := conn.Exec(ctx, query, renderedChartID, helmLintStdout)
+	if err != nil {
+		return fmt.Errorf("failed to update rendered chart helmLintStdout: %w", err)
+	}
+
+	return nil
+}
+
+func SetRenderedChartHelmLintStderr(ctx context.Context, renderedChartID string, helmLintStderr string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_rendered_chart SET helm_lint_stderr = $2 WHERE id = $1`
+	_, err := conn.Exec(ctx, query, renderedChartID, helmLintStderr)
+	if err != nil {
+		return fmt.Errorf("failed to update rendered chart helmLintStderr: %w", err)
+	}
+
+	return nil
+}
+
+// FinishRenderedChartHelmLint saves the output of helm lint and the findings parsed from it
+func FinishRenderedChartHelmLint(ctx context.Context, renderedChartID string, helmLintCommand string, helmLintStdout string, helmLintStderr string, findings []types.LintFinding) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	if findings == nil {
+		findings = []types.LintFinding{}
+	}
+
+	marshalled, err := json.Marshal(findings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal helm lint findings: %w", err)
+	}
+
+	query := `UPDATE workspace_rendered_chart SET helm_lint_command = $2, helm_lint_stdout = $3, helm_lint_stderr = $4, helm_lint_findings = $5 WHERE id = $1`
+	_, err = conn.Exec(ctx, query, renderedChartID, helmLintCommand, helmLintStdout, helmLintStderr, marshalled)
+	if err != nil {
+		return fmt.Errorf("failed to update rendered chart helm lint: %w", err)
+	}
+
+	return nil
+}
+
 func SetRenderedChartHelmTemplateCommand(ctx context.Context, renderedChartID string, helmTemplateCommand string) error {
 	conn := persistence.MustGetPooledPostgresSession()
 	defer conn.Release()
@@ -267,7 +346,9 @@ func SetRenderedChartHelmTemplateStdout(ctx context.Context, renderedChartID str
 	return nil
 }
 
-func SetRenderedFileContents(ctx context.Context, workspaceID string, revisionNumber int, filePath string, renderedContent string) error {
+// SetRenderedFileContents saves the content of a file rendered with a values profile, or with the chart's own
+// values when valuesProfile is empty
+func SetRenderedFileContents(ctx context.Context, workspaceID string, revisionNumber int, valuesProfile string, filePath string, renderedContent string) error {
 	conn := persistence.MustGetPooledPostgresSession()
 	defer conn.Release()
 
@@ -282,8 +363,8 @@ func SetRenderedFileContents(ctx context.Context, workspaceID string, revisionNu
 		return fmt.Errorf("failed to get file id: %w", err)
 	}
 
-	query = `INSERT INTO workspace_rendered_file (file_id, workspace_id, revision_number, file_path, content) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (file_id, workspace_id, revision_number) DO UPDATE SET content = $5`
-	_, err = conn.Exec(ctx, query, fileID, workspaceID, revisionNumber, filePath, renderedContent)
+	query = `INSERT INTO workspace_rendered_file (file_id, workspace_id, revision_number, values_profile, file_path, content) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (file_id, workspace_id, revision_number, values_profile) DO UPDATE SET content = $6`
+	_, err = conn.Exec(ctx, query, fileID, workspaceID, revisionNumber, valuesProfile, filePath, renderedContent)
 	if err != nil {
 		return fmt.Errorf("failed to insert rendered file: %w", err)
 	}
@@ -291,6 +372,67 @@ func SetRenderedFileContents(ctx context.Context, workspaceID string, revisionNu
 	return nil
 }
 
+// SetRenderedFileFindings replaces the findings of a file rendered with a values profile, or with the chart's
+// own values when valuesProfile is empty
+func SetRenderedFileFindings(ctx context.Context, workspaceID string, revisionNumber int, valuesProfile string, filePath string, findings []types.RenderFinding) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	if findings == nil {
+		findings = []types.RenderFinding{}
+	}
+
+	marshalled, err := json.Marshal(findings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal findings: %w", err)
+	}
+
+	query := `UPDATE workspace_rendered_file SET findings = $5 WHERE workspace_id = $1 AND revision_number = $2 AND values_profile = $3 AND file_path = $4`
+	_, err = conn.Exec(ctx, query, workspaceID, revisionNumber, valuesProfile, filePath, marshalled)
+	if err != nil {
+		return fmt.Errorf("failed to update rendered file findings: %w", err)
+	}
+
+	return nil
+}
+
+// ListRenderedFileFindings returns the findings of the files rendered with the chart's own values for a revision,
+// keyed by the path of the rendered file. Files without findings aren't included
+func ListRenderedFileFindings(ctx context.Context, workspaceID string, revisionNumber int) (map[string][]types.RenderFinding, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT file_path, findings FROM workspace_rendered_file
+		WHERE workspace_id = $1 AND revision_number = $2 AND values_profile = '' AND findings IS NOT NULL AND findings != '[]'::jsonb
+		ORDER BY file_path`
+	rows, err := conn.Query(ctx, query, workspaceID, revisionNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rendered file findings: %w", err)
+	}
+	defer rows.Close()
+
+	findings := map[string][]types.RenderFinding{}
+	for rows.Next() {
+		var filePath string
+		var marshalled []byte
+		if err := rows.Scan(&filePath, &marshalled); err != nil {
+			return nil, fmt.Errorf("failed to scan rendered file findings: %w", err)
+		}
+
+		fileFindings := []types.RenderFinding{}
+		if err := json.Unmarshal(marshalled, &fileFindings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal findings of %s: %w", filePath, err)
+		}
+		findings[filePath] = fileFindings
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rendered file findings: %w", err)
+	}
+
+	return findings, nil
+}
+
 func SetRenderedChartHelmTemplateStderr(ctx context.Context, renderedChartID string, helmTemplateStderr string) error {
 	conn := persistence.MustGetPooledPostgresSession()
 	defer conn.Release()
@@ -311,7 +453,7 @@ func EnqueueRenderWorkspaceForRevisionWithPendingContent(ctx context.Context, wo
 		zap.String("chatMessageID", chatMessageID),
 	)
 
-	return enqueueRenderWorkspaceForRevision(ctx, workspaceID, revisionNumber, chatMessageID, true)
+	return enqueueRenderWorkspaceForRevision(ctx, workspaceID, revisionNumber, chatMessageID, true, nil)
 }
 
 func EnqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string, revisionNumber int, chatMessageID string) error {
@@ -321,10 +463,30 @@ func EnqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string,
 		zap.String("chatMessageID", chatMessageID),
 	)
 
-	return enqueueRenderWorkspaceForRevision(ctx, workspaceID, revisionNumber, chatMessageID, false)
+	return enqueueRenderWorkspaceForRevision(ctx, workspaceID, revisionNumber, chatMessageID, false, nil)
 }
 
-func enqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string, revisionNumber int, chatMessageID string, usePendingContent bool) error {
+// EnqueueRenderWorkspaceForRevisionWithValuesProfiles renders each chart once with the values of each of the
+// named values profiles. Every chart and profile is its own rendered chart in the render job
+func EnqueueRenderWorkspaceForRevisionWithValuesProfiles(ctx context.Context, workspaceID string, revisionNumber int, chatMessageID string, valuesProfiles []string) error {
+	logger.Info("EnqueueRenderWorkspaceForRevisionWithValuesProfiles",
+		zap.String("workspaceID", workspaceID),
+		zap.Int("revisionNumber", revisionNumber),
+		zap.String("chatMessageID", chatMessageID),
+		zap.Strings("valuesProfiles", valuesProfiles),
+	)
+
+	return enqueueRenderWorkspaceForRevision(ctx, workspaceID, revisionNumber, chatMessageID, false, valuesProfiles)
+}
+
+// enqueueRenderWorkspaceForRevision creates a render job for the revision. With no valuesProfiles, each chart is
+// rendered with its own values
+func enqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string, revisionNumber int, chatMessageID string, usePendingContent bool, valuesProfiles []string) error {
+	valuesProfiles = normalizeValuesProfiles(valuesProfiles)
+	if err := checkValuesProfilesExist(ctx, workspaceID, valuesProfiles); err != nil {
+		return fmt.Errorf("failed to check values profiles: %w", err)
+	}
+
 	// Get workspace to retrieve charts
 	w, err := GetWorkspace(ctx, workspaceID)
 	if err != nil {
@@ -355,11 +517,20 @@ func enqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string,
 		}
 	}
 
-	// Check if there's already a render job in progress for this revision
-	query := `SELECT COUNT(*) FROM workspace_rendered
-	         WHERE workspace_id = $1 AND revision_number = $2 AND completed_at IS NULL`
+	dedupeKey := renderWorkspaceDedupeKey(workspaceID, revisionNumber, valuesProfiles, usePendingContent)
+
+	// Check if there's already a render job in progress for this revision, profiles and content. Renders
+	// that haven't been picked up by a worker yet don't count, they are replaced by this one
+	query := `SELECT COUNT(*) FROM workspace_rendered wr
+	         WHERE wr.workspace_id = $1 AND wr.revision_number = $2 AND wr.completed_at IS NULL
+	         AND coalesce(wr.values_profiles, '{}') = $4 AND wr.is_autorender = $5
+	         AND NOT EXISTS (
+	             SELECT 1 FROM work_queue wq
+	             WHERE wq.channel = 'render_workspace' AND wq.dedupe_key = $3 AND wq.payload->>'id' = wr.id
+	             AND wq.processing_started_at IS NULL AND wq.completed_at IS NULL
+	         )`
 	var count int
-	err = conn.QueryRow(ctx, query, workspaceID, revisionNumber).Scan(&count)
+	err = conn.QueryRow(ctx, query, workspaceID, revisionNumber, dedupeKey, valuesProfiles, usePendingContent).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check for existing render jobs: %w", err)
 	}
@@ -383,22 +554,33 @@ func enqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string,
 	}
 	defer tx.Rollback(ctx)
 
-	query = `INSERT INTO workspace_rendered (id, workspace_id, revision_number, created_at, is_autorender) VALUES ($1, $2, $3, now(), $4)`
-	_, err = tx.Exec(ctx, query, id, workspaceID, revisionNumber, usePendingContent)
+	query = `INSERT INTO workspace_rendered (id, workspace_id, revision_number, created_at, is_autorender, values_profiles) VALUES ($1, $2, $3, now(), $4, $5)`
+	_, err = tx.Exec(ctx, query, id, workspaceID, revisionNumber, usePendingContent, valuesProfiles)
 	if err != nil {
 		return fmt.Errorf("failed to enqueue render workspace: %w", err)
 	}
 
-	for _, chart := range w.Charts {
-		renderedChartID, err := securerandom.Hex(6)
-		if err != nil {
-			return fmt.Errorf("failed to generate rendered chart id: %w", err)
+	// a nil profile renders the chart with its own values
+	chartProfiles := []*string{nil}
+	if len(valuesProfiles) > 0 {
+		chartProfiles = []*string{}
+		for i := range valuesProfiles {
+			chartProfiles = append(chartProfiles, &valuesProfiles[i])
 		}
+	}
 
-		query := `INSERT INTO workspace_rendered_chart (id, workspace_render_id, chart_id, is_success, created_at) VALUES ($1, $2, $3, $4, now())`
-		_, err = tx.Exec(ctx, query, renderedChartID, id, chart.ID, false)
-		if err != nil {
-			return fmt.Errorf("failed to enqueue render workspace: %w", err)
+	for _, chart := range w.Charts {
+		for _, valuesProfile := range chartProfiles {
+			renderedChartID, err := securerandom.Hex(6)
+			if err != nil {
+				return fmt.Errorf("failed to generate rendered chart id: %w", err)
+			}
+
+			query := `INSERT INTO workspace_rendered_chart (id, workspace_render_id, chart_id, values_profile, is_success, created_at) VALUES ($1, $2, $3, $4, $5, now())`
+			_, err = tx.Exec(ctx, query, renderedChartID, id, chart.ID, valuesProfile, false)
+			if err != nil {
+				return fmt.Errorf("failed to enqueue render workspace: %w", err)
+			}
 		}
 	}
 
@@ -415,16 +597,86 @@ func enqueueRenderWorkspaceForRevision(ctx context.Context, workspaceID string,
 		}
 	}
 
-	if err := persistence.EnqueueWork(ctx, "render_workspace", map[string]interface{}{
+	return enqueueRenderJob(ctx, id, usePendingContent, dedupeKey)
+}
+
+// enqueueRenderJob queues the render_workspace job for a render, replacing pending jobs with the same dedupe key
+func enqueueRenderJob(ctx context.Context, id string, usePendingContent bool, dedupeKey string) error {
+	replaced, err := persistence.EnqueueWorkWithDedupeKey(ctx, "render_workspace", map[string]interface{}{
 		"id":                id,
 		"usePendingContent": usePendingContent,
-	}); err != nil {
+	}, dedupeKey)
+	if err != nil {
 		return fmt.Errorf("failed to enqueue render workspace: %w", err)
 	}
 
+	// the renders that were replaced will never run, this render takes their place
+	for _, payload := range replaced {
+		var p struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			logger.Error(fmt.Errorf("failed to unmarshal replaced render payload: %w", err))
+			continue
+		}
+		if p.ID == "" {
+			continue
+		}
+		if err := supersedeRendered(ctx, p.ID, id); err != nil {
+			logger.Error(fmt.Errorf("failed to supersede replaced render %s: %w", p.ID, err))
+		}
+	}
+
+	return nil
+}
+
+// supersedeRendered points the chat messages that are waiting for a render that never ran at the
+// render that replaced it, and removes the render so that it isn't listed as pending or failed
+func supersedeRendered(ctx context.Context, id string, supersededByID string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE workspace_chat SET response_render_id = $2 WHERE response_render_id = $1`
+	if _, err := tx.Exec(ctx, query, id, supersededByID); err != nil {
+		return fmt.Errorf("failed to update chat messages: %w", err)
+	}
+
+	query = `DELETE FROM workspace_rendered_chart WHERE workspace_render_id = $1`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete rendered charts: %w", err)
+	}
+
+	query = `DELETE FROM workspace_rendered WHERE id = $1`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete rendered: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// renderWorkspaceDedupeKey is the key that pending render_workspace jobs are deduplicated by. Renders
+// with different values profiles, or of the pending content and of the saved revision, don't replace each other
+func renderWorkspaceDedupeKey(workspaceID string, revisionNumber int, valuesProfiles []string, usePendingContent bool) string {
+	content := "revision"
+	if usePendingContent {
+		content = "pending"
+	}
+	if len(valuesProfiles) == 0 {
+		return fmt.Sprintf("%s/%d/%s", workspaceID, revisionNumber, content)
+	}
+	return fmt.Sprintf("%s/%d/%s/%s", workspaceID, revisionNumber, content, strings.Join(valuesProfiles, ","))
+}
+
 func EnqueueRenderWorkspace(ctx context.Context, workspaceID string, chatMessageID string) error {
 	w, err := GetWorkspace(ctx, workspaceID)
 	if err != nil {
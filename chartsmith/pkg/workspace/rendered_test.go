@@ -0,0 +1,171 @@
+package workspace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/testhelpers"
+)
+
+// renderedSchema creates the columns of the render and chat tables that enqueueing a render uses
+const renderedSchema = `
+CREATE TABLE workspace_rendered (
+	id text NOT NULL PRIMARY KEY,
+	workspace_id text NOT NULL,
+	revision_number integer NOT NULL,
+	created_at timestamp NOT NULL,
+	completed_at timestamp,
+	is_autorender boolean NOT NULL DEFAULT false,
+	error_message text,
+	values_profiles text[]
+);
+
+CREATE TABLE workspace_rendered_chart (
+	id text NOT NULL PRIMARY KEY,
+	workspace_render_id text NOT NULL,
+	chart_id text NOT NULL,
+	is_success boolean NOT NULL,
+	helm_template_stderr text,
+	created_at timestamp NOT NULL,
+	completed_at timestamp,
+	values_profile text
+);
+
+CREATE TABLE workspace_chat (
+	id text NOT NULL PRIMARY KEY,
+	workspace_id text NOT NULL,
+	revision_number integer NOT NULL,
+	created_at timestamp NOT NULL,
+	sent_by text NOT NULL,
+	prompt text NOT NULL,
+	response_render_id text
+);
+`
+
+func TestRenderWorkspaceDedupeKey(t *testing.T) {
+	tests := []struct {
+		name              string
+		workspaceID       string
+		revisionNumber    int
+		valuesProfiles    []string
+		usePendingContent bool
+		want              string
+	}{
+		{name: "default values", workspaceID: "ws1", revisionNumber: 3, want: "ws1/3/revision"},
+		{name: "empty profiles", workspaceID: "ws1", revisionNumber: 3, valuesProfiles: []string{}, want: "ws1/3/revision"},
+		{name: "one profile", workspaceID: "ws1", revisionNumber: 3, valuesProfiles: []string{"prod"}, want: "ws1/3/revision/prod"},
+		{name: "several profiles", workspaceID: "ws1", revisionNumber: 3, valuesProfiles: []string{"prod", "staging"}, want: "ws1/3/revision/prod,staging"},
+		{name: "other revision", workspaceID: "ws1", revisionNumber: 4, want: "ws1/4/revision"},
+		{name: "pending content", workspaceID: "ws1", revisionNumber: 3, usePendingContent: true, want: "ws1/3/pending"},
+		{name: "pending content with profile", workspaceID: "ws1", revisionNumber: 3, valuesProfiles: []string{"prod"}, usePendingContent: true, want: "ws1/3/pending/prod"},
+		{name: "profile named pending", workspaceID: "ws1", revisionNumber: 3, valuesProfiles: []string{"pending"}, want: "ws1/3/revision/pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderWorkspaceDedupeKey(tt.workspaceID, tt.revisionNumber, tt.valuesProfiles, tt.usePendingContent); got != tt.want {
+				t.Errorf("renderWorkspaceDedupeKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnqueueRenderJob(t *testing.T) {
+	pgURI := testhelpers.StartPostgres(t, testhelpers.WorkQueueSchema, renderedSchema)
+	if err := persistence.InitPostgres(persistence.PostgresOpts{URI: pgURI}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	// createRender creates a render of ws1 revision 1 with one chart, requested by a chat message
+	createRender := func(id string, chatMessageID string, usePendingContent bool) {
+		t.Helper()
+		if _, err := conn.Exec(ctx, `INSERT INTO workspace_rendered (id, workspace_id, revision_number, created_at, is_autorender) VALUES ($1, 'ws1', 1, NOW(), $2)`, id, usePendingContent); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Exec(ctx, `INSERT INTO workspace_rendered_chart (id, workspace_render_id, chart_id, is_success, created_at) VALUES ($1, $2, 'chart1', false, NOW())`, id+"-chart", id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Exec(ctx, `INSERT INTO workspace_chat (id, workspace_id, revision_number, created_at, sent_by, prompt, response_render_id) VALUES ($1, 'ws1', 1, NOW(), 'user1', 'render it', $2)`, chatMessageID, id); err != nil {
+			t.Fatal(err)
+		}
+		if err := enqueueRenderJob(ctx, id, usePendingContent, renderWorkspaceDedupeKey("ws1", 1, nil, usePendingContent)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createRender("render1", "chat1", false)
+	createRender("pending1", "chat2", true)
+	createRender("render2", "chat3", false)
+
+	// render2 replaced render1, the render of the pending content has its own key
+	var pendingJobs []string
+	rows, err := conn.Query(ctx, `SELECT payload->>'id' FROM work_queue WHERE channel = 'render_workspace' AND completed_at IS NULL ORDER BY created_at`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		pendingJobs = append(pendingJobs, id)
+	}
+	rows.Close()
+	if len(pendingJobs) != 2 || pendingJobs[0] != "pending1" || pendingJobs[1] != "render2" {
+		t.Errorf("pending render jobs = %v, want [pending1 render2]", pendingJobs)
+	}
+
+	var lastError string
+	if err := conn.QueryRow(ctx, `SELECT last_error FROM work_queue WHERE payload->>'id' = 'render1'`).Scan(&lastError); err != nil {
+		t.Fatal(err)
+	}
+	if lastError == "" {
+		t.Error("replaced job has no last error")
+	}
+
+	// the chat message that asked for render1 now shows render2
+	chatRenders := map[string]string{}
+	rows, err = conn.Query(ctx, `SELECT id, response_render_id FROM workspace_chat`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id, renderID string
+		if err := rows.Scan(&id, &renderID); err != nil {
+			t.Fatal(err)
+		}
+		chatRenders[id] = renderID
+	}
+	rows.Close()
+	wantChatRenders := map[string]string{"chat1": "render2", "chat2": "pending1", "chat3": "render2"}
+	for id, want := range wantChatRenders {
+		if chatRenders[id] != want {
+			t.Errorf("chat %s response render = %q, want %q", id, chatRenders[id], want)
+		}
+	}
+
+	// render1 never ran, it's gone rather than left pending or failed
+	var renders, charts int
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM workspace_rendered WHERE id = 'render1'`).Scan(&renders); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM workspace_rendered_chart WHERE workspace_render_id = 'render1'`).Scan(&charts); err != nil {
+		t.Fatal(err)
+	}
+	if renders != 0 || charts != 0 {
+		t.Errorf("superseded render has %d rows and %d chart rows, want none", renders, charts)
+	}
+
+	var failed int
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM workspace_rendered WHERE error_message IS NOT NULL OR completed_at IS NOT NULL`).Scan(&failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed != 0 {
+		t.Errorf("got %d completed or failed renders, want 0", failed)
+	}
+}
@@ -12,6 +12,10 @@ type File struct {
 	FilePath       string  `json:"filePath"`
 	Content        string  `json:"content"`
 	ContentPending *string `json:"content_pending,omitempty"`
+	// PendingDelete and FilePathPending are a delete and a rename made by a plan, which are applied
+	// when the change to the file is accepted and dropped when it's rejected
+	PendingDelete   bool    `json:"pending_delete,omitempty"`
+	FilePathPending *string `json:"file_path_pending,omitempty"`
 }
 
 type Chart struct {
@@ -34,6 +38,10 @@ type Workspace struct {
 	CreatedAt     time.Time `json:"created_at"`
 	LastUpdatedAt time.Time `json:"last_updated_at"`
 	Name          string    `json:"name"`
+	// KubernetesVersion is the version rendered manifests are validated against, e.g. 1.29. Empty for the default
+	KubernetesVersion string `json:"kubernetes_version,omitempty"`
+	// DisabledPolicyRules are the policy rules that aren't run over the rendered manifests
+	DisabledPolicyRules []string `json:"disabled_policy_rules,omitempty"`
 
 	CurrentRevision          int  `json:"current_revision"`
 	IncompleteRevisionNumber *int `json:"incomplete_revision_number,omitempty"`
@@ -58,11 +66,14 @@ type Revision struct {
 type PlanStatus string
 
 const (
-	PlanStatusPending  PlanStatus = "pending"
-	PlanStatusPlanning PlanStatus = "planning"
-	PlanStatusReview   PlanStatus = "review"
-	PlanStatusApplying PlanStatus = "applying"
-	PlanStatusApplied  PlanStatus = "applied"
+	PlanStatusPending   PlanStatus = "pending"
+	PlanStatusPlanning  PlanStatus = "planning"
+	PlanStatusReview    PlanStatus = "review"
+	PlanStatusApplying  PlanStatus = "applying"
+	PlanStatusApplied   PlanStatus = "applied"
+	PlanStatusCancelled PlanStatus = "cancelled"
+	// PlanStatusQuotaExceeded is a plan that wasn't created because an LLM quota was reached
+	PlanStatusQuotaExceeded PlanStatus = "quota_exceeded"
 )
 
 type Plan struct {
@@ -78,10 +89,19 @@ type Plan struct {
 	ProceedAt      *time.Time   `json:"proceedAt"`
 }
 
+const (
+	ActionFileActionCreate = "create"
+	ActionFileActionUpdate = "update"
+	ActionFileActionDelete = "delete"
+	ActionFileActionRename = "rename"
+)
+
 type ActionFile struct {
-	Action string `json:"action"`
-	Path   string `json:"path"`
-	Status string `json:"status"`
+	Action      string `json:"action"`
+	Path        string `json:"path"`
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+	NewPath     string `json:"newPath,omitempty"`
 }
 
 type ChatMessageFromPersona string
@@ -98,6 +118,7 @@ type Chat struct {
 	Prompt                           string                  `json:"prompt"`
 	Response                         string                  `json:"response"`
 	CreatedAt                        time.Time               `json:"createdAt"`
+	SentByUserID                     string                  `json:"-"`
 	IsIntentComplete                 bool                    `json:"isIntentComplete"`
 	Intent                           *Intent                 `json:"0"`
 	FollowupActions                  []FollowupAction        `json:"followupActions"`
@@ -107,6 +128,8 @@ type Chat struct {
 	ResponseRollbackToRevisionNumber *int                    `json:"responseRollbackToRevisionNumber"`
 	RevisionNumber                   int                     `json:"revisionNumber"`
 	MessageFromPersona               *ChatMessageFromPersona `json:"messageFromPersona"`
+	// ResponseModel is the model that generated the response, which can be a fallback for the one requested
+	ResponseModel string `json:"responseModel,omitempty"`
 }
 
 type FollowupAction struct {
@@ -134,11 +157,23 @@ type Rendered struct {
 	Charts         []RenderedChart `json:"charts"`
 }
 
+// ValuesProfile is a named set of values that the charts in a workspace can be rendered with,
+// e.g. the values of a production or minimal install
+type ValuesProfile struct {
+	WorkspaceID string    `json:"-"`
+	Name        string    `json:"name"`
+	ValuesYAML  string    `json:"valuesYaml"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
 type RenderedChart struct {
 	ID          string `json:"id"`
 	WorkspaceID string `json:"-"`
 	ChartID     string `json:"-"`
 	Name        string `json:"name"`
+	// ValuesProfile is the values profile the chart was rendered with, empty for the chart's own values
+	ValuesProfile string `json:"valuesProfile,omitempty"`
 
 	IsSuccess bool `json:"isSuccess"`
 
@@ -146,6 +181,12 @@ type RenderedChart struct {
 	DepupdateStdout  string `json:"depupdateStdout,omitempty"`
 	DepupdateStderr  string `json:"depupdateStderr,omitempty"`
 
+	HelmLintCommand string `json:"helmLintCommand,omitempty"`
+	HelmLintStdout  string `json:"helmLintStdout,omitempty"`
+	HelmLintStderr  string `json:"helmLintStderr,omitempty"`
+	// HelmLintFindings are the messages of helm lint
+	HelmLintFindings []LintFinding `json:"helmLintFindings,omitempty"`
+
 	HelmTemplateCommand string `json:"helmTemplateCommand,omitempty"`
 	HelmTemplateStdout  string `json:"helmTemplateStdout,omitempty"`
 	HelmTemplateStderr  string `json:"helmTemplateStderr,omitempty"`
@@ -161,6 +202,41 @@ type RenderedFile struct {
 	WorkspaceID     string `json:"-"`
 	FilePath        string `json:"filePath"`
 	RenderedContent string `json:"renderedContent"`
+	// Findings are the problems found in the rendered manifests of the file
+	Findings []RenderFinding `json:"findings,omitempty"`
+}
+
+const (
+	FindingSeverityError   = "error"
+	FindingSeverityWarning = "warning"
+	FindingSeverityInfo    = "info"
+
+	// FindingSourceSchema findings are manifests that don't match the schema of their kind
+	FindingSourceSchema = "schema"
+	// FindingSourcePolicy findings are manifests that are valid but don't follow a best practice
+	FindingSourcePolicy = "policy"
+)
+
+// LintFinding is a message of helm lint about a file of the chart
+type LintFinding struct {
+	Severity string `json:"severity"`
+	// FilePath is relative to the root of the chart, and empty for messages about the chart as a whole
+	FilePath string `json:"filePath,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// RenderFinding is a problem found in one of the manifests rendered from a file
+type RenderFinding struct {
+	Source   string `json:"source"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	// Kind and Name identify the manifest in the file
+	Kind    string `json:"kind,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
 }
 
 type ConversionStatus string
@@ -174,6 +250,9 @@ const (
 	ConversionStatusSimplifying ConversionStatus = "simplifying"
 	ConversionStatusFinalizing  ConversionStatus = "finalizing"
 	ConversionStatusComplete    ConversionStatus = "complete"
+	ConversionStatusCancelled   ConversionStatus = "cancelled"
+	// ConversionStatusQuotaExceeded is a conversion that stopped because an LLM quota was reached
+	ConversionStatusQuotaExceeded ConversionStatus = "quota_exceeded"
 )
 
 type Conversion struct {
@@ -0,0 +1,97 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/replicatedhq/chartsmith/pkg/persistence"
+	"github.com/replicatedhq/chartsmith/pkg/workspace/types"
+)
+
+func ListValuesProfiles(ctx context.Context, workspaceID string) ([]types.ValuesProfile, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT workspace_id, name, values_yaml, created_at, updated_at FROM workspace_values_profile WHERE workspace_id = $1 ORDER BY name`
+	rows, err := conn.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list values profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []types.ValuesProfile{}
+	for rows.Next() {
+		var profile types.ValuesProfile
+		if err := rows.Scan(&profile.WorkspaceID, &profile.Name, &profile.ValuesYAML, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan values profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate values profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// GetValuesProfile returns the values profile called name, or nil if the workspace doesn't have one
+func GetValuesProfile(ctx context.Context, workspaceID string, name string) (*types.ValuesProfile, error) {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `SELECT workspace_id, name, values_yaml, created_at, updated_at FROM workspace_values_profile WHERE workspace_id = $1 AND name = $2`
+	var profile types.ValuesProfile
+	err := conn.QueryRow(ctx, query, workspaceID, name).Scan(&profile.WorkspaceID, &profile.Name, &profile.ValuesYAML, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get values profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+// normalizeValuesProfiles removes duplicates from the names of values profiles and sorts them, so that
+// renders of the same profiles are recognized as the same render
+func normalizeValuesProfiles(names []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// checkValuesProfilesExist returns an error naming the first profile that the workspace doesn't have
+func checkValuesProfilesExist(ctx context.Context, workspaceID string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	profiles, err := ListValuesProfiles(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, profile := range profiles {
+		existing[profile.Name] = true
+	}
+
+	for _, name := range names {
+		if !existing[name] {
+			return fmt.Errorf("values profile %q not found in workspace %s", name, workspaceID)
+		}
+	}
+
+	return nil
+}
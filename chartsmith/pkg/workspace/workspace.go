@@ -48,6 +48,33 @@ func SetChatMessageResponse(ctx context.Context, chatMessageID string, response
 	return nil
 }
 
+// SetChatMessageResponseModel records the model that generated the response to a chat message
+func SetChatMessageResponseModel(ctx context.Context, chatMessageID string, model string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_chat SET response_model = $1 WHERE id = $2`
+	_, err := conn.Exec(ctx, query, model, chatMessageID)
+	if err != nil {
+		return fmt.Errorf("error updating chat message response model: %w", err)
+	}
+	return nil
+}
+
+// SetChatMessagePromptVersion records the version of the prompts used for stage while responding to a chat message
+func SetChatMessagePromptVersion(ctx context.Context, chatMessageID string, stage string, version string) error {
+	conn := persistence.MustGetPooledPostgresSession()
+	defer conn.Release()
+
+	query := `UPDATE workspace_chat SET prompt_versions = coalesce(prompt_versions, '{}'::jsonb) || jsonb_build_object($2::text, $3::text)
+		WHERE id = $1 AND prompt_versions->>$2 IS DISTINCT FROM $3`
+	_, err := conn.Exec(ctx, query, chatMessageID, stage, version)
+	if err != nil {
+		return fmt.Errorf("error updating chat message prompt versions: %w", err)
+	}
+	return nil
+}
+
 func AppendChatMessageResponse(ctx context.Context, chatMessageID string, response string) error {
 	conn := persistence.MustGetPooledPostgresSession()
 	defer conn.Release()
@@ -69,7 +96,9 @@ func GetWorkspace(ctx context.Context, id string) (*types.Workspace, error) {
 		workspace.created_at,
 		workspace.last_updated_at,
 		workspace.name,
-		workspace.current_revision_number
+		workspace.current_revision_number,
+		workspace.kubernetes_version,
+		workspace.disabled_policy_rules
 	FROM
 		workspace
 	WHERE
@@ -77,17 +106,21 @@ func GetWorkspace(ctx context.Context, id string) (*types.Workspace, error) {
 
 	row := conn.QueryRow(ctx, query, id)
 	var workspace types.Workspace
+	var kubernetesVersion sql.NullString
 	err := row.Scan(
 		&workspace.ID,
 		&workspace.CreatedAt,
 		&workspace.LastUpdatedAt,
 		&workspace.Name,
 		&workspace.CurrentRevision,
+		&kubernetesVersion,
+		&workspace.DisabledPolicyRules,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("error scanning workspace: %w", err)
 	}
+	workspace.KubernetesVersion = kubernetesVersion.String
 
 	charts, err := listChartsForWorkspace(ctx, id, workspace.CurrentRevision)
 	if err != nil {
@@ -226,7 +259,9 @@ func listFilesForChart(ctx context.Context, chartID string, revisionNumber int)
 		workspace_id,
 		file_path,
 		content,
-		content_pending
+		content_pending,
+		pending_delete,
+		file_path_pending
 	FROM
 		workspace_file
 	WHERE
@@ -245,6 +280,7 @@ func listFilesForChart(ctx context.Context, chartID string, revisionNumber int)
 		var file types.File
 		var chartID sql.NullString
 		var contentPending sql.NullString
+		var filePathPending sql.NullString
 
 		err := rows.Scan(
 			&file.ID,
@@ -254,6 +290,8 @@ func listFilesForChart(ctx context.Context, chartID string, revisionNumber int)
 			&file.FilePath,
 			&file.Content,
 			&contentPending,
+			&file.PendingDelete,
+			&filePathPending,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning file: %w", err)
@@ -265,6 +303,9 @@ func listFilesForChart(ctx context.Context, chartID string, revisionNumber int)
 		} else {
 			file.ContentPending = nil
 		}
+		if filePathPending.Valid {
+			file.FilePathPending = &filePathPending.String
+		}
 		files = append(files, file)
 	}
 	rows.Close()
@@ -283,7 +324,9 @@ func listFilesWithoutChartsForWorkspace(ctx context.Context, workspaceID string,
 		workspace_id,
 		file_path,
 		content,
-		content_pending
+		content_pending,
+		pending_delete,
+		file_path_pending
 	FROM
 		workspace_file
 	WHERE
@@ -303,6 +346,7 @@ func listFilesWithoutChartsForWorkspace(ctx context.Context, workspaceID string,
 		var file types.File
 		var chartID sql.NullString
 		var contentPending sql.NullString
+		var filePathPending sql.NullString
 
 		err := rows.Scan(
 			&file.ID,
@@ -312,6 +356,8 @@ func listFilesWithoutChartsForWorkspace(ctx context.Context, workspaceID string,
 			&file.FilePath,
 			&file.Content,
 			&contentPending,
+			&file.PendingDelete,
+			&filePathPending,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning file: %w", err)
@@ -320,6 +366,9 @@ func listFilesWithoutChartsForWorkspace(ctx context.Context, workspaceID string,
 		if contentPending.Valid {
 			file.ContentPending = &contentPending.String
 		}
+		if filePathPending.Valid {
+			file.FilePathPending = &filePathPending.String
+		}
 		files = append(files, file)
 	}
 
@@ -429,7 +478,8 @@ func NotifyWorkerToCaptureEmbeddings(ctx context.Context, workspaceID string, re
 			"revision": file.RevisionNumber,
 		}
 
-		if err := persistence.EnqueueWork(ctx, "new_summarize", p); err != nil {
+		dedupeKey := fmt.Sprintf("%s/%d", file.ID, file.RevisionNumber)
+		if _, err := persistence.EnqueueWorkWithDedupeKey(ctx, "new_summarize", p, dedupeKey); err != nil {
 			return fmt.Errorf("error enqueuing work: %w", err)
 		}
 	}